@@ -0,0 +1,164 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/royalrick/wechatwriter/app/archive"
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// archiveCmd 已发布文章归档命令组
+func archiveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "archive",
+		Short: "已发布文章本地归档",
+		Long: `已发布文章本地归档
+
+从发布接口（freepublish/batchget）拉取已发布的文章，保存元数据和原始 HTML，
+并转换为 Markdown，作为独立于公众号后台的备份，支持版本历史和全文搜索。
+
+默认归档目录为 <data_dir>/accounts/<account>/archive，可用 --dir 指定。
+
+支持的操作：
+  sync  - 同步已发布文章到本地归档
+  list  - 列出或搜索归档文章`,
+	}
+
+	cmd.AddCommand(archiveSyncCmd())
+	cmd.AddCommand(archiveListCmd())
+
+	return cmd
+}
+
+// archiveSyncCmd 同步已发布文章
+func archiveSyncCmd() *cobra.Command {
+	var (
+		accountID string
+		dir       string
+		full      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "同步已发布文章到本地归档",
+		Long: `同步已发布文章到本地归档
+
+按更新时间倒序分页拉取已发布文章（每页 20 条）：
+  - 新文章保存 HTML 和 Markdown
+  - 内容变化的文章将旧版本移入 history/<update_time>/ 后保存新版本
+  - 内容未变化的文章只更新元数据
+
+默认遇到整页文章都未变化时停止（增量同步），--full 遍历全部已发布文章。
+在公众号后台删除的文章仍保留本地归档，并在索引中标记 is_deleted。
+
+示例：
+  writer archive sync --account my-account
+  writer archive sync --full --dir ./backup`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			account, err := selectAccount(accountID)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			store, err := archive.Open(archiveDir(account.ID, dir))
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			ws := wechat.NewService(account, log)
+			result, err := archive.Sync(ws, store, archive.SyncOptions{Full: full}, log)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			log.Info("archive synced",
+				zap.String("account", account.ID),
+				zap.Int("new", result.New),
+				zap.Int("updated", result.Updated))
+			responseSuccess(map[string]any{
+				"account_id": account.ID,
+				"result":     result,
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().StringVar(&dir, "dir", "", "归档目录（默认 <data_dir>/accounts/<account>/archive）")
+	cmd.Flags().BoolVar(&full, "full", false, "遍历全部已发布文章（默认增量同步）")
+
+	return cmd
+}
+
+// archiveListCmd 列出或搜索归档文章
+func archiveListCmd() *cobra.Command {
+	var (
+		accountID string
+		dir       string
+		query     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "列出或搜索归档文章",
+		Long: `列出或搜索归档文章
+
+--query 按关键词搜索标题、作者、摘要和 Markdown 正文（不区分大小写）。
+返回的 markdown / html 为相对归档目录的路径。
+
+示例：
+  writer archive list
+  writer archive list --query "增长"`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			account, err := selectAccount(accountID)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			store, err := archive.Open(archiveDir(account.ID, dir))
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			entries, err := store.Search(query)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			responseSuccess(map[string]any{
+				"dir":      store.Dir(),
+				"articles": entries,
+				"total":    len(entries),
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().StringVar(&dir, "dir", "", "归档目录（默认 <data_dir>/accounts/<account>/archive）")
+	cmd.Flags().StringVarP(&query, "query", "q", "", "搜索关键词")
+
+	return cmd
+}
+
+// archiveDir 归档目录：--dir 优先，否则为账号数据目录下的 archive
+func archiveDir(accountID, dir string) string {
+	if dir != "" {
+		return dir
+	}
+	return filepath.Join(cfg.AccountDataDir(accountID), "archive")
+}
@@ -0,0 +1,338 @@
+package archive
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// tagPattern 匹配 HTML 标签、注释和 doctype
+var tagPattern = regexp.MustCompile(`(?s)<!--.*?-->|<!\w[^>]*>|</?([a-zA-Z][a-zA-Z0-9]*)((?:\s+[^\s=/>]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s>]+))?)*)\s*(/?)>`)
+
+// attrPattern 匹配标签属性
+var attrPattern = regexp.MustCompile(`([^\s=/>]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+)))?`)
+
+// spacePattern 连续空白
+var spacePattern = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+// blankLinesPattern 三个及以上连续换行
+var blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+
+// blockTags 块级标签，前后需要空行
+var blockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "header": true, "footer": true,
+	"figure": true, "figcaption": true, "table": true, "center": true,
+}
+
+// skipTags 内容直接丢弃的标签
+var skipTags = map[string]bool{"script": true, "style": true, "head": true, "title": true, "noscript": true}
+
+// listState 列表状态
+type listState struct {
+	ordered bool
+	n       int
+}
+
+// frame 需要收集子内容后再输出的元素（标题、链接、引用）
+type frame struct {
+	tag  string
+	href string
+	buf  strings.Builder
+}
+
+// mdWriter HTML → Markdown 转换状态
+type mdWriter struct {
+	root   strings.Builder
+	frames []*frame
+	lists  []listState
+	pre    int
+	skip   int
+	cell   int
+}
+
+// HTMLToMarkdown 将公众号文章 HTML 转换为 Markdown
+// 只处理常见排版元素（标题、段落、强调、链接、图片、列表、引用、代码、分隔线、表格），
+// 内联样式会被丢弃；图片优先使用公众号懒加载的 data-src
+func HTMLToMarkdown(src string) string {
+	w := &mdWriter{}
+
+	pos := 0
+	for _, m := range tagPattern.FindAllStringSubmatchIndex(src, -1) {
+		w.text(src[pos:m[0]])
+		pos = m[1]
+
+		if m[2] < 0 {
+			continue // 注释、doctype
+		}
+		name := strings.ToLower(src[m[2]:m[3]])
+		closing := src[m[0]+1] == '/'
+		selfClosing := m[6] < m[7]
+		if closing {
+			w.end(name)
+			continue
+		}
+		w.start(name, parseAttrs(src[m[4]:m[5]]))
+		if selfClosing {
+			w.end(name)
+		}
+	}
+	w.text(src[pos:])
+
+	// 未闭合的元素按闭合处理
+	for len(w.frames) > 0 {
+		w.end(w.frames[len(w.frames)-1].tag)
+	}
+
+	// 空的强调标记（公众号排版中常见的空 <strong></strong>）
+	out := strings.ReplaceAll(w.root.String(), "****", "")
+	lines := strings.Split(out, "\n")
+	for i, l := range lines {
+		if strings.HasSuffix(l, "  ") && strings.TrimSpace(l) != "" {
+			// 保留 Markdown 硬换行
+			lines[i] = strings.TrimRight(l, " ") + "  "
+			continue
+		}
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	out = blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(out) + "\n"
+}
+
+// parseAttrs 解析标签属性
+func parseAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attrPattern.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+	}
+	return attrs
+}
+
+// buf 当前输出位置
+func (w *mdWriter) buf() *strings.Builder {
+	if n := len(w.frames); n > 0 {
+		return &w.frames[n-1].buf
+	}
+	return &w.root
+}
+
+// write 写入文本
+func (w *mdWriter) write(s string) {
+	w.buf().WriteString(s)
+}
+
+// block 确保后续内容另起一段
+func (w *mdWriter) block() {
+	b := w.buf()
+	s := b.String()
+	if s == "" || strings.HasSuffix(s, "\n\n") {
+		return
+	}
+	if strings.HasSuffix(s, "\n") {
+		b.WriteString("\n")
+		return
+	}
+	b.WriteString("\n\n")
+}
+
+// newline 确保后续内容另起一行
+func (w *mdWriter) newline() {
+	s := w.buf().String()
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		w.write("\n")
+	}
+}
+
+// text 写入文本节点
+func (w *mdWriter) text(s string) {
+	if w.skip > 0 || s == "" {
+		return
+	}
+	s = html.UnescapeString(s)
+	if w.pre > 0 {
+		w.write(s)
+		return
+	}
+
+	s = spacePattern.ReplaceAllString(s, " ")
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+	// 行首不保留空格
+	cur := w.buf().String()
+	if cur == "" || strings.HasSuffix(cur, "\n") || strings.HasSuffix(cur, " ") {
+		s = strings.TrimLeft(s, " ")
+	}
+	w.write(s)
+}
+
+// start 处理开始标签
+func (w *mdWriter) start(name string, attrs map[string]string) {
+	if skipTags[name] {
+		w.skip++
+		return
+	}
+	if w.skip > 0 {
+		return
+	}
+
+	switch name {
+	case "h1", "h2", "h3", "h4", "h5", "h6", "blockquote":
+		w.block()
+		w.frames = append(w.frames, &frame{tag: name})
+	case "a":
+		w.frames = append(w.frames, &frame{tag: name, href: attrs["href"]})
+	case "br":
+		if w.pre > 0 {
+			w.write("\n")
+		} else {
+			w.write("  \n")
+		}
+	case "hr":
+		w.block()
+		w.write("---")
+		w.block()
+	case "strong", "b":
+		w.write("**")
+	case "em", "i":
+		w.write("*")
+	case "del", "s", "strike":
+		w.write("~~")
+	case "code":
+		if w.pre == 0 {
+			w.write("`")
+		}
+	case "pre":
+		w.block()
+		w.write("```\n")
+		w.pre++
+	case "img":
+		src := attrs["data-src"]
+		if src == "" {
+			src = attrs["src"]
+		}
+		if src != "" {
+			w.write(fmt.Sprintf("![%s](%s)", attrs["alt"], src))
+		}
+	case "ul", "ol":
+		if len(w.lists) == 0 {
+			w.block()
+		}
+		w.lists = append(w.lists, listState{ordered: name == "ol"})
+	case "li":
+		w.newline()
+		depth := len(w.lists)
+		if depth == 0 {
+			w.write("- ")
+			return
+		}
+		indent := strings.Repeat("  ", depth-1)
+		l := &w.lists[depth-1]
+		if l.ordered {
+			l.n++
+			w.write(fmt.Sprintf("%s%d. ", indent, l.n))
+		} else {
+			w.write(indent + "- ")
+		}
+	case "tr":
+		w.newline()
+		w.cell = 0
+	case "td", "th":
+		if w.cell > 0 {
+			w.write(" | ")
+		}
+		w.cell++
+	default:
+		if blockTags[name] {
+			w.block()
+		}
+	}
+}
+
+// end 处理结束标签
+func (w *mdWriter) end(name string) {
+	if skipTags[name] {
+		if w.skip > 0 {
+			w.skip--
+		}
+		return
+	}
+	if w.skip > 0 {
+		return
+	}
+
+	switch name {
+	case "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "a":
+		f := w.popFrame(name)
+		if f == nil {
+			return
+		}
+		content := f.buf.String()
+		switch name {
+		case "a":
+			text := strings.TrimSpace(content)
+			if f.href == "" || strings.HasPrefix(f.href, "javascript:") || text == "" {
+				w.write(text)
+			} else {
+				w.write(fmt.Sprintf("[%s](%s)", text, f.href))
+			}
+		case "blockquote":
+			var lines []string
+			for _, l := range strings.Split(strings.TrimSpace(content), "\n") {
+				lines = append(lines, strings.TrimRight("> "+l, " "))
+			}
+			w.write(strings.Join(lines, "\n"))
+			w.block()
+		default:
+			text := strings.TrimSpace(spacePattern.ReplaceAllString(content, " "))
+			if text != "" {
+				level := int(name[1] - '0')
+				w.write(strings.Repeat("#", level) + " " + text)
+			}
+			w.block()
+		}
+	case "strong", "b":
+		w.write("**")
+	case "em", "i":
+		w.write("*")
+	case "del", "s", "strike":
+		w.write("~~")
+	case "code":
+		if w.pre == 0 {
+			w.write("`")
+		}
+	case "pre":
+		if w.pre > 0 {
+			w.pre--
+		}
+		w.newline()
+		w.write("```")
+		w.block()
+	case "ul", "ol":
+		if n := len(w.lists); n > 0 {
+			w.lists = w.lists[:n-1]
+		}
+		if len(w.lists) == 0 {
+			w.block()
+		}
+	default:
+		if blockTags[name] {
+			w.block()
+		}
+	}
+}
+
+// popFrame 弹出最近的同名元素（忽略中间未闭合的元素）
+func (w *mdWriter) popFrame(tag string) *frame {
+	for i := len(w.frames) - 1; i >= 0; i-- {
+		if w.frames[i].tag != tag {
+			continue
+		}
+		// 中间未闭合的元素内容并入当前元素
+		f := w.frames[i]
+		for _, inner := range w.frames[i+1:] {
+			f.buf.WriteString(inner.buf.String())
+		}
+		w.frames = w.frames[:i]
+		return f
+	}
+	return nil
+}
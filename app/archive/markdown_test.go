@@ -0,0 +1,46 @@
+package archive
+
+import "testing"
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "heading and paragraph",
+			html: `<h2 style="color:red">标题</h2><p>第一段<strong>加粗</strong>和<em>斜体</em></p><p>第二段</p>`,
+			want: "## 标题\n\n第一段**加粗**和*斜体*\n\n第二段\n",
+		},
+		{
+			name: "lazy image and link",
+			html: `<p><img data-src="https://mmbiz.qpic.cn/a.png" src="data:image/gif;base64,xx" alt="图"></p><p><a href="https://example.com">链接</a></p>`,
+			want: "![图](https://mmbiz.qpic.cn/a.png)\n\n[链接](https://example.com)\n",
+		},
+		{
+			name: "lists",
+			html: `<ul><li>甲</li><li>乙</li></ul><ol><li>一</li><li>二</li></ol>`,
+			want: "- 甲\n- 乙\n\n1. 一\n2. 二\n",
+		},
+		{
+			name: "blockquote and code",
+			html: `<blockquote><p>引用</p></blockquote><pre><code>a := 1
+b := 2</code></pre>`,
+			want: "> 引用\n\n```\na := 1\nb := 2\n```\n",
+		},
+		{
+			name: "drop script and empty strong",
+			html: `<section><script>alert(1)</script><p>正文&nbsp;&amp;<strong></strong></p></section>`,
+			want: "正文 &\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTMLToMarkdown(tt.html); got != tt.want {
+				t.Errorf("HTMLToMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+// Package archive 已发布文章的本地归档
+//
+// 归档目录结构：
+//
+//	<dir>/index.json                    归档索引
+//	<dir>/<article_id>/<n>.html         第 n 篇文章的原始 HTML（n 从 1 开始）
+//	<dir>/<article_id>/<n>.md           转换后的 Markdown（带 front matter）
+//	<dir>/<article_id>/history/<time>/  内容变化前的旧版本
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"gopkg.in/yaml.v3"
+)
+
+// indexFile 归档索引文件名
+const indexFile = "index.json"
+
+// Entry 归档索引条目（一篇文章）
+type Entry struct {
+	ArticleID        string    `json:"article_id"`
+	Index            int       `json:"index"` // 在本次发布中的序号，从 1 开始
+	Title            string    `json:"title"`
+	Author           string    `json:"author,omitempty"`
+	Digest           string    `json:"digest,omitempty"`
+	URL              string    `json:"url,omitempty"`
+	ContentSourceURL string    `json:"content_source_url,omitempty"`
+	ThumbMediaID     string    `json:"thumb_media_id,omitempty"`
+	UpdateTime       int64     `json:"update_time"`
+	IsDeleted        bool      `json:"is_deleted,omitempty"`
+	Hash             string    `json:"hash"`     // HTML 内容的 SHA-256
+	Versions         int       `json:"versions"` // 已保存的版本数（含当前版本）
+	Markdown         string    `json:"markdown"` // Markdown 文件路径（相对归档目录）
+	HTML             string    `json:"html"`     // HTML 文件路径（相对归档目录）
+	SyncedAt         time.Time `json:"synced_at"`
+}
+
+// key 索引键
+func (e *Entry) key() string {
+	return entryKey(e.ArticleID, e.Index)
+}
+
+// entryKey 索引键
+func entryKey(articleID string, index int) string {
+	return articleID + "#" + strconv.Itoa(index)
+}
+
+// Store 归档目录
+type Store struct {
+	dir     string
+	entries map[string]*Entry
+}
+
+// Open 打开归档目录，索引不存在时返回空归档
+func Open(dir string) (*Store, error) {
+	s := &Store{dir: dir, entries: make(map[string]*Entry)}
+
+	data, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("read archive index: %w", err)
+	}
+
+	var list []*Entry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse archive index: %w", err)
+	}
+	for _, e := range list {
+		s.entries[e.key()] = e
+	}
+	return s, nil
+}
+
+// Dir 返回归档目录
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Get 查找归档条目
+func (s *Store) Get(articleID string, index int) (*Entry, bool) {
+	e, ok := s.entries[entryKey(articleID, index)]
+	return e, ok
+}
+
+// List 返回全部条目，按更新时间倒序
+func (s *Store) List() []*Entry {
+	list := make([]*Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].UpdateTime != list[j].UpdateTime {
+			return list[i].UpdateTime > list[j].UpdateTime
+		}
+		if list[i].ArticleID != list[j].ArticleID {
+			return list[i].ArticleID < list[j].ArticleID
+		}
+		return list[i].Index < list[j].Index
+	})
+	return list
+}
+
+// PutResult 保存结果
+type PutResult string
+
+const (
+	PutNew       PutResult = "new"       // 新归档
+	PutUpdated   PutResult = "updated"   // 内容变化，旧版本已移入 history
+	PutUnchanged PutResult = "unchanged" // 内容未变化
+)
+
+// Put 保存一篇文章；内容变化时旧版本移入 history 目录
+func (s *Store) Put(articleID string, index int, updateTime int64, a wechat.PublishedArticle) (PutResult, error) {
+	hash := hashContent(a.Content)
+	prev, exists := s.Get(articleID, index)
+
+	entry := &Entry{
+		ArticleID:        articleID,
+		Index:            index,
+		Title:            a.Title,
+		Author:           a.Author,
+		Digest:           a.Digest,
+		URL:              a.URL,
+		ContentSourceURL: a.ContentSourceURL,
+		ThumbMediaID:     a.ThumbMediaID,
+		UpdateTime:       updateTime,
+		IsDeleted:        a.IsDeleted,
+		Hash:             hash,
+		Versions:         1,
+		HTML:             filepath.ToSlash(filepath.Join(articleID, fmt.Sprintf("%d.html", index))),
+		Markdown:         filepath.ToSlash(filepath.Join(articleID, fmt.Sprintf("%d.md", index))),
+		SyncedAt:         time.Now(),
+	}
+
+	result := PutNew
+	if exists {
+		entry.Versions = prev.Versions
+		if prev.Hash == hash {
+			// 内容未变，只更新元数据（删除状态、标题等）
+			if prev.Title != entry.Title || prev.Author != entry.Author || prev.Digest != entry.Digest || prev.URL != entry.URL {
+				if err := os.WriteFile(filepath.Join(s.dir, entry.Markdown), []byte(renderMarkdown(entry, a.Content)), 0644); err != nil {
+					return "", fmt.Errorf("write archive markdown: %w", err)
+				}
+			}
+			s.entries[entry.key()] = entry
+			return PutUnchanged, nil
+		}
+		if err := s.moveToHistory(prev); err != nil {
+			return "", err
+		}
+		entry.Versions++
+		result = PutUpdated
+	}
+
+	if err := os.MkdirAll(filepath.Join(s.dir, articleID), 0755); err != nil {
+		return "", fmt.Errorf("create archive directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, entry.HTML), []byte(a.Content), 0644); err != nil {
+		return "", fmt.Errorf("write archive html: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, entry.Markdown), []byte(renderMarkdown(entry, a.Content)), 0644); err != nil {
+		return "", fmt.Errorf("write archive markdown: %w", err)
+	}
+
+	s.entries[entry.key()] = entry
+	return result, nil
+}
+
+// moveToHistory 将当前版本移入 history/<update_time>/
+func (s *Store) moveToHistory(e *Entry) error {
+	histDir := filepath.Join(s.dir, e.ArticleID, "history", strconv.FormatInt(e.UpdateTime, 10))
+	if err := os.MkdirAll(histDir, 0755); err != nil {
+		return fmt.Errorf("create archive history: %w", err)
+	}
+	for _, rel := range []string{e.HTML, e.Markdown} {
+		src := filepath.Join(s.dir, filepath.FromSlash(rel))
+		if err := os.Rename(src, filepath.Join(histDir, filepath.Base(src))); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("move archive history: %w", err)
+		}
+	}
+	return nil
+}
+
+// Save 写入归档索引
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(s.List(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal archive index: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("create archive directory: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免中断时损坏索引
+	path := filepath.Join(s.dir, indexFile)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write archive index: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("write archive index: %w", err)
+	}
+	return nil
+}
+
+// Search 按关键词搜索标题、作者、摘要和正文（不区分大小写）
+func (s *Store) Search(query string) ([]*Entry, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return s.List(), nil
+	}
+
+	var matched []*Entry
+	for _, e := range s.List() {
+		if strings.Contains(strings.ToLower(e.Title+"\n"+e.Author+"\n"+e.Digest), query) {
+			matched = append(matched, e)
+			continue
+		}
+		body, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(e.Markdown)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("read archive markdown: %w", err)
+		}
+		if strings.Contains(strings.ToLower(string(body)), query) {
+			matched = append(matched, e)
+		}
+	}
+	return matched, nil
+}
+
+// markdownMeta Markdown 文件的 front matter（字段名与 writer convert 的 front matter 一致）
+type markdownMeta struct {
+	Title            string `yaml:"title"`
+	Author           string `yaml:"author,omitempty"`
+	Digest           string `yaml:"digest,omitempty"`
+	ContentSourceURL string `yaml:"content_source_url,omitempty"`
+	ArticleID        string `yaml:"article_id"`
+	URL              string `yaml:"url,omitempty"`
+	UpdateTime       string `yaml:"update_time"`
+}
+
+// renderMarkdown 生成带 front matter 的 Markdown
+func renderMarkdown(e *Entry, content string) string {
+	meta, _ := yaml.Marshal(markdownMeta{
+		Title:            e.Title,
+		Author:           e.Author,
+		Digest:           e.Digest,
+		ContentSourceURL: e.ContentSourceURL,
+		ArticleID:        e.ArticleID,
+		URL:              e.URL,
+		UpdateTime:       time.Unix(e.UpdateTime, 0).Format(time.RFC3339),
+	})
+	return "---\n" + string(meta) + "---\n\n" + HTMLToMarkdown(content)
+}
+
+// hashContent 计算内容哈希
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,94 @@
+package archive
+
+import (
+	"fmt"
+
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"go.uber.org/zap"
+)
+
+// Lister 已发布列表接口（由 wechat.Service 实现）
+type Lister interface {
+	ListPublished(offset, count int64, noContent bool) (*wechat.PublishedPage, error)
+}
+
+// SyncOptions 同步选项
+type SyncOptions struct {
+	Full bool // 遍历全部已发布文章；默认遇到整页未变化时停止（列表按更新时间倒序）
+}
+
+// SyncResult 同步结果
+type SyncResult struct {
+	Dir       string   `json:"dir"`
+	Total     int64    `json:"total"`     // 已发布总数（按发布记录计）
+	Scanned   int      `json:"scanned"`   // 本次扫描的文章数
+	New       int      `json:"new"`       // 新归档
+	Updated   int      `json:"updated"`   // 内容变化
+	Unchanged int      `json:"unchanged"` // 未变化
+	Deleted   int      `json:"deleted"`   // 已在公众号后台删除（仍保留本地归档）
+	Changed   []string `json:"changed,omitempty"`
+}
+
+// Sync 分页拉取已发布文章并写入归档
+func Sync(lister Lister, store *Store, opts SyncOptions, log *zap.Logger) (*SyncResult, error) {
+	result := &SyncResult{Dir: store.Dir()}
+
+	var offset int64
+	for {
+		page, err := lister.ListPublished(offset, wechat.MaxPublishedPageSize, false)
+		if err != nil {
+			// 已处理的部分仍然保存
+			if saveErr := store.Save(); saveErr != nil {
+				log.Warn("save archive index failed", zap.Error(saveErr))
+			}
+			return result, err
+		}
+		result.Total = page.TotalCount
+
+		pageChanged := 0
+		for _, item := range page.Items {
+			for i, a := range item.Articles {
+				index := i + 1
+				res, err := store.Put(item.ArticleID, index, item.UpdateTime, a)
+				if err != nil {
+					return result, fmt.Errorf("archive %s#%d: %w", item.ArticleID, index, err)
+				}
+
+				result.Scanned++
+				if a.IsDeleted {
+					result.Deleted++
+				}
+				switch res {
+				case PutNew:
+					result.New++
+				case PutUpdated:
+					result.Updated++
+				default:
+					result.Unchanged++
+					continue
+				}
+				pageChanged++
+				result.Changed = append(result.Changed, a.Title)
+				log.Info("article archived",
+					zap.String("article_id", item.ArticleID),
+					zap.Int("index", index),
+					zap.String("title", a.Title),
+					zap.String("result", string(res)))
+			}
+		}
+
+		offset += int64(len(page.Items))
+		if len(page.Items) == 0 || offset >= page.TotalCount {
+			break
+		}
+		if !opts.Full && pageChanged == 0 {
+			log.Info("archive up to date, stopping early", zap.Int64("offset", offset))
+			break
+		}
+	}
+
+	if err := store.Save(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
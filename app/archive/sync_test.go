@@ -0,0 +1,137 @@
+package archive
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/royalrick/wechatwriter/app/config"
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"github.com/royalrick/wechatwriter/app/wechat/wechattest"
+	"go.uber.org/zap"
+)
+
+// publishArticle 通过模拟服务创建草稿并发布，返回 article_id
+func publishArticle(t *testing.T, ws *wechat.Service, thumb, title, content string) string {
+	t.Helper()
+	res, err := ws.CreateDraft([]*wechat.Article{{Title: title, Content: content, ThumbMediaID: thumb}})
+	if err != nil {
+		t.Fatalf("CreateDraft() error = %v", err)
+	}
+	publishID, err := ws.SubmitPublish(res.MediaID)
+	if err != nil {
+		t.Fatalf("SubmitPublish() error = %v", err)
+	}
+	status, err := ws.GetPublishStatus(publishID)
+	if err != nil {
+		t.Fatalf("GetPublishStatus() error = %v", err)
+	}
+	return status.ArticleID
+}
+
+func TestSyncArchivesPublishedArticles(t *testing.T) {
+	ts := httptest.NewServer(wechattest.NewServer())
+	t.Cleanup(ts.Close)
+	ws := wechat.NewService(&config.WechatAccount{
+		ID:      "mock",
+		AppID:   "mock_appid",
+		Secret:  "mock_secret",
+		APIBase: ts.URL,
+	}, zap.NewNop())
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	coverPath := filepath.Join(t.TempDir(), "cover.png")
+	if err := os.WriteFile(coverPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cover, err := ws.UploadMaterial(coverPath)
+	if err != nil {
+		t.Fatalf("UploadMaterial() error = %v", err)
+	}
+
+	first := publishArticle(t, ws, cover.MediaID, "第一篇", "<p>关于增长的思考</p>")
+	publishArticle(t, ws, cover.MediaID, "第二篇", "<h2>小标题</h2><p>正文</p>")
+
+	dir := t.TempDir()
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	result, err := Sync(ws, store, SyncOptions{}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if result.New != 2 || result.Scanned != 2 {
+		t.Fatalf("Sync() = %+v, want 2 new", result)
+	}
+
+	md, err := os.ReadFile(filepath.Join(dir, first, "1.md"))
+	if err != nil {
+		t.Fatalf("read markdown: %v", err)
+	}
+	if !strings.Contains(string(md), "title: 第一篇") || !strings.Contains(string(md), "关于增长的思考") {
+		t.Errorf("markdown = %q, want front matter and body", md)
+	}
+
+	// 重新打开后再次同步：没有变化
+	store, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	result, err = Sync(ws, store, SyncOptions{}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if result.New != 0 || result.Updated != 0 || result.Unchanged != 2 {
+		t.Errorf("second Sync() = %+v, want all unchanged", result)
+	}
+
+	matched, err := store.Search("增长")
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(matched) != 1 || matched[0].ArticleID != first {
+		t.Errorf("Search() = %+v, want only the first article", matched)
+	}
+}
+
+func TestStorePutKeepsHistory(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	a := wechat.PublishedArticle{Title: "文章", Content: "<p>v1</p>"}
+	if res, err := store.Put("art", 1, 100, a); err != nil || res != PutNew {
+		t.Fatalf("Put() = %s, %v, want new", res, err)
+	}
+	if res, err := store.Put("art", 1, 100, a); err != nil || res != PutUnchanged {
+		t.Fatalf("Put() same content = %s, %v, want unchanged", res, err)
+	}
+
+	a.Content = "<p>v2</p>"
+	if res, err := store.Put("art", 1, 200, a); err != nil || res != PutUpdated {
+		t.Fatalf("Put() new content = %s, %v, want updated", res, err)
+	}
+
+	old, err := os.ReadFile(filepath.Join(dir, "art", "history", "100", "1.html"))
+	if err != nil || string(old) != "<p>v1</p>" {
+		t.Errorf("history html = %q, %v, want v1", old, err)
+	}
+	cur, err := os.ReadFile(filepath.Join(dir, "art", "1.html"))
+	if err != nil || string(cur) != "<p>v2</p>" {
+		t.Errorf("current html = %q, %v, want v2", cur, err)
+	}
+	if e, _ := store.Get("art", 1); e.Versions != 2 {
+		t.Errorf("Versions = %d, want 2", e.Versions)
+	}
+}
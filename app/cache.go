@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/config"
+	"github.com/royalrick/wechatwriter/app/image"
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd 上传缓存管理命令组
+func cacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "图片上传缓存管理",
+		Long: `图片上传缓存管理
+
+上传图片时会按处理后图片内容的 SHA-256 记录微信素材 ID 和 URL，
+再次转换同一篇文章时相同图片直接复用，不会重复上传。
+缓存按账号存储在数据目录下（默认 ~/.config/wechatwriter/data）。
+
+支持的操作：
+  ls     - 列出缓存条目
+  prune  - 清理缓存条目`,
+	}
+
+	cmd.AddCommand(cacheListCmd())
+	cmd.AddCommand(cachePruneCmd())
+
+	return cmd
+}
+
+// cacheListCmd 列出上传缓存
+func cacheListCmd() *cobra.Command {
+	var accountID string
+
+	cmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "列出上传缓存条目",
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			account, cache, err := openUploadCache(accountID)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			entries := cache.Entries()
+			var totalSize int64
+			for _, e := range entries {
+				totalSize += e.Size
+			}
+
+			responseSuccess(map[string]any{
+				"account_id": account.ID,
+				"cache_file": cache.Path(),
+				"entries":    entries,
+				"total":      len(entries),
+				"total_size": totalSize,
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+
+	return cmd
+}
+
+// cachePruneCmd 清理上传缓存
+func cachePruneCmd() *cobra.Command {
+	var (
+		accountID string
+		olderThan int
+		all       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "清理上传缓存条目",
+		Long: `清理上传缓存条目
+
+默认删除 30 天内未使用的条目；使用 --all 清空当前账号的全部缓存。
+注意：清理缓存不会删除微信素材库中的素材，删除素材请使用 'writer image delete'。`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if !all && olderThan <= 0 {
+				responseError(fmt.Errorf("--older-than 必须大于 0，或使用 --all 清空缓存"))
+				return
+			}
+
+			account, cache, err := openUploadCache(accountID)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			var age time.Duration
+			if !all {
+				age = time.Duration(olderThan) * 24 * time.Hour
+			}
+
+			removed, err := cache.Prune(age)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			responseSuccess(map[string]any{
+				"account_id": account.ID,
+				"removed":    removed,
+				"remaining":  len(cache.Entries()),
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().IntVar(&olderThan, "older-than", 30, "删除超过指定天数未使用的条目")
+	cmd.Flags().BoolVar(&all, "all", false, "清空全部缓存")
+
+	return cmd
+}
+
+// openUploadCache 打开指定账号的上传缓存
+func openUploadCache(accountID string) (*config.WechatAccount, *image.UploadCache, error) {
+	account, err := selectAccount(accountID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cache, err := image.OpenUploadCache(cfg.AccountDataDir(account.ID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return account, cache, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/callback"
+	"github.com/royalrick/wechatwriter/app/config"
+	"github.com/royalrick/wechatwriter/app/schedule"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// serveCallbackCmd 公众号事件回调服务
+func serveCallbackCmd() *cobra.Command {
+	var (
+		addr      string
+		accountID string
+		webhook   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "serve-callback",
+		Short: "接收公众号事件推送（发布完成、审核结果）",
+		Long: `接收公众号事件推送（发布完成、审核结果）
+
+在公众号后台 设置与开发 > 基本配置 > 服务器配置 中填写：
+  URL:            http(s)://<你的域名>/callback/<account_id>
+  Token:          与账号的 callback_token 一致
+  EncodingAESKey: 与账号的 encoding_aes_key 一致（明文模式可不配置）
+
+支持 URL 验证（signature + echostr）和安全模式的消息解密。收到发布完成事件
+（PUBLISHJOBFINISH，包含审核不通过、原创声明失败等结果）时更新定时发布队列中
+对应的任务，并将事件摘要 POST 到 --webhook（默认取配置 callback.webhook）。
+
+只有配置了 callback_token 的账号会被注册。微信只推送到 80/443 端口，
+需要通过反向代理将请求转发到 --addr。
+
+示例：
+  writer serve-callback --addr 127.0.0.1:8091
+  writer serve-callback --account my-account --webhook https://example.com/hook`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			accounts, err := callbackAccounts(accountID)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			if webhook == "" {
+				webhook = cfg.CallbackWebhook
+			}
+			var hook *callback.Webhook
+			if webhook != "" {
+				hook = callback.NewWebhook(webhook, time.Duration(cfg.HTTPTimeout)*time.Second, log)
+				defer hook.Close()
+			}
+
+			runner := schedule.NewRunner(schedule.OpenQueue(cfg.GetDataDir()), schedulePublisher, log)
+			onEvent := func(accountID string, event *callback.Event) error {
+				return handleCallbackEvent(runner, hook, accountID, event)
+			}
+
+			mux := http.NewServeMux()
+			for _, acc := range accounts {
+				path := "/callback/" + acc.ID
+				mux.Handle(path, callback.NewHandler(acc.ID, acc.AppID, acc.CallbackToken, acc.EncodingAESKey, onEvent, log))
+				log.Info("callback registered",
+					zap.String("account", acc.ID),
+					zap.String("path", path),
+					zap.Bool("aes", acc.EncodingAESKey != ""))
+			}
+
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				responseError(fmt.Errorf("监听 %s 失败: %w", addr, err))
+				return
+			}
+
+			srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				srv.Shutdown(shutdownCtx)
+			}()
+
+			fmt.Fprintf(os.Stderr, "callback server listening on http://%s\n", ln.Addr())
+			if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				responseError(err)
+				return
+			}
+			log.Info("callback server stopped")
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:8091", "监听地址")
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "只注册指定账号（默认注册所有配置了 callback_token 的账号）")
+	cmd.Flags().StringVar(&webhook, "webhook", "", "事件摘要转发地址（默认取配置 callback.webhook）")
+
+	return cmd
+}
+
+// callbackAccounts 需要注册回调的账号
+func callbackAccounts(accountID string) ([]config.WechatAccount, error) {
+	if accountID != "" {
+		account, err := selectAccount(accountID)
+		if err != nil {
+			return nil, err
+		}
+		if account.CallbackToken == "" {
+			return nil, &config.ConfigError{
+				Field:   "callback_token",
+				Message: fmt.Sprintf("账号 '%s' 未配置 callback_token", account.ID),
+				Hint:    "在 wechat.accounts[] 中设置 callback_token（与公众号后台服务器配置的 Token 一致）",
+			}
+		}
+		return []config.WechatAccount{*account}, nil
+	}
+
+	var accounts []config.WechatAccount
+	for _, acc := range cfg.WechatAccounts {
+		if acc.CallbackToken != "" {
+			accounts = append(accounts, acc)
+		}
+	}
+	if len(accounts) == 0 {
+		return nil, &config.ConfigError{
+			Field:   "callback_token",
+			Message: "没有账号配置 callback_token",
+			Hint:    "在 wechat.accounts[] 中设置 callback_token（与公众号后台服务器配置的 Token 一致）",
+		}
+	}
+	return accounts, nil
+}
+
+// handleCallbackEvent 更新定时发布任务并转发事件摘要
+func handleCallbackEvent(runner *schedule.Runner, hook *callback.Webhook, accountID string, event *callback.Event) error {
+	summary := callback.NewSummary(accountID, event)
+
+	if status := event.PublishStatus(); status != nil {
+		job, err := runner.HandleStatus(accountID, status)
+		if err != nil {
+			return fmt.Errorf("update schedule job: %w", err)
+		}
+		if job != nil {
+			summary.JobID = job.ID
+			summary.Title = job.Title
+			log.Info("schedule job updated from callback",
+				zap.String("job", job.ID),
+				zap.Int64("publish_id", status.PublishID),
+				zap.String("status", job.Status))
+		}
+	}
+
+	// 转发 webhook 在后台进行，不阻塞回复微信
+	if hook != nil {
+		hook.Post(summary)
+	}
+	return nil
+}
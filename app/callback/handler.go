@@ -0,0 +1,239 @@
+// Package callback 公众号服务器配置（消息与事件推送）的接收处理
+//
+// 实现 URL 验证（signature + echostr，校验 timestamp 防重放）、安全模式的 AES 消息解密，
+// 以及发布完成（PUBLISHJOBFINISH）等事件的解析。
+package callback
+
+import (
+	"crypto/subtle"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"github.com/silenceper/wechat/v2/util"
+	"go.uber.org/zap"
+)
+
+// 事件类型
+const (
+	EventPublishJobFinish  = "PUBLISHJOBFINISH"  // 发布任务完成（含审核结果）
+	EventMassSendJobFinish = "MASSSENDJOBFINISH" // 群发任务完成
+)
+
+// maxBodySize 推送消息最大长度
+const maxBodySize = 1 << 20
+
+// DefaultReplayWindow 请求 timestamp 与本机时间允许的最大偏差，超出视为重放
+const DefaultReplayWindow = 5 * time.Minute
+
+// ErrSignature 签名校验失败
+var ErrSignature = errors.New("invalid signature")
+
+// ErrTimestamp timestamp 缺失或超出允许的时间窗口
+var ErrTimestamp = errors.New("timestamp outside replay window")
+
+// Event 推送的事件消息
+type Event struct {
+	ToUserName   string `xml:"ToUserName" json:"to_user_name"`
+	FromUserName string `xml:"FromUserName" json:"from_user_name"`
+	CreateTime   int64  `xml:"CreateTime" json:"create_time"`
+	MsgType      string `xml:"MsgType" json:"msg_type"`
+	Event        string `xml:"Event" json:"event"`
+
+	// 发布完成事件
+	PublishEventInfo *struct {
+		PublishID     int64  `xml:"publish_id"`
+		PublishStatus int    `xml:"publish_status"`
+		ArticleID     string `xml:"article_id"`
+		ArticleDetail struct {
+			Items []struct {
+				Index      uint   `xml:"idx"`
+				ArticleURL string `xml:"article_url"`
+			} `xml:"item"`
+		} `xml:"article_detail"`
+		FailIndex []uint `xml:"fail_idx"`
+	} `xml:"PublishEventInfo" json:"-"`
+
+	// 群发完成事件
+	MsgID       int64  `xml:"MsgID" json:"msg_id,omitempty"`
+	Status      string `xml:"Status" json:"status,omitempty"`
+	TotalCount  int64  `xml:"TotalCount" json:"total_count,omitempty"`
+	SentCount   int64  `xml:"SentCount" json:"sent_count,omitempty"`
+	ErrorCount  int64  `xml:"ErrorCount" json:"error_count,omitempty"`
+	FilterCount int64  `xml:"FilterCount" json:"filter_count,omitempty"`
+}
+
+// PublishStatus 发布完成事件中的发布结果，非发布事件返回 nil
+func (e *Event) PublishStatus() *wechat.PublishStatus {
+	if e.Event != EventPublishJobFinish || e.PublishEventInfo == nil {
+		return nil
+	}
+	info := e.PublishEventInfo
+	status := &wechat.PublishStatus{
+		PublishID: info.PublishID,
+		Status:    info.PublishStatus,
+		ArticleID: info.ArticleID,
+		FailIndex: info.FailIndex,
+	}
+	for _, item := range info.ArticleDetail.Items {
+		status.ArticleURLs = append(status.ArticleURLs, item.ArticleURL)
+	}
+	return status
+}
+
+// encryptedMessage 安全模式下的加密消息
+type encryptedMessage struct {
+	ToUserName string `xml:"ToUserName"`
+	Encrypt    string `xml:"Encrypt"`
+}
+
+// Handler 单个公众号的回调处理器
+type Handler struct {
+	AccountID      string
+	AppID          string
+	Token          string
+	EncodingAESKey string // 为空时只处理明文消息
+
+	// OnEvent 收到事件后调用；返回错误时仍回复 success，避免微信重复推送
+	// 在请求处理中同步执行，耗时操作（如转发 webhook）应异步处理
+	OnEvent func(accountID string, event *Event) error
+
+	ReplayWindow time.Duration // timestamp 允许的偏差，默认 DefaultReplayWindow
+	Now          func() time.Time
+
+	log *zap.Logger
+}
+
+// NewHandler 创建回调处理器
+func NewHandler(accountID, appID, token, aesKey string, onEvent func(string, *Event) error, log *zap.Logger) *Handler {
+	return &Handler{
+		AccountID:      accountID,
+		AppID:          appID,
+		Token:          token,
+		EncodingAESKey: aesKey,
+		OnEvent:        onEvent,
+		ReplayWindow:   DefaultReplayWindow,
+		Now:            time.Now,
+		log:            log,
+	}
+}
+
+// ServeHTTP 处理微信推送
+// GET 为服务器地址验证，原样返回 echostr；POST 为消息推送，处理后回复 success
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if !h.checkSignature(q.Get("signature"), q.Get("timestamp"), q.Get("nonce")) {
+		h.log.Warn("callback signature mismatch",
+			zap.String("account", h.AccountID),
+			zap.String("remote", r.RemoteAddr))
+		http.Error(w, ErrSignature.Error(), http.StatusForbidden)
+		return
+	}
+	if !h.checkTimestamp(q.Get("timestamp")) {
+		h.log.Warn("callback timestamp outside replay window",
+			zap.String("account", h.AccountID),
+			zap.String("timestamp", q.Get("timestamp")),
+			zap.String("remote", r.RemoteAddr))
+		http.Error(w, ErrTimestamp.Error(), http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		io.WriteString(w, q.Get("echostr"))
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+		if err != nil {
+			http.Error(w, "read body failed", http.StatusBadRequest)
+			return
+		}
+		event, err := h.parse(body, q.Get("encrypt_type"), q.Get("msg_signature"), q.Get("timestamp"), q.Get("nonce"))
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrSignature) {
+				status = http.StatusForbidden
+			}
+			h.log.Warn("parse callback message failed",
+				zap.String("account", h.AccountID),
+				zap.Error(err))
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		h.log.Info("callback event received",
+			zap.String("account", h.AccountID),
+			zap.String("msg_type", event.MsgType),
+			zap.String("event", event.Event))
+		if h.OnEvent != nil {
+			if err := h.OnEvent(h.AccountID, event); err != nil {
+				h.log.Error("handle callback event failed",
+					zap.String("account", h.AccountID),
+					zap.String("event", event.Event),
+					zap.Error(err))
+			}
+		}
+		io.WriteString(w, "success")
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// checkSignature 校验 URL 签名：sha1(sort(token, timestamp, nonce))
+func (h *Handler) checkSignature(signature, timestamp, nonce string) bool {
+	return signature != "" && equalSignature(util.Signature(h.Token, timestamp, nonce), signature)
+}
+
+// checkTimestamp 校验 timestamp 在允许的时间窗口内，防止截获的请求被重放
+func (h *Handler) checkTimestamp(timestamp string) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	window := h.ReplayWindow
+	if window <= 0 {
+		window = DefaultReplayWindow
+	}
+	now := time.Now
+	if h.Now != nil {
+		now = h.Now
+	}
+	skew := now().Sub(time.Unix(ts, 0))
+	return skew <= window && skew >= -window
+}
+
+// equalSignature 常量时间比较签名，避免通过响应时间逐字节猜测
+func equalSignature(expected, actual string) bool {
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) == 1
+}
+
+// parse 解析推送消息，安全模式下先校验 msg_signature 并解密
+func (h *Handler) parse(body []byte, encryptType, msgSignature, timestamp, nonce string) (*Event, error) {
+	if encryptType == "aes" {
+		if h.EncodingAESKey == "" {
+			return nil, fmt.Errorf("received encrypted message but encoding_aes_key is not configured")
+		}
+		var enc encryptedMessage
+		if err := xml.Unmarshal(body, &enc); err != nil {
+			return nil, fmt.Errorf("parse encrypted message: %w", err)
+		}
+		if !equalSignature(util.Signature(h.Token, timestamp, nonce, enc.Encrypt), msgSignature) {
+			return nil, fmt.Errorf("msg_signature: %w", ErrSignature)
+		}
+		_, raw, err := util.DecryptMsg(h.AppID, enc.Encrypt, h.EncodingAESKey)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt message: %w", err)
+		}
+		body = raw
+	}
+
+	var event Event
+	if err := xml.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("parse message: %w", err)
+	}
+	return &event, nil
+}
@@ -0,0 +1,204 @@
+package callback
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"github.com/silenceper/wechat/v2/util"
+	"go.uber.org/zap"
+)
+
+const (
+	testToken  = "test_token"
+	testAppID  = "wx_test_appid"
+	testAESKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+)
+
+const publishEventXML = `<xml>
+<ToUserName><![CDATA[gh_test]]></ToUserName>
+<FromUserName><![CDATA[oTest]]></FromUserName>
+<CreateTime>1700000000</CreateTime>
+<MsgType><![CDATA[event]]></MsgType>
+<Event><![CDATA[PUBLISHJOBFINISH]]></Event>
+<PublishEventInfo>
+<publish_id>2247483999</publish_id>
+<publish_status>0</publish_status>
+<article_id><![CDATA[art_1]]></article_id>
+<article_detail><count>1</count><item><idx>1</idx><article_url><![CDATA[https://mp.weixin.qq.com/s/x]]></article_url></item></article_detail>
+</PublishEventInfo>
+</xml>`
+
+// signedURL 生成带签名的回调地址
+func signedURL(extra url.Values) string {
+	q := url.Values{}
+	q.Set("timestamp", "1700000000")
+	q.Set("nonce", "12345")
+	q.Set("signature", util.Signature(testToken, "1700000000", "12345"))
+	for k, v := range extra {
+		q[k] = v
+	}
+	return "/callback/test?" + q.Encode()
+}
+
+// newTestHandler 创建记录收到事件的处理器
+func newTestHandler(aesKey string) (*Handler, *[]*Event) {
+	var events []*Event
+	h := NewHandler("test", testAppID, testToken, aesKey, func(accountID string, e *Event) error {
+		events = append(events, e)
+		return nil
+	}, zap.NewNop())
+	h.Now = func() time.Time { return time.Unix(1700000000, 0) }
+	return h, &events
+}
+
+func TestHandlerVerifiesURL(t *testing.T) {
+	h, _ := newTestHandler("")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, signedURL(url.Values{"echostr": {"hello"}}), nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "hello" {
+		t.Errorf("verify = %d %q, want 200 echostr", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/callback/test?timestamp=1&nonce=2&signature=bad&echostr=hello", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("bad signature status = %d, want 403", rec.Code)
+	}
+}
+
+func TestHandlerRejectsReplayedRequest(t *testing.T) {
+	h, events := newTestHandler("")
+
+	// 签名正确但 timestamp 超出时间窗口
+	h.Now = func() time.Time { return time.Unix(1700000000, 0).Add(DefaultReplayWindow + time.Second) }
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, signedURL(nil), strings.NewReader(publishEventXML)))
+	if rec.Code != http.StatusForbidden || len(*events) != 0 {
+		t.Errorf("replayed request = %d, events %d, want 403 and no event", rec.Code, len(*events))
+	}
+
+	h.Now = func() time.Time { return time.Unix(1700000000, 0).Add(DefaultReplayWindow - time.Second) }
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, signedURL(nil), strings.NewReader(publishEventXML)))
+	if rec.Code != http.StatusOK || len(*events) != 1 {
+		t.Errorf("request within window = %d, events %d, want 200 and one event", rec.Code, len(*events))
+	}
+}
+
+func TestHandlerParsesPublishEvent(t *testing.T) {
+	h, events := newTestHandler("")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, signedURL(nil), strings.NewReader(publishEventXML)))
+	if rec.Code != http.StatusOK || rec.Body.String() != "success" {
+		t.Fatalf("response = %d %q, want 200 success", rec.Code, rec.Body.String())
+	}
+	if len(*events) != 1 {
+		t.Fatalf("OnEvent called %d times, want 1", len(*events))
+	}
+
+	status := (*events)[0].PublishStatus()
+	if status == nil || status.PublishID != 2247483999 || !status.Succeeded() ||
+		status.ArticleID != "art_1" || len(status.ArticleURLs) != 1 {
+		t.Errorf("PublishStatus() = %+v", status)
+	}
+}
+
+func TestHandlerDecryptsAESMessage(t *testing.T) {
+	h, events := newTestHandler(testAESKey)
+
+	encrypted, err := util.EncryptMsg([]byte("0123456789abcdef"), []byte(publishEventXML), testAppID, testAESKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := xml.Marshal(struct {
+		XMLName    xml.Name `xml:"xml"`
+		ToUserName string   `xml:"ToUserName"`
+		Encrypt    string   `xml:"Encrypt"`
+	}{ToUserName: "gh_test", Encrypt: string(encrypted)})
+
+	extra := url.Values{
+		"encrypt_type":  {"aes"},
+		"msg_signature": {util.Signature(testToken, "1700000000", "12345", string(encrypted))},
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, signedURL(extra), strings.NewReader(string(body))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d %q, want 200", rec.Code, rec.Body.String())
+	}
+	if len(*events) != 1 || (*events)[0].Event != EventPublishJobFinish {
+		t.Fatalf("events = %+v, want one PUBLISHJOBFINISH", *events)
+	}
+
+	// msg_signature 不匹配时拒绝
+	extra.Set("msg_signature", "bad")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, signedURL(extra), strings.NewReader(string(body))))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("bad msg_signature status = %d, want 403", rec.Code)
+	}
+}
+
+func TestWebhookSendsSummary(t *testing.T) {
+	var got Summary
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	var event Event
+	if err := xml.Unmarshal([]byte(strings.Replace(publishEventXML, "<publish_status>0", "<publish_status>4", 1)), &event); err != nil {
+		t.Fatal(err)
+	}
+	hook := NewWebhook(ts.URL, 0, zap.NewNop())
+	defer hook.Close()
+	if err := hook.Send(NewSummary("test", &event)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got.PublishID != 2247483999 || got.Succeeded || got.Status != (&wechat.PublishStatus{Status: wechat.PublishStatusAuditRefused}).Description() {
+		t.Errorf("summary = %+v, want audit refused", got)
+	}
+}
+
+func TestWebhookPostDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	received := make(chan Summary, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		var s Summary
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			t.Error(err)
+		}
+		received <- s
+	}))
+	defer ts.Close()
+
+	hook := NewWebhook(ts.URL, 5*time.Second, zap.NewNop())
+	start := time.Now()
+	hook.Post(&Summary{AccountID: "test", Event: EventPublishJobFinish})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Post() blocked for %v", elapsed)
+	}
+
+	// 接收端返回后 Close 等待发送完成
+	close(release)
+	hook.Close()
+	select {
+	case s := <-received:
+		if s.AccountID != "test" {
+			t.Errorf("summary = %+v", s)
+		}
+	default:
+		t.Error("Close() returned before the queued summary was sent")
+	}
+}
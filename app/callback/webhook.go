@@ -0,0 +1,134 @@
+package callback
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// webhookQueueSize 等待转发的事件摘要上限，队列满时丢弃新事件
+const webhookQueueSize = 64
+
+// Summary 转发到 webhook 的事件摘要
+type Summary struct {
+	AccountID   string   `json:"account_id"`
+	Event       string   `json:"event"`
+	CreateTime  int64    `json:"create_time"`
+	PublishID   int64    `json:"publish_id,omitempty"`
+	Status      string   `json:"status,omitempty"` // 发布状态描述或群发状态
+	Succeeded   bool     `json:"succeeded"`
+	ArticleID   string   `json:"article_id,omitempty"`
+	ArticleURLs []string `json:"article_urls,omitempty"`
+	JobID       string   `json:"job_id,omitempty"` // 匹配到的定时发布任务
+	Title       string   `json:"title,omitempty"`
+	Text        string   `json:"text"` // 一行可读描述，便于直接推送到聊天工具
+}
+
+// NewSummary 生成事件摘要
+func NewSummary(accountID string, event *Event) *Summary {
+	s := &Summary{
+		AccountID:  accountID,
+		Event:      event.Event,
+		CreateTime: event.CreateTime,
+	}
+
+	if status := event.PublishStatus(); status != nil {
+		s.PublishID = status.PublishID
+		s.Status = status.Description()
+		s.Succeeded = status.Succeeded()
+		s.ArticleID = status.ArticleID
+		s.ArticleURLs = status.ArticleURLs
+		s.Text = fmt.Sprintf("[%s] 发布任务 %d：%s", accountID, status.PublishID, s.Status)
+		return s
+	}
+
+	switch event.Event {
+	case EventMassSendJobFinish:
+		s.Status = event.Status
+		s.Succeeded = event.Status == "send success"
+		s.Text = fmt.Sprintf("[%s] 群发 %d：%s（成功 %d / 失败 %d）",
+			accountID, event.MsgID, event.Status, event.SentCount, event.ErrorCount)
+	default:
+		s.Text = fmt.Sprintf("[%s] 收到事件 %s", accountID, event.Event)
+	}
+	return s
+}
+
+// Webhook 事件摘要转发
+// Post 只把摘要放入队列，由后台 goroutine 逐个发送，不阻塞回复微信
+type Webhook struct {
+	URL    string
+	client *http.Client
+	log    *zap.Logger
+
+	queue chan *Summary
+	done  chan struct{}
+}
+
+// NewWebhook 创建 webhook 转发器并启动后台发送，timeout 为单次发送的超时时间
+func NewWebhook(url string, timeout time.Duration, log *zap.Logger) *Webhook {
+	w := &Webhook{
+		URL:    url,
+		client: &http.Client{Timeout: timeout},
+		log:    log,
+		queue:  make(chan *Summary, webhookQueueSize),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Post 将事件摘要加入发送队列，立即返回；队列已满时丢弃并记录警告
+func (w *Webhook) Post(summary *Summary) {
+	select {
+	case w.queue <- summary:
+	default:
+		w.log.Warn("webhook queue full, dropping event",
+			zap.String("account", summary.AccountID),
+			zap.String("event", summary.Event))
+	}
+}
+
+// Close 停止接收新事件，等待队列中的事件发送完毕
+func (w *Webhook) Close() {
+	close(w.queue)
+	<-w.done
+}
+
+// run 后台逐个发送队列中的事件摘要
+func (w *Webhook) run() {
+	defer close(w.done)
+	for summary := range w.queue {
+		if err := w.Send(summary); err != nil {
+			w.log.Error("forward to webhook failed",
+				zap.String("account", summary.AccountID),
+				zap.String("event", summary.Event),
+				zap.Error(err))
+		}
+	}
+}
+
+// Send 以 JSON POST 同步发送事件摘要
+func (w *Webhook) Send(summary *Summary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("marshal summary: %w", err)
+	}
+
+	resp, err := w.client.Post(w.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// commentsCmd 留言管理命令组
+func commentsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "comments",
+		Short: "文章留言管理",
+		Long: `文章留言管理
+
+封装微信留言管理接口，<article> 为群发图文 ID：
+  2247483653_1  msg_data_id 加篇序号（从 1 开始，与 'writer stats' 的 msgid 一致）
+  2247483653    多图文的第一篇
+
+支持的操作：
+  list    - 列出留言（可只看未回复 / 精选留言）
+  reply   - 回复留言
+  delete  - 删除留言或作者回复
+  elect   - 精选 / 取消精选留言
+  open    - 打开文章留言
+  close   - 关闭文章留言
+  export  - 导出留言，并把留言数写入统计数据供 'writer score' 使用`,
+	}
+
+	cmd.AddCommand(commentsListCmd())
+	cmd.AddCommand(commentsReplyCmd())
+	cmd.AddCommand(commentsDeleteCmd())
+	cmd.AddCommand(commentsElectCmd())
+	cmd.AddCommand(commentsSwitchCmd("open", "打开文章留言", true))
+	cmd.AddCommand(commentsSwitchCmd("close", "关闭文章留言", false))
+	cmd.AddCommand(commentsExportCmd())
+
+	return cmd
+}
+
+// commentsListCmd 列出留言
+func commentsListCmd() *cobra.Command {
+	var (
+		accountID string
+		unreplied bool
+		featured  bool
+		limit     int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list <article>",
+		Short: "列出文章留言",
+		Long: `列出文章留言
+
+示例：
+  writer comments list 2247483653_1
+  writer comments list 2247483653_1 --unreplied
+  writer comments list 2247483653_1 --featured --limit 10`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ws, article, err := commentTarget(accountID, args[0])
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			commentType := wechat.CommentTypeAll
+			if featured {
+				commentType = wechat.CommentTypeFeatured
+			}
+			comments, err := ws.AllComments(article, commentType)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			total := len(comments)
+			if unreplied {
+				comments = filterUnreplied(comments)
+			}
+			if limit > 0 && len(comments) > limit {
+				comments = comments[:limit]
+			}
+			if comments == nil {
+				comments = []*wechat.Comment{}
+			}
+
+			responseSuccess(map[string]any{
+				"article":  args[0],
+				"total":    total,
+				"count":    len(comments),
+				"comments": comments,
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().BoolVar(&unreplied, "unreplied", false, "只列出未回复的留言")
+	cmd.Flags().BoolVar(&featured, "featured", false, "只列出精选留言")
+	cmd.Flags().IntVar(&limit, "limit", 0, "最多列出的留言数（0 为不限）")
+
+	return cmd
+}
+
+// commentsReplyCmd 回复留言
+func commentsReplyCmd() *cobra.Command {
+	var accountID string
+
+	cmd := &cobra.Command{
+		Use:   "reply <article> <comment_id> <content>",
+		Short: "回复留言（已有回复时覆盖）",
+		Args:  cobra.ExactArgs(3),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ws, article, err := commentTarget(accountID, args[0])
+			if err != nil {
+				responseError(err)
+				return
+			}
+			commentID, err := parseCommentID(args[1])
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			if err := ws.ReplyComment(article, commentID, args[2]); err != nil {
+				responseError(err)
+				return
+			}
+			responseSuccess(map[string]any{
+				"article":         args[0],
+				"user_comment_id": commentID,
+				"replied":         true,
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+
+	return cmd
+}
+
+// commentsDeleteCmd 删除留言或作者回复
+func commentsDeleteCmd() *cobra.Command {
+	var (
+		accountID string
+		replyOnly bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "delete <article> <comment_id>",
+		Short: "删除留言（--reply 只删除作者回复）",
+		Args:  cobra.ExactArgs(2),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ws, article, err := commentTarget(accountID, args[0])
+			if err != nil {
+				responseError(err)
+				return
+			}
+			commentID, err := parseCommentID(args[1])
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			if replyOnly {
+				err = ws.DeleteCommentReply(article, commentID)
+			} else {
+				err = ws.DeleteComment(article, commentID)
+			}
+			if err != nil {
+				responseError(err)
+				return
+			}
+			responseSuccess(map[string]any{
+				"article":         args[0],
+				"user_comment_id": commentID,
+				"deleted":         map[bool]string{true: "reply", false: "comment"}[replyOnly],
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().BoolVar(&replyOnly, "reply", false, "只删除作者回复，保留留言")
+
+	return cmd
+}
+
+// commentsElectCmd 精选留言
+func commentsElectCmd() *cobra.Command {
+	var (
+		accountID string
+		undo      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "elect <article> <comment_id>",
+		Short: "将留言设为精选（--undo 取消精选）",
+		Args:  cobra.ExactArgs(2),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ws, article, err := commentTarget(accountID, args[0])
+			if err != nil {
+				responseError(err)
+				return
+			}
+			commentID, err := parseCommentID(args[1])
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			if err := ws.ElectComment(article, commentID, !undo); err != nil {
+				responseError(err)
+				return
+			}
+			responseSuccess(map[string]any{
+				"article":         args[0],
+				"user_comment_id": commentID,
+				"featured":        !undo,
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().BoolVar(&undo, "undo", false, "取消精选")
+
+	return cmd
+}
+
+// commentsSwitchCmd 打开 / 关闭文章留言
+func commentsSwitchCmd(use, short string, open bool) *cobra.Command {
+	var accountID string
+
+	cmd := &cobra.Command{
+		Use:   use + " <article>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ws, article, err := commentTarget(accountID, args[0])
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			if open {
+				err = ws.OpenComment(article)
+			} else {
+				err = ws.CloseComment(article)
+			}
+			if err != nil {
+				responseError(err)
+				return
+			}
+			responseSuccess(map[string]any{
+				"article": args[0],
+				"open":    open,
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+
+	return cmd
+}
+
+// CommentExport 单篇文章的留言导出
+type CommentExport struct {
+	MsgID     string            `json:"msgid"`
+	Title     string            `json:"title,omitempty"`
+	Total     int               `json:"total"`
+	Featured  int               `json:"featured"`
+	Unreplied int               `json:"unreplied"`
+	Comments  []*wechat.Comment `json:"comments,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// commentsExportCmd 导出留言并更新统计数据中的留言数
+func commentsExportCmd() *cobra.Command {
+	var (
+		accountID string
+		from      string
+		to        string
+		output    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export [article...]",
+		Short: "导出留言并把留言数写入统计数据",
+		Long: `导出留言并把留言数写入统计数据
+
+未指定文章时导出本地统计数据（'writer stats fetch'）中的文章，可用 --from / --to 按群发日期过滤。
+每篇文章的留言总数写入统计数据的 comment_count，之后 'writer score --article <msgid>' 即包含留言指标。
+
+不指定 -o 时留言明细在 JSON 结果中返回；指定 -o 时写入文件，结果只包含汇总。
+
+示例：
+  writer comments export --from 2026-10-01 --to 2026-10-07 -o comments.json
+  writer comments export 2247483653_1 2247483653_2`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			result, err := runCommentsExport(accountID, args, from, to, output)
+			if err != nil {
+				responseError(err)
+				return
+			}
+			responseSuccess(result)
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().StringVar(&from, "from", "", "按群发日期过滤：开始日期 (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&to, "to", "", "按群发日期过滤：结束日期 (YYYY-MM-DD)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "留言明细输出文件（JSON）")
+
+	return cmd
+}
+
+// runCommentsExport 拉取文章的全部留言，更新统计数据中的 CommentCount
+// 单篇文章失败时记录错误并继续
+func runCommentsExport(accountID string, articles []string, from, to, output string) (map[string]any, error) {
+	account, err := selectAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := loadArticleStats(account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(articles) == 0 {
+		for _, rec := range filterArticleStats(sortedArticleStats(records), from, to) {
+			articles = append(articles, rec.MsgID)
+		}
+		if len(articles) == 0 {
+			return nil, fmt.Errorf("没有可导出的文章：请指定文章 ID，或先运行 'writer stats fetch --account %s --from <群发日期>'", account.ID)
+		}
+	}
+
+	ws := wechat.NewService(account, log)
+	exports := make([]*CommentExport, 0, len(articles))
+	failed := 0
+	for _, id := range articles {
+		article, err := parseCommentArticle(id)
+		if err != nil {
+			return nil, err
+		}
+		msgID := statsMsgID(article)
+		export := &CommentExport{MsgID: msgID}
+		exports = append(exports, export)
+
+		comments, err := ws.AllComments(article, wechat.CommentTypeAll)
+		if err != nil {
+			export.Error = err.Error()
+			failed++
+			log.Warn("export comments failed",
+				zap.String("msgid", msgID),
+				zap.Error(err))
+			continue
+		}
+
+		export.Comments = comments
+		export.Total = len(comments)
+		for _, c := range comments {
+			if c.Featured() {
+				export.Featured++
+			}
+			if !c.Replied() {
+				export.Unreplied++
+			}
+		}
+
+		// 留言接口不返回群发日期，未拉取过统计的文章在下次 stats fetch 时补上
+		rec := getOrCreateStats(records, msgID, "")
+		rec.Metrics.CommentCount = int64(export.Total)
+		rec.UpdatedAt = time.Now()
+		export.Title = rec.Title
+	}
+
+	if err := saveArticleStats(account.ID, records); err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{
+		"account_id": account.ID,
+		"exported":   len(exports) - failed,
+		"failed":     failed,
+	}
+	if output != "" {
+		data, err := json.MarshalIndent(exports, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("序列化留言失败: %w", err)
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return nil, fmt.Errorf("写入导出文件失败: %w", err)
+		}
+		result["output"] = output
+
+		// 汇总中不再重复留言明细
+		summary := make([]CommentExport, len(exports))
+		for i, e := range exports {
+			summary[i] = *e
+			summary[i].Comments = nil
+		}
+		result["articles"] = summary
+	} else {
+		result["articles"] = exports
+	}
+	return result, nil
+}
+
+// commentTarget 选择账号并解析文章 ID
+func commentTarget(accountID, id string) (*wechat.Service, wechat.CommentArticle, error) {
+	article, err := parseCommentArticle(id)
+	if err != nil {
+		return nil, article, err
+	}
+	account, err := selectAccount(accountID)
+	if err != nil {
+		return nil, article, err
+	}
+	return wechat.NewService(account, log), article, nil
+}
+
+// parseCommentArticle 解析 <msg_data_id>[_<篇序号>]，篇序号从 1 开始
+func parseCommentArticle(id string) (wechat.CommentArticle, error) {
+	var article wechat.CommentArticle
+	msgDataID, index, hasIndex := strings.Cut(strings.TrimSpace(id), "_")
+
+	n, err := strconv.ParseInt(msgDataID, 10, 64)
+	if err != nil || n <= 0 {
+		return article, fmt.Errorf("无效的文章 ID %q：应为 msg_data_id 或 msg_data_id_篇序号（如 2247483653_1）", id)
+	}
+	article.MsgDataID = n
+
+	if hasIndex {
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 1 {
+			return article, fmt.Errorf("无效的文章 ID %q：篇序号从 1 开始", id)
+		}
+		article.Index = i - 1
+	}
+	return article, nil
+}
+
+// statsMsgID 文章在统计数据中的 msgid（篇序号从 1 开始）
+func statsMsgID(article wechat.CommentArticle) string {
+	return fmt.Sprintf("%d_%d", article.MsgDataID, article.Index+1)
+}
+
+// parseCommentID 解析留言 ID
+func parseCommentID(s string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("无效的留言 ID %q", s)
+	}
+	return id, nil
+}
+
+// filterUnreplied 只保留未回复的留言
+func filterUnreplied(comments []*wechat.Comment) []*wechat.Comment {
+	var out []*wechat.Comment
+	for _, c := range comments {
+		if !c.Replied() {
+			out = append(out, c)
+		}
+	}
+	return out
+}
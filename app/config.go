@@ -207,6 +207,10 @@ func initConfigFile(outputFile string) error {
 		MaxImageWidth:  1920,
 		MaxImageSize:   5 * 1024 * 1024,
 		HTTPTimeout:    30,
+
+		ImageCompressMode:  config.CompressModeFixed,
+		ImageTargetSizes:   config.ImageTargetSizes{Cover: 1024, Body: 1024, GIF: 2048},
+		StripImageMetadata: true,
 	}
 
 	return config.SaveConfig(outputFile, cfg)
@@ -281,6 +285,11 @@ func configAccountsCmd() *cobra.Command {
 					"appid":         acc.AppID,
 					"keywords":      acc.Keywords,
 					"default_style": acc.DefaultStyle,
+					"preview_users": acc.PreviewUsers,
+					"api_base":      acc.APIBase,
+					"theme":         acc.Theme,
+					"footer":        acc.Footer != "",
+					"callback":      acc.CallbackToken != "",
 					"is_default":    acc.ID == cfg.DefaultAccount,
 				})
 			}
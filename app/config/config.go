@@ -13,12 +13,66 @@ import (
 
 // WechatAccount represents a single WeChat Official Account
 type WechatAccount struct {
-	ID           string   `json:"id" yaml:"id"`                       // Unique identifier
-	Name         string   `json:"name" yaml:"name"`                   // Display name
-	AppID        string   `json:"appid" yaml:"appid"`                 // WeChat AppID
-	Secret       string   `json:"secret" yaml:"secret"`               // WeChat Secret
-	Keywords     []string `json:"keywords" yaml:"keywords"`           // Auto-match keywords
-	DefaultStyle string   `json:"default_style" yaml:"default_style"` // Associated style
+	ID           string   `json:"id" yaml:"id"`                                           // Unique identifier
+	Name         string   `json:"name" yaml:"name"`                                       // Display name
+	AppID        string   `json:"appid" yaml:"appid"`                                     // WeChat AppID
+	Secret       string   `json:"secret" yaml:"secret"`                                   // WeChat Secret
+	Keywords     []string `json:"keywords" yaml:"keywords"`                               // Auto-match keywords
+	DefaultStyle string   `json:"default_style" yaml:"default_style"`                     // Associated style
+	PreviewUsers []string `json:"preview_users,omitempty" yaml:"preview_users,omitempty"` // Preview recipients (wxname or openid)
+	APIBase      string   `json:"api_base,omitempty" yaml:"api_base,omitempty"`           // WeChat API base URL (empty = https://api.weixin.qq.com)
+	Theme        string   `json:"theme,omitempty" yaml:"theme,omitempty"`                 // Conversion theme (empty = default_theme)
+	Footer       string   `json:"footer,omitempty" yaml:"footer,omitempty"`               // HTML appended to articles (inline HTML or .html file path)
+
+	CallbackToken  string `json:"callback_token,omitempty" yaml:"callback_token,omitempty"`     // Server callback token (writer serve-callback)
+	EncodingAESKey string `json:"encoding_aes_key,omitempty" yaml:"encoding_aes_key,omitempty"` // Server callback EncodingAESKey (safe mode, 43 chars)
+
+	Watermark     *Watermark `json:"watermark,omitempty" yaml:"watermark,omitempty"`           // Watermark added to uploaded body images (nil = none)
+	CoverTemplate string     `json:"cover_template,omitempty" yaml:"cover_template,omitempty"` // Brand template YAML for writer cover compose
+}
+
+// 水印位置
+const (
+	WatermarkTopLeft     = "top-left"
+	WatermarkTopRight    = "top-right"
+	WatermarkBottomLeft  = "bottom-left"
+	WatermarkBottomRight = "bottom-right"
+	WatermarkCenter      = "center"
+)
+
+// Watermark 上传正文图片时叠加的水印，按账号配置；未填写的字段使用默认值
+type Watermark struct {
+	Text     string  `json:"text,omitempty" yaml:"text,omitempty"`           // 文字水印
+	Font     string  `json:"font,omitempty" yaml:"font,omitempty"`           // 文字字体（TTF/OTF），文字含中文时必填
+	Color    string  `json:"color,omitempty" yaml:"color,omitempty"`         // 文字颜色 #RRGGBB，默认白色
+	Logo     string  `json:"logo,omitempty" yaml:"logo,omitempty"`           // Logo 图片（PNG），同时配置时优先于文字
+	Position string  `json:"position,omitempty" yaml:"position,omitempty"`   // 位置，默认 bottom-right
+	Opacity  float64 `json:"opacity,omitempty" yaml:"opacity,omitempty"`     // 不透明度 0~1，默认 0.6
+	Margin   int     `json:"margin,omitempty" yaml:"margin,omitempty"`       // 距图片边缘的像素，默认 20
+	Scale    float64 `json:"scale,omitempty" yaml:"scale,omitempty"`         // 水印宽度占图片宽度的比例 0~1，默认 0.2
+	MinWidth int     `json:"min_width,omitempty" yaml:"min_width,omitempty"` // 宽度小于该值的图片不加水印，默认 300
+}
+
+// validate 检查水印配置
+func (w *Watermark) validate() error {
+	if w.Text == "" && w.Logo == "" {
+		return fmt.Errorf("需要配置 text 或 logo")
+	}
+	switch w.Position {
+	case "", WatermarkTopLeft, WatermarkTopRight, WatermarkBottomLeft, WatermarkBottomRight, WatermarkCenter:
+	default:
+		return fmt.Errorf("不支持的位置 %q", w.Position)
+	}
+	if w.Opacity < 0 || w.Opacity > 1 {
+		return fmt.Errorf("opacity 必须在 0 到 1 之间")
+	}
+	if w.Scale < 0 || w.Scale > 1 {
+		return fmt.Errorf("scale 必须在 0 到 1 之间")
+	}
+	if w.Margin < 0 || w.MinWidth < 0 {
+		return fmt.Errorf("margin 和 min_width 不能为负数")
+	}
+	return nil
 }
 
 // Config 应用配置
@@ -31,24 +85,99 @@ type Config struct {
 	DefaultTheme string `json:"default_theme" yaml:"default_theme" env:"DEFAULT_THEME"`
 
 	// 图片生成 API 配置
-	ImageProvider string `json:"image_provider" yaml:"image_provider" env:"IMAGE_PROVIDER"`
+	ImageProvider string `json:"image_provider" yaml:"image_provider" env:"IMAGE_PROVIDER"` // 可为逗号分隔的多个提供者，按顺序降级
 	ImageAPIKey   string `json:"image_api_key" yaml:"image_api_key" env:"IMAGE_API_KEY"`
 	ImageAPIBase  string `json:"image_api_base" yaml:"image_api_base" env:"IMAGE_API_BASE"`
 	ImageModel    string `json:"image_model" yaml:"image_model" env:"IMAGE_MODEL"`
 	ImageSize     string `json:"image_size" yaml:"image_size" env:"IMAGE_SIZE"`
+	ImageWorkflow string `json:"image_workflow" yaml:"image_workflow" env:"IMAGE_WORKFLOW"` // ComfyUI 工作流 JSON 路径（API 格式）
+
+	// 各图片服务单独的配置（降级链中的备用服务使用），键为提供者名称
+	ImageProviders map[string]ImageProviderConfig `json:"image_providers" yaml:"image_providers"`
+	ImageRetries   int                            `json:"image_retries" yaml:"image_retries" env:"IMAGE_RETRIES"` // 可重试错误的重试次数
 
 	// 图片处理配置
 	CompressImages bool  `json:"compress_images" yaml:"compress_images" env:"COMPRESS_IMAGES"`
 	MaxImageWidth  int   `json:"max_image_width" yaml:"max_image_width" env:"MAX_IMAGE_WIDTH"`
 	MaxImageSize   int64 `json:"max_image_size" yaml:"max_image_size" env:"MAX_IMAGE_SIZE"`
 
+	ImageCompressMode  string           `json:"image_compress_mode" yaml:"image_compress_mode" env:"IMAGE_COMPRESS_MODE"`    // fixed（固定质量）或 target（按目标大小搜索）
+	ImageTargetSizes   ImageTargetSizes `json:"image_target_sizes" yaml:"image_target_sizes"`                                // target 模式下各类图片的目标大小
+	StripImageMetadata bool             `json:"strip_image_metadata" yaml:"strip_image_metadata" env:"STRIP_IMAGE_METADATA"` // 上传前去除 EXIF/GPS 等元数据
+
+	// 图片并发处理配置
+	ImageConcurrency int            `json:"image_concurrency" yaml:"image_concurrency" env:"IMAGE_CONCURRENCY"` // 同时处理的图片数
+	ImageRateLimits  map[string]int `json:"image_rate_limits" yaml:"image_rate_limits"`                         // 各图片服务每分钟最多生成次数
+
 	// 超时配置
 	HTTPTimeout int `json:"http_timeout" yaml:"http_timeout" env:"HTTP_TIMEOUT"`
 
+	// 本地数据目录（上传缓存等）
+	DataDir string `json:"data_dir" yaml:"data_dir" env:"DATA_DIR"`
+
+	// 事件回调转发地址（writer serve-callback）
+	CallbackWebhook string `json:"callback_webhook" yaml:"callback_webhook" env:"CALLBACK_WEBHOOK"`
+
 	// 配置文件路径（用于追踪）
 	configFile string
 }
 
+// 图片压缩模式
+const (
+	CompressModeFixed  = "fixed"  // 超过 max_size_mb 时用固定 JPEG 质量重新编码
+	CompressModeTarget = "target" // 二分搜索质量、再缩小尺寸，直到不超过目标大小
+)
+
+// ImageTargetSizes target 压缩模式下各类图片的目标大小（KB）
+type ImageTargetSizes struct {
+	Cover int `json:"cover" yaml:"cover"` // 封面
+	Body  int `json:"body" yaml:"body"`   // 正文配图
+	GIF   int `json:"gif" yaml:"gif"`     // GIF 动图（不超过 2048）
+}
+
+// ImageProviderConfig 单个图片服务的配置，未填写的字段使用该服务的默认值
+type ImageProviderConfig struct {
+	Key      string `json:"key" yaml:"key"`
+	BaseURL  string `json:"base_url" yaml:"base_url"`
+	Model    string `json:"model" yaml:"model"`
+	Size     string `json:"size" yaml:"size"`
+	Workflow string `json:"workflow" yaml:"workflow"`
+}
+
+// providerList api.image_provider 支持单个名称、逗号分隔的名称或列表，统一存为逗号分隔字符串
+type providerList string
+
+func (l *providerList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		var names []string
+		if err := value.Decode(&names); err != nil {
+			return err
+		}
+		*l = providerList(strings.Join(names, ","))
+		return nil
+	}
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	*l = providerList(s)
+	return nil
+}
+
+func (l *providerList) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err == nil {
+		*l = providerList(strings.Join(names, ","))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*l = providerList(s)
+	return nil
+}
+
 // ConfigFile 配置文件结构（YAML/JSON）
 type configFile struct {
 	Wechat struct {
@@ -57,22 +186,40 @@ type configFile struct {
 	} `json:"wechat" yaml:"wechat"`
 
 	API struct {
-		WechatWriterKey string `json:"wechat_writer_key" yaml:"wechat_writer_key"`
-		ImageKey        string `json:"image_key" yaml:"image_key"`
-		ImageBaseURL    string `json:"image_base_url" yaml:"image_base_url"`
-		ImageProvider   string `json:"image_provider" yaml:"image_provider"`
-		ImageModel      string `json:"image_model" yaml:"image_model"`
-		ImageSize       string `json:"image_size" yaml:"image_size"`
-		ConvertMode     string `json:"convert_mode" yaml:"convert_mode"`
-		DefaultTheme    string `json:"default_theme" yaml:"default_theme"`
-		HTTPTimeout     int    `json:"http_timeout" yaml:"http_timeout"`
+		WechatWriterKey string                         `json:"wechat_writer_key" yaml:"wechat_writer_key"`
+		ImageKey        string                         `json:"image_key" yaml:"image_key"`
+		ImageBaseURL    string                         `json:"image_base_url" yaml:"image_base_url"`
+		ImageProvider   providerList                   `json:"image_provider" yaml:"image_provider"`
+		ImageModel      string                         `json:"image_model" yaml:"image_model"`
+		ImageSize       string                         `json:"image_size" yaml:"image_size"`
+		ImageWorkflow   string                         `json:"image_workflow,omitempty" yaml:"image_workflow,omitempty"`
+		ImageProviders  map[string]ImageProviderConfig `json:"image_providers,omitempty" yaml:"image_providers,omitempty"`
+		ConvertMode     string                         `json:"convert_mode" yaml:"convert_mode"`
+		DefaultTheme    string                         `json:"default_theme" yaml:"default_theme"`
+		HTTPTimeout     int                            `json:"http_timeout" yaml:"http_timeout"`
 	} `json:"api" yaml:"api"`
 
 	Image struct {
 		Compress bool `json:"compress" yaml:"compress"`
 		MaxWidth int  `json:"max_width" yaml:"max_width"`
 		MaxSize  int  `json:"max_size_mb" yaml:"max_size_mb"`
+
+		Concurrency int            `json:"concurrency" yaml:"concurrency"`
+		RateLimits  map[string]int `json:"rate_limits,omitempty" yaml:"rate_limits,omitempty"`
+		Retries     *int           `json:"retries" yaml:"retries"`
+
+		CompressMode  string           `json:"compress_mode,omitempty" yaml:"compress_mode,omitempty"`
+		TargetKB      ImageTargetSizes `json:"target_kb" yaml:"target_kb"`
+		StripMetadata *bool            `json:"strip_metadata,omitempty" yaml:"strip_metadata,omitempty"`
 	} `json:"image" yaml:"image"`
+
+	Storage struct {
+		DataDir string `json:"data_dir" yaml:"data_dir"`
+	} `json:"storage" yaml:"storage"`
+
+	Callback struct {
+		Webhook string `json:"webhook,omitempty" yaml:"webhook,omitempty"`
+	} `json:"callback" yaml:"callback"`
 }
 
 // Load 从配置文件和环境变量加载配置
@@ -84,15 +231,20 @@ func Load() (*Config, error) {
 // LoadWithDefaults 使用指定配置文件路径加载配置
 func LoadWithDefaults(configPath string) (*Config, error) {
 	cfg := &Config{
-		DefaultTheme:   "default",
-		CompressImages: true,
-		MaxImageWidth:  1920,
-		MaxImageSize:   5 * 1024 * 1024, // 5MB
-		HTTPTimeout:    30,
-		ImageProvider:  "openai",
-		ImageAPIBase:   "https://api.openai.com/v1",
-		ImageModel:     "dall-e-3",
-		ImageSize:      "1024x1024",
+		DefaultTheme:       "default",
+		CompressImages:     true,
+		MaxImageWidth:      1920,
+		MaxImageSize:       5 * 1024 * 1024, // 5MB
+		ImageCompressMode:  CompressModeFixed,
+		ImageTargetSizes:   ImageTargetSizes{Cover: 1024, Body: 1024, GIF: 2048},
+		StripImageMetadata: true,
+		ImageConcurrency:   4,
+		ImageRetries:       2,
+		HTTPTimeout:        30,
+		ImageProvider:      "openai",
+		ImageAPIBase:       DefaultImageAPIBase,
+		ImageModel:         "dall-e-3",
+		ImageSize:          "1024x1024",
 	}
 
 	// 1. 尝试从配置文件加载
@@ -201,7 +353,7 @@ func loadFromYAML(cfg *Config, data []byte) error {
 		cfg.ImageAPIBase = cf.API.ImageBaseURL
 	}
 	if cf.API.ImageProvider != "" {
-		cfg.ImageProvider = cf.API.ImageProvider
+		cfg.ImageProvider = string(cf.API.ImageProvider)
 	}
 	if cf.API.ImageModel != "" {
 		cfg.ImageModel = cf.API.ImageModel
@@ -209,6 +361,12 @@ func loadFromYAML(cfg *Config, data []byte) error {
 	if cf.API.ImageSize != "" {
 		cfg.ImageSize = cf.API.ImageSize
 	}
+	if cf.API.ImageWorkflow != "" {
+		cfg.ImageWorkflow = cf.API.ImageWorkflow
+	}
+	if len(cf.API.ImageProviders) > 0 {
+		cfg.ImageProviders = cf.API.ImageProviders
+	}
 	if cf.API.DefaultTheme != "" {
 		cfg.DefaultTheme = cf.API.DefaultTheme
 	}
@@ -222,6 +380,36 @@ func loadFromYAML(cfg *Config, data []byte) error {
 	if cf.Image.MaxSize > 0 {
 		cfg.MaxImageSize = int64(cf.Image.MaxSize) * 1024 * 1024
 	}
+	if cf.Image.Concurrency > 0 {
+		cfg.ImageConcurrency = cf.Image.Concurrency
+	}
+	if len(cf.Image.RateLimits) > 0 {
+		cfg.ImageRateLimits = cf.Image.RateLimits
+	}
+	if cf.Image.Retries != nil {
+		cfg.ImageRetries = *cf.Image.Retries
+	}
+	if cf.Image.CompressMode != "" {
+		cfg.ImageCompressMode = cf.Image.CompressMode
+	}
+	if cf.Image.TargetKB.Cover > 0 {
+		cfg.ImageTargetSizes.Cover = cf.Image.TargetKB.Cover
+	}
+	if cf.Image.TargetKB.Body > 0 {
+		cfg.ImageTargetSizes.Body = cf.Image.TargetKB.Body
+	}
+	if cf.Image.TargetKB.GIF > 0 {
+		cfg.ImageTargetSizes.GIF = cf.Image.TargetKB.GIF
+	}
+	if cf.Image.StripMetadata != nil {
+		cfg.StripImageMetadata = *cf.Image.StripMetadata
+	}
+	if cf.Storage.DataDir != "" {
+		cfg.DataDir = cf.Storage.DataDir
+	}
+	if cf.Callback.Webhook != "" {
+		cfg.CallbackWebhook = cf.Callback.Webhook
+	}
 
 	return nil
 }
@@ -245,7 +433,7 @@ func loadFromJSON(cfg *Config, data []byte) error {
 		cfg.ImageAPIBase = cf.API.ImageBaseURL
 	}
 	if cf.API.ImageProvider != "" {
-		cfg.ImageProvider = cf.API.ImageProvider
+		cfg.ImageProvider = string(cf.API.ImageProvider)
 	}
 	if cf.API.ImageModel != "" {
 		cfg.ImageModel = cf.API.ImageModel
@@ -253,6 +441,12 @@ func loadFromJSON(cfg *Config, data []byte) error {
 	if cf.API.ImageSize != "" {
 		cfg.ImageSize = cf.API.ImageSize
 	}
+	if cf.API.ImageWorkflow != "" {
+		cfg.ImageWorkflow = cf.API.ImageWorkflow
+	}
+	if len(cf.API.ImageProviders) > 0 {
+		cfg.ImageProviders = cf.API.ImageProviders
+	}
 	if cf.API.DefaultTheme != "" {
 		cfg.DefaultTheme = cf.API.DefaultTheme
 	}
@@ -266,6 +460,36 @@ func loadFromJSON(cfg *Config, data []byte) error {
 	if cf.Image.MaxSize > 0 {
 		cfg.MaxImageSize = int64(cf.Image.MaxSize) * 1024 * 1024
 	}
+	if cf.Image.Concurrency > 0 {
+		cfg.ImageConcurrency = cf.Image.Concurrency
+	}
+	if len(cf.Image.RateLimits) > 0 {
+		cfg.ImageRateLimits = cf.Image.RateLimits
+	}
+	if cf.Image.Retries != nil {
+		cfg.ImageRetries = *cf.Image.Retries
+	}
+	if cf.Image.CompressMode != "" {
+		cfg.ImageCompressMode = cf.Image.CompressMode
+	}
+	if cf.Image.TargetKB.Cover > 0 {
+		cfg.ImageTargetSizes.Cover = cf.Image.TargetKB.Cover
+	}
+	if cf.Image.TargetKB.Body > 0 {
+		cfg.ImageTargetSizes.Body = cf.Image.TargetKB.Body
+	}
+	if cf.Image.TargetKB.GIF > 0 {
+		cfg.ImageTargetSizes.GIF = cf.Image.TargetKB.GIF
+	}
+	if cf.Image.StripMetadata != nil {
+		cfg.StripImageMetadata = *cf.Image.StripMetadata
+	}
+	if cf.Storage.DataDir != "" {
+		cfg.DataDir = cf.Storage.DataDir
+	}
+	if cf.Callback.Webhook != "" {
+		cfg.CallbackWebhook = cf.Callback.Webhook
+	}
 
 	return nil
 }
@@ -293,6 +517,9 @@ func loadFromEnv(cfg *Config) {
 	if v := os.Getenv("IMAGE_SIZE"); v != "" {
 		cfg.ImageSize = v
 	}
+	if v := os.Getenv("IMAGE_WORKFLOW"); v != "" {
+		cfg.ImageWorkflow = v
+	}
 	if v := os.Getenv("COMPRESS_IMAGES"); v != "" {
 		cfg.CompressImages = getEnvBool("COMPRESS_IMAGES", true)
 	}
@@ -302,9 +529,27 @@ func loadFromEnv(cfg *Config) {
 	if v := os.Getenv("MAX_IMAGE_SIZE"); v != "" {
 		cfg.MaxImageSize = int64(getEnvInt("MAX_IMAGE_SIZE", int(cfg.MaxImageSize)))
 	}
+	if v := os.Getenv("IMAGE_CONCURRENCY"); v != "" {
+		cfg.ImageConcurrency = getEnvInt("IMAGE_CONCURRENCY", cfg.ImageConcurrency)
+	}
+	if v := os.Getenv("IMAGE_RETRIES"); v != "" {
+		cfg.ImageRetries = getEnvInt("IMAGE_RETRIES", cfg.ImageRetries)
+	}
+	if v := os.Getenv("IMAGE_COMPRESS_MODE"); v != "" {
+		cfg.ImageCompressMode = v
+	}
+	if v := os.Getenv("STRIP_IMAGE_METADATA"); v != "" {
+		cfg.StripImageMetadata = getEnvBool("STRIP_IMAGE_METADATA", true)
+	}
 	if v := os.Getenv("HTTP_TIMEOUT"); v != "" {
 		cfg.HTTPTimeout = getEnvInt("HTTP_TIMEOUT", cfg.HTTPTimeout)
 	}
+	if v := os.Getenv("DATA_DIR"); v != "" {
+		cfg.DataDir = v
+	}
+	if v := os.Getenv("CALLBACK_WEBHOOK"); v != "" {
+		cfg.CallbackWebhook = v
+	}
 }
 
 // Validate 验证配置
@@ -340,6 +585,15 @@ func (c *Config) Validate() error {
 				Hint:    "登录微信公众平台 > 设置与开发 > 基本配置 > 获取 Secret",
 			}
 		}
+		if acc.Watermark != nil {
+			if err := acc.Watermark.validate(); err != nil {
+				return &ConfigError{
+					Field:   fmt.Sprintf("WechatAccounts[%d].Watermark", i),
+					Message: fmt.Sprintf("账号 '%s' 的水印配置错误: %v", acc.ID, err),
+					Hint:    "配置文件中设置 wechat.accounts[].watermark: {text: \"@账号名\", position: bottom-right, opacity: 0.6}",
+				}
+			}
+		}
 	}
 
 	// 验证数值范围
@@ -357,6 +611,36 @@ func (c *Config) Validate() error {
 			Hint:    "配置文件中设置 image.max_size_mb: 5",
 		}
 	}
+	if c.ImageConcurrency < 0 || c.ImageConcurrency > 16 {
+		return &ConfigError{
+			Field:   "ImageConcurrency",
+			Message: "图片并发数必须在 1 到 16 之间（0 使用默认值 4）",
+			Hint:    "配置文件中设置 image.concurrency: 4",
+		}
+	}
+	if c.ImageRetries < 0 || c.ImageRetries > 10 {
+		return &ConfigError{
+			Field:   "ImageRetries",
+			Message: "图片生成重试次数必须在 0 到 10 之间",
+			Hint:    "配置文件中设置 image.retries: 2",
+		}
+	}
+	switch c.ImageCompressMode {
+	case "", CompressModeFixed, CompressModeTarget:
+	default:
+		return &ConfigError{
+			Field:   "ImageCompressMode",
+			Message: fmt.Sprintf("不支持的图片压缩模式: %s", c.ImageCompressMode),
+			Hint:    "配置文件中设置 image.compress_mode: fixed 或 target",
+		}
+	}
+	if c.ImageTargetSizes.Cover < 0 || c.ImageTargetSizes.Body < 0 || c.ImageTargetSizes.GIF < 0 || c.ImageTargetSizes.GIF > 2048 {
+		return &ConfigError{
+			Field:   "ImageTargetSizes",
+			Message: "图片目标大小不能为负数，GIF 目标大小不能超过 2048KB",
+			Hint:    "配置文件中设置 image.target_kb: {cover: 1024, body: 1024, gif: 2048}",
+		}
+	}
 	if c.HTTPTimeout < 1 || c.HTTPTimeout > 300 {
 		return &ConfigError{
 			Field:   "HTTPTimeout",
@@ -368,12 +652,81 @@ func (c *Config) Validate() error {
 	return nil
 }
 
-// ValidateForImageGeneration 验证图片生成配置
+// DefaultImageAPIBase 未配置 api.image_base_url 时的图片 API 地址
+const DefaultImageAPIBase = "https://api.openai.com/v1"
+
+// LocalImageProvider 首选图片服务是否为自建的本地服务（Stable Diffusion WebUI / ComfyUI），不需要 API Key
+func (c *Config) LocalImageProvider() bool {
+	return isLocalImageProvider(c.ImageProviderChain()[0])
+}
+
+func isLocalImageProvider(name string) bool {
+	switch name {
+	case "sdwebui", "sd", "comfyui":
+		return true
+	}
+	return false
+}
+
+// ImageProviderChain 按降级顺序返回图片服务提供者名称，未配置时为 openai
+func (c *Config) ImageProviderChain() []string {
+	var chain []string
+	for _, name := range strings.Split(c.ImageProvider, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			chain = append(chain, name)
+		}
+	}
+	if len(chain) == 0 {
+		chain = []string{"openai"}
+	}
+	return chain
+}
+
+// ForImageProvider 返回单个提供者使用的配置副本
+//
+// 首选提供者沿用 api.image_key 等顶层配置；降级链中的其他提供者只使用
+// api.image_providers 中的配置（尺寸沿用顶层），两者都可被 image_providers 覆盖。
+func (c *Config) ForImageProvider(name string) *Config {
+	sub := *c
+	sub.ImageProvider = name
+	if name != c.ImageProviderChain()[0] {
+		sub.ImageAPIKey = ""
+		sub.ImageAPIBase = ""
+		sub.ImageModel = ""
+		sub.ImageWorkflow = ""
+		if name == "openai" {
+			sub.ImageAPIBase = DefaultImageAPIBase
+		}
+	}
+
+	if pc, ok := c.ImageProviders[name]; ok {
+		if pc.Key != "" {
+			sub.ImageAPIKey = pc.Key
+		}
+		if pc.BaseURL != "" {
+			sub.ImageAPIBase = pc.BaseURL
+		}
+		if pc.Model != "" {
+			sub.ImageModel = pc.Model
+		}
+		if pc.Size != "" {
+			sub.ImageSize = pc.Size
+		}
+		if pc.Workflow != "" {
+			sub.ImageWorkflow = pc.Workflow
+		}
+	}
+	return &sub
+}
+
+// ValidateForImageGeneration 验证图片生成配置：降级链中至少有一个提供者可用
 func (c *Config) ValidateForImageGeneration() error {
-	if c.ImageAPIKey == "" {
-		return &ConfigError{Field: "ImageAPIKey", Message: "IMAGE_API_KEY is required for image generation"}
+	for _, name := range c.ImageProviderChain() {
+		if isLocalImageProvider(name) || c.ForImageProvider(name).ImageAPIKey != "" {
+			return nil
+		}
 	}
-	return nil
+	return &ConfigError{Field: "ImageAPIKey", Message: "IMAGE_API_KEY is required for image generation"}
 }
 
 // GetConfigFile 获取配置文件路径
@@ -381,6 +734,20 @@ func (c *Config) GetConfigFile() string {
 	return c.configFile
 }
 
+// GetDataDir 获取本地数据目录，未配置时使用 ~/.config/wechatwriter/data
+func (c *Config) GetDataDir() string {
+	if c.DataDir != "" {
+		return c.DataDir
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "wechatwriter", "data")
+}
+
+// AccountDataDir 获取指定账号的本地数据目录（素材 ID 按账号隔离）
+func (c *Config) AccountDataDir(accountID string) string {
+	return filepath.Join(c.GetDataDir(), "accounts", accountID)
+}
+
 // ToMap 转换为 map 用于显示
 func (c *Config) ToMap(maskSecret bool) map[string]any {
 	// Build accounts list with masked secrets
@@ -393,23 +760,38 @@ func (c *Config) ToMap(maskSecret bool) map[string]any {
 			"secret":        maskIf(acc.Secret, maskSecret),
 			"keywords":      acc.Keywords,
 			"default_style": acc.DefaultStyle,
+			"preview_users": acc.PreviewUsers,
+			"api_base":      acc.APIBase,
+			"theme":         acc.Theme,
+			"footer":        acc.Footer != "",
+			"callback":      acc.CallbackToken != "",
 		}
 	}
 
 	result := map[string]any{
-		"wechat_accounts":   accounts,
-		"default_account":   c.DefaultAccount,
-		"default_theme":     c.DefaultTheme,
-		"image_provider":    c.ImageProvider,
-		"image_api_key":     maskIf(c.ImageAPIKey, maskSecret),
-		"image_api_base":    c.ImageAPIBase,
-		"image_model":       c.ImageModel,
-		"image_size":        c.ImageSize,
-		"compress_images":   c.CompressImages,
-		"max_image_width":   c.MaxImageWidth,
-		"max_image_size_mb": c.MaxImageSize / 1024 / 1024,
-		"http_timeout":      c.HTTPTimeout,
-		"config_file":       c.configFile,
+		"wechat_accounts":      accounts,
+		"default_account":      c.DefaultAccount,
+		"default_theme":        c.DefaultTheme,
+		"image_provider":       c.ImageProvider,
+		"image_api_key":        maskIf(c.ImageAPIKey, maskSecret),
+		"image_api_base":       c.ImageAPIBase,
+		"image_model":          c.ImageModel,
+		"image_size":           c.ImageSize,
+		"image_workflow":       c.ImageWorkflow,
+		"compress_images":      c.CompressImages,
+		"max_image_width":      c.MaxImageWidth,
+		"max_image_size_mb":    c.MaxImageSize / 1024 / 1024,
+		"image_compress_mode":  c.ImageCompressMode,
+		"image_target_kb":      c.ImageTargetSizes,
+		"strip_image_metadata": c.StripImageMetadata,
+		"image_concurrency":    c.ImageConcurrency,
+		"image_rate_limits":    c.ImageRateLimits,
+		"image_retries":        c.ImageRetries,
+		"image_providers":      maskProviderKeys(c.ImageProviders, maskSecret),
+		"http_timeout":         c.HTTPTimeout,
+		"data_dir":             c.GetDataDir(),
+		"callback_webhook":     c.CallbackWebhook,
+		"config_file":          c.configFile,
 	}
 	return result
 }
@@ -426,14 +808,24 @@ func SaveConfig(path string, cfg *Config) error {
 
 	cf.API.ImageKey = cfg.ImageAPIKey
 	cf.API.ImageBaseURL = cfg.ImageAPIBase
-	cf.API.ImageProvider = cfg.ImageProvider
+	cf.API.ImageProvider = providerList(cfg.ImageProvider)
+	cf.API.ImageProviders = cfg.ImageProviders
 	cf.API.ImageModel = cfg.ImageModel
 	cf.API.ImageSize = cfg.ImageSize
+	cf.API.ImageWorkflow = cfg.ImageWorkflow
 	cf.API.DefaultTheme = cfg.DefaultTheme
 	cf.API.HTTPTimeout = cfg.HTTPTimeout
 	cf.Image.Compress = cfg.CompressImages
 	cf.Image.MaxWidth = cfg.MaxImageWidth
 	cf.Image.MaxSize = int(cfg.MaxImageSize / 1024 / 1024)
+	cf.Image.Concurrency = cfg.ImageConcurrency
+	cf.Image.RateLimits = cfg.ImageRateLimits
+	cf.Image.Retries = &cfg.ImageRetries
+	cf.Image.CompressMode = cfg.ImageCompressMode
+	cf.Image.TargetKB = cfg.ImageTargetSizes
+	cf.Image.StripMetadata = &cfg.StripImageMetadata
+	cf.Storage.DataDir = cfg.DataDir
+	cf.Callback.Webhook = cfg.CallbackWebhook
 
 	var data []byte
 	var err error
@@ -511,6 +903,19 @@ func maskIf(value string, mask bool) string {
 	return value[:2] + "***" + value[len(value)-2:]
 }
 
+// maskProviderKeys 脱敏各图片服务的 API Key
+func maskProviderKeys(providers map[string]ImageProviderConfig, mask bool) map[string]ImageProviderConfig {
+	if len(providers) == 0 {
+		return nil
+	}
+	out := make(map[string]ImageProviderConfig, len(providers))
+	for name, pc := range providers {
+		pc.Key = maskIf(pc.Key, mask)
+		out[name] = pc
+	}
+	return out
+}
+
 // getRelativePath 获取相对路径（用于更友好的显示）
 func getRelativePath(fullPath string) string {
 	// 如果是用户目录，显示为 ~/.wechatwriter.yaml
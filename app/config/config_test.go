@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -534,6 +535,51 @@ func TestSaveConfig_JSON(t *testing.T) {
 	}
 }
 
+func TestSaveConfig_RoundTrip(t *testing.T) {
+	cfg := &Config{
+		WechatAccounts:   []WechatAccount{{ID: "test-1", AppID: "wx123456", Secret: "secret123"}},
+		DefaultAccount:   "test-1",
+		MaxImageWidth:    1920,
+		MaxImageSize:     5 * 1024 * 1024,
+		HTTPTimeout:      30,
+		ImageConcurrency: 8,
+		ImageRateLimits:  map[string]int{"openai": 5},
+		ImageRetries:     0,
+		ImageWorkflow:    "/opt/comfyui/workflow_api.json",
+		CallbackWebhook:  "https://hooks.example.com/wechat",
+	}
+
+	for _, name := range []string{"test.yaml", "test.json"} {
+		t.Run(name, func(t *testing.T) {
+			tmpFile := filepath.Join(t.TempDir(), name)
+			if err := SaveConfig(tmpFile, cfg); err != nil {
+				t.Fatalf("SaveConfig() error = %v", err)
+			}
+
+			// 预置默认值：显式保存的 0 次重试不能在加载时回退为默认值
+			loaded := &Config{ImageRetries: 2}
+			if err := loadFromFile(loaded, tmpFile); err != nil {
+				t.Fatalf("loadFromFile() error = %v", err)
+			}
+			if loaded.ImageConcurrency != cfg.ImageConcurrency {
+				t.Errorf("ImageConcurrency = %d, want %d", loaded.ImageConcurrency, cfg.ImageConcurrency)
+			}
+			if loaded.ImageRateLimits["openai"] != 5 {
+				t.Errorf("ImageRateLimits = %v, want openai: 5", loaded.ImageRateLimits)
+			}
+			if loaded.ImageWorkflow != cfg.ImageWorkflow {
+				t.Errorf("ImageWorkflow = %q, want %q", loaded.ImageWorkflow, cfg.ImageWorkflow)
+			}
+			if loaded.CallbackWebhook != cfg.CallbackWebhook {
+				t.Errorf("CallbackWebhook = %q, want %q", loaded.CallbackWebhook, cfg.CallbackWebhook)
+			}
+			if loaded.ImageRetries != 0 {
+				t.Errorf("ImageRetries = %d, want 0", loaded.ImageRetries)
+			}
+		})
+	}
+}
+
 func TestConfig_ToMap(t *testing.T) {
 	cfg := &Config{
 		WechatAccounts: []WechatAccount{
@@ -595,6 +641,68 @@ func TestConfig_ValidateForImageGeneration(t *testing.T) {
 	}
 }
 
+func TestConfig_ImageProviderChain(t *testing.T) {
+	configContent := `
+wechat:
+  accounts:
+    - id: "main"
+      appid: "wx_test"
+      secret: "secret_test"
+api:
+  image_provider: [modelscope, tuzi, openai]
+  image_key: "ms_key"
+  image_base_url: "https://api-inference.modelscope.cn/"
+  image_size: "1024x1024"
+  image_providers:
+    tuzi:
+      key: "tuzi_key"
+      base_url: "https://api.tu-zi.com/v1"
+      model: "doubao-seedream-4-5-251128"
+      size: "2048x2048"
+image:
+  max_size_mb: 5
+  retries: 0
+`
+	tmpFile := filepath.Join(t.TempDir(), "test.yaml")
+	if err := os.WriteFile(tmpFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create temp config file: %v", err)
+	}
+	cfg, err := LoadWithDefaults(tmpFile)
+	if err != nil {
+		t.Fatalf("LoadWithDefaults() error = %v", err)
+	}
+
+	chain := cfg.ImageProviderChain()
+	if strings.Join(chain, ",") != "modelscope,tuzi,openai" {
+		t.Fatalf("ImageProviderChain() = %v", chain)
+	}
+	if cfg.ImageRetries != 0 {
+		t.Errorf("ImageRetries = %d, want 0", cfg.ImageRetries)
+	}
+
+	primary := cfg.ForImageProvider("modelscope")
+	if primary.ImageAPIKey != "ms_key" || primary.ImageProvider != "modelscope" {
+		t.Errorf("primary = %s/%s, want top-level settings", primary.ImageProvider, primary.ImageAPIKey)
+	}
+
+	tuzi := cfg.ForImageProvider("tuzi")
+	if tuzi.ImageAPIKey != "tuzi_key" || tuzi.ImageAPIBase != "https://api.tu-zi.com/v1" || tuzi.ImageSize != "2048x2048" {
+		t.Errorf("tuzi = %+v, want image_providers.tuzi settings", tuzi)
+	}
+
+	// 未单独配置的备用服务不继承首选服务的 Key
+	openai := cfg.ForImageProvider("openai")
+	if openai.ImageAPIKey != "" || openai.ImageAPIBase != DefaultImageAPIBase || openai.ImageSize != "1024x1024" {
+		t.Errorf("openai = %s/%s/%s", openai.ImageAPIKey, openai.ImageAPIBase, openai.ImageSize)
+	}
+
+	// 首选服务缺少 Key 时，备用服务可用也算通过
+	cfg.ImageAPIKey = ""
+	if err := cfg.ValidateForImageGeneration(); err != nil {
+		t.Errorf("ValidateForImageGeneration() error = %v, want nil", err)
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && (s[:len(substr)] == substr || s[len(s)-len(substr):] == substr || containsMiddle(s, substr)))
 }
@@ -607,3 +715,76 @@ func containsMiddle(s, substr string) bool {
 	}
 	return false
 }
+
+func TestConfig_ImageCompressMode(t *testing.T) {
+	configContent := `
+wechat:
+  accounts:
+    - id: "main"
+      appid: "wx_test"
+      secret: "secret_test"
+image:
+  max_size_mb: 5
+  compress_mode: target
+  target_kb:
+    cover: 500
+  strip_metadata: false
+`
+	tmpFile := filepath.Join(t.TempDir(), "test.yaml")
+	if err := os.WriteFile(tmpFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create temp config file: %v", err)
+	}
+	cfg, err := LoadWithDefaults(tmpFile)
+	if err != nil {
+		t.Fatalf("LoadWithDefaults() error = %v", err)
+	}
+
+	if cfg.ImageCompressMode != CompressModeTarget || cfg.StripImageMetadata {
+		t.Errorf("mode = %s, strip = %v", cfg.ImageCompressMode, cfg.StripImageMetadata)
+	}
+	want := ImageTargetSizes{Cover: 500, Body: 1024, GIF: 2048}
+	if cfg.ImageTargetSizes != want {
+		t.Errorf("ImageTargetSizes = %+v, want %+v", cfg.ImageTargetSizes, want)
+	}
+
+	cfg.ImageCompressMode = "lossless"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject unknown compress mode")
+	}
+}
+
+func TestConfig_AccountWatermark(t *testing.T) {
+	configContent := `
+wechat:
+  accounts:
+    - id: "main"
+      appid: "wx_test"
+      secret: "secret_test"
+      watermark:
+        text: "@main"
+        position: top-left
+        opacity: 0.4
+`
+	tmpFile := filepath.Join(t.TempDir(), "test.yaml")
+	if err := os.WriteFile(tmpFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create temp config file: %v", err)
+	}
+	cfg, err := LoadWithDefaults(tmpFile)
+	if err != nil {
+		t.Fatalf("LoadWithDefaults() error = %v", err)
+	}
+
+	wm := cfg.WechatAccounts[0].Watermark
+	if wm == nil || wm.Text != "@main" || wm.Position != WatermarkTopLeft || wm.Opacity != 0.4 {
+		t.Fatalf("Watermark = %+v", wm)
+	}
+
+	wm.Position = "middle"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject unknown watermark position")
+	}
+	wm.Position, wm.Opacity = "", 1.5
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject opacity > 1")
+	}
+}
@@ -1,15 +1,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/royalrick/wechatwriter/app/config"
 	"github.com/royalrick/wechatwriter/app/converter"
 	"github.com/royalrick/wechatwriter/app/draft"
 	"github.com/royalrick/wechatwriter/app/image"
-	"github.com/royalrick/wechatwriter/app/wechat"
+	"github.com/royalrick/wechatwriter/app/media"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -49,7 +51,12 @@ var (
 	convertUpload       bool
 	convertDraft        bool
 	convertSaveDraft    string
-	convertCoverImage   string // 封面图片路径
+	convertCoverImage   string   // 封面图片路径
+	convertHTML         []string // 已转换的 HTML（path 或 account=path）
+	convertAccounts     []string // 多账号发布的账号 ID
+	convertAllAccounts  bool     // 发布到全部账号
+	convertForceNew     bool     // 总是新建草稿，不更新已有草稿
+	convertConcurrency  int      // 同时处理的图片数（0 使用配置）
 )
 
 func init() {
@@ -65,6 +72,11 @@ func init() {
 	convertCmd.Flags().BoolVar(&convertDraft, "draft", false, "Create WeChat draft after conversion")
 	convertCmd.Flags().StringVar(&convertSaveDraft, "save-draft", "", "Save draft JSON to file")
 	convertCmd.Flags().StringVar(&convertCoverImage, "cover", "", "Cover image path for draft (required when using --draft)")
+	convertCmd.Flags().StringArrayVar(&convertHTML, "html", nil, "Use converted HTML instead of building an AI request (path, or account=path per account)")
+	convertCmd.Flags().StringSliceVar(&convertAccounts, "accounts", nil, "Create drafts on multiple accounts (comma separated IDs, requires --draft)")
+	convertCmd.Flags().BoolVar(&convertAllAccounts, "all-accounts", false, "Create drafts on all configured accounts (requires --draft)")
+	convertCmd.Flags().BoolVar(&convertForceNew, "force-new", false, "Always create a new draft instead of updating the one created from this file")
+	convertCmd.Flags().IntVar(&convertConcurrency, "concurrency", 0, "Number of images processed in parallel (0 = image.concurrency)")
 }
 
 // runConvert 执行转换
@@ -82,19 +94,62 @@ func runConvert(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("read markdown file: %w", err)
 	}
 
+	// 解析 front matter（标题、作者、封面、评论设置等草稿字段）
+	meta, body, err := converter.ParseFrontMatter(string(markdown))
+	if err != nil {
+		return err
+	}
+
+	htmlInput, err := parseHTMLInputs(convertHTML)
+	if err != nil {
+		return err
+	}
+
+	// 多账号发布
+	if len(convertAccounts) > 0 || convertAllAccounts {
+		if !convertDraft {
+			return fmt.Errorf("--accounts / --all-accounts 需要配合 --draft 使用")
+		}
+		accounts, err := resolveFanOutAccounts(convertAccounts, convertAllAccounts)
+		if err != nil {
+			return err
+		}
+		return runConvertFanOut(cmd, meta, markdown, body, markdownFile, accounts, htmlInput)
+	}
+	if len(htmlInput.byAccount) > 0 {
+		return fmt.Errorf("--html account=path 只能与 --accounts / --all-accounts 一起使用")
+	}
+
 	// 创建转换器
 	conv := converter.NewConverter(cfg, log)
 
-	// 构建转换请求
-	req := &converter.ConvertRequest{
-		Markdown:     string(markdown),
-		Mode:         converter.ConvertMode(convertMode),
-		Theme:        convertTheme,
-		CustomPrompt: convertCustomPrompt,
+	var result *converter.ConvertResult
+	if htmlInput.shared != "" {
+		// 使用已转换的 HTML（AI 转换完成后回填）
+		html, err := os.ReadFile(htmlInput.shared)
+		if err != nil {
+			return fmt.Errorf("read html file: %w", err)
+		}
+		result = converter.CompleteAIConversion(string(html), conv.ExtractImages(body), convertTheme)
+		_, result.Media = converter.ExtractMedia(body)
+	} else {
+		// 构建转换请求
+		req := &converter.ConvertRequest{
+			Markdown:     body,
+			Mode:         converter.ConvertMode(convertMode),
+			Theme:        convertTheme,
+			CustomPrompt: convertCustomPrompt,
+		}
+
+		// 执行转换
+		result = conv.Convert(req)
 	}
 
-	// 执行转换
-	result := conv.Convert(req)
+	// 根据模式处理结果
+	if convertMode == "ai" && converter.IsAIRequest(result) {
+		// AI 模式需要外部处理
+		return handleAIResult(result, markdownFile)
+	}
 
 	if !result.Success {
 		return fmt.Errorf("conversion failed: %s", result.Error)
@@ -105,28 +160,56 @@ func runConvert(cmd *cobra.Command, args []string) error {
 		zap.String("theme", result.Theme),
 		zap.Int("image_count", len(result.Images)))
 
-	// 根据模式处理结果
-	if convertMode == "ai" && converter.IsAIRequest(result) {
-		// AI 模式需要外部处理
-		return handleAIResult(result, markdownFile)
+	// 草稿字段（标题、封面等取自 front matter）
+	article := articleFromFrontMatter(meta, body, markdownFile, result.HTML, convertCoverImage)
+
+	// 源文件未变化时不重复上传图片和创建草稿
+	var sourceHash string
+	if convertDraft {
+		assets := localAssetsDigest(filepath.Dir(markdownFile), result.Images, result.Media)
+		sourceHash, err = draftSourceHash(markdown, result.HTML, "", article, result.Theme, assets)
+		if err != nil {
+			return err
+		}
+		if !convertForceNew {
+			unchanged, err := draft.NewService(cfg, log).UnchangedDraft(markdownFile, sourceHash, "")
+			if err != nil {
+				return err
+			}
+			if unchanged != nil {
+				log.Info("source unchanged, draft not modified",
+					zap.String("media_id", maskMediaID(unchanged.MediaID)))
+				responseSuccess(unchanged)
+				return nil
+			}
+		}
 	}
 
-	// 处理图片
+	// 处理图片和视频/语音
 	if convertUpload || convertDraft {
-		if err := processImages(result); err != nil {
+		if err := processImages(result, markdownFile); err != nil {
 			log.Warn("image processing failed", zap.Error(err))
+			// 不记录哈希，下次转换时重新上传失败的图片
+			sourceHash = ""
 		}
+		if !processMedia(result, filepath.Dir(markdownFile)) {
+			sourceHash = ""
+		}
+	}
+
+	article.Content = result.HTML
+	if meta.Digest == "" && result.HTML != "" {
+		article.Digest = draft.GenerateDigestFromContent(result.HTML, 120)
 	}
 
-	// 输出结果
 	if convertSaveDraft != "" {
-		if err := saveDraft(result); err != nil {
+		if err := saveDraft(article); err != nil {
 			return fmt.Errorf("save draft: %w", err)
 		}
 	}
 
 	if convertDraft {
-		if err := createWeChatDraft(result, convertCoverImage); err != nil {
+		if err := createWeChatDraft(article, markdownFile, sourceHash); err != nil {
 			return fmt.Errorf("create draft: %w", err)
 		}
 	}
@@ -157,6 +240,9 @@ func handleAIResult(result *converter.ConvertResult, markdownFile string) error
 		"prompt":        prompt,
 		"images":        images,
 	}
+	if len(result.Media) > 0 {
+		response["media"] = result.Media
+	}
 
 	printJSON(response)
 
@@ -170,75 +256,187 @@ func handleAIResult(result *converter.ConvertResult, markdownFile string) error
 	return nil
 }
 
-// processImages 处理图片上传
-func processImages(result *converter.ConvertResult) error {
+// processImages 处理图片上传，markdownFile 记录到 AI 生成图片的图片库元数据中
+func processImages(result *converter.ConvertResult, markdownFile string) error {
 	if len(result.Images) == 0 {
 		log.Info("no images to process")
 		return nil
 	}
 
 	processor := image.NewProcessor(cfg, log)
+	processor.SetArticle(markdownFile)
+	var failures []string
+	result.Images, failures = uploadImages(processor, result.Images, nil)
 
-	for i, imgRef := range result.Images {
-		log.Info("processing image",
+	// 替换 HTML 中的图片占位符
+	result.HTML = converter.ReplaceImagePlaceholders(result.HTML, result.Images)
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d 张图片上传失败", len(failures))
+	}
+	return nil
+}
+
+// processMedia 上传视频/语音素材并替换 HTML 中的媒体占位符，有素材上传失败时返回 false
+func processMedia(result *converter.ConvertResult, baseDir string) bool {
+	if len(result.Media) == 0 {
+		return true
+	}
+
+	processor := image.NewProcessor(cfg, log)
+	uploaded, failures := uploadMedia(processor, result.Media, baseDir)
+	for _, failure := range failures {
+		log.Warn("media upload failed", zap.String("media", failure))
+	}
+	result.Media = uploaded
+	result.HTML = converter.ReplaceMediaPlaceholders(result.HTML, uploaded)
+	return len(failures) == 0
+}
+
+// uploadMedia 检查并上传视频/语音，返回填充了 MediaID 的副本，以及失败的说明
+// 相对路径按 Markdown 文件所在目录解析
+func uploadMedia(processor *image.Processor, refs []converter.MediaRef, baseDir string) ([]converter.MediaRef, []string) {
+	out := make([]converter.MediaRef, len(refs))
+	copy(out, refs)
+
+	var failures []string
+	for i, ref := range out {
+		path := ref.Original
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+
+		info, err := media.Probe(path, ref.Kind)
+		if err == nil {
+			out[i].MediaID, err = processor.UploadMedia(info, ref.Title, ref.Introduction)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", ref.Original, err))
+			continue
+		}
+		out[i].PlayLength = info.Duration.Milliseconds()
+
+		log.Info("media uploaded",
 			zap.Int("index", i),
-			zap.String("type", string(imgRef.Type)),
-			zap.String("original", imgRef.Original))
+			zap.String("kind", string(ref.Kind)),
+			zap.String("media_id", maskMediaID(out[i].MediaID)),
+			zap.Duration("duration", info.Duration))
+	}
 
-		var uploadResult *image.UploadResult
-		var err error
+	return out, failures
+}
+
+// uploadImages 并发上传图片并返回填充了 WechatURL 的副本，以及上传失败的说明
+// 进度逐张输出到 stderr；generated 非 nil 时记录 AI 图片提示词对应的图片库 ID（或原始地址）：
+// 已生成过的图片直接从图片库上传，多账号发布时每张 AI 图片只生成一次
+func uploadImages(processor *image.Processor, images []converter.ImageRef, generated map[string]image.Job) ([]converter.ImageRef, []string) {
+	out := make([]converter.ImageRef, len(images))
+	copy(out, images)
 
+	jobs := make([]image.Job, len(out))
+	for i, imgRef := range out {
 		switch imgRef.Type {
 		case converter.ImageTypeLocal:
-			uploadResult, err = processor.UploadLocalImage(imgRef.Original)
+			jobs[i] = image.Job{Kind: image.JobLocal, Source: imgRef.Original}
 		case converter.ImageTypeOnline:
-			uploadResult, err = processor.DownloadAndUpload(imgRef.Original)
+			jobs[i] = image.Job{Kind: image.JobOnline, Source: imgRef.Original}
+		case converter.ImageTypeGallery:
+			jobs[i] = image.Job{Kind: image.JobGallery, Source: imgRef.Original}
 		case converter.ImageTypeAI:
-			// AI 生成的图片需要先调用生成 API
-			genResult, genErr := processor.GenerateAndUpload(imgRef.AIPrompt)
-			if genErr != nil {
-				err = genErr
+			if job, ok := generated[imgRef.AIPrompt]; ok {
+				jobs[i] = job
 			} else {
-				uploadResult = &image.UploadResult{
-					MediaID:   genResult.MediaID,
-					WechatURL: genResult.WechatURL,
-				}
+				jobs[i] = image.Job{Kind: image.JobGenerate, Source: imgRef.AIPrompt}
 			}
+		default:
+			jobs[i] = image.Job{Kind: image.JobKind(imgRef.Type), Source: imgRef.Original}
 		}
+		jobs[i].NoWatermark = imgRef.NoWatermark
+	}
 
-		if err != nil {
-			log.Warn("image upload failed",
-				zap.Int("index", i),
-				zap.Error(err))
+	summary := processor.ProcessBatch(context.Background(), jobs, image.BatchOptions{
+		Concurrency: convertConcurrency,
+		Progress:    os.Stderr,
+	})
+
+	var failures []string
+	for i, res := range summary.Results {
+		if !res.OK() {
+			failures = append(failures, fmt.Sprintf("%s: %s", out[i].Original, res.Error))
 			continue
 		}
 
 		// 更新图片 URL
-		result.Images[i].WechatURL = uploadResult.WechatURL
+		out[i].WechatURL = res.WechatURL
+		if generated != nil && res.Kind == image.JobGenerate {
+			switch {
+			case res.GalleryID != "":
+				generated[out[i].AIPrompt] = image.Job{Kind: image.JobGallery, Source: res.GalleryID}
+			case res.OriginalURL != "":
+				generated[out[i].AIPrompt] = image.Job{Kind: image.JobOnline, Source: res.OriginalURL}
+			}
+		}
 
 		log.Info("image uploaded",
 			zap.Int("index", i),
-			zap.String("media_id", maskMediaID(uploadResult.MediaID)),
-			zap.String("wechat_url", uploadResult.WechatURL))
+			zap.String("media_id", maskMediaID(res.MediaID)),
+			zap.String("wechat_url", res.WechatURL),
+			zap.String("provider", res.Provider),
+			zap.String("gallery_id", res.GalleryID))
 	}
 
-	// 替换 HTML 中的图片占位符
-	result.HTML = converter.ReplaceImagePlaceholders(result.HTML, result.Images)
-
-	return nil
+	return out, failures
 }
 
-// saveDraft 保存草稿 JSON 到文件
-func saveDraft(result *converter.ConvertResult) error {
-	articles := []draft.Article{
-		{
-			Title:   "Draft Article", // TODO: 从 markdown 提取标题
-			Content: result.HTML,
-		},
+// articleFromFrontMatter 根据 front matter 构建草稿文章
+// 标题缺省时使用正文第一个一级标题；coverFlag (--cover) 优先于 front matter 中的 cover
+func articleFromFrontMatter(meta *converter.FrontMatter, body, markdownFile, html, coverFlag string) draft.Article {
+	article := draft.Article{
+		Title:              meta.Title,
+		Author:             meta.Author,
+		Digest:             meta.Digest,
+		Content:            html,
+		ContentSourceURL:   meta.ContentSourceURL,
+		ThumbMediaID:       meta.ThumbMediaID,
+		ShowCoverPic:       1, // 默认显示封面
+		NeedOpenComment:    int(meta.NeedOpenComment),
+		OnlyFansCanComment: int(meta.OnlyFansCanComment),
+		PicCrop2351:        meta.PicCrop2351,
+		PicCrop11:          meta.PicCrop11,
+		BaseDir:            filepath.Dir(markdownFile),
+	}
+
+	if article.Title == "" {
+		article.Title = converter.ExtractTitle(body)
+	}
+	if article.Title == "" {
+		article.Title = strings.TrimSuffix(filepath.Base(markdownFile), filepath.Ext(markdownFile))
+	}
+	if article.Digest == "" && html != "" {
+		article.Digest = draft.GenerateDigestFromContent(html, 120)
+	}
+	if meta.ShowCoverPic != nil {
+		article.ShowCoverPic = int(*meta.ShowCoverPic)
 	}
 
+	switch {
+	case coverFlag != "":
+		article.Cover = coverFlag
+	case meta.Cover != "":
+		// front matter 中的相对路径相对于 Markdown 文件所在目录
+		article.Cover = meta.Cover
+		if !filepath.IsAbs(meta.Cover) {
+			article.Cover = filepath.Join(filepath.Dir(markdownFile), meta.Cover)
+		}
+	}
+
+	return article
+}
+
+// saveDraft 保存草稿 JSON 到文件
+func saveDraft(article draft.Article) error {
 	draftData := map[string]any{
-		"articles": articles,
+		"articles": []draft.Article{article},
 	}
 
 	jsonData, err := json.MarshalIndent(draftData, "", "  ")
@@ -254,66 +452,73 @@ func saveDraft(result *converter.ConvertResult) error {
 	return nil
 }
 
-// createWeChatDraft 创建微信草稿
-func createWeChatDraft(result *converter.ConvertResult, coverImagePath string) error {
+// createWeChatDraft 创建或更新微信草稿
+// 同一 Markdown 文件再次转换时更新之前创建的草稿，--force-new 时总是新建
+func createWeChatDraft(article draft.Article, markdownFile, sourceHash string) error {
 	svc := draft.NewService(cfg, log)
 
 	// 检查封面图片（微信要求必须有封面图）
-	if coverImagePath == "" {
+	if article.Cover == "" && article.ThumbMediaID == "" {
 		return &DraftError{
 			Message: "创建草稿需要封面图片",
 			Hint: "请使用 --cover 参数指定封面图片路径，例如: --cover /path/to/cover.jpg\n" +
-				"或者先上传封面图片到微信素材库: writer upload_image /path/to/cover.jpg",
+				"或在 Markdown front matter 中设置 cover: ./cover.jpg 或 thumb_media_id",
 		}
 	}
 
-	// 上传封面图片到微信素材库
-	log.Info("uploading cover image", zap.String("path", coverImagePath))
-	coverMediaID, err := uploadCoverImage(coverImagePath)
-	if err != nil {
-		return fmt.Errorf("上传封面图片失败: %w", err)
-	}
-	log.Info("cover image uploaded", zap.String("media_id", maskMediaID(coverMediaID)))
-
-	// 提取标题（TODO: 从 markdown frontmatter 或第一个标题获取）
-	title := "Article Title"
-
-	draftResult, err := svc.CreateDraft([]draft.Article{
-		{
-			Title:        title,
-			Content:      result.HTML,
-			Digest:       draft.GenerateDigestFromContent(result.HTML, 120),
-			ThumbMediaID: coverMediaID,
-			ShowCoverPic: 1, // 显示封面
-		},
-	})
-
+	// 封面由草稿服务上传到所选账号的素材库，并按封面尺寸计算裁剪坐标
+	result, err := svc.SyncDraft(markdownFile, sourceHash, article, "", convertForceNew)
 	if err != nil {
 		return fmt.Errorf("create draft: %w", err)
 	}
 
-	log.Info("draft created",
-		zap.String("media_id", maskMediaID(draftResult.MediaID)),
-		zap.String("draft_url", draftResult.DraftURL))
+	log.Info("draft "+result.Action,
+		zap.String("media_id", maskMediaID(result.MediaID)),
+		zap.String("draft_url", result.DraftURL))
 
 	return nil
 }
 
-// uploadCoverImage 上传封面图片到微信素材库
-func uploadCoverImage(imagePath string) (string, error) {
-	// 选择默认账号
-	selector := config.NewAccountSelector(cfg.WechatAccounts, cfg.DefaultAccount)
-	account, err := selector.SelectAccount("", "")
-	if err != nil {
-		return "", fmt.Errorf("select WeChat account: %w", err)
+// draftSourceHash 草稿输入的哈希：Markdown 源文件、转换后的 HTML（图片上传前）、
+// 账号尾部、封面图片、主题和引用的本地图片 / 视频 / 语音，任一变化时需要更新草稿
+func draftSourceHash(markdown []byte, html, footer string, article draft.Article, theme string, assets []byte) (string, error) {
+	cover := []byte(article.ThumbMediaID)
+	if article.Cover != "" {
+		data, err := os.ReadFile(article.Cover)
+		if err != nil {
+			return "", fmt.Errorf("read cover image: %w", err)
+		}
+		cover = data
 	}
+	return draft.SourceHash(markdown, []byte(html), []byte(footer), cover, []byte(theme), assets), nil
+}
 
-	svc := wechat.NewService(account, log)
-	result, err := svc.UploadMaterial(imagePath)
-	if err != nil {
-		return "", err
+// localAssetsDigest 文章引用的本地图片和视频 / 语音的内容摘要（相对路径按 Markdown 所在目录解析），
+// 只修改图片文件、未修改 Markdown 时也能检测到变化；读取失败的文件记为缺失
+func localAssetsDigest(baseDir string, images []converter.ImageRef, mediaRefs []converter.MediaRef) []byte {
+	var paths []string
+	for _, img := range images {
+		if img.Type == converter.ImageTypeLocal {
+			paths = append(paths, img.Original)
+		}
+	}
+	for _, ref := range mediaRefs {
+		paths = append(paths, ref.Original)
+	}
+
+	var digest []byte
+	for _, path := range paths {
+		full := path
+		if !filepath.IsAbs(full) {
+			full = filepath.Join(baseDir, full)
+		}
+		hash, _, err := image.HashFile(full)
+		if err != nil {
+			hash = "missing"
+		}
+		digest = append(digest, path+"\x00"+hash+"\n"...)
 	}
-	return result.MediaID, nil
+	return digest
 }
 
 // DraftError 草稿错误
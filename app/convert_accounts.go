@@ -0,0 +1,398 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/royalrick/wechatwriter/app/config"
+	"github.com/royalrick/wechatwriter/app/converter"
+	"github.com/royalrick/wechatwriter/app/draft"
+	"github.com/royalrick/wechatwriter/app/image"
+	"github.com/royalrick/wechatwriter/app/writer"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// 多账号发布中单个账号的结果状态
+const (
+	fanOutCreated   = draft.ActionCreated   // 新建草稿
+	fanOutUpdated   = draft.ActionUpdated   // 更新了之前由该文件创建的草稿
+	fanOutUnchanged = draft.ActionUnchanged // 内容未变化，未调用接口
+	fanOutFailed    = "failed"              // 失败
+	fanOutAIRequest = "ai_request"          // 缺少该账号的 HTML，返回 AI 转换请求
+)
+
+// htmlInputs --html 参数：所有账号共用的 HTML 或按账号指定的 HTML
+type htmlInputs struct {
+	shared    string
+	byAccount map[string]string
+}
+
+// forAccount 返回账号对应的 HTML 文件，未指定时使用共用 HTML
+func (h htmlInputs) forAccount(accountID string) string {
+	if path, ok := h.byAccount[accountID]; ok {
+		return path
+	}
+	return h.shared
+}
+
+// AccountDraftResult 单个账号的草稿创建结果
+type AccountDraftResult struct {
+	AccountID   string   `json:"account_id"`
+	AccountName string   `json:"account_name"`
+	Theme       string   `json:"theme"`
+	Style       string   `json:"style,omitempty"`
+	Status      string   `json:"status"`
+	MediaID     string   `json:"media_id,omitempty"`
+	DraftURL    string   `json:"draft_url,omitempty"`
+	Images      int      `json:"images"`                 // 上传成功的图片数
+	ImageErrors []string `json:"image_errors,omitempty"` // 上传失败的图片
+	Media       int      `json:"media,omitempty"`        // 上传成功的视频/语音数
+	MediaErrors []string `json:"media_errors,omitempty"` // 上传失败的视频/语音
+	Footer      bool     `json:"footer"`                 // 是否追加了账号尾部
+	Error       string   `json:"error,omitempty"`
+	Prompt      string   `json:"prompt,omitempty"` // ai_request 时的转换提示词
+}
+
+// parseHTMLInputs 解析 --html 参数
+// 值为 "account=path" 且 account 是已配置的账号 ID 时按账号指定，否则作为共用 HTML
+func parseHTMLInputs(values []string) (htmlInputs, error) {
+	in := htmlInputs{byAccount: make(map[string]string)}
+	for _, v := range values {
+		if id, path, ok := strings.Cut(v, "="); ok && accountExists(id) {
+			in.byAccount[id] = path
+			continue
+		}
+		if in.shared != "" {
+			return in, fmt.Errorf("--html 只能指定一个共用 HTML 文件，按账号指定请使用 account=path")
+		}
+		in.shared = v
+	}
+	return in, nil
+}
+
+// accountExists 检查账号 ID 是否已配置
+func accountExists(id string) bool {
+	for _, acc := range cfg.WechatAccounts {
+		if acc.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFanOutAccounts 解析多账号发布的目标账号
+func resolveFanOutAccounts(ids []string, all bool) ([]config.WechatAccount, error) {
+	if all {
+		if len(cfg.WechatAccounts) == 0 {
+			return nil, fmt.Errorf("未配置微信公众号账号")
+		}
+		return cfg.WechatAccounts, nil
+	}
+
+	var accounts []config.WechatAccount
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		found := false
+		for _, acc := range cfg.WechatAccounts {
+			if acc.ID == id {
+				accounts = append(accounts, acc)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, &config.ConfigError{
+				Field:   "accounts",
+				Message: fmt.Sprintf("账号 '%s' 不存在", id),
+				Hint:    "运行 'writer config accounts' 查看已配置的账号",
+			}
+		}
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("--accounts 未指定有效账号")
+	}
+	return accounts, nil
+}
+
+// runConvertFanOut 将同一篇文章创建到多个账号的草稿箱
+// 每个账号独立上传封面和图片（素材 ID 按账号隔离），使用各自的主题、写作风格和尾部；
+// 单个账号失败不影响其他账号
+func runConvertFanOut(cmd *cobra.Command, meta *converter.FrontMatter, markdown []byte, body, markdownFile string, accounts []config.WechatAccount, htmlInput htmlInputs) error {
+	base := articleFromFrontMatter(meta, body, markdownFile, "", convertCoverImage)
+	if base.Cover == "" {
+		return &DraftError{
+			Message: "多账号发布需要本地封面图片",
+			Hint: "素材 ID 只在所属账号内有效，thumb_media_id 无法跨账号使用。\n" +
+				"   请使用 --cover 或在 front matter 中设置 cover: ./cover.jpg",
+		}
+	}
+	if base.ThumbMediaID != "" {
+		log.Warn("thumb_media_id ignored for multi-account drafts, uploading cover per account")
+		base.ThumbMediaID = ""
+	}
+
+	conv := converter.NewConverter(cfg, log)
+	images := conv.ExtractImages(body)
+	_, mediaRefs := converter.ExtractMedia(body)
+	generated := make(map[string]image.Job)
+	svc := draft.NewService(cfg, log)
+
+	styles := writer.NewStyleManager()
+	if err := styles.LoadStyles(); err != nil {
+		log.Warn("load writing styles failed", zap.Error(err))
+	}
+
+	results := make([]*AccountDraftResult, 0, len(accounts))
+	var created, updated, unchanged, failed, pending int
+	for i := range accounts {
+		account := &accounts[i]
+		res := &AccountDraftResult{
+			AccountID:   account.ID,
+			AccountName: account.Name,
+			Theme:       accountTheme(cmd, account),
+			Style:       account.DefaultStyle,
+		}
+		results = append(results, res)
+
+		htmlFile := htmlInput.forAccount(account.ID)
+		if htmlFile == "" {
+			// 没有该账号的 HTML：按账号主题和写作风格生成 AI 转换请求
+			prompt, err := accountAIPrompt(conv, styles, account, res.Theme, body)
+			if err != nil {
+				res.Status, res.Error = fanOutFailed, err.Error()
+				failed++
+				continue
+			}
+			res.Status, res.Prompt = fanOutAIRequest, prompt
+			pending++
+			continue
+		}
+
+		html, err := os.ReadFile(htmlFile)
+		if err != nil {
+			err = fmt.Errorf("read html file: %w", err)
+		} else {
+			err = createAccountDraft(svc, account, base, markdown, markdownFile, string(html), images, mediaRefs, generated, res)
+		}
+		if err != nil {
+			res.Status, res.Error = fanOutFailed, err.Error()
+			failed++
+			log.Error("create draft for account failed",
+				zap.String("account_id", account.ID),
+				zap.Error(err))
+			continue
+		}
+		switch res.Status {
+		case fanOutUpdated:
+			updated++
+		case fanOutUnchanged:
+			unchanged++
+		case fanOutCreated:
+			created++
+		}
+	}
+
+	report := map[string]any{
+		"markdown_file": markdownFile,
+		"accounts":      results,
+		"created":       created,
+		"updated":       updated,
+		"unchanged":     unchanged,
+		"failed":        failed,
+		"ai_requests":   pending,
+	}
+	if pending > 0 {
+		// 只对尚未创建草稿的账号重新运行，避免重复创建
+		var ids, htmlArgs []string
+		for _, r := range results {
+			if r.Status == fanOutAIRequest {
+				ids = append(ids, r.AccountID)
+				htmlArgs = append(htmlArgs, fmt.Sprintf("--html %s=%s.html", r.AccountID, r.AccountID))
+			}
+		}
+		report["next_step"] = fmt.Sprintf("按各账号的 prompt 生成 HTML 后重新运行: writer convert %s --draft --accounts %s %s",
+			markdownFile, strings.Join(ids, ","), strings.Join(htmlArgs, " "))
+	}
+
+	if failed > 0 {
+		printJSON(map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("%d 个账号创建草稿失败", failed),
+			"data":    report,
+		})
+		os.Exit(1)
+	}
+	responseSuccess(report)
+	return nil
+}
+
+// createAccountDraft 为单个账号上传图片、追加尾部并创建或更新草稿
+// 该账号中已有由同一文件创建的草稿时更新该草稿，内容未变化时跳过
+func createAccountDraft(svc *draft.Service, account *config.WechatAccount, base draft.Article, markdown []byte, markdownFile, html string, images []converter.ImageRef, mediaRefs []converter.MediaRef, generated map[string]image.Job, res *AccountDraftResult) error {
+	// 先读取尾部，配置错误时不浪费图片上传
+	footer, err := loadAccountFooter(account)
+	if err != nil {
+		return err
+	}
+
+	assets := localAssetsDigest(filepath.Dir(markdownFile), images, mediaRefs)
+	hash, err := draftSourceHash(markdown, html, footer, base, res.Theme, assets)
+	if err != nil {
+		return err
+	}
+	if !convertForceNew {
+		unchanged, err := svc.UnchangedDraft(markdownFile, hash, account.ID)
+		if err != nil {
+			return err
+		}
+		if unchanged != nil {
+			res.Status, res.MediaID = fanOutUnchanged, unchanged.MediaID
+			return nil
+		}
+	}
+
+	if len(images) > 0 || len(mediaRefs) > 0 {
+		processor, err := image.NewProcessorForAccount(cfg, log, account.ID)
+		if err != nil {
+			return err
+		}
+		processor.SetArticle(markdownFile)
+		if len(images) > 0 {
+			uploaded, failures := uploadImages(processor, images, generated)
+			html = converter.ReplaceImagePlaceholders(html, uploaded)
+			res.Images = len(images) - len(failures)
+			res.ImageErrors = failures
+		}
+		if len(mediaRefs) > 0 {
+			uploaded, failures := uploadMedia(processor, mediaRefs, filepath.Dir(markdownFile))
+			html = converter.ReplaceMediaPlaceholders(html, uploaded)
+			res.Media = len(mediaRefs) - len(failures)
+			res.MediaErrors = failures
+		}
+	}
+
+	article := base
+	article.Content = html
+	if article.Digest == "" {
+		article.Digest = draft.GenerateDigestFromContent(html, 120)
+	}
+
+	if footer != "" {
+		article.Content += "\n" + footer
+		res.Footer = true
+	}
+
+	// 有图片或素材上传失败时不记录哈希，下次运行时更新草稿并重试失败的部分
+	if len(res.ImageErrors) > 0 || len(res.MediaErrors) > 0 {
+		hash = ""
+	}
+	result, err := svc.SyncDraft(markdownFile, hash, article, account.ID, convertForceNew)
+	if err != nil {
+		return err
+	}
+	res.Status = result.Action
+	res.MediaID = result.MediaID
+	res.DraftURL = result.DraftURL
+
+	log.Info("draft synced for account",
+		zap.String("account_id", account.ID),
+		zap.String("action", result.Action),
+		zap.String("media_id", maskMediaID(result.MediaID)))
+	return nil
+}
+
+// accountTheme 账号使用的主题：显式 --theme 优先，其次账号 theme，最后全局默认主题
+func accountTheme(cmd *cobra.Command, account *config.WechatAccount) string {
+	switch {
+	case cmd.Flags().Changed("theme"):
+		return convertTheme
+	case account.Theme != "":
+		return account.Theme
+	case cfg.DefaultTheme != "":
+		return cfg.DefaultTheme
+	default:
+		return convertTheme
+	}
+}
+
+// accountAIPrompt 按账号主题生成 AI 转换提示词，并附加账号写作风格的排版规则
+func accountAIPrompt(conv converter.Converter, styles *writer.StyleManager, account *config.WechatAccount, theme, body string) (string, error) {
+	result := conv.Convert(&converter.ConvertRequest{
+		Markdown:     body,
+		Mode:         converter.ModeAI,
+		Theme:        theme,
+		CustomPrompt: convertCustomPrompt,
+	})
+	prompt, _, ok := converter.GetAIRequestInfo(result)
+	if !ok {
+		return "", fmt.Errorf("conversion failed: %s", result.Error)
+	}
+
+	if account.DefaultStyle == "" {
+		return prompt, nil
+	}
+	style, err := styles.GetStyle(account.DefaultStyle)
+	if err != nil {
+		log.Warn("account style not found",
+			zap.String("account_id", account.ID),
+			zap.String("style", account.DefaultStyle))
+		return prompt, nil
+	}
+	return prompt + styleFormattingHints(style), nil
+}
+
+// styleFormattingHints 写作风格中的排版强调规则
+func styleFormattingHints(style *writer.WriterStyle) string {
+	if style.Formatting == nil {
+		return ""
+	}
+
+	var rules []string
+	if style.Formatting.BoldFor != "" {
+		rules = append(rules, "- 粗体用于："+style.Formatting.BoldFor)
+	}
+	if style.Formatting.ItalicFor != "" {
+		rules = append(rules, "- 斜体用于："+style.Formatting.ItalicFor)
+	}
+	if style.Formatting.QuoteMarksFor != "" {
+		rules = append(rules, "- 引号用于："+style.Formatting.QuoteMarksFor)
+	}
+	if len(rules) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n\n## 写作风格排版规则（%s）\n%s", style.Name, strings.Join(rules, "\n"))
+}
+
+// loadAccountFooter 读取账号尾部 HTML
+// footer 含 "<" 时视为 HTML 片段，否则视为文件路径（相对路径相对于配置文件所在目录）
+func loadAccountFooter(account *config.WechatAccount) (string, error) {
+	footer := strings.TrimSpace(account.Footer)
+	if footer == "" || strings.Contains(footer, "<") {
+		return footer, nil
+	}
+
+	path := footer
+	if !filepath.IsAbs(path) {
+		if configFile := cfg.GetConfigFile(); configFile != "" {
+			path = filepath.Join(filepath.Dir(configFile), path)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", &config.ConfigError{
+			Field:   "footer",
+			Message: fmt.Sprintf("账号 '%s' 的尾部文件读取失败: %v", account.ID, err),
+			Hint:    "footer 可直接填写 HTML 片段，或填写相对配置文件目录的 .html 文件路径",
+		}
+	}
+	return strings.TrimSpace(string(data)), nil
+}
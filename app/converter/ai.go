@@ -2,6 +2,7 @@ package converter
 
 import (
 	"fmt"
+	"strings"
 
 	"go.uber.org/zap"
 )
@@ -44,15 +45,25 @@ func (c *converter) convertViaAI(req *ConvertRequest) *ConvertResult {
 		Success: false,
 	}
 
+	// 视频/语音、图片替换为占位符，由 AI 原样保留；
+	// 图片按出现顺序编号，与 ExtractImages 返回的引用一一对应
+	_, mediaRefs := ExtractMedia(req.Markdown)
+	markdown, images := MarkImages(req.Markdown)
+	aiReq := *req
+	aiReq.Markdown = markdown
+
 	// 获取提示词
-	prompt, err := c.buildAIPrompt(req)
+	prompt, err := c.buildAIPrompt(&aiReq)
 	if err != nil {
 		result.Error = fmt.Sprintf("build AI prompt failed: %s", err.Error())
 		return result
 	}
-
-	// 提取图片引用
-	images := c.ExtractImages(req.Markdown)
+	if len(mediaRefs) > 0 {
+		prompt += "\n\n" + MediaPromptNote
+	}
+	if len(images) > 0 {
+		prompt += "\n\n" + ImagePromptNote
+	}
 
 	// AI 模式由外部调用者处理，这里返回准备好的请求
 	// 实际使用时，调用者应该：
@@ -64,10 +75,12 @@ func (c *converter) convertViaAI(req *ConvertRequest) *ConvertResult {
 	// 为了保持接口一致性，这里返回一个包含提示词的特殊结果
 	result.Error = "AI_MODE_REQUEST:" + prompt
 	result.Images = images
+	result.Media = mediaRefs
 
 	c.log.Info("AI conversion request prepared",
 		zap.String("theme", req.Theme),
 		zap.Int("image_count", len(images)),
+		zap.Int("media_count", len(mediaRefs)),
 		zap.Int("prompt_length", len(prompt)))
 
 	return result
@@ -165,13 +178,14 @@ func CompleteAIConversion(html string, images []ImageRef, theme string) *Convert
 
 // IsAIRequest 检查结果是否是 AI 请求
 func IsAIRequest(result *ConvertResult) bool {
-	return result.Error != "" && len(result.Error) > 14 && result.Error[:14] == "AI_MODE_REQUEST"
+	return result.Error != "" &&
+		strings.HasPrefix(result.Error, "AI_MODE_REQUEST:")
 }
 
 // ExtractAIRequest 从结果中提取 AI 请求
 func ExtractAIRequest(result *ConvertResult) string {
 	if IsAIRequest(result) {
-		return result.Error[14:] // 去掉前缀
+		return strings.TrimPrefix(result.Error, "AI_MODE_REQUEST:")
 	}
 	return ""
 }
@@ -3,7 +3,9 @@
 package converter
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/royalrick/wechatwriter/app/config"
@@ -21,9 +23,10 @@ const (
 type ImageType string
 
 const (
-	ImageTypeLocal  ImageType = "local"  // 本地图片
-	ImageTypeOnline ImageType = "online" // 在线图片
-	ImageTypeAI     ImageType = "ai"     // AI 生成图片
+	ImageTypeLocal   ImageType = "local"   // 本地图片
+	ImageTypeOnline  ImageType = "online"  // 在线图片
+	ImageTypeAI      ImageType = "ai"      // AI 生成图片
+	ImageTypeGallery ImageType = "gallery" // 图片库中已生成的图片
 )
 
 // ConvertRequest 转换请求
@@ -40,11 +43,12 @@ type ConvertRequest struct {
 // ImageRef 图片引用
 type ImageRef struct {
 	Index       int       // 位置索引
-	Original    string    // 原始路径或提示词
+	Original    string    // 原始路径、提示词或图片库 ID
 	Placeholder string    // HTML 中的占位符 <!-- IMG:0 -->
 	WechatURL   string    // 上传后的 URL (处理完成后)
 	Type        ImageType // 图片类型
 	AIPrompt    string    // AI 图片的生成提示词
+	NoWatermark bool      // 说明文字中带 nowm，上传时不加账号水印
 }
 
 // ConvertResult 转换结果
@@ -53,6 +57,7 @@ type ConvertResult struct {
 	Mode    ConvertMode // 使用的模式
 	Theme   string      // 使用的主题
 	Images  []ImageRef  // 图片引用列表
+	Media   []MediaRef  // 视频/语音引用列表
 	Success bool        // 是否成功
 	Error   string      // 错误信息
 }
@@ -119,60 +124,92 @@ func (c *converter) validateRequest(req *ConvertRequest) error {
 	return nil
 }
 
-// ExtractImages 从 Markdown 中提取图片引用
+// ExtractImages 从 Markdown 中提取图片引用，按在文档中出现的顺序编号
 func (c *converter) ExtractImages(markdown string) []ImageRef {
-	var images []ImageRef
-
-	// 匹配本地图片: ![alt](./path/to/image.png)
-	localPattern := regexp.MustCompile(`!\[([^\]]*)\]\((\.\/[^)]+)\)`)
-	for i, match := range localPattern.FindAllStringSubmatch(markdown, -1) {
-		if len(match) >= 3 {
-			images = append(images, ImageRef{
-				Index:       i,
-				Original:    match[2],
-				Placeholder: "",
-				Type:        ImageTypeLocal,
-			})
-		}
-	}
+	_, images := MarkImages(markdown)
+	return images
+}
+
+// 图片语法：本地 ./path、在线 http(s)://、AI 生成 __generate:prompt__
+var (
+	localImagePattern  = regexp.MustCompile(`!\[([^\]]*)\]\((\.\/[^)]+)\)`)
+	onlineImagePattern = regexp.MustCompile(`!\[([^\]]*)\]\((https?://[^)]+)\)`)
+	aiImagePattern     = regexp.MustCompile(`!\[([^\]]*)\]\(__generate:([^)]+)__\)`)
+)
+
+// ImagePromptNote 有图片占位符时附加到 AI 提示词的说明
+const ImagePromptNote = "注意：Markdown 中的 <!-- IMG:index --> 是图片占位符，请原样保留在对应位置，不要改写、删除或重新编号。"
 
-	// 匹配在线图片: ![alt](https://...)
-	onlinePattern := regexp.MustCompile(`!\[([^\]]*)\]\((https?://[^)]+)\)`)
-	offset := len(images)
-	for i, match := range onlinePattern.FindAllStringSubmatch(markdown, -1) {
-		if len(match) >= 3 {
-			images = append(images, ImageRef{
-				Index:       offset + i,
-				Original:    match[2],
-				Placeholder: "",
-				Type:        ImageTypeOnline,
-			})
+// MarkImages 提取 Markdown 中的图片引用，按出现顺序编号并替换为 <!-- IMG:n --> 占位符
+// 返回替换后的 Markdown（发送给 AI，保证占位符编号与图片引用一致）和图片引用
+func MarkImages(markdown string) (string, []ImageRef) {
+	// 视频/语音不作为图片处理
+	markdown, _ = ExtractMedia(markdown)
+
+	type match struct {
+		loc []int
+		typ ImageType
+	}
+	var matches []match
+	for _, p := range []struct {
+		pattern *regexp.Regexp
+		typ     ImageType
+	}{
+		{localImagePattern, ImageTypeLocal},
+		{onlineImagePattern, ImageTypeOnline},
+		{aiImagePattern, ImageTypeAI},
+		{galleryPattern, ImageTypeGallery},
+	} {
+		for _, loc := range p.pattern.FindAllStringSubmatchIndex(markdown, -1) {
+			matches = append(matches, match{loc: loc, typ: p.typ})
 		}
 	}
-
-	// 匹配 AI 生成图片: ![alt](__generate:prompt__)
-	aiPattern := regexp.MustCompile(`!\[([^\]]*)\]\(__generate:([^)]+)__\)`)
-	offset = len(images)
-	for i, match := range aiPattern.FindAllStringSubmatch(markdown, -1) {
-		if len(match) >= 3 {
-			images = append(images, ImageRef{
-				Index:       offset + i,
-				Original:    match[2],
-				Placeholder: "",
-				Type:        ImageTypeAI,
-				AIPrompt:    match[2],
-			})
+	sort.Slice(matches, func(i, j int) bool { return matches[i].loc[0] < matches[j].loc[0] })
+
+	var (
+		images []ImageRef
+		out    strings.Builder
+		last   int
+	)
+	for i, m := range matches {
+		alt := markdown[m.loc[2]:m.loc[3]]
+		ref := ImageRef{
+			Index:       i,
+			Original:    markdown[m.loc[4]:m.loc[5]],
+			Placeholder: imagePlaceholder(i),
+			Type:        m.typ,
+			NoWatermark: HasNoWatermark(alt),
+		}
+		if m.typ == ImageTypeAI {
+			ref.AIPrompt = ref.Original
 		}
+		images = append(images, ref)
+
+		out.WriteString(markdown[last:m.loc[0]])
+		out.WriteString(ref.Placeholder)
+		last = m.loc[1]
 	}
+	out.WriteString(markdown[last:])
+	return out.String(), images
+}
 
-	return images
+// noWatermarkPattern 说明文字中的 nowm 标记（独立单词，不区分大小写）
+var noWatermarkPattern = regexp.MustCompile(`(?i)\bnowm\b`)
+
+// HasNoWatermark 判断图片说明文字是否带 nowm 标记，如 ![示意图 nowm](./a.png)，
+// 也用于 HTML 转存时判断 <img> 的 alt
+func HasNoWatermark(alt string) bool {
+	return noWatermarkPattern.MatchString(alt)
 }
 
+// galleryPattern 匹配 ![alt](gallery:id)，id 为图片库 ID 或其前缀
+var galleryPattern = regexp.MustCompile(`!\[([^\]]*)\]\(gallery:([0-9a-fA-F]{4,})\)`)
+
 // ReplaceImagePlaceholders 在 HTML 中替换图片占位符
 func ReplaceImagePlaceholders(html string, images []ImageRef) string {
 	result := html
 	for _, img := range images {
-		if img.WechatURL != "" {
+		if img.WechatURL != "" && img.Placeholder != "" {
 			// 替换占位符为实际图片标签
 			imgTag := `<img src="` + img.WechatURL + `" style="max-width:100%;height:auto;display:block;margin:20px auto;" />`
 			result = strings.ReplaceAll(result, img.Placeholder, imgTag)
@@ -181,6 +218,11 @@ func ReplaceImagePlaceholders(html string, images []ImageRef) string {
 	return result
 }
 
+// imagePlaceholder 返回第 index 张图片在 HTML 中的占位符（与 AI 提示词约定一致）
+func imagePlaceholder(index int) string {
+	return fmt.Sprintf("<!-- IMG:%d -->", index)
+}
+
 // InsertImagePlaceholders 在 HTML 中插入图片占位符
 func InsertImagePlaceholders(html string, images []ImageRef) string {
 	// 简化实现：直接返回原 HTML
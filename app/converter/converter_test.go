@@ -0,0 +1,171 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestConvertReturnsAIRequest(t *testing.T) {
+	conv := NewConverter(nil, zap.NewNop())
+	result := conv.Convert(&ConvertRequest{Markdown: "# 标题\n\n正文"})
+
+	prompt, _, ok := GetAIRequestInfo(result)
+	if !ok {
+		t.Fatalf("GetAIRequestInfo() ok = false, error = %q", result.Error)
+	}
+	if strings.HasPrefix(prompt, "AI_MODE_REQUEST") || !strings.Contains(prompt, "# 标题") {
+		t.Errorf("prompt = %q, want markdown without request prefix", prompt)
+	}
+}
+
+func TestIsAIRequest(t *testing.T) {
+	tests := []struct {
+		err  string
+		want bool
+	}{
+		{"AI_MODE_REQUEST:# 标题", true},
+		{"AI_MODE_REQUEST:", true},
+		{"AI_MODE_REQUES", false},
+		{"conversion failed", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsAIRequest(&ConvertResult{Error: tt.err}); got != tt.want {
+			t.Errorf("IsAIRequest(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+	if got := ExtractAIRequest(&ConvertResult{Error: "AI_MODE_REQUEST:# 标题"}); got != "# 标题" {
+		t.Errorf("ExtractAIRequest() = %q, want %q", got, "# 标题")
+	}
+}
+
+func TestReplaceImagePlaceholders(t *testing.T) {
+	conv := NewConverter(nil, zap.NewNop())
+	images := conv.ExtractImages("![a](./a.png)\n\n![b](https://example.com/b.png)")
+	if len(images) != 2 || images[0].Placeholder != "<!-- IMG:0 -->" || images[1].Placeholder != "<!-- IMG:1 -->" {
+		t.Fatalf("ExtractImages() = %+v", images)
+	}
+
+	images[1].WechatURL = "https://mmbiz.qpic.cn/b"
+	html := ReplaceImagePlaceholders("<p>x</p><!-- IMG:0 --><!-- IMG:1 -->", images)
+	if !strings.Contains(html, `<img src="https://mmbiz.qpic.cn/b"`) {
+		t.Errorf("uploaded image not replaced: %s", html)
+	}
+	if !strings.Contains(html, "<!-- IMG:0 -->") {
+		t.Errorf("image without WechatURL should keep its placeholder: %s", html)
+	}
+}
+
+func TestExtractGalleryImages(t *testing.T) {
+	conv := NewConverter(nil, zap.NewNop())
+	images := conv.ExtractImages("![a](./a.png)\n\n![复用](gallery:3f2a9c01b7de)\n\n![x](gallery:../etc)")
+	if len(images) != 2 {
+		t.Fatalf("ExtractImages() = %+v, want local and gallery images", images)
+	}
+	if images[1].Type != ImageTypeGallery || images[1].Original != "3f2a9c01b7de" || images[1].Placeholder != "<!-- IMG:1 -->" {
+		t.Errorf("gallery image = %+v", images[1])
+	}
+}
+
+func TestExtractMedia(t *testing.T) {
+	markdown := "![video:冲泡演示](./clip.mp4 \"第一泡\")\n\n![封面](./a.png)\n\n![voice](audio/intro.mp3)\n\n![讲解](./talk.amr)"
+	out, refs := ExtractMedia(markdown)
+
+	if len(refs) != 3 {
+		t.Fatalf("ExtractMedia() = %+v, want 3 refs", refs)
+	}
+	if refs[0].Kind != "video" || refs[0].Title != "冲泡演示" || refs[0].Introduction != "第一泡" || refs[0].Original != "./clip.mp4" {
+		t.Errorf("video ref = %+v", refs[0])
+	}
+	if refs[1].Kind != "voice" || refs[1].Title != "intro" {
+		t.Errorf("voice ref = %+v", refs[1])
+	}
+	if refs[2].Kind != "voice" || refs[2].Title != "讲解" {
+		t.Errorf("voice ref by extension = %+v", refs[2])
+	}
+	if !strings.Contains(out, "<!-- MEDIA:0 -->") || !strings.Contains(out, "![封面](./a.png)") {
+		t.Errorf("markdown = %q", out)
+	}
+
+	// 媒体不作为图片提取
+	conv := NewConverter(nil, zap.NewNop())
+	if images := conv.ExtractImages(markdown); len(images) != 1 || images[0].Original != "./a.png" {
+		t.Errorf("ExtractImages() = %+v, want only ./a.png", images)
+	}
+
+	result := conv.Convert(&ConvertRequest{Markdown: markdown})
+	prompt, _, _ := GetAIRequestInfo(result)
+	if len(result.Media) != 3 || strings.Contains(prompt, "clip.mp4") || !strings.Contains(prompt, MediaPromptNote) {
+		t.Errorf("AI request should carry media placeholders, media = %d", len(result.Media))
+	}
+}
+
+func TestReplaceMediaPlaceholders(t *testing.T) {
+	_, refs := ExtractMedia("![video](./clip.mp4)\n![voice:<介绍>](./a.mp3)\n![video](./b.mp4)")
+	refs[0].MediaID = "VIDEO_ID"
+	refs[1].MediaID, refs[1].PlayLength = "VOICE_ID", 12000
+
+	html := ReplaceMediaPlaceholders("<!-- MEDIA:0 --><!--MEDIA:1--><!-- MEDIA:2 -->", refs)
+	// 永久素材的 media_id 不能作为编辑器内嵌视频/语音的 ID，只输出素材卡片
+	for _, attr := range []string{"data-mpvid", "vid=", "voice_encode_fileid", "<iframe", "<mpvoice"} {
+		if strings.Contains(html, attr) {
+			t.Errorf("media should not be embedded with %s: %s", attr, html)
+		}
+	}
+	if !strings.Contains(html, `data-kind="video" data-media-id="VIDEO_ID"`) || !strings.Contains(html, "视频：clip") {
+		t.Errorf("video card missing: %s", html)
+	}
+	if !strings.Contains(html, `data-media-id="VOICE_ID"`) || !strings.Contains(html, "语音：&lt;介绍&gt;（12 秒）") {
+		t.Errorf("voice card missing: %s", html)
+	}
+	if !strings.Contains(html, "<!-- MEDIA:2 -->") {
+		t.Errorf("media without MediaID should keep its placeholder: %s", html)
+	}
+}
+
+func TestExtractImagesNoWatermark(t *testing.T) {
+	conv := NewConverter(nil, zap.NewNop())
+	images := conv.ExtractImages("![截图 nowm](./a.png)\n\n![NoWm](https://example.com/b.png)\n\n![nowmark](./c.png)\n\n![](gallery:3f2a9c01b7de)")
+	if len(images) != 4 {
+		t.Fatalf("ExtractImages() = %+v", images)
+	}
+
+	got := map[string]bool{}
+	for _, img := range images {
+		got[img.Original] = img.NoWatermark
+	}
+	want := map[string]bool{"./a.png": true, "./c.png": false, "https://example.com/b.png": true, "3f2a9c01b7de": false}
+	for src, nowm := range want {
+		if got[src] != nowm {
+			t.Errorf("%s NoWatermark = %v, want %v", src, got[src], nowm)
+		}
+	}
+}
+
+func TestExtractImagesDocumentOrder(t *testing.T) {
+	markdown := "![a](https://x.com/first.png)\n\n![b](./second.png)\n\n![c](__generate:cat__)\n\n![d](gallery:3f2a9c01b7de)"
+	conv := NewConverter(nil, zap.NewNop())
+	images := conv.ExtractImages(markdown)
+	want := []string{"https://x.com/first.png", "./second.png", "cat", "3f2a9c01b7de"}
+	if len(images) != len(want) {
+		t.Fatalf("ExtractImages() = %+v", images)
+	}
+	for i, img := range images {
+		if img.Original != want[i] || img.Index != i || img.Placeholder != fmt.Sprintf("<!-- IMG:%d -->", i) {
+			t.Errorf("images[%d] = %+v, want %s as IMG:%d", i, img, want[i], i)
+		}
+	}
+
+	// 发给 AI 的 Markdown 中图片已替换为同样编号的占位符
+	result := conv.Convert(&ConvertRequest{Markdown: markdown})
+	prompt, _, ok := GetAIRequestInfo(result)
+	if !ok {
+		t.Fatalf("GetAIRequestInfo() ok = false, error = %q", result.Error)
+	}
+	if strings.Contains(prompt, "first.png") || !strings.Contains(prompt, "<!-- IMG:0 -->\n\n<!-- IMG:1 -->") {
+		t.Errorf("prompt should carry numbered placeholders instead of image syntax:\n%s", prompt)
+	}
+}
@@ -0,0 +1,117 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatter Markdown 文件头部的 YAML 元数据，字段名与草稿 JSON 一致
+//
+//	---
+//	title: 文章标题
+//	author: 作者
+//	digest: 摘要
+//	content_source_url: https://example.com
+//	cover: ./cover.jpg
+//	need_open_comment: true
+//	only_fans_can_comment: false
+//	---
+type FrontMatter struct {
+	Title              string `yaml:"title"`
+	Author             string `yaml:"author"`
+	Digest             string `yaml:"digest"`
+	ContentSourceURL   string `yaml:"content_source_url"`
+	Cover              string `yaml:"cover"`          // 本地封面图片路径（相对于 Markdown 文件）
+	ThumbMediaID       string `yaml:"thumb_media_id"` // 已上传的封面素材 ID
+	ShowCoverPic       *Flag  `yaml:"show_cover_pic"`
+	NeedOpenComment    Flag   `yaml:"need_open_comment"`
+	OnlyFansCanComment Flag   `yaml:"only_fans_can_comment"`
+	PicCrop2351        string `yaml:"pic_crop_235_1"`
+	PicCrop11          string `yaml:"pic_crop_1_1"`
+}
+
+// Flag 0/1 开关，YAML 中可写作 true/false 或 0/1
+type Flag int
+
+// UnmarshalYAML 支持布尔值和整数
+func (f *Flag) UnmarshalYAML(value *yaml.Node) error {
+	var b bool
+	if err := value.Decode(&b); err == nil {
+		if b {
+			*f = 1
+		} else {
+			*f = 0
+		}
+		return nil
+	}
+
+	var i int
+	if err := value.Decode(&i); err != nil || (i != 0 && i != 1) {
+		return fmt.Errorf("line %d: %q 应为 true/false 或 0/1", value.Line, value.Value)
+	}
+	*f = Flag(i)
+	return nil
+}
+
+// frontMatterDelimiter front matter 分隔行
+const frontMatterDelimiter = "---"
+
+// ParseFrontMatter 解析 Markdown 开头的 YAML front matter
+// 返回元数据和去掉 front matter 后的正文；没有 front matter 时返回空元数据和原文
+func ParseFrontMatter(markdown string) (*FrontMatter, string, error) {
+	fm := &FrontMatter{}
+
+	text := strings.TrimPrefix(markdown, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if !strings.HasPrefix(text, frontMatterDelimiter+"\n") {
+		return fm, markdown, nil
+	}
+
+	lines := strings.Split(text, "\n")
+	closing := -1
+	for i := 1; i < len(lines); i++ {
+		if l := strings.TrimRight(lines[i], " \t"); l == frontMatterDelimiter || l == "..." {
+			closing = i
+			break
+		}
+	}
+
+	// 只有开头的分隔线而没有结束分隔线，按普通 Markdown 处理（可能是水平线）
+	if closing < 0 {
+		return fm, markdown, nil
+	}
+
+	yamlPart := strings.Join(lines[1:closing], "\n")
+	body := strings.Join(lines[closing+1:], "\n")
+
+	if err := yaml.Unmarshal([]byte(yamlPart), fm); err != nil {
+		return nil, "", fmt.Errorf("解析 front matter 失败: %w", err)
+	}
+
+	return fm, strings.TrimLeft(body, "\n"), nil
+}
+
+// h1Pattern 匹配一级标题
+var h1Pattern = regexp.MustCompile(`(?m)^#\s+(.+?)\s*#*\s*$`)
+
+// ExtractTitle 提取 Markdown 中的第一个一级标题
+func ExtractTitle(markdown string) string {
+	inCode := false
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		if m := h1Pattern.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package converter
+
+import "testing"
+
+func TestParseFrontMatter(t *testing.T) {
+	md := "---\ntitle: 标题\nauthor: 作者\ncontent_source_url: https://example.com\ncover: ./cover.jpg\nneed_open_comment: true\nonly_fans_can_comment: 1\nshow_cover_pic: false\n---\n\n# 正文标题\n\n内容\n"
+
+	fm, body, err := ParseFrontMatter(md)
+	if err != nil {
+		t.Fatalf("ParseFrontMatter() error = %v", err)
+	}
+	if fm.Title != "标题" || fm.Author != "作者" || fm.ContentSourceURL != "https://example.com" || fm.Cover != "./cover.jpg" {
+		t.Errorf("front matter = %+v", fm)
+	}
+	if fm.NeedOpenComment != 1 || fm.OnlyFansCanComment != 1 {
+		t.Errorf("comment flags = %d, %d, want 1, 1", fm.NeedOpenComment, fm.OnlyFansCanComment)
+	}
+	if fm.ShowCoverPic == nil || *fm.ShowCoverPic != 0 {
+		t.Errorf("ShowCoverPic = %v, want 0", fm.ShowCoverPic)
+	}
+	if body != "# 正文标题\n\n内容\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestParseFrontMatterAbsent(t *testing.T) {
+	tests := []string{
+		"# 标题\n\n内容",
+		"---\n\n只有水平线，没有结束分隔符",
+	}
+	for _, md := range tests {
+		fm, body, err := ParseFrontMatter(md)
+		if err != nil {
+			t.Fatalf("ParseFrontMatter(%q) error = %v", md, err)
+		}
+		if body != md || fm.Title != "" {
+			t.Errorf("ParseFrontMatter(%q) = %+v, %q; want unchanged", md, fm, body)
+		}
+	}
+}
+
+func TestParseFrontMatterInvalidFlag(t *testing.T) {
+	if _, _, err := ParseFrontMatter("---\nneed_open_comment: maybe\n---\n"); err == nil {
+		t.Error("ParseFrontMatter() with invalid flag should fail")
+	}
+}
+
+func TestExtractTitle(t *testing.T) {
+	md := "```bash\n# 注释不是标题\n```\n\n## 二级标题\n\n# 一级标题 #\n"
+	if got := ExtractTitle(md); got != "一级标题" {
+		t.Errorf("ExtractTitle() = %q, want 一级标题", got)
+	}
+}
@@ -134,51 +134,9 @@ func (p *imageProcessor) CountImages(markdown string) int {
 	return count
 }
 
-// ParseImageSyntax 解析图片语法
+// ParseImageSyntax 解析图片语法，按在文档中出现的顺序编号
 func (p *imageProcessor) ParseImageSyntax(markdown string) []ImageRef {
-	var images []ImageRef
-	index := 0
-
-	// 匹配本地图片: ![alt](./path/to/image.png)
-	localPattern := regexp.MustCompile(`!\[([^\]]*)\]\((\.\/[^)]+)\)`)
-	for _, match := range localPattern.FindAllStringSubmatch(markdown, -1) {
-		if len(match) >= 3 {
-			images = append(images, ImageRef{
-				Index:    index,
-				Original: match[2],
-				Type:     ImageTypeLocal,
-			})
-			index++
-		}
-	}
-
-	// 匹配在线图片: ![alt](https://...)
-	onlinePattern := regexp.MustCompile(`!\[([^\]]*)\]\((https?://[^)]+)\)`)
-	for _, match := range onlinePattern.FindAllStringSubmatch(markdown, -1) {
-		if len(match) >= 3 {
-			images = append(images, ImageRef{
-				Index:    index,
-				Original: match[2],
-				Type:     ImageTypeOnline,
-			})
-			index++
-		}
-	}
-
-	// 匹配 AI 生成图片: ![alt](__generate:prompt__)
-	aiPattern := regexp.MustCompile(`!\[([^\]]*)\]\(__generate:([^)]+)__\)`)
-	for _, match := range aiPattern.FindAllStringSubmatch(markdown, -1) {
-		if len(match) >= 3 {
-			images = append(images, ImageRef{
-				Index:    index,
-				Original: match[2],
-				Type:     ImageTypeAI,
-				AIPrompt: match[2],
-			})
-			index++
-		}
-	}
-
+	_, images := MarkImages(markdown)
 	return images
 }
 
@@ -0,0 +1,130 @@
+package converter
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/royalrick/wechatwriter/app/media"
+)
+
+// MediaRef 视频/语音引用
+type MediaRef struct {
+	Index        int        // 位置索引
+	Kind         media.Kind // video / voice
+	Original     string     // 本地文件路径
+	Title        string     // 标题（视频素材标题、语音名称）
+	Introduction string     // 视频简介
+	Placeholder  string     // HTML 中的占位符 <!-- MEDIA:0 -->
+	MediaID      string     // 上传后的素材 ID (处理完成后)
+	PlayLength   int64      // 语音时长（毫秒，处理完成后）
+}
+
+// mediaPattern 匹配 ![video](./clip.mp4 "简介")，alt 为 video / voice / audio（可带 :标题）或文件为视频/语音格式
+var mediaPattern = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"([^"]*)")?\s*\)`)
+
+// mediaPlaceholderPattern 匹配 AI 输出中的媒体占位符
+var mediaPlaceholderPattern = regexp.MustCompile(`<!--\s*MEDIA:(\d+)\s*-->`)
+
+// MediaPromptNote 有媒体占位符时附加到 AI 提示词的说明
+const MediaPromptNote = "注意：Markdown 中的 <!-- MEDIA:index --> 是视频/语音占位符，请原样保留在对应位置，不要改写或删除。"
+
+// ExtractMedia 提取 Markdown 中的视频/语音引用，并替换为 <!-- MEDIA:n --> 占位符
+// 返回替换后的 Markdown（用于 AI 转换和图片提取）
+func ExtractMedia(markdown string) (string, []MediaRef) {
+	var refs []MediaRef
+	out := mediaPattern.ReplaceAllStringFunc(markdown, func(match string) string {
+		m := mediaPattern.FindStringSubmatch(match)
+		kind, title, ok := classifyMedia(m[1], m[2])
+		if !ok {
+			return match
+		}
+
+		ref := MediaRef{
+			Index:        len(refs),
+			Kind:         kind,
+			Original:     m[2],
+			Title:        title,
+			Introduction: m[3],
+			Placeholder:  mediaPlaceholder(len(refs)),
+		}
+		refs = append(refs, ref)
+		return ref.Placeholder
+	})
+	return out, refs
+}
+
+// classifyMedia 根据 alt 文本和扩展名判断媒体类型，返回标题
+func classifyMedia(alt, path string) (media.Kind, string, bool) {
+	keyword, title, _ := strings.Cut(alt, ":")
+	var kind media.Kind
+	switch strings.ToLower(strings.TrimSpace(keyword)) {
+	case "video":
+		kind = media.KindVideo
+	case "voice", "audio":
+		kind = media.KindVoice
+	default:
+		byExt, ok := media.KindOf(path)
+		if !ok || strings.HasPrefix(path, "__generate:") {
+			return "", "", false
+		}
+		kind, title = byExt, alt
+	}
+
+	title = strings.TrimSpace(title)
+	if title == "" {
+		base := filepath.Base(path)
+		title = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	return kind, title, true
+}
+
+// mediaPlaceholder 返回第 index 个媒体在 HTML 中的占位符
+func mediaPlaceholder(index int) string {
+	return fmt.Sprintf("<!-- MEDIA:%d -->", index)
+}
+
+// ReplaceMediaPlaceholders 将已上传的媒体占位符替换为素材卡片（见 MediaRef.HTML）
+// 未上传成功的占位符保留（HTML 注释，不显示）
+func ReplaceMediaPlaceholders(content string, refs []MediaRef) string {
+	return mediaPlaceholderPattern.ReplaceAllStringFunc(content, func(match string) string {
+		index := parseInt(mediaPlaceholderPattern.FindStringSubmatch(match)[1])
+		if index >= len(refs) || refs[index].MediaID == "" {
+			return match
+		}
+		return refs[index].HTML()
+	})
+}
+
+// mediaCardStyle 素材卡片样式
+const mediaCardStyle = "margin:16px 0;padding:12px 16px;border:1px dashed #c8c8c8;border-radius:6px;text-align:center;color:#888;font-size:14px;line-height:1.6;"
+
+// HTML 返回占位的素材卡片，标明素材标题和 media_id
+//
+// 公众号编辑器内嵌视频（data-mpvid / vid）和语音（voice_encode_fileid）使用的是编辑器自己的 ID，
+// 不是永久素材的 media_id，直接写入 media_id 无法播放，接口也没有换取这些 ID 的方法。
+// 因此只输出卡片，需在公众号后台编辑器中通过「视频 / 音频 → 素材库」选择该素材替换卡片。
+func (m MediaRef) HTML() string {
+	label := "视频"
+	if m.Kind == media.KindVoice {
+		label = "语音"
+	}
+	title := html.EscapeString(m.Title)
+	if m.Kind == media.KindVoice && m.PlayLength > 0 {
+		title += fmt.Sprintf("（%d 秒）", (m.PlayLength+999)/1000)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<section class="wechatwriter-media" data-kind="%s" data-media-id="%s" style="%s">`,
+		m.Kind, html.EscapeString(m.MediaID), mediaCardStyle)
+	fmt.Fprintf(&b, `<p><strong>%s：%s</strong></p>`, label, title)
+	if m.Introduction != "" {
+		fmt.Fprintf(&b, `<p>%s</p>`, html.EscapeString(m.Introduction))
+	}
+	fmt.Fprintf(&b, `<p style="font-size:12px;">已上传到素材库（media_id: %s），请在编辑器中从素材库插入%s替换此卡片</p>`,
+		html.EscapeString(m.MediaID), label)
+	b.WriteString(`</section>`)
+	return b.String()
+}
@@ -122,6 +122,11 @@ func (tm *ThemeManager) getThemeDir() string {
 	return "themes"
 }
 
+// GetThemeDir 获取主题目录路径（公开方法）
+func (tm *ThemeManager) GetThemeDir() string {
+	return tm.getThemeDir()
+}
+
 // LoadTheme 加载单个主题（支持自定义路径）
 func (tm *ThemeManager) LoadTheme(path string) error {
 	return tm.loadThemeFromFile(path)
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	stdimage "image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/royalrick/wechatwriter/app/config"
+	"github.com/royalrick/wechatwriter/app/converter"
+	"github.com/royalrick/wechatwriter/app/cover"
+	"github.com/royalrick/wechatwriter/app/draft"
+	"github.com/royalrick/wechatwriter/app/image"
+	"github.com/royalrick/wechatwriter/app/writer"
+	"github.com/spf13/cobra"
+)
+
+// coverCmd 封面命令组
+func coverCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cover",
+		Short: "封面合成",
+		Long: `封面命令组
+
+支持的操作：
+  compose  - 在背景图上叠加标题和品牌元素，生成 2.35:1 封面和 1:1 缩略图并上传封面`,
+	}
+
+	cmd.AddCommand(coverComposeCmd())
+
+	return cmd
+}
+
+// coverComposeOptions cover compose 参数
+type coverComposeOptions struct {
+	title      string
+	subtitle   string
+	article    string
+	background string
+	galleryID  string
+	prompt     string
+	template   string
+	style      string
+	font       string
+	output     string
+	accountID  string
+	preview    bool
+}
+
+// coverComposeCmd 合成封面
+func coverComposeCmd() *cobra.Command {
+	var opts coverComposeOptions
+
+	cmd := &cobra.Command{
+		Use:   "compose",
+		Short: "合成带标题的封面并上传为 thumb_media_id",
+		Long: `在背景图上叠加标题、副标题和 Logo，生成 2.35:1 封面和 1:1 分享缩略图，
+并将封面上传为永久素材，输出可写入 front matter 的 thumb_media_id 和裁剪坐标。
+封面上的文字和 Logo 排在 1:1 裁剪区域内（左对齐取左侧，居中取正中），转发卡片裁剪后完整显示；
+1:1 缩略图只保存在本地，不上传。
+
+背景图三选一，都不指定时使用配色渐变：
+  --background  本地图片
+  --gallery     图片库中的图片 ID
+  --prompt      AI 生成新图片（默认 2.35:1，保存到图片库）
+
+品牌模板（--template 或账号的 cover_template）定义字体、安全区、配色和 Logo 位置；
+未配置颜色时使用写作风格（--style 或模板中的 style）的 cover_color_scheme。
+
+示例：
+  writer cover compose --title "慢下来，才能更快" --subtitle "关于专注的三个误区" --background ./bg.jpg
+  writer cover compose --article posts/focus.md --prompt "晨雾中的山路，极简" --template brand/cover.yaml
+  writer cover compose --title "Deep Work" --style dan-koe --preview`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			result, err := composeCover(opts)
+			if err != nil {
+				responseError(err)
+				return
+			}
+			responseSuccess(result)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.title, "title", "", "标题（默认取 --article 的 front matter title 或一级标题）")
+	cmd.Flags().StringVar(&opts.subtitle, "subtitle", "", "副标题（默认取 --article 的 front matter digest）")
+	cmd.Flags().StringVar(&opts.article, "article", "", "从 Markdown 文件读取标题和摘要")
+	cmd.Flags().StringVar(&opts.background, "background", "", "背景图片（本地路径）")
+	cmd.Flags().StringVar(&opts.galleryID, "gallery", "", "使用图片库中的图片作为背景")
+	cmd.Flags().StringVar(&opts.prompt, "prompt", "", "AI 生成背景图片的提示词（可带 |size=... 等选项）")
+	cmd.Flags().StringVarP(&opts.template, "template", "t", "", "品牌封面模板 YAML（默认使用账号的 cover_template）")
+	cmd.Flags().StringVar(&opts.style, "style", "", "写作风格，使用其 cover_color_scheme 配色")
+	cmd.Flags().StringVar(&opts.font, "font", "", "标题字体（TTF/OTF），覆盖模板中的 font")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "封面输出路径（默认 <文章名>-cover.jpg 或 cover.jpg），缩略图加 -square 后缀")
+	cmd.Flags().StringVarP(&opts.accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().BoolVar(&opts.preview, "preview", false, "只生成本地图片，不上传")
+
+	return cmd
+}
+
+// composeCover 解析文字、模板和背景，合成封面并按需上传
+func composeCover(opts coverComposeOptions) (map[string]any, error) {
+	sources := 0
+	for _, s := range []string{opts.background, opts.galleryID, opts.prompt} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, fmt.Errorf("--background、--gallery、--prompt 只能指定一个")
+	}
+
+	text, err := coverText(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 上传需要账号；--preview 时账号只用于读取 cover_template，未配置也可以合成
+	selector := config.NewAccountSelector(cfg.WechatAccounts, cfg.DefaultAccount)
+	account, accountErr := selector.SelectAccount("", opts.accountID)
+	var processor *image.Processor
+	switch {
+	case !opts.preview:
+		if accountErr != nil {
+			return nil, fmt.Errorf("select WeChat account: %w", accountErr)
+		}
+		if processor, err = image.NewProcessorForAccount(cfg, log, account.ID); err != nil {
+			return nil, err
+		}
+	case opts.prompt != "":
+		processor = image.NewProcessor(cfg, log)
+	}
+	if processor != nil {
+		processor.SetArticle(opts.article)
+	}
+
+	tpl, err := loadCoverTemplate(opts, account)
+	if err != nil {
+		return nil, err
+	}
+	composer, err := cover.NewComposer(tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	bg, backgroundInfo, err := loadCoverBackground(opts, processor)
+	if err != nil {
+		return nil, err
+	}
+	wide, square, err := composer.Compose(bg, text)
+	if err != nil {
+		return nil, err
+	}
+
+	widePath, squarePath := coverOutputPaths(opts)
+	if err := saveCoverImage(widePath, wide); err != nil {
+		return nil, err
+	}
+	if err := saveCoverImage(squarePath, square); err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{
+		"title":  text.Title,
+		"cover":  widePath,
+		"square": squarePath,
+	}
+	for k, v := range backgroundInfo {
+		result[k] = v
+	}
+	if opts.preview {
+		return result, nil
+	}
+
+	// 只上传 2.35:1 封面作为 thumb_media_id；转发卡片按 pic_crop_1_1 从封面裁剪，
+	// 裁剪区域与排版文字和 Logo 的区域一致，方形缩略图只保存在本地供预览
+	upload, err := processor.UploadCover(widePath)
+	if err != nil {
+		return nil, fmt.Errorf("upload cover: %w", err)
+	}
+	width, height := wide.Bounds().Dx(), wide.Bounds().Dy()
+	crop2351, _ := draft.CoverCrops(width, height)
+	crop11 := draft.CropCoords(composer.SquareCrop(), width, height)
+
+	result["thumb_media_id"] = upload.MediaID
+	result["thumb_url"] = upload.WechatURL
+	result["pic_crop_235_1"] = crop2351
+	result["pic_crop_1_1"] = crop11
+	result["front_matter"] = fmt.Sprintf("thumb_media_id: %s\npic_crop_235_1: %q\npic_crop_1_1: %q", upload.MediaID, crop2351, crop11)
+	return result, nil
+}
+
+// coverText 命令行参数优先，其次取文章 front matter 的 title / digest 或一级标题
+func coverText(opts coverComposeOptions) (cover.Text, error) {
+	text := cover.Text{Title: opts.title, Subtitle: opts.subtitle}
+	if opts.article != "" && (text.Title == "" || text.Subtitle == "") {
+		data, err := os.ReadFile(opts.article)
+		if err != nil {
+			return text, fmt.Errorf("read article: %w", err)
+		}
+		meta, body, err := converter.ParseFrontMatter(string(data))
+		if err != nil {
+			return text, err
+		}
+		if text.Title == "" {
+			text.Title = meta.Title
+		}
+		if text.Title == "" {
+			text.Title = converter.ExtractTitle(body)
+		}
+		if text.Subtitle == "" {
+			text.Subtitle = meta.Digest
+		}
+	}
+	if strings.TrimSpace(text.Title) == "" {
+		return text, fmt.Errorf("请通过 --title 指定标题，或用 --article 指定含标题的文章")
+	}
+	return text, nil
+}
+
+// loadCoverTemplate 按 --template、账号 cover_template、默认模板的顺序加载模板，并补全风格配色
+func loadCoverTemplate(opts coverComposeOptions, account *config.WechatAccount) (*cover.Template, error) {
+	path := opts.template
+	if path == "" && account != nil && account.CoverTemplate != "" {
+		path = account.CoverTemplate
+		if !filepath.IsAbs(path) {
+			if configFile := cfg.GetConfigFile(); configFile != "" {
+				path = filepath.Join(filepath.Dir(configFile), path)
+			}
+		}
+	}
+
+	tpl := cover.DefaultTemplate()
+	if path != "" {
+		var err error
+		if tpl, err = cover.LoadTemplate(path); err != nil {
+			return nil, err
+		}
+	}
+	if opts.font != "" {
+		tpl.Font = opts.font
+	}
+
+	styleName := opts.style
+	if styleName == "" {
+		styleName = tpl.Style
+	}
+	if styleName != "" {
+		style, err := writer.NewStyleManager().GetStyle(styleName)
+		if err != nil {
+			return nil, err
+		}
+		tpl.ApplyColorScheme(style.CoverColorScheme)
+	}
+	return tpl, nil
+}
+
+// loadCoverBackground 读取背景图，返回背景来源信息；未指定背景时返回 nil（使用渐变）
+func loadCoverBackground(opts coverComposeOptions, processor *image.Processor) (stdimage.Image, map[string]any, error) {
+	var path string
+	info := map[string]any{}
+	switch {
+	case opts.background != "":
+		path = opts.background
+		info["background"] = path
+	case opts.galleryID != "":
+		gallery := image.OpenGallery(cfg.GetDataDir())
+		entry, err := gallery.Get(opts.galleryID)
+		if err != nil {
+			return nil, nil, err
+		}
+		path = gallery.Path(entry)
+		info["gallery_id"] = entry.ID
+	case opts.prompt != "":
+		prompt, genOpts, err := image.ParseGenerateSpec(opts.prompt)
+		if err != nil {
+			return nil, nil, err
+		}
+		if genOpts.Size == "" && genOpts.AspectRatio == "" {
+			genOpts.AspectRatio = "2.35:1"
+		}
+		entry, err := processor.GenerateToGallery(context.Background(), prompt, genOpts)
+		if err != nil {
+			return nil, nil, err
+		}
+		path = processor.Gallery().Path(entry)
+		info["gallery_id"] = entry.ID
+		info["provider"] = entry.Provider
+	default:
+		return nil, info, nil
+	}
+
+	img, err := imaging.Open(path, imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, nil, fmt.Errorf("open background: %w", err)
+	}
+	return img, info, nil
+}
+
+// coverOutputPaths 封面和缩略图的输出路径
+func coverOutputPaths(opts coverComposeOptions) (wide, square string) {
+	wide = opts.output
+	if wide == "" {
+		wide = "cover.jpg"
+		if opts.article != "" {
+			base := strings.TrimSuffix(opts.article, filepath.Ext(opts.article))
+			wide = base + "-cover.jpg"
+		}
+	}
+	ext := filepath.Ext(wide)
+	return wide, strings.TrimSuffix(wide, ext) + "-square" + ext
+}
+
+// saveCoverImage 按扩展名保存为 JPEG 或 PNG
+func saveCoverImage(path string, img stdimage.Image) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	if strings.EqualFold(filepath.Ext(path), ".png") {
+		return imaging.Save(img, path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return jpeg.Encode(f, img, &jpeg.Options{Quality: 92})
+}
@@ -0,0 +1,383 @@
+package cover
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/disintegration/imaging"
+	"github.com/royalrick/wechatwriter/app/config"
+	wximage "github.com/royalrick/wechatwriter/app/image"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// 输出尺寸
+const (
+	WideWidth  = 1410 // 2.35:1 封面
+	WideHeight = 600
+	SquareSize = 600 // 1:1 分享缩略图
+)
+
+// 放不下时标题字号最多缩小到配置值的比例
+const minTitleScale = 0.6
+
+// Text 封面文字
+type Text struct {
+	Title    string
+	Subtitle string
+}
+
+// Composer 按模板合成封面
+type Composer struct {
+	tpl          *Template
+	titleFont    *sfnt.Font
+	subtitleFont *sfnt.Font
+	logo         image.Image
+}
+
+// NewComposer 加载模板中的字体和 Logo；未配置字体时使用内置的西文字体
+func NewComposer(tpl *Template) (*Composer, error) {
+	if tpl == nil {
+		tpl = DefaultTemplate()
+	}
+	if err := tpl.Validate(); err != nil {
+		return nil, err
+	}
+
+	c := &Composer{tpl: tpl}
+	var err error
+	if c.titleFont, err = wximage.LoadFont(tpl.Font); err != nil {
+		return nil, fmt.Errorf("cover title: %w", err)
+	}
+	c.subtitleFont = c.titleFont
+	if tpl.SubtitleFont != "" {
+		if c.subtitleFont, err = wximage.LoadFont(tpl.SubtitleFont); err != nil {
+			return nil, fmt.Errorf("cover subtitle: %w", err)
+		}
+	}
+	if tpl.Logo != nil && tpl.Logo.Path != "" {
+		if c.logo, err = imaging.Open(tpl.Logo.Path); err != nil {
+			return nil, fmt.Errorf("open cover logo: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Compose 合成 2.35:1 封面和 1:1 缩略图；bg 为 nil 时使用配色渐变作为背景
+func (c *Composer) Compose(bg image.Image, text Text) (wide, square *image.NRGBA, err error) {
+	text.Title = strings.TrimSpace(text.Title)
+	text.Subtitle = strings.TrimSpace(text.Subtitle)
+	if text.Title == "" {
+		return nil, nil, fmt.Errorf("封面标题不能为空")
+	}
+	if err := wximage.CheckGlyphs(c.titleFont, text.Title); err != nil {
+		return nil, nil, fmt.Errorf("cover title: %w, set font in the cover template or pass --font", err)
+	}
+	if err := wximage.CheckGlyphs(c.subtitleFont, text.Subtitle); err != nil {
+		return nil, nil, fmt.Errorf("cover subtitle: %w, set subtitle_font in the cover template", err)
+	}
+
+	if wide, err = c.render(bg, text, WideWidth, WideHeight, c.tpl.Wide, c.SquareCrop()); err != nil {
+		return nil, nil, err
+	}
+	squareFrame := image.Rect(0, 0, SquareSize, SquareSize)
+	if square, err = c.render(bg, text, SquareSize, SquareSize, c.tpl.Square, squareFrame); err != nil {
+		return nil, nil, err
+	}
+	return wide, square, nil
+}
+
+// SquareCrop 2.35:1 封面中的 1:1 裁剪区域（转发卡片、小图展示的部分）：
+// 左对齐时取左侧，居中时取正中。文字和 Logo 都排在该区域内，裁剪后不会被切掉
+func (c *Composer) SquareCrop() image.Rectangle {
+	if c.tpl.Align == AlignCenter {
+		x := (WideWidth - WideHeight) / 2
+		return image.Rect(x, 0, x+WideHeight, WideHeight)
+	}
+	return image.Rect(0, 0, WideHeight, WideHeight)
+}
+
+// render 绘制单个画布：背景 → 遮罩 → 文字 → Logo；文字和 Logo 排在 frame 内
+func (c *Composer) render(bg image.Image, text Text, width, height int, layout Layout, frame image.Rectangle) (*image.NRGBA, error) {
+	colors := c.colors()
+
+	var canvas *image.NRGBA
+	if bg != nil {
+		canvas = imaging.Fill(bg, width, height, imaging.Center, imaging.Lanczos)
+		if c.tpl.Overlay > 0 {
+			overlay := colors.background
+			overlay.A = uint8(math.Round(c.tpl.Overlay * 255))
+			draw.Draw(canvas, canvas.Bounds(), image.NewUniform(overlay), image.Point{}, draw.Over)
+		}
+	} else {
+		canvas = gradient(width, height, colors.background, colors.gradient)
+	}
+
+	// 安全区（相对 frame）
+	zone := layout.SafeZone
+	area := image.Rect(
+		frame.Min.X+int(zone.Left*float64(frame.Dx())),
+		frame.Min.Y+int(zone.Top*float64(frame.Dy())),
+		frame.Max.X-int(zone.Right*float64(frame.Dx())),
+		frame.Max.Y-int(zone.Bottom*float64(frame.Dy())),
+	)
+
+	title, err := c.fitTitle(text.Title, layout, height, area)
+	if err != nil {
+		return nil, err
+	}
+	defer title.face.Close()
+
+	var subtitle *textBlock
+	if text.Subtitle != "" {
+		size := layout.SubtitleSize * float64(height) * title.scale
+		if subtitle, err = layoutText(c.subtitleFont, text.Subtitle, size, area.Dx(), 2); err != nil {
+			return nil, err
+		}
+		defer subtitle.face.Close()
+	}
+
+	// 强调色条、标题、副标题作为整体在安全区内垂直居中
+	accentH, accentGap := 0, 0
+	if colors.hasAccent {
+		accentH = max(2, height/80)
+		accentGap = title.lineHeight / 3
+	}
+	total := accentH + accentGap + title.height()
+	gap := 0
+	if subtitle != nil {
+		gap = title.lineHeight / 3
+		total += gap + subtitle.height()
+	}
+	y := area.Min.Y + (area.Dy()-total)/2
+
+	if colors.hasAccent {
+		barW := max(accentH*6, area.Dx()/10)
+		x := area.Min.X
+		if c.tpl.Align == AlignCenter {
+			x = area.Min.X + (area.Dx()-barW)/2
+		}
+		draw.Draw(canvas, image.Rect(x, y, x+barW, y+accentH), image.NewUniform(colors.accent), image.Point{}, draw.Over)
+		y += accentH + accentGap
+	}
+	y = title.draw(canvas, area, y, c.tpl.Align, colors.title)
+	if subtitle != nil {
+		subtitle.draw(canvas, area, y+gap, c.tpl.Align, colors.subtitle)
+	}
+
+	c.drawLogo(canvas, frame)
+	return canvas, nil
+}
+
+// fitTitle 在最多 MaxTitleLines 行内排下标题，放不下时逐步缩小字号；最小字号仍放不下时截断末行
+func (c *Composer) fitTitle(title string, layout Layout, height int, area image.Rectangle) (*textBlock, error) {
+	base := layout.TitleSize * float64(height)
+	maxLines := max(1, layout.MaxTitleLines)
+	for scale := 1.0; ; scale -= 0.1 {
+		block, err := layoutText(c.titleFont, title, base*scale, area.Dx(), 0)
+		if err != nil {
+			return nil, err
+		}
+		block.scale = scale
+		if len(block.lines) <= maxLines && block.height() <= area.Dy() {
+			return block, nil
+		}
+		if scale-0.1 < minTitleScale {
+			block.truncate(maxLines, area.Dx())
+			return block, nil
+		}
+		block.face.Close()
+	}
+}
+
+// drawLogo 按模板位置在 frame 内绘制 Logo
+func (c *Composer) drawLogo(canvas *image.NRGBA, b image.Rectangle) {
+	if c.logo == nil {
+		return
+	}
+	width := c.tpl.Logo.Width
+	if width <= 0 {
+		width = 0.12
+	}
+	margin := c.tpl.Logo.Margin
+	if margin <= 0 {
+		margin = 0.06
+	}
+	logo := imaging.Resize(c.logo, int(width*float64(b.Dx())), 0, imaging.Lanczos)
+	m := int(margin * float64(b.Dy()))
+	lw, lh := logo.Bounds().Dx(), logo.Bounds().Dy()
+
+	var x, y int
+	switch c.tpl.Logo.Position {
+	case config.WatermarkTopLeft:
+		x, y = b.Min.X+m, b.Min.Y+m
+	case config.WatermarkBottomLeft:
+		x, y = b.Min.X+m, b.Max.Y-m-lh
+	case config.WatermarkBottomRight:
+		x, y = b.Max.X-m-lw, b.Max.Y-m-lh
+	default:
+		x, y = b.Max.X-m-lw, b.Min.Y+m
+	}
+	draw.Draw(canvas, image.Rect(x, y, x+lw, y+lh), logo, image.Point{}, draw.Over)
+}
+
+// palette 解析后的配色
+type palette struct {
+	background, gradient, title, subtitle, accent color.NRGBA
+	hasAccent                                     bool
+}
+
+// colors 解析模板颜色，未配置时为黑底白字
+func (c *Composer) colors() palette {
+	parse := func(s string, fallback color.NRGBA) color.NRGBA {
+		if v, err := wximage.ParseHexColor(s); err == nil {
+			return v
+		}
+		return fallback
+	}
+	cs := c.tpl.Colors
+	p := palette{
+		background: parse(cs.Background, color.NRGBA{A: 0xFF}),
+		title:      parse(cs.Title, color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}),
+	}
+	p.gradient = parse(cs.Gradient, p.background)
+	p.subtitle = parse(cs.Subtitle, p.title)
+	if cs.Accent != "" {
+		p.accent, p.hasAccent = parse(cs.Accent, p.title), true
+	}
+	return p
+}
+
+// gradient 从左上到右下的线性渐变
+func gradient(width, height int, from, to color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	span := float64(width + height - 2)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			t := float64(x+y) / math.Max(span, 1)
+			i := img.PixOffset(x, y)
+			img.Pix[i] = lerp(from.R, to.R, t)
+			img.Pix[i+1] = lerp(from.G, to.G, t)
+			img.Pix[i+2] = lerp(from.B, to.B, t)
+			img.Pix[i+3] = 0xFF
+		}
+	}
+	return img
+}
+
+func lerp(a, b uint8, t float64) uint8 {
+	return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+}
+
+// textBlock 已折行的一段文字
+type textBlock struct {
+	face       font.Face
+	lines      []string
+	lineHeight int
+	ascent     int
+	scale      float64 // 标题相对配置字号的缩放比例
+}
+
+func (b *textBlock) height() int {
+	return b.lineHeight * len(b.lines)
+}
+
+// draw 从 y 开始逐行绘制，返回下一行的 y
+func (b *textBlock) draw(dst *image.NRGBA, area image.Rectangle, y int, align string, c color.NRGBA) int {
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(c), Face: b.face}
+	for _, line := range b.lines {
+		x := area.Min.X
+		if align == AlignCenter {
+			x = area.Min.X + (area.Dx()-d.MeasureString(line).Ceil())/2
+		}
+		d.Dot = fixed.P(x, y+b.ascent)
+		d.DrawString(line)
+		y += b.lineHeight
+	}
+	return y
+}
+
+// truncate 只保留前 n 行，末行放不下省略号时逐字删减
+func (b *textBlock) truncate(n, width int) {
+	if len(b.lines) <= n {
+		return
+	}
+	b.lines = b.lines[:n]
+	last := []rune(b.lines[n-1])
+	for len(last) > 0 && font.MeasureString(b.face, string(last)+"…").Ceil() > width {
+		last = last[:len(last)-1]
+	}
+	b.lines[n-1] = strings.TrimSpace(string(last)) + "…"
+}
+
+// layoutText 按宽度折行；maxLines > 0 时超出部分截断
+func layoutText(f *sfnt.Font, text string, size float64, width, maxLines int) (*textBlock, error) {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: math.Max(size, 8), DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return nil, fmt.Errorf("load cover font: %w", err)
+	}
+	m := face.Metrics()
+	b := &textBlock{
+		face:       face,
+		lines:      wrapText(face, text, width),
+		lineHeight: int(math.Ceil(float64((m.Ascent + m.Descent).Ceil()) * 1.2)),
+		ascent:     m.Ascent.Ceil(),
+		scale:      1,
+	}
+	if maxLines > 0 {
+		b.truncate(maxLines, width)
+	}
+	return b, nil
+}
+
+// wrapText 按像素宽度折行：中日韩文字可在任意字间断行，西文在空格处断行，
+// 句末标点不放在行首
+func wrapText(face font.Face, text string, width int) []string {
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		var line []rune
+		lastBreak := -1 // 可断行的位置（该位置之前的内容留在本行）
+		for _, r := range para {
+			if len(line) == 0 && unicode.IsSpace(r) {
+				continue
+			}
+			line = append(line, r)
+			// 行尾空格会被去掉，不触发折行
+			if !unicode.IsSpace(r) && font.MeasureString(face, string(line)).Ceil() > width && len(line) > 1 {
+				cut := len(line) - 1
+				if !isCJK(r) && !isClosingPunct(r) && lastBreak > 0 {
+					cut = lastBreak
+				}
+				// 避头：句末标点随上一个字留在本行
+				for cut > 1 && isClosingPunct(line[cut]) {
+					cut--
+				}
+				lines = append(lines, strings.TrimRightFunc(string(line[:cut]), unicode.IsSpace))
+				line = []rune(strings.TrimLeftFunc(string(line[cut:]), unicode.IsSpace))
+				lastBreak = -1
+			}
+			if unicode.IsSpace(r) || isCJK(r) {
+				lastBreak = len(line)
+			}
+		}
+		if s := strings.TrimSpace(string(line)); s != "" {
+			lines = append(lines, s)
+		}
+	}
+	return lines
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isClosingPunct(r rune) bool {
+	return strings.ContainsRune("，。、；：！？）》」』】”’,.;:!?)", r)
+}
@@ -0,0 +1,158 @@
+package cover
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font/basicfont"
+)
+
+func TestWrapText(t *testing.T) {
+	// basicfont 每个字符宽 7 像素，宽度 35 可放 5 个字符
+	face := basicfont.Face7x13
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"中文任意断行", "一二三四五六七八", []string{"一二三四五", "六七八"}},
+		{"标点不放行首", "一二三四五，六七", []string{"一二三四", "五，六七"}},
+		{"西文按空格断行", "ab cd efgh", []string{"ab cd", "efgh"}},
+		{"保留换行", "ab\ncd", []string{"ab", "cd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapText(face, tt.text, 35); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrapText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompose(t *testing.T) {
+	tpl := DefaultTemplate()
+	tpl.ApplyColorScheme([]string{"#1a1a2e", "#ffffff", "#e94560"})
+	c, err := NewComposer(tpl)
+	if err != nil {
+		t.Fatalf("NewComposer() error = %v", err)
+	}
+
+	wide, square, err := c.Compose(nil, Text{Title: "Shipping small, shipping often", Subtitle: "why tiny releases win"})
+	if err != nil {
+		t.Fatalf("Compose() error = %v", err)
+	}
+	if b := wide.Bounds(); b.Dx() != WideWidth || b.Dy() != WideHeight {
+		t.Errorf("wide = %dx%d, want %dx%d", b.Dx(), b.Dy(), WideWidth, WideHeight)
+	}
+	if b := square.Bounds(); b.Dx() != SquareSize || b.Dy() != SquareSize {
+		t.Errorf("square = %dx%d, want %dx%d", b.Dx(), b.Dy(), SquareSize, SquareSize)
+	}
+
+	// 内置字体不含中文，应提示配置字体而不是渲染出方框
+	if _, _, err := c.Compose(nil, Text{Title: "慢下来"}); err == nil {
+		t.Error("Compose() with CJK title and default font should fail")
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "brand.yaml")
+	content := `style: dan-koe
+font: fonts/title.ttf
+align: center
+colors:
+  title: "#ffcc00"
+wide:
+  title_size: 0.15
+logo:
+  path: logo.png
+  position: bottom-left
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, err := LoadTemplate(path)
+	if err != nil {
+		t.Fatalf("LoadTemplate() error = %v", err)
+	}
+	if tpl.Font != filepath.Join(dir, "fonts/title.ttf") || tpl.Logo.Path != filepath.Join(dir, "logo.png") {
+		t.Errorf("paths not resolved: font=%s logo=%s", tpl.Font, tpl.Logo.Path)
+	}
+	if tpl.Align != AlignCenter || tpl.Wide.TitleSize != 0.15 {
+		t.Errorf("template fields not loaded: %+v", tpl)
+	}
+	// 未填写的字段保留默认值
+	if tpl.Wide.MaxTitleLines != 2 || tpl.Square.TitleSize != DefaultTemplate().Square.TitleSize {
+		t.Errorf("defaults not kept: wide=%+v square=%+v", tpl.Wide, tpl.Square)
+	}
+
+	tpl.ApplyColorScheme([]string{"#000000", "#ffffff", "#ff0000", "#333333"})
+	want := Colors{Background: "#000000", Title: "#ffcc00", Accent: "#ff0000", Gradient: "#333333"}
+	if tpl.Colors != want {
+		t.Errorf("ApplyColorScheme() colors = %+v, want %+v", tpl.Colors, want)
+	}
+
+	if err := os.WriteFile(path, []byte("align: right\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTemplate(path); err == nil {
+		t.Error("LoadTemplate() should reject unsupported align")
+	}
+}
+
+func TestComposeKeepsTextInsideSquareCrop(t *testing.T) {
+	logo := filepath.Join(t.TempDir(), "logo.png")
+	if err := imaging.Save(imaging.New(40, 40, color.NRGBA{R: 255, A: 255}), logo); err != nil {
+		t.Fatal(err)
+	}
+	bg := imaging.New(200, 100, color.NRGBA{R: 40, G: 80, B: 120, A: 255})
+
+	for _, align := range []string{AlignLeft, AlignCenter} {
+		t.Run(align, func(t *testing.T) {
+			tpl := DefaultTemplate()
+			tpl.Align = align
+			tpl.Colors.Accent = "#e94560"
+			tpl.Logo = &Logo{Path: logo}
+			c, err := NewComposer(tpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wide, _, err := c.Compose(bg, Text{Title: "Shipping small, shipping often", Subtitle: "why tiny releases win"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 裁剪区域外只有背景，标题、强调色条和 Logo 都在区域内
+			crop := c.SquareCrop()
+			if crop.Dx() != WideHeight || crop.Dy() != WideHeight {
+				t.Fatalf("crop = %v, want %dx%d square", crop, WideHeight, WideHeight)
+			}
+			plain := wide.NRGBAAt(WideWidth-1, WideHeight-1)
+			if align == AlignCenter {
+				plain = wide.NRGBAAt(0, WideHeight-1)
+			}
+			drawn := 0
+			for y := 0; y < WideHeight; y++ {
+				for x := 0; x < WideWidth; x++ {
+					p := wide.NRGBAAt(x, y)
+					if image.Pt(x, y).In(crop) {
+						if p != plain {
+							drawn++
+						}
+					} else if p != plain {
+						t.Fatalf("pixel (%d,%d) = %v outside crop %v, want background %v", x, y, p, crop, plain)
+					}
+				}
+			}
+			if drawn == 0 {
+				t.Error("nothing drawn inside the crop region")
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+// Package cover 将背景图、标题和品牌元素合成为公众号封面（2.35:1）和分享缩略图（1:1）
+package cover
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/royalrick/wechatwriter/app/config"
+	wximage "github.com/royalrick/wechatwriter/app/image"
+	"gopkg.in/yaml.v3"
+)
+
+// 文字对齐方式
+const (
+	AlignLeft   = "left"
+	AlignCenter = "center"
+)
+
+// Template 品牌封面模板（YAML），未填写的字段保留 DefaultTemplate 中的值
+type Template struct {
+	Name         string  `yaml:"name,omitempty"`
+	Style        string  `yaml:"style,omitempty"`         // 写作风格，未配置 colors 时取其 cover_color_scheme
+	Font         string  `yaml:"font,omitempty"`          // 标题字体（TTF/OTF），标题含中文时必填
+	SubtitleFont string  `yaml:"subtitle_font,omitempty"` // 副标题字体，默认与标题相同
+	Align        string  `yaml:"align,omitempty"`         // 文字对齐：left / center
+	Overlay      float64 `yaml:"overlay,omitempty"`       // 背景图上的遮罩不透明度 0~1，保证文字可读
+	Colors       Colors  `yaml:"colors,omitempty"`
+	Wide         Layout  `yaml:"wide,omitempty"`   // 2.35:1 封面，文字排在 1:1 裁剪区域内
+	Square       Layout  `yaml:"square,omitempty"` // 1:1 分享缩略图
+	Logo         *Logo   `yaml:"logo,omitempty"`
+}
+
+// Colors 封面配色（#RRGGBB）
+type Colors struct {
+	Background string `yaml:"background,omitempty"` // 遮罩颜色，无背景图时为渐变起点
+	Gradient   string `yaml:"gradient,omitempty"`   // 无背景图时的渐变终点
+	Title      string `yaml:"title,omitempty"`
+	Subtitle   string `yaml:"subtitle,omitempty"` // 默认与标题相同
+	Accent     string `yaml:"accent,omitempty"`   // 标题上方的强调色条，留空不绘制
+}
+
+// Layout 单个画布的排版
+type Layout struct {
+	SafeZone      SafeZone `yaml:"safe_zone,omitempty"`       // 文字只排在安全区内
+	TitleSize     float64  `yaml:"title_size,omitempty"`      // 标题字号，占画布高度的比例
+	SubtitleSize  float64  `yaml:"subtitle_size,omitempty"`   // 副标题字号，占画布高度的比例
+	MaxTitleLines int      `yaml:"max_title_lines,omitempty"` // 标题最多行数，放不下时缩小字号
+}
+
+// SafeZone 安全区到画布四边的距离，占画布宽 / 高的比例（2.35:1 封面按其 1:1 裁剪区域计算）
+type SafeZone struct {
+	Left   float64 `yaml:"left,omitempty"`
+	Top    float64 `yaml:"top,omitempty"`
+	Right  float64 `yaml:"right,omitempty"`
+	Bottom float64 `yaml:"bottom,omitempty"`
+}
+
+// Logo 品牌 Logo 的位置
+type Logo struct {
+	Path     string  `yaml:"path"`
+	Position string  `yaml:"position,omitempty"` // top-left / top-right / bottom-left / bottom-right
+	Width    float64 `yaml:"width,omitempty"`    // 占画布宽度的比例（2.35:1 封面按其 1:1 裁剪区域计算）
+	Margin   float64 `yaml:"margin,omitempty"`   // 距画布边缘，占画布高度的比例
+}
+
+// DefaultTemplate 默认模板：黑色遮罩、白色文字，左对齐
+func DefaultTemplate() *Template {
+	return &Template{
+		Align:   AlignLeft,
+		Overlay: 0.35,
+		Wide: Layout{
+			SafeZone:      SafeZone{Left: 0.08, Top: 0.14, Right: 0.08, Bottom: 0.14},
+			TitleSize:     0.12,
+			SubtitleSize:  0.055,
+			MaxTitleLines: 2,
+		},
+		Square: Layout{
+			SafeZone:      SafeZone{Left: 0.1, Top: 0.12, Right: 0.1, Bottom: 0.12},
+			TitleSize:     0.1,
+			SubtitleSize:  0.05,
+			MaxTitleLines: 3,
+		},
+	}
+}
+
+// LoadTemplate 读取模板文件，font / logo 的相对路径相对于模板所在目录
+func LoadTemplate(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cover template: %w", err)
+	}
+	t := DefaultTemplate()
+	if err := yaml.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("parse cover template %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	t.Font = resolvePath(dir, t.Font)
+	t.SubtitleFont = resolvePath(dir, t.SubtitleFont)
+	if t.Logo != nil {
+		t.Logo.Path = resolvePath(dir, t.Logo.Path)
+	}
+
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("cover template %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// ApplyColorScheme 用写作风格的 cover_color_scheme 补全未配置的颜色：
+// 第 1 个为遮罩 / 底色，第 2 个为文字颜色，第 3 个为强调色，第 4 个为渐变终点
+func (t *Template) ApplyColorScheme(scheme []string) {
+	fill := func(dst *string, i int) {
+		if *dst == "" && i < len(scheme) {
+			*dst = scheme[i]
+		}
+	}
+	fill(&t.Colors.Background, 0)
+	fill(&t.Colors.Title, 1)
+	fill(&t.Colors.Accent, 2)
+	fill(&t.Colors.Gradient, 3)
+}
+
+// Validate 检查对齐方式、比例和颜色
+func (t *Template) Validate() error {
+	switch t.Align {
+	case "", AlignLeft, AlignCenter:
+	default:
+		return fmt.Errorf("不支持的对齐方式 %q", t.Align)
+	}
+	if t.Overlay < 0 || t.Overlay > 1 {
+		return fmt.Errorf("overlay 必须在 0 到 1 之间")
+	}
+	for name, l := range map[string]Layout{"wide": t.Wide, "square": t.Square} {
+		z := l.SafeZone
+		if z.Left < 0 || z.Right < 0 || z.Top < 0 || z.Bottom < 0 || z.Left+z.Right >= 1 || z.Top+z.Bottom >= 1 {
+			return fmt.Errorf("%s.safe_zone 超出画布", name)
+		}
+		if l.TitleSize < 0 || l.TitleSize > 0.5 || l.SubtitleSize < 0 || l.SubtitleSize > 0.5 {
+			return fmt.Errorf("%s 的字号比例必须在 0 到 0.5 之间", name)
+		}
+	}
+	for _, c := range []string{t.Colors.Background, t.Colors.Gradient, t.Colors.Title, t.Colors.Subtitle, t.Colors.Accent} {
+		if c == "" {
+			continue
+		}
+		if _, err := wximage.ParseHexColor(c); err != nil {
+			return err
+		}
+	}
+	if t.Logo != nil {
+		switch t.Logo.Position {
+		case "", config.WatermarkTopLeft, config.WatermarkTopRight, config.WatermarkBottomLeft, config.WatermarkBottomRight:
+		default:
+			return fmt.Errorf("不支持的 logo 位置 %q", t.Logo.Position)
+		}
+		if t.Logo.Width < 0 || t.Logo.Width > 1 || t.Logo.Margin < 0 || t.Logo.Margin > 0.5 {
+			return fmt.Errorf("logo 的 width / margin 比例超出范围")
+		}
+	}
+	return nil
+}
+
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/config"
+	"github.com/royalrick/wechatwriter/app/converter"
+	"github.com/royalrick/wechatwriter/app/image"
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"github.com/royalrick/wechatwriter/app/writer"
+	"github.com/spf13/cobra"
+)
+
+// 诊断结果状态
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// quotaWarnRatio 剩余额度低于该比例时警告
+const quotaWarnRatio = 0.1
+
+// doctorQuotaPaths 需要检查额度的接口
+var doctorQuotaPaths = []string{
+	"/cgi-bin/material/add_material",
+	"/cgi-bin/draft/add",
+}
+
+// DoctorCheck 单项检查结果
+type DoctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// DoctorAccountReport 单个账号的检查结果
+type DoctorAccountReport struct {
+	AccountID string        `json:"account_id"`
+	Name      string        `json:"name"`
+	APIBase   string        `json:"api_base"`
+	Checks    []DoctorCheck `json:"checks"`
+}
+
+// DoctorReport 诊断报告
+type DoctorReport struct {
+	OK          bool                  `json:"ok"`
+	Environment []DoctorCheck         `json:"environment"`
+	Accounts    []DoctorAccountReport `json:"accounts"`
+	Summary     map[string]int        `json:"summary"`
+}
+
+// add 追加检查结果
+func (r *DoctorReport) add(list *[]DoctorCheck, c DoctorCheck) {
+	*list = append(*list, c)
+	r.Summary[c.Status]++
+	if c.Status == checkFail {
+		r.OK = false
+	}
+}
+
+// doctorCmd 账号与环境诊断
+func doctorCmd() *cobra.Command {
+	var (
+		accountID string
+		format    string
+		skipImage bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "诊断公众号账号与运行环境",
+		Long: `诊断公众号账号与运行环境
+
+对每个公众号账号检查：
+  - access_token 获取（AppID / AppSecret 是否正确）
+  - 出口 IP 是否在公众号 IP 白名单中
+  - 素材、草稿接口权限
+  - 素材上传、新建草稿接口的今日调用额度
+
+同时检查配置文件、数据目录、主题目录、风格目录和图片服务连通性。
+存在失败项时以非 0 状态码退出。`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if format != "json" && format != "text" {
+				responseError(fmt.Errorf("不支持的输出格式: %s（可选 json, text）", format))
+				return
+			}
+
+			report := runDoctor(accountID, skipImage)
+			if format == "text" {
+				printDoctorText(report)
+			} else {
+				responseSuccess(report)
+			}
+			if !report.OK {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "只检查指定账号（默认检查全部账号）")
+	cmd.Flags().StringVarP(&format, "format", "f", "json", "输出格式: json, text")
+	cmd.Flags().BoolVar(&skipImage, "skip-image", false, "跳过图片服务连通性检查")
+
+	return cmd
+}
+
+// runDoctor 执行全部检查
+func runDoctor(accountID string, skipImage bool) *DoctorReport {
+	report := &DoctorReport{
+		OK:          true,
+		Environment: []DoctorCheck{},
+		Accounts:    []DoctorAccountReport{},
+		Summary:     map[string]int{checkPass: 0, checkWarn: 0, checkFail: 0, checkSkip: 0},
+	}
+
+	// 配置加载失败时仍然检查主题和风格目录
+	configErr := initConfig()
+	if configErr != nil {
+		report.add(&report.Environment, checkFromError("config", configErr))
+	} else {
+		report.add(&report.Environment, checkConfigFile())
+		report.add(&report.Environment, checkDataDir())
+	}
+
+	report.add(&report.Environment, checkThemeDir())
+	report.add(&report.Environment, checkStyleDir())
+
+	if configErr != nil {
+		return report
+	}
+
+	if skipImage {
+		report.add(&report.Environment, DoctorCheck{Name: "image_provider", Status: checkSkip, Message: "已跳过（--skip-image）"})
+	} else {
+		report.add(&report.Environment, checkImageProvider())
+	}
+
+	accounts := cfg.WechatAccounts
+	if accountID != "" {
+		account, err := selectAccount(accountID)
+		if err != nil {
+			report.add(&report.Environment, checkFromError("account", err))
+			return report
+		}
+		accounts = []config.WechatAccount{*account}
+	}
+
+	for i := range accounts {
+		report.Accounts = append(report.Accounts, diagnoseAccount(report, &accounts[i]))
+	}
+
+	return report
+}
+
+// diagnoseAccount 检查单个公众号账号
+func diagnoseAccount(report *DoctorReport, account *config.WechatAccount) DoctorAccountReport {
+	result := DoctorAccountReport{
+		AccountID: account.ID,
+		Name:      account.Name,
+		Checks:    []DoctorCheck{},
+	}
+
+	ws := wechat.NewService(account, log)
+	ws.SetRetryPolicy(wechat.RetryPolicy{MaxAttempts: 2, BaseDelay: 500 * time.Millisecond})
+	result.APIBase = ws.APIBase()
+
+	// access_token 与 IP 白名单
+	token, err := ws.GetAccessToken()
+	if err != nil {
+		tokenCheck := checkFromError("access_token", err)
+		report.add(&result.Checks, tokenCheck)
+		if wechat.Classify(err) == wechat.ClassIPWhitelist {
+			report.add(&result.Checks, DoctorCheck{Name: "ip_whitelist", Status: checkFail, Message: tokenCheck.Message, Hint: tokenCheck.Hint})
+		} else {
+			report.add(&result.Checks, DoctorCheck{Name: "ip_whitelist", Status: checkSkip, Message: "access_token 获取失败，无法判断"})
+		}
+
+		for _, name := range []string{"material_permission", "draft_permission", "quota"} {
+			report.add(&result.Checks, DoctorCheck{Name: name, Status: checkSkip, Message: "access_token 获取失败，跳过"})
+		}
+		report.add(&result.Checks, checkAccountStyle(account))
+		return result
+	}
+
+	report.add(&result.Checks, DoctorCheck{
+		Name:    "access_token",
+		Status:  checkPass,
+		Message: fmt.Sprintf("获取成功 (%s***)", token.AccessToken[:min(6, len(token.AccessToken))]),
+	})
+	report.add(&result.Checks, DoctorCheck{Name: "ip_whitelist", Status: checkPass, Message: "出口 IP 已在白名单中（或未启用白名单）"})
+
+	// 素材接口权限
+	if count, err := ws.GetMaterialCount(); err != nil {
+		report.add(&result.Checks, checkFromError("material_permission", err))
+	} else {
+		report.add(&result.Checks, DoctorCheck{
+			Name:    "material_permission",
+			Status:  checkPass,
+			Message: fmt.Sprintf("素材接口可用（图片 %d，视频 %d，语音 %d）", count.Image, count.Video, count.Voice),
+		})
+	}
+
+	// 草稿接口权限
+	if total, err := ws.CountDrafts(); err != nil {
+		report.add(&result.Checks, checkFromError("draft_permission", err))
+	} else {
+		report.add(&result.Checks, DoctorCheck{
+			Name:    "draft_permission",
+			Status:  checkPass,
+			Message: fmt.Sprintf("草稿接口可用（草稿箱 %d 篇）", total),
+		})
+	}
+
+	// 接口额度
+	for _, path := range doctorQuotaPaths {
+		report.add(&result.Checks, checkQuota(ws, path))
+	}
+
+	report.add(&result.Checks, checkAccountStyle(account))
+	return result
+}
+
+// checkQuota 检查接口今日调用额度
+func checkQuota(ws *wechat.Service, path string) DoctorCheck {
+	name := "quota:" + path
+	quota, err := ws.GetQuota(path)
+	if err != nil {
+		// 额度查询失败不影响正常使用，仅提示
+		c := checkFromError(name, err)
+		c.Status = checkWarn
+		return c
+	}
+
+	msg := fmt.Sprintf("今日已用 %d / %d，剩余 %d", quota.Used, quota.DailyLimit, quota.Remain)
+	switch {
+	case quota.DailyLimit > 0 && quota.Remain <= 0:
+		return DoctorCheck{Name: name, Status: checkFail, Message: msg,
+			Hint: "今日额度已用完，请明天再试，或在公众平台 → 设置与开发 → 接口权限 中重置（每月 10 次）"}
+	case quota.DailyLimit > 0 && float64(quota.Remain) < float64(quota.DailyLimit)*quotaWarnRatio:
+		return DoctorCheck{Name: name, Status: checkWarn, Message: msg, Hint: "剩余额度不足 10%"}
+	default:
+		return DoctorCheck{Name: name, Status: checkPass, Message: msg}
+	}
+}
+
+// checkAccountStyle 检查账号关联的写作风格是否存在
+func checkAccountStyle(account *config.WechatAccount) DoctorCheck {
+	if account.DefaultStyle == "" {
+		return DoctorCheck{Name: "default_style", Status: checkSkip, Message: "未配置 default_style"}
+	}
+
+	sm := writer.NewStyleManager()
+	if err := sm.LoadStyles(); err != nil {
+		return checkFromError("default_style", err)
+	}
+	if !sm.HasStyle(account.DefaultStyle) {
+		return DoctorCheck{
+			Name:    "default_style",
+			Status:  checkFail,
+			Message: fmt.Sprintf("风格 '%s' 不存在", account.DefaultStyle),
+			Hint:    fmt.Sprintf("在 %s 中添加该风格，或运行 'writer write --list' 查看可用风格", sm.GetWritersDir()),
+		}
+	}
+	return DoctorCheck{Name: "default_style", Status: checkPass, Message: fmt.Sprintf("风格 '%s' 可用", account.DefaultStyle)}
+}
+
+// checkConfigFile 检查配置文件
+func checkConfigFile() DoctorCheck {
+	path := cfg.GetConfigFile()
+	if path == "" {
+		return DoctorCheck{
+			Name:    "config",
+			Status:  checkWarn,
+			Message: "未使用配置文件（仅环境变量和默认值）",
+			Hint:    "运行 'writer config init' 生成配置文件",
+		}
+	}
+	return DoctorCheck{Name: "config", Status: checkPass, Message: "配置文件: " + path}
+}
+
+// checkDataDir 检查数据目录是否可写
+func checkDataDir() DoctorCheck {
+	dir := cfg.GetDataDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return DoctorCheck{Name: "data_dir", Status: checkFail, Message: fmt.Sprintf("无法创建数据目录 %s: %v", dir, err),
+			Hint: "通过 storage.data_dir 或 DATA_DIR 指定可写目录"}
+	}
+
+	probe, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return DoctorCheck{Name: "data_dir", Status: checkFail, Message: fmt.Sprintf("数据目录不可写 %s: %v", dir, err),
+			Hint: "通过 storage.data_dir 或 DATA_DIR 指定可写目录"}
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return DoctorCheck{Name: "data_dir", Status: checkPass, Message: "数据目录: " + dir}
+}
+
+// checkThemeDir 检查主题目录及默认主题
+func checkThemeDir() DoctorCheck {
+	tm := converter.NewThemeManager()
+	dir, _ := filepath.Abs(tm.GetThemeDir())
+
+	if _, err := os.Stat(dir); err != nil {
+		return DoctorCheck{Name: "theme_dir", Status: checkWarn, Message: "主题目录不存在: " + dir,
+			Hint: "在当前目录创建 themes/ 或 ~/.config/wechatwriter/themes/"}
+	}
+	if err := tm.LoadThemes(); err != nil {
+		return checkFromError("theme_dir", err)
+	}
+
+	themes := tm.ListThemes()
+	msg := fmt.Sprintf("主题目录: %s（%d 个主题）", dir, len(themes))
+	if cfg != nil && cfg.DefaultTheme != "" && !tm.IsAITheme(cfg.DefaultTheme) && !tm.IsAPITheme(cfg.DefaultTheme) {
+		return DoctorCheck{Name: "theme_dir", Status: checkWarn, Message: msg,
+			Hint: fmt.Sprintf("默认主题 '%s' 不在主题目录中，可用主题: %s", cfg.DefaultTheme, strings.Join(themes, ", "))}
+	}
+	return DoctorCheck{Name: "theme_dir", Status: checkPass, Message: msg}
+}
+
+// checkStyleDir 检查写作风格目录
+func checkStyleDir() DoctorCheck {
+	sm := writer.NewStyleManager()
+	if err := sm.LoadStyles(); err != nil {
+		return checkFromError("style_dir", err)
+	}
+
+	dir, _ := filepath.Abs(sm.GetWritersDir())
+	if _, err := os.Stat(dir); err != nil {
+		return DoctorCheck{Name: "style_dir", Status: checkWarn, Message: "风格目录不存在: " + dir,
+			Hint: "在当前目录创建 writers/ 或 ~/.config/wechatwriter/writers/"}
+	}
+	return DoctorCheck{Name: "style_dir", Status: checkPass, Message: fmt.Sprintf("风格目录: %s（%d 个风格）", dir, sm.GetStyleCount())}
+}
+
+// checkImageProvider 检查图片服务配置与连通性
+func checkImageProvider() DoctorCheck {
+	if cfg.ValidateForImageGeneration() != nil {
+		return DoctorCheck{Name: "image_provider", Status: checkWarn, Message: "未配置图片服务 API Key，AI 生成图片不可用",
+			Hint: "设置 IMAGE_API_KEY 或配置文件中的 api.image_key"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	name, err := image.CheckProvider(ctx, cfg)
+	if err != nil {
+		return checkFromError("image_provider", err)
+	}
+	return DoctorCheck{Name: "image_provider", Status: checkPass, Message: fmt.Sprintf("%s 可用（%s）", name, cfg.ImageAPIBase)}
+}
+
+// checkFromError 将错误转换为失败的检查项，提取错误中的处理建议
+func checkFromError(name string, err error) DoctorCheck {
+	c := DoctorCheck{Name: name, Status: checkFail, Message: err.Error()}
+
+	var apiErr *wechat.APIError
+	var cfgErr *config.ConfigError
+	var genErr *image.GenerateError
+	switch {
+	case errors.As(err, &apiErr):
+		c.Message = fmt.Sprintf("errcode=%d, errmsg=%s", apiErr.Code, apiErr.Msg)
+		c.Hint = apiErr.Hint
+	case errors.As(err, &cfgErr):
+		c.Message = cfgErr.Message
+		c.Hint = cfgErr.Hint
+	case errors.As(err, &genErr):
+		c.Message = genErr.Message
+		c.Hint = genErr.Hint
+	}
+	return c
+}
+
+// printDoctorText 以文本格式输出诊断报告
+func printDoctorText(report *DoctorReport) {
+	fmt.Println("环境")
+	for _, c := range report.Environment {
+		printDoctorCheck(c)
+	}
+
+	for _, acc := range report.Accounts {
+		fmt.Printf("\n账号 %s (%s) → %s\n", acc.AccountID, acc.Name, acc.APIBase)
+		for _, c := range acc.Checks {
+			printDoctorCheck(c)
+		}
+	}
+
+	fmt.Printf("\n通过 %d，警告 %d，失败 %d，跳过 %d\n",
+		report.Summary[checkPass], report.Summary[checkWarn], report.Summary[checkFail], report.Summary[checkSkip])
+}
+
+// printDoctorCheck 输出单项检查结果
+func printDoctorCheck(c DoctorCheck) {
+	icon := map[string]string{checkPass: "✅", checkWarn: "⚠️ ", checkFail: "❌", checkSkip: "⏭️ "}[c.Status]
+	fmt.Printf("  %s %-36s %s\n", icon, c.Name, c.Message)
+	if c.Hint != "" {
+		fmt.Printf("     💡 %s\n", c.Hint)
+	}
+}
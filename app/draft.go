@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/royalrick/wechatwriter/app/draft"
 	"github.com/spf13/cobra"
@@ -19,12 +20,14 @@ func draftCmd() *cobra.Command {
 支持的操作：
   create   - 从 JSON 文件创建草稿
   test     - 测试草稿 HTML
-  publish  - 创建并发布草稿`,
+  publish  - 创建并发布草稿
+  preview  - 发送草稿预览到测试用户手机`,
 	}
 
 	cmd.AddCommand(draftCreateCmd())
 	cmd.AddCommand(draftTestCmd())
 	cmd.AddCommand(draftPublishCmd())
+	cmd.AddCommand(draftPreviewCmd())
 
 	return cmd
 }
@@ -65,6 +68,7 @@ func draftCreateCmd() *cobra.Command {
 				responseError(fmt.Errorf("no articles in request"))
 				return
 			}
+			draft.SetBaseDir(req.Articles, filepath.Dir(jsonFile))
 
 			// 使用指定账号创建草稿
 			var result *draft.DraftResult
@@ -109,13 +113,6 @@ func draftTestCmd() *cobra.Command {
 				return
 			}
 
-			// 上传封面图片
-			coverMediaID, err := uploadCoverImage(coverImage)
-			if err != nil {
-				responseError(fmt.Errorf("upload cover: %w", err))
-				return
-			}
-
 			// 创建草稿
 			svc := draft.NewService(cfg, log)
 			articles := []draft.Article{
@@ -123,8 +120,9 @@ func draftTestCmd() *cobra.Command {
 					Title:        "AI生成测试文章",
 					Content:      string(html),
 					Digest:       "这是AI生成的微信公众号文章测试",
-					ThumbMediaID: coverMediaID,
+					Cover:        coverImage,
 					ShowCoverPic: 1,
+					BaseDir:      filepath.Dir(htmlFile),
 				},
 			}
 
@@ -191,6 +189,49 @@ func draftPublishCmd() *cobra.Command {
 	return cmd
 }
 
+// draftPreviewCmd 发送草稿预览
+func draftPreviewCmd() *cobra.Command {
+	var (
+		accountID  string
+		recipients []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "preview <media_id>",
+		Short: "发送草稿预览到测试用户手机",
+		Long: `通过群发预览接口将草稿发送到指定用户的微信，在真实客户端中审阅效果，
+无需登录公众号后台。
+
+接收人可以是微信号或 OpenID，也可以使用 "wxname:" / "openid:" 前缀显式指定。
+未指定 --to 时使用配置文件中账号的 preview_users 列表：
+
+  wechat:
+    accounts:
+      - id: my-account
+        preview_users: ["editor_wx", "openid:oAbC..."]
+
+注意：预览接口每日调用次数有限（100 次），接收人需已关注公众号。`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			svc := draft.NewService(cfg, log)
+			result, err := svc.PreviewDraft(args[0], accountID, recipients)
+			if err != nil {
+				responseError(err)
+				return
+			}
+			responseSuccess(result)
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().StringSliceVar(&recipients, "to", nil, "预览接收人（微信号或 OpenID，可重复或逗号分隔）")
+
+	return cmd
+}
+
 func runPublish(title, content, author, digest, coverID, outputDir string) error {
 	// 创建草稿JSON
 	draftData := map[string]interface{}{
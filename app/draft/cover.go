@@ -0,0 +1,85 @@
+package draft
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"strconv"
+)
+
+// 微信封面的两种展示比例
+const (
+	coverRatioWide   = 2.35 // 消息列表大图
+	coverRatioSquare = 1.0  // 转发卡片、小图
+)
+
+// CoverCrops 根据封面尺寸计算居中裁剪坐标
+// 返回 pic_crop_235_1 和 pic_crop_1_1，格式为 X1_Y1_X2_Y2（相对坐标，取值 0~1）
+func CoverCrops(width, height int) (crop2351, crop11 string) {
+	if width <= 0 || height <= 0 {
+		return "", ""
+	}
+	return centerCrop(width, height, coverRatioWide), centerCrop(width, height, coverRatioSquare)
+}
+
+// CropCoords 把图片中的矩形区域转换为裁剪坐标 X1_Y1_X2_Y2（相对坐标，取值 0~1）
+func CropCoords(r image.Rectangle, width, height int) string {
+	if width <= 0 || height <= 0 {
+		return ""
+	}
+	w, h := float64(width), float64(height)
+	return formatCoord(float64(r.Min.X)/w) + "_" + formatCoord(float64(r.Min.Y)/h) + "_" +
+		formatCoord(float64(r.Max.X)/w) + "_" + formatCoord(float64(r.Max.Y)/h)
+}
+
+// CoverCropsFromFile 读取封面图片尺寸并计算裁剪坐标
+func CoverCropsFromFile(path string) (crop2351, crop11 string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", fmt.Errorf("open cover: %w", err)
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return "", "", fmt.Errorf("decode cover: %w", err)
+	}
+
+	crop2351, crop11 = CoverCrops(cfg.Width, cfg.Height)
+	return crop2351, crop11, nil
+}
+
+// centerCrop 计算指定宽高比的最大居中裁剪区域
+func centerCrop(width, height int, ratio float64) string {
+	w, h := float64(width), float64(height)
+	x1, y1, x2, y2 := 0.0, 0.0, 1.0, 1.0
+
+	if w/h > ratio {
+		// 图片更宽：裁掉左右
+		cropW := h * ratio / w
+		x1 = (1 - cropW) / 2
+		x2 = x1 + cropW
+	} else {
+		// 图片更高：裁掉上下
+		cropH := w / ratio / h
+		y1 = (1 - cropH) / 2
+		y2 = y1 + cropH
+	}
+
+	return formatCoord(x1) + "_" + formatCoord(y1) + "_" + formatCoord(x2) + "_" + formatCoord(y2)
+}
+
+// formatCoord 格式化坐标，保留 6 位小数并去掉多余的 0
+func formatCoord(v float64) string {
+	if v < 0 {
+		v = 0
+	}
+	if v > 1 {
+		v = 1
+	}
+	return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+}
@@ -0,0 +1,39 @@
+package draft
+
+import (
+	"image"
+	"testing"
+)
+
+func TestCoverCrops(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		want2351      string
+		want11        string
+	}{
+		{"exact 2.35:1", 940, 400, "0_0_1_1", "0.287234_0_0.712766_1"},
+		{"wide", 2000, 500, "0.20625_0_0.79375_1", "0.375_0_0.625_1"},
+		{"square", 600, 600, "0_0.287234_1_0.712766", "0_0_1_1"},
+		{"tall", 500, 1000, "0_0.393617_1_0.606383", "0_0.25_1_0.75"},
+		{"invalid", 0, 100, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got2351, got11 := CoverCrops(tt.width, tt.height)
+			if got2351 != tt.want2351 || got11 != tt.want11 {
+				t.Errorf("CoverCrops(%d, %d) = %q, %q; want %q, %q",
+					tt.width, tt.height, got2351, got11, tt.want2351, tt.want11)
+			}
+		})
+	}
+}
+
+func TestCropCoords(t *testing.T) {
+	if got := CropCoords(image.Rect(0, 0, 600, 600), 1410, 600); got != "0_0_0.425532_1" {
+		t.Errorf("CropCoords(left square) = %q", got)
+	}
+	if got := CropCoords(image.Rect(405, 0, 1005, 600), 1410, 600); got != "0.287234_0_0.712766_1" {
+		t.Errorf("CropCoords(center square) = %q", got)
+	}
+}
@@ -0,0 +1,125 @@
+package draft
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// manifestFile 草稿清单文件名（位于账号数据目录）
+const manifestFile = "drafts.json"
+
+// 草稿同步结果
+const (
+	ActionCreated   = "created"   // 新建草稿
+	ActionUpdated   = "updated"   // 更新已有草稿
+	ActionUnchanged = "unchanged" // 源文件未变化，未调用接口
+)
+
+// ManifestEntry 源文件与草稿的对应关系
+type ManifestEntry struct {
+	Source    string    `json:"source"` // 源文件绝对路径
+	Hash      string    `json:"hash"`   // 源内容哈希（见 SourceHash）
+	MediaID   string    `json:"media_id"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Manifest 账号的草稿清单
+// 记录 Markdown 源文件 → 草稿 media_id，重复转换同一文件时更新原草稿而不是新建
+type Manifest struct {
+	path    string
+	entries map[string]*ManifestEntry
+}
+
+// OpenManifest 读取账号数据目录中的草稿清单，不存在时返回空清单
+func OpenManifest(dataDir string) (*Manifest, error) {
+	m := &Manifest{
+		path:    filepath.Join(dataDir, manifestFile),
+		entries: make(map[string]*ManifestEntry),
+	}
+
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, fmt.Errorf("read draft manifest: %w", err)
+	}
+
+	var list []*ManifestEntry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse draft manifest: %w", err)
+	}
+	for _, e := range list {
+		m.entries[e.Source] = e
+	}
+	return m, nil
+}
+
+// Get 查找源文件对应的草稿
+func (m *Manifest) Get(source string) (*ManifestEntry, bool) {
+	e, ok := m.entries[source]
+	return e, ok
+}
+
+// Put 记录源文件对应的草稿
+func (m *Manifest) Put(e *ManifestEntry) {
+	m.entries[e.Source] = e
+}
+
+// Save 写入草稿清单
+func (m *Manifest) Save() error {
+	list := make([]*ManifestEntry, 0, len(m.entries))
+	for _, e := range m.entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Source < list[j].Source })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal draft manifest: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("create data directory: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免中断时损坏清单
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write draft manifest: %w", err)
+	}
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("write draft manifest: %w", err)
+	}
+	return nil
+}
+
+// SourceKey 清单中源文件的键（绝对路径）
+func SourceKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// SourceHash 计算草稿输入的哈希（Markdown、HTML、封面等），任一部分变化时哈希变化
+func SourceHash(parts ...[]byte) string {
+	h := sha256.New()
+	var size [8]byte
+	for _, p := range parts {
+		// 写入长度前缀，避免不同切分得到相同哈希
+		binary.BigEndian.PutUint64(size[:], uint64(len(p)))
+		h.Write(size[:])
+		h.Write(p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
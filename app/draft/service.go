@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/royalrick/wechatwriter/app/config"
+	"github.com/royalrick/wechatwriter/app/image"
 	"github.com/royalrick/wechatwriter/app/wechat"
-	"github.com/silenceper/wechat/v2/officialaccount/draft"
 	"go.uber.org/zap"
 )
 
@@ -36,13 +38,24 @@ type DraftRequest struct {
 
 // Article 文章
 type Article struct {
-	Title            string `json:"title"`
-	Author           string `json:"author,omitempty"`
-	Digest           string `json:"digest,omitempty"`
-	Content          string `json:"content"`
-	ContentSourceURL string `json:"content_source_url,omitempty"`
-	ThumbMediaID     string `json:"thumb_media_id,omitempty"`
-	ShowCoverPic     int    `json:"show_cover_pic,omitempty"`
+	Title              string `json:"title"`
+	Author             string `json:"author,omitempty"`
+	Digest             string `json:"digest,omitempty"`
+	Content            string `json:"content"`
+	ContentSourceURL   string `json:"content_source_url,omitempty"`
+	ThumbMediaID       string `json:"thumb_media_id,omitempty"`
+	ShowCoverPic       int    `json:"show_cover_pic,omitempty"`
+	NeedOpenComment    int    `json:"need_open_comment,omitempty"`     // 是否打开评论，0 不打开，1 打开
+	OnlyFansCanComment int    `json:"only_fans_can_comment,omitempty"` // 是否仅粉丝可评论，0 所有人，1 仅粉丝
+	PicCrop2351        string `json:"pic_crop_235_1,omitempty"`        // 2.35:1 封面裁剪坐标，为空时按封面尺寸居中裁剪
+	PicCrop11          string `json:"pic_crop_1_1,omitempty"`          // 1:1 封面裁剪坐标，为空时按封面尺寸居中裁剪
+
+	// Cover 本地封面图片路径（可选）
+	// 未指定 thumb_media_id 时自动上传为封面，并根据图片尺寸计算裁剪坐标
+	Cover string `json:"cover,omitempty"`
+
+	// BaseDir 解析正文中相对图片路径的目录（HTML、JSON 或 Markdown 文件所在目录）
+	BaseDir string `json:"-"`
 }
 
 // DraftResult 草稿结果
@@ -51,6 +64,15 @@ type DraftResult struct {
 	DraftURL string `json:"draft_url,omitempty"`
 }
 
+// SetBaseDir 设置正文相对图片路径的解析目录（已设置的不覆盖）
+func SetBaseDir(articles []Article, dir string) {
+	for i := range articles {
+		if articles[i].BaseDir == "" {
+			articles[i].BaseDir = dir
+		}
+	}
+}
+
 // CreateDraftFromFile 从 JSON 文件创建草稿
 func (s *Service) CreateDraftFromFile(jsonFile string) (*DraftResult, error) {
 	s.log.Info("creating draft from file", zap.String("file", jsonFile))
@@ -71,6 +93,7 @@ func (s *Service) CreateDraftFromFile(jsonFile string) (*DraftResult, error) {
 	if len(req.Articles) == 0 {
 		return nil, fmt.Errorf("no articles in request")
 	}
+	SetBaseDir(req.Articles, filepath.Dir(jsonFile))
 
 	// 使用 CreateDraft 方法（会自动选择账号）
 	return s.CreateDraft(req.Articles)
@@ -96,26 +119,10 @@ func (s *Service) CreateDraftWithAccount(articles []Article, accountID string) (
 	// 创建 WeChat Service
 	ws := wechat.NewService(account, s.log)
 
-	// 转换为 SDK 格式
-	var draftArticles []*draft.Article
-	for _, a := range articles {
-		article := &draft.Article{
-			Title:   a.Title,
-			Content: a.Content,
-			Digest:  a.Digest,
-			Author:  a.Author,
-		}
-
-		if a.ThumbMediaID != "" {
-			article.ThumbMediaID = a.ThumbMediaID
-			article.ShowCoverPic = uint(a.ShowCoverPic)
-		}
-
-		if a.ContentSourceURL != "" {
-			article.ContentSourceURL = a.ContentSourceURL
-		}
-
-		draftArticles = append(draftArticles, article)
+	// 转换为接口格式（按需上传本地封面）
+	draftArticles, err := s.toWechatArticles(ws, account, articles)
+	if err != nil {
+		return nil, err
 	}
 
 	// 调用微信 API
@@ -130,6 +137,104 @@ func (s *Service) CreateDraftWithAccount(articles []Article, accountID string) (
 	}, nil
 }
 
+// SyncResult 按源文件同步草稿的结果
+type SyncResult struct {
+	AccountID string `json:"account_id"`
+	Action    string `json:"action"` // created, updated, unchanged
+	MediaID   string `json:"media_id"`
+	DraftURL  string `json:"draft_url,omitempty"`
+}
+
+// UnchangedDraft 源文件内容未变化时返回清单中的草稿，否则返回 nil
+// 用于在上传图片前判断是否可以跳过；清单中哈希为空（上次上传不完整）时总是返回 nil
+func (s *Service) UnchangedDraft(source, hash, accountID string) (*SyncResult, error) {
+	account, err := s.selector.SelectAccount("", accountID)
+	if err != nil {
+		return nil, fmt.Errorf("select account: %w", err)
+	}
+	manifest, err := OpenManifest(s.cfg.AccountDataDir(account.ID))
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := manifest.Get(SourceKey(source))
+	if !ok || entry.Hash == "" || entry.Hash != hash {
+		return nil, nil
+	}
+	return &SyncResult{AccountID: account.ID, Action: ActionUnchanged, MediaID: entry.MediaID}, nil
+}
+
+// SyncDraft 按源文件创建或更新草稿
+// 清单中已有该源文件的草稿时调用 draft/update 更新（草稿已删除或已发布时改为新建），
+// 内容未变化时不调用接口；forceNew 时总是新建并替换清单记录。
+// hash 为空表示本次图片或素材上传不完整，清单中记录空哈希，下次运行时不会被当作未变化
+func (s *Service) SyncDraft(source, hash string, article Article, accountID string, forceNew bool) (*SyncResult, error) {
+	account, err := s.selector.SelectAccount("", accountID)
+	if err != nil {
+		return nil, fmt.Errorf("select account: %w", err)
+	}
+	manifest, err := OpenManifest(s.cfg.AccountDataDir(account.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	key := SourceKey(source)
+	entry, exists := manifest.Get(key)
+	if exists && !forceNew && hash != "" && entry.Hash == hash {
+		return &SyncResult{AccountID: account.ID, Action: ActionUnchanged, MediaID: entry.MediaID}, nil
+	}
+
+	ws := wechat.NewService(account, s.log)
+	draftArticles, err := s.toWechatArticles(ws, account, []Article{article})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{AccountID: account.ID}
+	if exists && !forceNew {
+		err := ws.UpdateDraft(entry.MediaID, 0, draftArticles[0])
+		switch {
+		case err == nil:
+			result.Action = ActionUpdated
+			result.MediaID = entry.MediaID
+		case wechat.IsErrCode(err, wechat.ErrCodeInvalidMediaID):
+			// 草稿已在后台删除或已发布，改为新建
+			s.log.Warn("draft in manifest no longer exists, creating a new one",
+				zap.String("source", key),
+				zap.String("media_id", entry.MediaID))
+		default:
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	if result.Action == "" {
+		created, err := ws.CreateDraft(draftArticles)
+		if err != nil {
+			return nil, err
+		}
+		result.Action = ActionCreated
+		result.MediaID = created.MediaID
+		result.DraftURL = created.DraftURL
+		entry = &ManifestEntry{Source: key, CreatedAt: now}
+	}
+
+	entry.Hash = hash
+	entry.MediaID = result.MediaID
+	entry.Title = article.Title
+	entry.UpdatedAt = now
+	manifest.Put(entry)
+	if err := manifest.Save(); err != nil {
+		// 草稿已创建，清单写入失败只影响下次去重
+		s.log.Warn("save draft manifest failed", zap.Error(err))
+	}
+
+	s.log.Info("draft synced",
+		zap.String("account_id", account.ID),
+		zap.String("source", key),
+		zap.String("action", result.Action))
+	return result, nil
+}
+
 // CreateDraftForPrompt 根据提示词选择账号并创建草稿
 func (s *Service) CreateDraftForPrompt(articles []Article, prompt string) (*DraftResult, error) {
 	// 选择账号
@@ -146,38 +251,186 @@ func (s *Service) CreateDraftForPrompt(articles []Article, prompt string) (*Draf
 	// 创建 WeChat Service
 	ws := wechat.NewService(account, s.log)
 
-	// 转换为 SDK 格式
-	var draftArticles []*draft.Article
-	for _, a := range articles {
-		article := &draft.Article{
-			Title:   a.Title,
-			Content: a.Content,
-			Digest:  a.Digest,
-			Author:  a.Author,
+	// 转换为接口格式（按需上传本地封面）
+	draftArticles, err := s.toWechatArticles(ws, account, articles)
+	if err != nil {
+		return nil, err
+	}
+
+	// 调用微信 API
+	result, err := ws.CreateDraft(draftArticles)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DraftResult{
+		MediaID:  result.MediaID,
+		DraftURL: result.DraftURL,
+	}, nil
+}
+
+// toWechatArticles 转换为微信草稿接口格式
+// 指定了本地封面 (Cover) 且没有 thumb_media_id 时先上传封面（摆正方向、去除元数据、按封面目标大小压缩）；
+// 未指定裁剪坐标时按上传后的封面尺寸计算
+// 正文中的外部图片先转存到微信（uploadimg），单张失败时保留原地址
+func (s *Service) toWechatArticles(ws *wechat.Service, account *config.WechatAccount, articles []Article) ([]*wechat.Article, error) {
+	result := make([]*wechat.Article, 0, len(articles))
+	for i, a := range articles {
+		a.Content = s.rehostContent(account, a)
+
+		if a.Cover != "" {
+			var coverWidth, coverHeight int
+			if a.ThumbMediaID == "" {
+				upload, err := s.uploadCover(account, a.Cover)
+				if err != nil {
+					return nil, fmt.Errorf("upload cover for article %d: %w", i+1, err)
+				}
+				a.ThumbMediaID = upload.MediaID
+				coverWidth, coverHeight = upload.Width, upload.Height
+			}
+			if a.PicCrop2351 == "" || a.PicCrop11 == "" {
+				var crop2351, crop11 string
+				var err error
+				if coverWidth > 0 {
+					crop2351, crop11 = CoverCrops(coverWidth, coverHeight)
+				} else {
+					crop2351, crop11, err = CoverCropsFromFile(a.Cover)
+				}
+				if err != nil {
+					s.log.Warn("compute cover crops failed, using WeChat defaults",
+						zap.String("cover", a.Cover),
+						zap.Error(err))
+				} else {
+					if a.PicCrop2351 == "" {
+						a.PicCrop2351 = crop2351
+					}
+					if a.PicCrop11 == "" {
+						a.PicCrop11 = crop11
+					}
+				}
+			}
+		}
+
+		article := &wechat.Article{
+			Title:              a.Title,
+			Author:             a.Author,
+			Digest:             a.Digest,
+			Content:            a.Content,
+			ContentSourceURL:   a.ContentSourceURL,
+			NeedOpenComment:    uint(a.NeedOpenComment),
+			OnlyFansCanComment: uint(a.OnlyFansCanComment),
 		}
 
 		if a.ThumbMediaID != "" {
 			article.ThumbMediaID = a.ThumbMediaID
 			article.ShowCoverPic = uint(a.ShowCoverPic)
+			article.PicCrop2351 = a.PicCrop2351
+			article.PicCrop11 = a.PicCrop11
 		}
 
-		if a.ContentSourceURL != "" {
-			article.ContentSourceURL = a.ContentSourceURL
-		}
-
-		draftArticles = append(draftArticles, article)
+		result = append(result, article)
 	}
+	return result, nil
+}
 
-	// 调用微信 API
-	result, err := ws.CreateDraft(draftArticles)
+// uploadCover 通过图片处理器上传封面
+func (s *Service) uploadCover(account *config.WechatAccount, cover string) (*image.UploadResult, error) {
+	processor, err := image.NewProcessorForAccount(s.cfg, s.log, account.ID)
 	if err != nil {
 		return nil, err
 	}
+	return processor.UploadCover(cover)
+}
 
-	return &DraftResult{
-		MediaID:  result.MediaID,
-		DraftURL: result.DraftURL,
-	}, nil
+// rehostContent 将正文中的外部图片转存到微信并改写地址
+func (s *Service) rehostContent(account *config.WechatAccount, a Article) string {
+	processor, err := image.NewProcessorForAccount(s.cfg, s.log, account.ID)
+	if err != nil {
+		s.log.Warn("rehost images skipped", zap.Error(err))
+		return a.Content
+	}
+
+	content, report := processor.RehostHTML(a.Content, a.BaseDir)
+	if report.Total > 0 {
+		s.log.Info("article images rehosted",
+			zap.String("title", a.Title),
+			zap.Int("rehosted", report.Rehosted),
+			zap.Int("skipped", report.Skipped),
+			zap.Int("failed", report.Failed))
+	}
+	return content
+}
+
+// PreviewResult 预览发送结果
+type PreviewResult struct {
+	AccountID string           `json:"account_id"`
+	MediaID   string           `json:"media_id"`
+	Sent      []PreviewOutcome `json:"sent"`
+	Failed    int              `json:"failed"`
+}
+
+// PreviewOutcome 单个接收人的预览发送结果
+type PreviewOutcome struct {
+	To    string `json:"to"`
+	MsgID int64  `json:"msg_id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// PreviewDraft 将草稿预览发送到测试用户手机
+// recipients 为空时使用账号配置中的 preview_users
+func (s *Service) PreviewDraft(mediaID, accountID string, recipients []string) (*PreviewResult, error) {
+	account, err := s.selector.SelectAccount("", accountID)
+	if err != nil {
+		return nil, fmt.Errorf("select account: %w", err)
+	}
+
+	if len(recipients) == 0 {
+		recipients = account.PreviewUsers
+	}
+	if len(recipients) == 0 {
+		return nil, &config.ConfigError{
+			Field:   "PreviewUsers",
+			Message: fmt.Sprintf("账号 '%s' 未配置预览接收人", account.ID),
+			Hint:    "使用 --to 指定微信号或 OpenID，或在配置文件中设置 wechat.accounts[].preview_users",
+		}
+	}
+
+	// 先解析全部接收人，避免发送到一半才发现格式错误
+	targets := make([]wechat.PreviewRecipient, 0, len(recipients))
+	for _, r := range recipients {
+		target, err := wechat.ParsePreviewRecipient(r)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+
+	s.log.Info("sending draft preview",
+		zap.String("account_id", account.ID),
+		zap.Int("recipients", len(targets)))
+
+	ws := wechat.NewService(account, s.log)
+	result := &PreviewResult{
+		AccountID: account.ID,
+		MediaID:   mediaID,
+	}
+	for _, target := range targets {
+		outcome := PreviewOutcome{To: target.String()}
+		msgID, err := ws.SendPreview(mediaID, target)
+		if err != nil {
+			outcome.Error = err.Error()
+			result.Failed++
+		} else {
+			outcome.MsgID = msgID
+		}
+		result.Sent = append(result.Sent, outcome)
+	}
+
+	if result.Failed == len(targets) {
+		return result, fmt.Errorf("preview failed for all %d recipients: %s", len(targets), result.Sent[0].Error)
+	}
+
+	return result, nil
 }
 
 // GenerateDigestFromContent 从内容生成摘要
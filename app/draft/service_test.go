@@ -0,0 +1,254 @@
+package draft
+
+import (
+	"image"
+	"image/png"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/royalrick/wechatwriter/app/config"
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"github.com/royalrick/wechatwriter/app/wechat/wechattest"
+	"go.uber.org/zap"
+)
+
+// newMockDraftService 创建指向模拟服务的草稿服务，并上传一张封面图
+func newMockDraftService(t *testing.T) (*Service, *wechattest.Server, string) {
+	t.Helper()
+	srv := wechattest.NewServer()
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	cfg := &config.Config{
+		WechatAccounts: []config.WechatAccount{
+			{ID: "mock", Name: "Mock", AppID: "mock_appid", Secret: "mock_secret", APIBase: ts.URL},
+			{ID: "other", Name: "Other", AppID: "other_appid", Secret: "other_secret", APIBase: ts.URL},
+		},
+		DefaultAccount: "mock",
+		DataDir:        t.TempDir(),
+	}
+
+	cover := filepath.Join(t.TempDir(), "cover.jpg")
+	if err := os.WriteFile(cover, []byte("\xff\xd8\xff\xe0fake jpeg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	upload, err := wechat.NewService(&cfg.WechatAccounts[0], zap.NewNop()).UploadMaterial(cover)
+	if err != nil {
+		t.Fatalf("upload cover: %v", err)
+	}
+
+	return NewService(cfg, zap.NewNop()), srv, upload.MediaID
+}
+
+func TestCreateDraftWithAccount(t *testing.T) {
+	s, srv, thumb := newMockDraftService(t)
+
+	result, err := s.CreateDraftWithAccount([]Article{{
+		Title:            "标题",
+		Author:           "作者",
+		Digest:           "摘要",
+		Content:          "<p>正文</p>",
+		ContentSourceURL: "https://example.com",
+		ThumbMediaID:     thumb,
+		ShowCoverPic:     1,
+	}}, "other")
+	if err != nil {
+		t.Fatalf("CreateDraftWithAccount() error = %v", err)
+	}
+
+	d, ok := srv.Draft(result.MediaID)
+	if !ok {
+		t.Fatalf("draft %s not found on mock server", result.MediaID)
+	}
+	got := d.Articles[0]
+	if got.Title != "标题" || got.Author != "作者" || got.Digest != "摘要" ||
+		got.ContentSourceURL != "https://example.com" || got.ThumbMediaID != thumb || got.ShowCoverPic != 1 {
+		t.Errorf("draft article = %+v", got)
+	}
+}
+
+func TestCreateDraftUnknownAccount(t *testing.T) {
+	s, _, thumb := newMockDraftService(t)
+
+	_, err := s.CreateDraftWithAccount([]Article{{Title: "标题", Content: "x", ThumbMediaID: thumb}}, "missing")
+	if err == nil {
+		t.Fatal("CreateDraftWithAccount() with unknown account should fail")
+	}
+}
+
+func TestPreviewDraft(t *testing.T) {
+	s, srv, thumb := newMockDraftService(t)
+
+	result, err := s.CreateDraft([]Article{{Title: "标题", Content: "x", ThumbMediaID: thumb}})
+	if err != nil {
+		t.Fatalf("CreateDraft() error = %v", err)
+	}
+
+	preview, err := s.PreviewDraft(result.MediaID, "", []string{"wxname:alice", "oABCDEFGHIJKLMNOPQRSTUVWXYZa"})
+	if err != nil {
+		t.Fatalf("PreviewDraft() error = %v", err)
+	}
+	if len(preview.Sent) != 2 || preview.Failed != 0 {
+		t.Errorf("preview result = %+v", preview)
+	}
+	if n := len(srv.Previews()); n != 2 {
+		t.Errorf("previews on mock server = %d, want 2", n)
+	}
+
+	// 未指定接收人且账号未配置 preview_users
+	if _, err := s.PreviewDraft(result.MediaID, "", nil); err == nil {
+		t.Error("PreviewDraft() without recipients should fail")
+	}
+}
+
+func TestCreateDraftAllFields(t *testing.T) {
+	s, srv, thumb := newMockDraftService(t)
+
+	result, err := s.CreateDraft([]Article{{
+		Title:              "标题",
+		Content:            "<p>正文</p>",
+		ThumbMediaID:       thumb,
+		ShowCoverPic:       1,
+		NeedOpenComment:    1,
+		OnlyFansCanComment: 1,
+		PicCrop2351:        "0.1_0_0.9_1",
+		PicCrop11:          "0.2_0_0.8_1",
+	}})
+	if err != nil {
+		t.Fatalf("CreateDraft() error = %v", err)
+	}
+
+	d, _ := srv.Draft(result.MediaID)
+	got := d.Articles[0]
+	if got.NeedOpenComment != 1 || got.OnlyFansCanComment != 1 {
+		t.Errorf("comment flags = %d, %d, want 1, 1", got.NeedOpenComment, got.OnlyFansCanComment)
+	}
+	if got.PicCrop2351 != "0.1_0_0.9_1" || got.PicCrop11 != "0.2_0_0.8_1" {
+		t.Errorf("crops = %q, %q", got.PicCrop2351, got.PicCrop11)
+	}
+}
+
+func TestCreateDraftUploadsCoverAndComputesCrops(t *testing.T) {
+	s, srv, _ := newMockDraftService(t)
+
+	cover := filepath.Join(t.TempDir(), "cover.png")
+	f, err := os.Create(cover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, 600, 600))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	result, err := s.CreateDraft([]Article{{Title: "标题", Content: "x", Cover: cover, ShowCoverPic: 1}})
+	if err != nil {
+		t.Fatalf("CreateDraft() error = %v", err)
+	}
+
+	d, _ := srv.Draft(result.MediaID)
+	got := d.Articles[0]
+	if got.ThumbMediaID == "" {
+		t.Fatal("cover was not uploaded as thumb_media_id")
+	}
+	if got.PicCrop2351 != "0_0.287234_1_0.712766" || got.PicCrop11 != "0_0_1_1" {
+		t.Errorf("crops = %q, %q", got.PicCrop2351, got.PicCrop11)
+	}
+}
+
+func TestSyncDraftUpdatesInPlace(t *testing.T) {
+	s, srv, thumb := newMockDraftService(t)
+	source := filepath.Join(t.TempDir(), "article.md")
+	article := Article{Title: "标题", Content: "<p>v1</p>", ThumbMediaID: thumb}
+
+	first, err := s.SyncDraft(source, SourceHash([]byte("v1")), article, "", false)
+	if err != nil {
+		t.Fatalf("SyncDraft() error = %v", err)
+	}
+	if first.Action != ActionCreated {
+		t.Fatalf("first Action = %s, want created", first.Action)
+	}
+
+	// 内容未变化：不调用接口
+	if got, err := s.UnchangedDraft(source, SourceHash([]byte("v1")), ""); err != nil || got == nil || got.MediaID != first.MediaID {
+		t.Fatalf("UnchangedDraft() = %+v, %v, want the existing draft", got, err)
+	}
+	if got, _ := s.SyncDraft(source, SourceHash([]byte("v1")), article, "", false); got.Action != ActionUnchanged {
+		t.Errorf("unchanged Action = %s", got.Action)
+	}
+
+	// 内容变化：更新原草稿
+	article.Content = "<p>v2</p>"
+	second, err := s.SyncDraft(source, SourceHash([]byte("v2")), article, "", false)
+	if err != nil {
+		t.Fatalf("SyncDraft() error = %v", err)
+	}
+	if second.Action != ActionUpdated || second.MediaID != first.MediaID {
+		t.Fatalf("second = %+v, want updated %s", second, first.MediaID)
+	}
+	if d, _ := srv.Draft(first.MediaID); d.Articles[0].Content != "<p>v2</p>" {
+		t.Errorf("draft content = %q, want v2", d.Articles[0].Content)
+	}
+	if n := len(srv.Drafts()); n != 1 {
+		t.Errorf("drafts on mock server = %d, want 1", n)
+	}
+
+	// --force-new：新建并替换清单记录
+	forced, err := s.SyncDraft(source, SourceHash([]byte("v2")), article, "", true)
+	if err != nil {
+		t.Fatalf("SyncDraft(forceNew) error = %v", err)
+	}
+	if forced.Action != ActionCreated || forced.MediaID == first.MediaID {
+		t.Errorf("forced = %+v, want a new draft", forced)
+	}
+	if got, _ := s.UnchangedDraft(source, SourceHash([]byte("v2")), ""); got == nil || got.MediaID != forced.MediaID {
+		t.Errorf("manifest after force-new = %+v, want %s", got, forced.MediaID)
+	}
+}
+
+func TestSyncDraftIncompleteUploadNotUnchanged(t *testing.T) {
+	s, srv, thumb := newMockDraftService(t)
+	source := filepath.Join(t.TempDir(), "article.md")
+	article := Article{Title: "标题", Content: "<p>图片上传失败</p>", ThumbMediaID: thumb}
+
+	// 上传不完整时以空哈希同步：下次运行不会被当作未变化，而是更新同一草稿
+	first, err := s.SyncDraft(source, "", article, "", false)
+	if err != nil || first.Action != ActionCreated {
+		t.Fatalf("SyncDraft() = %+v, %v, want created", first, err)
+	}
+	if got, err := s.UnchangedDraft(source, "", ""); err != nil || got != nil {
+		t.Fatalf("UnchangedDraft() after incomplete upload = %+v, %v, want nil", got, err)
+	}
+	article.Content = "<p>图片已上传</p>"
+	second, err := s.SyncDraft(source, SourceHash([]byte("v1")), article, "", false)
+	if err != nil || second.Action != ActionUpdated || second.MediaID != first.MediaID {
+		t.Fatalf("retry = %+v, %v, want updated %s", second, err, first.MediaID)
+	}
+	if n := len(srv.Drafts()); n != 1 {
+		t.Errorf("drafts on mock server = %d, want 1", n)
+	}
+}
+
+func TestSyncDraftRecreatesDeletedDraft(t *testing.T) {
+	s, srv, thumb := newMockDraftService(t)
+	source := filepath.Join(t.TempDir(), "article.md")
+	article := Article{Title: "标题", Content: "x", ThumbMediaID: thumb}
+
+	first, err := s.SyncDraft(source, "h1", article, "mock", false)
+	if err != nil {
+		t.Fatalf("SyncDraft() error = %v", err)
+	}
+	if !srv.RemoveDraft(first.MediaID) {
+		t.Fatalf("draft %s not found on mock server", first.MediaID)
+	}
+
+	second, err := s.SyncDraft(source, "h2", article, "mock", false)
+	if err != nil {
+		t.Fatalf("SyncDraft() error = %v", err)
+	}
+	if second.Action != ActionCreated || second.MediaID == first.MediaID {
+		t.Errorf("second = %+v, want a new draft after deletion", second)
+	}
+}
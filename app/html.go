@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/royalrick/wechatwriter/app/image"
+	"github.com/spf13/cobra"
+)
+
+// htmlCmd HTML 处理命令组
+func htmlCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "html",
+		Short: "公众号 HTML 处理",
+		Long: `公众号 HTML 处理
+
+支持的操作：
+  rehost  - 将 HTML 中的外部图片转存到微信`,
+	}
+
+	cmd.AddCommand(htmlRehostCmd())
+
+	return cmd
+}
+
+// htmlRehostCmd 转存 HTML 中的外部图片
+func htmlRehostCmd() *cobra.Command {
+	var (
+		accountID string
+		output    string
+		inPlace   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rehost <file.html>",
+		Short: "将 HTML 中的外部图片转存到微信",
+		Long: `将 HTML 中的外部图片转存到微信
+
+查找所有 <img> 的 src / data-src 和 CSS background-image 的 url()，
+下载或读取图片（支持 http(s) 地址、HTML 文件所在目录下的本地路径和 base64 data URI），
+非 jpg/png 格式转码，超过 1MB 时压缩，通过 uploadimg 接口上传后改写地址。
+
+已在微信托管的图片（mmbiz.qpic.cn 等）保持不变；单张图片失败时保留原地址并在结果中列出。
+创建草稿（draft create / convert --draft）时会自动执行同样的转存。
+
+不指定 -o / --in-place 时，改写后的 HTML 在 JSON 结果的 html 字段中返回。
+
+示例：
+  writer html rehost article.html -o article.wechat.html
+  writer html rehost article.html --in-place --account my-account`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			htmlFile := args[0]
+			if inPlace && output != "" {
+				responseError(fmt.Errorf("--in-place 和 -o 不能同时使用"))
+				return
+			}
+
+			content, err := os.ReadFile(htmlFile)
+			if err != nil {
+				responseError(fmt.Errorf("read HTML file: %w", err))
+				return
+			}
+
+			processor, err := image.NewProcessorForAccount(cfg, log, accountID)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			html, report := processor.RehostHTML(string(content), filepath.Dir(htmlFile))
+
+			if inPlace {
+				output = htmlFile
+			}
+			result := map[string]any{"report": report}
+			if output != "" {
+				if err := os.WriteFile(output, []byte(html), 0644); err != nil {
+					responseError(fmt.Errorf("write output: %w", err))
+					return
+				}
+				result["output"] = output
+			} else {
+				result["html"] = html
+			}
+			responseSuccess(result)
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "上传使用的微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "改写后的 HTML 输出路径")
+	cmd.Flags().BoolVar(&inPlace, "in-place", false, "直接改写输入文件")
+
+	return cmd
+}
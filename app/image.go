@@ -15,12 +15,16 @@ func imageCmd() *cobra.Command {
 支持的操作：
   upload    - 上传本地图片到微信素材库
   download  - 下载在线图片并上传到微信
-  generate  - AI 生成图片并上传到微信`,
+  generate  - AI 生成图片并上传到微信
+  delete    - 删除微信素材库中的图片（同时清除上传缓存）
+  gallery   - AI 生成图片的本地图片库（list / show / reuse）`,
 	}
 
 	cmd.AddCommand(imageUploadCmd())
 	cmd.AddCommand(imageDownloadCmd())
 	cmd.AddCommand(imageGenerateCmd())
+	cmd.AddCommand(imageDeleteCmd())
+	cmd.AddCommand(imageGalleryCmd())
 
 	return cmd
 }
@@ -71,31 +75,36 @@ func imageDownloadCmd() *cobra.Command {
 
 // imageGenerateCmd AI 生成图片
 func imageGenerateCmd() *cobra.Command {
-	var size string
+	var flags image.GenerateOptions
 
 	cmd := &cobra.Command{
 		Use:   "generate <prompt>",
 		Short: "AI 生成图片并上传到微信",
-		Args:  cobra.ExactArgs(1),
+		Long: `AI 生成图片并上传到微信
+
+提示词可以使用与 Markdown 相同的选项语法，命令行参数优先：
+  writer image generate "一杯乌龙茶，晨光|size=16:9|quality=hd"
+  writer image generate "一杯乌龙茶，晨光" --aspect 16:9 --negative "文字, 水印" --seed 42
+
+--count 大于 1 时只上传第一张，其余图片地址在 candidates 中返回。`,
+		Args: cobra.ExactArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			return initConfig()
 		},
 		Run: func(cmd *cobra.Command, args []string) {
-			prompt := args[0]
-			processor := image.NewProcessor(cfg, log)
-
-			// 如果指定了尺寸，临时覆盖配置
-			if size != "" {
-				result, err := processor.GenerateAndUploadWithSize(prompt, size)
-				if err != nil {
-					responseError(err)
-					return
-				}
-				responseSuccess(result)
+			prompt, opts, err := image.ParseGenerateSpec(args[0])
+			if err != nil {
+				responseError(err)
+				return
+			}
+			mergeGenerateFlags(&opts, flags)
+			if err := opts.Validate(); err != nil {
+				responseError(err)
 				return
 			}
 
-			result, err := processor.GenerateAndUpload(prompt)
+			processor := image.NewProcessor(cfg, log)
+			result, err := processor.GenerateAndUploadWithOptions(prompt, opts)
 			if err != nil {
 				responseError(err)
 				return
@@ -104,7 +113,78 @@ func imageGenerateCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&size, "size", "s", "", "Image size (e.g., 2560x1440 for 16:9)")
+	cmd.Flags().StringVarP(&flags.Size, "size", "s", "", "Image size (e.g., 2560x1440 for 16:9)")
+	cmd.Flags().StringVar(&flags.AspectRatio, "aspect", "", "宽高比，如 16:9、2.35:1（按服务支持的尺寸换算）")
+	cmd.Flags().StringVar(&flags.Quality, "quality", "", "画质，如 standard、hd")
+	cmd.Flags().StringVar(&flags.Style, "style", "", "风格，如 vivid、natural")
+	cmd.Flags().StringVar(&flags.NegativePrompt, "negative", "", "反向提示词")
+	cmd.Flags().Int64Var(&flags.Seed, "seed", 0, "随机种子（0 为随机）")
+	cmd.Flags().IntVar(&flags.Count, "count", 0, "生成张数（1-4）")
+	cmd.Flags().StringVar(&flags.ResponseFormat, "format", "", "响应格式: url, b64_json")
+
+	return cmd
+}
+
+// mergeGenerateFlags 用命令行参数覆盖提示词中的生成选项
+func mergeGenerateFlags(opts *image.GenerateOptions, flags image.GenerateOptions) {
+	if flags.Size != "" {
+		opts.Size, opts.AspectRatio = flags.Size, ""
+	}
+	if flags.AspectRatio != "" {
+		opts.AspectRatio, opts.Size = flags.AspectRatio, ""
+	}
+	if flags.Quality != "" {
+		opts.Quality = flags.Quality
+	}
+	if flags.Style != "" {
+		opts.Style = flags.Style
+	}
+	if flags.NegativePrompt != "" {
+		opts.NegativePrompt = flags.NegativePrompt
+	}
+	if flags.Seed != 0 {
+		opts.Seed = flags.Seed
+	}
+	if flags.Count != 0 {
+		opts.Count = flags.Count
+	}
+	if flags.ResponseFormat != "" {
+		opts.ResponseFormat = flags.ResponseFormat
+	}
+}
+
+// imageDeleteCmd 删除素材
+func imageDeleteCmd() *cobra.Command {
+	var accountID string
+
+	cmd := &cobra.Command{
+		Use:   "delete <media_id>",
+		Short: "删除微信素材库中的图片",
+		Long: `删除微信素材库中的永久素材，并清除上传缓存中指向该素材的条目，
+避免之后的转换复用已失效的 media_id。`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			mediaID := args[0]
+			processor, err := image.NewProcessorForAccount(cfg, log, accountID)
+			if err != nil {
+				responseError(err)
+				return
+			}
+			if err := processor.DeleteMaterial(mediaID); err != nil {
+				responseError(err)
+				return
+			}
+			responseSuccess(map[string]any{
+				"media_id": mediaID,
+				"deleted":  true,
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
 
 	return cmd
 }
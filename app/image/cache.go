@@ -0,0 +1,219 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// uploadCacheFile 上传缓存文件名（位于账号数据目录下）
+const uploadCacheFile = "upload_cache.json"
+
+// lastUsedSaveInterval 命中时最近使用时间超过该间隔才写回文件，
+// prune 以天为粒度，无需每次命中都写盘
+const lastUsedSaveInterval = time.Hour
+
+// UploadCacheEntry 上传缓存条目
+type UploadCacheEntry struct {
+	Hash       string    `json:"hash"`             // 处理后图片内容的 SHA-256
+	MediaID    string    `json:"media_id"`         // 微信素材 ID
+	WechatURL  string    `json:"wechat_url"`       // 微信图片 URL
+	Source     string    `json:"source,omitempty"` // 原始路径、URL 或提示词
+	Size       int64     `json:"size"`             // 上传的字节数
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// UploadCache 按内容哈希缓存已上传的图片，避免重复上传
+// 素材 ID 只在所属账号内有效，因此每个账号一个缓存文件
+type UploadCache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*UploadCacheEntry
+}
+
+// OpenUploadCache 打开账号数据目录下的上传缓存，文件不存在时返回空缓存
+func OpenUploadCache(accountDir string) (*UploadCache, error) {
+	c := &UploadCache{
+		path:    filepath.Join(accountDir, uploadCacheFile),
+		entries: make(map[string]*UploadCacheEntry),
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, fmt.Errorf("read upload cache: %w", err)
+	}
+
+	var entries []*UploadCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse upload cache: %w", err)
+	}
+	for _, e := range entries {
+		if e.Hash != "" {
+			c.entries[e.Hash] = e
+		}
+	}
+
+	return c, nil
+}
+
+// Path 返回缓存文件路径
+func (c *UploadCache) Path() string {
+	return c.path
+}
+
+// Lookup 按内容哈希查找缓存，命中时刷新最近使用时间并持久化，
+// 避免 prune --older-than 删除仍在使用的条目
+func (c *UploadCache) Lookup(hash string) (*UploadCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[hash]
+	if !ok {
+		return nil, false
+	}
+	now := time.Now()
+	stale := now.Sub(e.LastUsedAt) >= lastUsedSaveInterval
+	e.LastUsedAt = now
+	if stale {
+		// 写回失败只影响 prune 的判断，不影响本次命中
+		_ = c.saveLocked()
+	}
+	entry := *e
+	return &entry, true
+}
+
+// Store 写入缓存条目并持久化
+func (c *UploadCache) Store(entry UploadCacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = now
+	}
+	entry.LastUsedAt = now
+	c.entries[entry.Hash] = &entry
+
+	return c.saveLocked()
+}
+
+// RemoveByMediaID 删除指向指定素材的缓存条目（素材被删除后调用）
+// 返回删除的条目数
+func (c *UploadCache) RemoveByMediaID(mediaID string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for hash, e := range c.entries {
+		if e.MediaID == mediaID {
+			delete(c.entries, hash)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, c.saveLocked()
+}
+
+// Prune 删除最近使用时间早于 olderThan 之前的条目，olderThan 为 0 时清空缓存
+// 返回删除的条目数
+func (c *UploadCache) Prune(olderThan time.Duration) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cutoff := time.Now().Add(-olderThan)
+	removed := 0
+	for hash, e := range c.entries {
+		if olderThan == 0 || e.LastUsedAt.Before(cutoff) {
+			delete(c.entries, hash)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, c.saveLocked()
+}
+
+// Entries 返回所有缓存条目，按最近使用时间倒序
+func (c *UploadCache) Entries() []UploadCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	list := make([]UploadCacheEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastUsedAt.After(list[j].LastUsedAt)
+	})
+	return list
+}
+
+// Save 持久化缓存
+func (c *UploadCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saveLocked()
+}
+
+// saveLocked 写入缓存文件（调用方需持有锁）
+func (c *UploadCache) saveLocked() error {
+	list := make([]*UploadCacheEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal upload cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免中断时损坏缓存
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write upload cache: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("write upload cache: %w", err)
+	}
+
+	return nil
+}
+
+// HashFile 计算文件内容的 SHA-256
+func HashFile(filePath string) (string, int64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", 0, fmt.Errorf("open file: %w", err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, fmt.Errorf("hash file: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
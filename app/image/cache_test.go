@@ -0,0 +1,135 @@
+package image
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUploadCache_StoreAndLookup(t *testing.T) {
+	dir := t.TempDir()
+
+	cache, err := OpenUploadCache(dir)
+	if err != nil {
+		t.Fatalf("OpenUploadCache() error = %v", err)
+	}
+
+	if _, ok := cache.Lookup("abc"); ok {
+		t.Fatalf("Lookup() on empty cache should miss")
+	}
+
+	if err := cache.Store(UploadCacheEntry{
+		Hash:      "abc",
+		MediaID:   "media-123",
+		WechatURL: "https://mmbiz.qpic.cn/abc",
+		Source:    "./a.png",
+		Size:      42,
+	}); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	// 重新打开，验证持久化
+	reopened, err := OpenUploadCache(dir)
+	if err != nil {
+		t.Fatalf("OpenUploadCache() error = %v", err)
+	}
+
+	entry, ok := reopened.Lookup("abc")
+	if !ok {
+		t.Fatalf("Lookup() after reopen should hit")
+	}
+	if entry.MediaID != "media-123" {
+		t.Errorf("MediaID = %v, want media-123", entry.MediaID)
+	}
+	if entry.WechatURL != "https://mmbiz.qpic.cn/abc" {
+		t.Errorf("WechatURL = %v, want https://mmbiz.qpic.cn/abc", entry.WechatURL)
+	}
+}
+
+func TestUploadCache_RemoveByMediaID(t *testing.T) {
+	cache, _ := OpenUploadCache(t.TempDir())
+	cache.Store(UploadCacheEntry{Hash: "h1", MediaID: "m1"})
+	cache.Store(UploadCacheEntry{Hash: "h2", MediaID: "m1"})
+	cache.Store(UploadCacheEntry{Hash: "h3", MediaID: "m2"})
+
+	removed, err := cache.RemoveByMediaID("m1")
+	if err != nil {
+		t.Fatalf("RemoveByMediaID() error = %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("removed = %v, want 2", removed)
+	}
+	if _, ok := cache.Lookup("h1"); ok {
+		t.Errorf("h1 should be invalidated")
+	}
+	if _, ok := cache.Lookup("h3"); !ok {
+		t.Errorf("h3 should remain")
+	}
+}
+
+func TestUploadCache_Prune(t *testing.T) {
+	dir := t.TempDir()
+	cache, _ := OpenUploadCache(dir)
+	cache.Store(UploadCacheEntry{Hash: "old", MediaID: "m1"})
+	cache.Store(UploadCacheEntry{Hash: "new", MediaID: "m2"})
+	cache.entries["old"].LastUsedAt = time.Now().Add(-48 * time.Hour)
+
+	removed, err := cache.Prune(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("removed = %v, want 1", removed)
+	}
+	if len(cache.Entries()) != 1 {
+		t.Errorf("Entries() length = %v, want 1", len(cache.Entries()))
+	}
+
+	removed, _ = cache.Prune(0)
+	if removed != 1 || len(cache.Entries()) != 0 {
+		t.Errorf("Prune(0) should clear cache, removed = %v", removed)
+	}
+}
+
+func TestUploadCache_LookupPersistsLastUsed(t *testing.T) {
+	dir := t.TempDir()
+	cache, _ := OpenUploadCache(dir)
+	cache.Store(UploadCacheEntry{Hash: "h1", MediaID: "m1"})
+	cache.entries["h1"].LastUsedAt = time.Now().Add(-48 * time.Hour)
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// 命中后重新打开，最近使用时间应已写回，prune 不会删除
+	if _, ok := cache.Lookup("h1"); !ok {
+		t.Fatalf("Lookup() should hit")
+	}
+	reopened, err := OpenUploadCache(dir)
+	if err != nil {
+		t.Fatalf("OpenUploadCache() error = %v", err)
+	}
+	if removed, _ := reopened.Prune(24 * time.Hour); removed != 0 {
+		t.Errorf("Prune() removed %d entries used just now", removed)
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.bin")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, size, err := HashFile(path)
+	if err != nil {
+		t.Fatalf("HashFile() error = %v", err)
+	}
+	if size != 5 {
+		t.Errorf("size = %v, want 5", size)
+	}
+	// sha256("hello")
+	want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if hash != want {
+		t.Errorf("hash = %v, want %v", hash, want)
+	}
+}
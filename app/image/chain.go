@@ -0,0 +1,139 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultRetryBackoff 可重试错误第一次重试前的等待时间，之后每次翻倍
+const DefaultRetryBackoff = 2 * time.Second
+
+// ChainProvider 按顺序尝试多个图片服务
+//
+// 每个服务遇到可重试错误（限流、网络错误、超时）时按退避重试，仍失败或遇到
+// 其他错误（如内容审核拒绝、余额不足）则降级到下一个服务。
+type ChainProvider struct {
+	providers  []Provider
+	retries    int            // 每个服务的重试次数
+	backoff    time.Duration  // 第一次重试前的等待时间
+	rateLimits map[string]int // 各服务每分钟最多请求次数（image.rate_limits）
+	skipped    []error        // 因配置错误未加入降级链的提供者
+}
+
+// NewChainProvider 创建降级链
+func NewChainProvider(providers []Provider, retries int, rateLimits map[string]int) *ChainProvider {
+	return &ChainProvider{
+		providers:  providers,
+		retries:    retries,
+		backoff:    DefaultRetryBackoff,
+		rateLimits: rateLimits,
+	}
+}
+
+// Name 返回提供者名称，多个服务时按顺序用 → 连接
+func (c *ChainProvider) Name() string {
+	names := make([]string, len(c.providers))
+	for i, p := range c.providers {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, "→")
+}
+
+// Providers 降级链中的图片服务
+func (c *ChainProvider) Providers() []Provider {
+	return c.providers
+}
+
+// Skipped 因配置错误被跳过的提供者
+func (c *ChainProvider) Skipped() []error {
+	return c.skipped
+}
+
+// Generate 依次尝试各服务生成图片，结果的 Provider 为实际生成图片的服务
+func (c *ChainProvider) Generate(ctx context.Context, prompt string, opts GenerateOptions) (*GenerateResult, error) {
+	var failures []string
+	var lastErr error
+
+	for _, p := range c.providers {
+		result, err := c.generateWithRetry(ctx, p, prompt, opts, &failures)
+		if err == nil {
+			if result.Provider == "" {
+				result.Provider = p.Name()
+			}
+			result.Failures = failures
+			return result, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if len(c.providers) == 1 {
+		return nil, lastErr
+	}
+	return nil, &GenerateError{
+		Provider: c.Name(),
+		Code:     errorCode(lastErr),
+		Message:  "所有图片服务均生成失败: " + strings.Join(failures, "; "),
+		Hint:     "检查各服务的配置（api.image_providers）和额度，或稍后重试",
+		Original: lastErr,
+	}
+}
+
+// generateWithRetry 调用单个服务，可重试错误按指数退避重试
+func (c *ChainProvider) generateWithRetry(ctx context.Context, p Provider, prompt string, opts GenerateOptions, failures *[]string) (*GenerateResult, error) {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		if err := waitRateLimit(ctx, c.rateLimits, p.Name()); err != nil {
+			return nil, err
+		}
+		result, err := p.Generate(ctx, prompt, opts)
+		if err == nil {
+			return result, nil
+		}
+		*failures = append(*failures, fmt.Sprintf("%s: %s", p.Name(), errorSummary(err)))
+
+		if attempt >= c.retries || !retryable(err) {
+			return nil, err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// retryable 是否为值得重试的临时错误
+func retryable(err error) bool {
+	switch errorCode(err) {
+	case "rate_limit", "network_error", "timeout":
+		return true
+	}
+	return false
+}
+
+// errorCode 取出 GenerateError 的错误码
+func errorCode(err error) string {
+	var genErr *GenerateError
+	if errors.As(err, &genErr) {
+		return genErr.Code
+	}
+	return ""
+}
+
+// errorSummary 单行错误描述（不含提示）
+func errorSummary(err error) string {
+	var genErr *GenerateError
+	if errors.As(err, &genErr) {
+		return fmt.Sprintf("%s (%s)", genErr.Message, genErr.Code)
+	}
+	return err.Error()
+}
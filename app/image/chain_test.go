@@ -0,0 +1,96 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedProvider 依次返回预设错误，用完后生成成功
+type scriptedProvider struct {
+	name  string
+	errs  []string // 错误码
+	calls int
+}
+
+func (s *scriptedProvider) Name() string { return s.name }
+
+func (s *scriptedProvider) Generate(ctx context.Context, prompt string, opts GenerateOptions) (*GenerateResult, error) {
+	s.calls++
+	if s.calls <= len(s.errs) {
+		code := s.errs[s.calls-1]
+		return nil, &GenerateError{Provider: s.name, Code: code, Message: "failed: " + code}
+	}
+	return &GenerateResult{URL: "https://img.example.com/" + s.name + ".png"}, nil
+}
+
+func newTestChain(retries int, providers ...Provider) *ChainProvider {
+	c := NewChainProvider(providers, retries, nil)
+	c.backoff = time.Millisecond
+	return c
+}
+
+func TestChainProviderRetriesRetryableErrors(t *testing.T) {
+	primary := &scriptedProvider{name: "OpenAI", errs: []string{"rate_limit", "network_error"}}
+	backup := &scriptedProvider{name: "TuZi"}
+
+	res, err := newTestChain(2, primary, backup).Generate(context.Background(), "tea", GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if res.Provider != "OpenAI" || primary.calls != 3 || backup.calls != 0 {
+		t.Errorf("provider = %s, calls = %d/%d; want OpenAI after 3 attempts", res.Provider, primary.calls, backup.calls)
+	}
+	if len(res.Failures) != 2 {
+		t.Errorf("failures = %v, want 2 entries", res.Failures)
+	}
+}
+
+func TestChainProviderFallsThrough(t *testing.T) {
+	tests := []struct {
+		name         string
+		errs         []string
+		primaryCalls int
+	}{
+		{"retries exhausted", []string{"rate_limit", "rate_limit", "rate_limit"}, 2},
+		{"content policy is not retried", []string{"content_policy"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &scriptedProvider{name: "ModelScope", errs: tt.errs}
+			backup := &scriptedProvider{name: "TuZi"}
+
+			res, err := newTestChain(1, primary, backup).Generate(context.Background(), "tea", GenerateOptions{})
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if res.Provider != "TuZi" {
+				t.Errorf("Provider = %s, want TuZi", res.Provider)
+			}
+			if primary.calls != tt.primaryCalls {
+				t.Errorf("primary calls = %d, want %d", primary.calls, tt.primaryCalls)
+			}
+		})
+	}
+}
+
+func TestChainProviderAllFailed(t *testing.T) {
+	chain := newTestChain(0,
+		&scriptedProvider{name: "ModelScope", errs: []string{"timeout"}},
+		&scriptedProvider{name: "OpenAI", errs: []string{"unauthorized"}},
+	)
+
+	_, err := chain.Generate(context.Background(), "tea", GenerateOptions{})
+	var genErr *GenerateError
+	if !errors.As(err, &genErr) {
+		t.Fatalf("error = %v, want GenerateError", err)
+	}
+	if genErr.Code != "unauthorized" || genErr.Provider != "ModelScope→OpenAI" {
+		t.Errorf("error = %+v", genErr)
+	}
+	if !strings.Contains(genErr.Message, "ModelScope: failed: timeout") {
+		t.Errorf("message = %q, want each attempt listed", genErr.Message)
+	}
+}
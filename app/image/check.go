@@ -0,0 +1,88 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/config"
+)
+
+// CheckProvider 检查图片服务的配置和连通性（不会生成图片）
+// 通过 GET {base}/models 验证 API Key，返回提供者名称；
+// 配置了降级链时依次检查每个提供者，返回第一个错误
+func CheckProvider(ctx context.Context, cfg *config.Config) (string, error) {
+	var names []string
+	for _, name := range cfg.ImageProviderChain() {
+		checked, err := checkProvider(ctx, cfg.ForImageProvider(name))
+		if err != nil {
+			return checked, err
+		}
+		names = append(names, checked)
+	}
+	return strings.Join(names, "→"), nil
+}
+
+// checkProvider 检查单个提供者
+func checkProvider(ctx context.Context, cfg *config.Config) (string, error) {
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return cfg.ImageProvider, err
+	}
+
+	url := modelsURL(cfg)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return provider.Name(), fmt.Errorf("create request: %w", err)
+	}
+	if cfg.LocalImageProvider() {
+		setBasicAuth(req, cfg.ImageAPIKey)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+cfg.ImageAPIKey)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return provider.Name(), &GenerateError{
+			Provider: provider.Name(),
+			Code:     "unreachable",
+			Message:  fmt.Sprintf("无法连接图片服务: %v", err),
+			Hint:     "检查网络和 image_base_url 配置，需要代理时设置 HTTPS_PROXY",
+			Original: err,
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return provider.Name(), &GenerateError{
+			Provider: provider.Name(),
+			Code:     "unauthorized",
+			Message:  fmt.Sprintf("API Key 被拒绝 (HTTP %d)", resp.StatusCode),
+			Hint:     "检查 IMAGE_API_KEY 是否正确、是否过期，以及与 image_provider 是否匹配",
+		}
+	}
+
+	// 其他状态码（含 404）说明服务可达，不视为失败
+	return provider.Name(), nil
+}
+
+// modelsURL 返回用于连通性检查的模型列表地址
+func modelsURL(cfg *config.Config) string {
+	switch cfg.ImageProvider {
+	case "modelscope", "ms":
+		base := cfg.ImageAPIBase
+		if base == "" {
+			base = "https://api-inference.modelscope.cn/"
+		}
+		return strings.TrimRight(base, "/") + "/v1/models"
+	case "sdwebui", "sd":
+		return localAPIBase(cfg, "http://127.0.0.1:7860") + "/sdapi/v1/sd-models"
+	case "comfyui":
+		return localAPIBase(cfg, "http://127.0.0.1:8188") + "/system_stats"
+	default:
+		return strings.TrimRight(cfg.ImageAPIBase, "/") + "/models"
+	}
+}
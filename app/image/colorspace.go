@@ -0,0 +1,285 @@
+package image
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math"
+)
+
+// iccProfile 矩阵 / 曲线型 RGB 色彩配置（手机和相机常见的 Display P3、Adobe RGB 等）
+type iccProfile struct {
+	toXYZ [3][3]float64 // 线性 RGB → PCS XYZ（D50）
+	trc   [3]toneCurve  // 各通道的编码值 → 线性值
+}
+
+// toneCurve 编码值（0~1）→ 线性值（0~1）
+type toneCurve func(v float64) float64
+
+// sRGB 在 PCS（D50）下的原色，即 sRGB IEC61966-2.1 配置中的 rXYZ / gXYZ / bXYZ
+var srgbToXYZ = [3][3]float64{
+	{0.4360747, 0.3850649, 0.1430804},
+	{0.2225045, 0.7168786, 0.0606169},
+	{0.0139322, 0.0971045, 0.7141733},
+}
+
+var xyzToSRGB = invert3(srgbToXYZ)
+
+// parseICC 解析 RGB 矩阵型 ICC 配置；基于查找表（LUT）的配置返回错误
+func parseICC(data []byte) (*iccProfile, error) {
+	if len(data) < 132 {
+		return nil, fmt.Errorf("icc profile too short")
+	}
+	if string(data[16:20]) != "RGB " {
+		return nil, fmt.Errorf("icc color space %q is not RGB", data[16:20])
+	}
+
+	tags := map[string][]byte{}
+	count := int(binary.BigEndian.Uint32(data[128:]))
+	for i := 0; i < count; i++ {
+		entry := 132 + i*12
+		if entry+12 > len(data) {
+			return nil, fmt.Errorf("icc tag table truncated")
+		}
+		offset := int(binary.BigEndian.Uint32(data[entry+4:]))
+		size := int(binary.BigEndian.Uint32(data[entry+8:]))
+		if offset < 0 || size < 0 || offset+size > len(data) {
+			return nil, fmt.Errorf("icc tag out of range")
+		}
+		tags[string(data[entry:entry+4])] = data[offset : offset+size]
+	}
+
+	p := &iccProfile{}
+	for ch, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		xyz, err := parseXYZTag(tags[sig])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sig, err)
+		}
+		for row := 0; row < 3; row++ {
+			p.toXYZ[row][ch] = xyz[row]
+		}
+	}
+	for ch, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		curve, err := parseCurveTag(tags[sig])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sig, err)
+		}
+		p.trc[ch] = curve
+	}
+	return p, nil
+}
+
+// isSRGB 原色与 sRGB 基本一致时无需转换
+func (p *iccProfile) isSRGB() bool {
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if math.Abs(p.toXYZ[i][j]-srgbToXYZ[i][j]) > 0.003 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// needsSRGBConversion 判断内嵌配置是否需要转换到 sRGB；无法解析的配置按 sRGB 处理
+func needsSRGBConversion(profile []byte) (*iccProfile, bool) {
+	if len(profile) == 0 {
+		return nil, false
+	}
+	p, err := parseICC(profile)
+	if err != nil || p.isSRGB() {
+		return nil, false
+	}
+	return p, true
+}
+
+// convertToSRGB 将按 profile 编码的图片转换为 sRGB
+func convertToSRGB(img image.Image, p *iccProfile) *image.NRGBA {
+	// 源编码值 → 线性值查找表
+	var decode [3][256]float64
+	for ch := 0; ch < 3; ch++ {
+		for v := 0; v < 256; v++ {
+			decode[ch][v] = p.trc[ch](float64(v) / 255)
+		}
+	}
+	// 线性 sRGB → 编码值查找表
+	const encodeSteps = 4096
+	var encode [encodeSteps + 1]uint8
+	for i := range encode {
+		encode[i] = uint8(math.Round(srgbEncode(float64(i)/encodeSteps) * 255))
+	}
+
+	m := mul3(xyzToSRGB, p.toXYZ)
+	b := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := out.PixOffset(x-b.Min.X, y-b.Min.Y)
+			r, g, bl, a := img.At(x, y).RGBA()
+			if a == 0 {
+				continue
+			}
+			// 还原非预乘的 8 位值
+			src := [3]float64{
+				decode[0][uint8(r*0xff/a)],
+				decode[1][uint8(g*0xff/a)],
+				decode[2][uint8(bl*0xff/a)],
+			}
+			for ch := 0; ch < 3; ch++ {
+				lin := m[ch][0]*src[0] + m[ch][1]*src[1] + m[ch][2]*src[2]
+				lin = math.Max(0, math.Min(1, lin))
+				out.Pix[i+ch] = encode[int(lin*encodeSteps+0.5)]
+			}
+			out.Pix[i+3] = uint8(a >> 8)
+		}
+	}
+	return out
+}
+
+// parseXYZTag 解析 XYZType
+func parseXYZTag(tag []byte) ([3]float64, error) {
+	var xyz [3]float64
+	if len(tag) < 20 || string(tag[:4]) != "XYZ " {
+		return xyz, fmt.Errorf("missing or invalid XYZ tag")
+	}
+	for i := 0; i < 3; i++ {
+		xyz[i] = s15Fixed16(tag[8+i*4:])
+	}
+	return xyz, nil
+}
+
+// parseCurveTag 解析 curveType（伽马或采样表）和 parametricCurveType
+func parseCurveTag(tag []byte) (toneCurve, error) {
+	if len(tag) < 12 {
+		return nil, fmt.Errorf("missing or invalid curve tag")
+	}
+	switch string(tag[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(tag[8:]))
+		switch {
+		case n == 0:
+			return func(v float64) float64 { return v }, nil
+		case n == 1:
+			if len(tag) < 14 {
+				return nil, fmt.Errorf("curve tag truncated")
+			}
+			gamma := float64(binary.BigEndian.Uint16(tag[12:])) / 256
+			return func(v float64) float64 { return math.Pow(v, gamma) }, nil
+		default:
+			if len(tag) < 12+n*2 {
+				return nil, fmt.Errorf("curve tag truncated")
+			}
+			table := make([]float64, n)
+			for i := range table {
+				table[i] = float64(binary.BigEndian.Uint16(tag[12+i*2:])) / 65535
+			}
+			return func(v float64) float64 {
+				pos := v * float64(n-1)
+				i := int(pos)
+				if i >= n-1 {
+					return table[n-1]
+				}
+				frac := pos - float64(i)
+				return table[i]*(1-frac) + table[i+1]*frac
+			}, nil
+		}
+	case "para":
+		return parseParametricCurve(tag)
+	default:
+		return nil, fmt.Errorf("unsupported curve type %q", tag[:4])
+	}
+}
+
+// parseParametricCurve 解析 ICC parametricCurveType（函数类型 0~4）
+func parseParametricCurve(tag []byte) (toneCurve, error) {
+	fn := binary.BigEndian.Uint16(tag[8:])
+	counts := []int{1, 3, 4, 5, 7}
+	if int(fn) >= len(counts) || len(tag) < 12+counts[fn]*4 {
+		return nil, fmt.Errorf("invalid parametric curve")
+	}
+	var p [7]float64
+	for i := 0; i < counts[fn]; i++ {
+		p[i] = s15Fixed16(tag[12+i*4:])
+	}
+	g, a, b, c, d, e, f := p[0], p[1], p[2], p[3], p[4], p[5], p[6]
+
+	switch fn {
+	case 0:
+		return func(v float64) float64 { return math.Pow(v, g) }, nil
+	case 1:
+		return func(v float64) float64 {
+			if v >= -b/a {
+				return math.Pow(a*v+b, g)
+			}
+			return 0
+		}, nil
+	case 2:
+		return func(v float64) float64 {
+			if v >= -b/a {
+				return math.Pow(a*v+b, g) + c
+			}
+			return c
+		}, nil
+	case 3:
+		return func(v float64) float64 {
+			if v >= d {
+				return math.Pow(a*v+b, g)
+			}
+			return c * v
+		}, nil
+	default:
+		return func(v float64) float64 {
+			if v >= d {
+				return math.Pow(a*v+b, g) + e
+			}
+			return c*v + f
+		}, nil
+	}
+}
+
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// srgbEncode 线性值 → sRGB 编码值
+func srgbEncode(v float64) float64 {
+	if v <= 0.0031308 {
+		return 12.92 * v
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+func mul3(a, b [3][3]float64) [3][3]float64 {
+	var out [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				out[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return out
+}
+
+func invert3(m [3][3]float64) [3][3]float64 {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	return [3][3]float64{
+		{
+			(m[1][1]*m[2][2] - m[1][2]*m[2][1]) / det,
+			(m[0][2]*m[2][1] - m[0][1]*m[2][2]) / det,
+			(m[0][1]*m[1][2] - m[0][2]*m[1][1]) / det,
+		},
+		{
+			(m[1][2]*m[2][0] - m[1][0]*m[2][2]) / det,
+			(m[0][0]*m[2][2] - m[0][2]*m[2][0]) / det,
+			(m[0][2]*m[1][0] - m[0][0]*m[1][2]) / det,
+		},
+		{
+			(m[1][0]*m[2][1] - m[1][1]*m[2][0]) / det,
+			(m[0][1]*m[2][0] - m[0][0]*m[2][1]) / det,
+			(m[0][0]*m[1][1] - m[0][1]*m[1][0]) / det,
+		},
+	}
+}
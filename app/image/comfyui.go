@@ -0,0 +1,415 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/config"
+)
+
+// ComfyUI 工作流中的占位符，生成时替换为本次请求的参数
+// 整个字符串值等于 {{seed}}、{{width}}、{{height}}、{{count}} 时替换为数字
+const (
+	comfyPrompt         = "{{prompt}}"
+	comfyNegativePrompt = "{{negative_prompt}}"
+	comfySeed           = "{{seed}}"
+	comfyWidth          = "{{width}}"
+	comfyHeight         = "{{height}}"
+	comfyCount          = "{{count}}"
+)
+
+// ComfyUIProvider 自建 ComfyUI 图片生成服务
+// 提交 API 格式的工作流（POST /prompt），轮询 /history 后通过 /view 取回图片内容
+type ComfyUIProvider struct {
+	baseURL      string
+	apiKey       string
+	workflow     []byte // API 格式的工作流 JSON，含占位符
+	size         string
+	client       *http.Client
+	pollInterval time.Duration // 轮询间隔，默认 1s
+	maxPollTime  time.Duration // 最大轮询时间，默认 5 分钟
+}
+
+// NewComfyUIProvider 创建 ComfyUI Provider，工作流来自 api.image_workflow
+func NewComfyUIProvider(cfg *config.Config) (*ComfyUIProvider, error) {
+	if cfg.ImageWorkflow == "" {
+		return nil, &config.ConfigError{
+			Field:   "ImageWorkflow",
+			Message: "使用 ComfyUI 需要配置工作流文件",
+			Hint:    "在 ComfyUI 中用 \"Save (API Format)\" 导出工作流，在提示词节点填入 {{prompt}}，配置 api.image_workflow 指向该文件",
+		}
+	}
+	workflow, err := os.ReadFile(cfg.ImageWorkflow)
+	if err != nil {
+		return nil, &config.ConfigError{
+			Field:   "ImageWorkflow",
+			Message: fmt.Sprintf("读取 ComfyUI 工作流失败: %v", err),
+			Hint:    "检查 api.image_workflow 路径是否正确",
+		}
+	}
+	if !bytes.Contains(workflow, []byte(comfyPrompt)) {
+		return nil, &config.ConfigError{
+			Field:   "ImageWorkflow",
+			Message: "ComfyUI 工作流中没有 {{prompt}} 占位符",
+			Hint:    "将正向提示词节点（如 CLIPTextEncode 的 text）改为 {{prompt}}",
+		}
+	}
+
+	size := cfg.ImageSize
+	if size == "" {
+		size = "1024x1024"
+	}
+
+	return &ComfyUIProvider{
+		baseURL:      localAPIBase(cfg, "http://127.0.0.1:8188"),
+		apiKey:       cfg.ImageAPIKey,
+		workflow:     workflow,
+		size:         size,
+		pollInterval: time.Second,
+		maxPollTime:  5 * time.Minute,
+		client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}, nil
+}
+
+// Name 返回提供者名称
+func (p *ComfyUIProvider) Name() string {
+	return "ComfyUI"
+}
+
+// comfyImage ComfyUI 输出节点中的图片
+type comfyImage struct {
+	Filename  string `json:"filename"`
+	Subfolder string `json:"subfolder"`
+	Type      string `json:"type"`
+}
+
+// Generate 提交工作流并等待图片生成
+func (p *ComfyUIProvider) Generate(ctx context.Context, prompt string, opts GenerateOptions) (*GenerateResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "invalid_options",
+			Message:  fmt.Sprintf("生成选项错误: %v", err),
+			Original: err,
+		}
+	}
+	var width, height int
+	size, err := opts.resolveSize(p.size)
+	if err == nil {
+		width, height, err = parseSize(size)
+	}
+	if err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "invalid_size",
+			Message:  fmt.Sprintf("图片尺寸格式错误: %v", err),
+			Hint:     "请使用 WIDTHxHEIGHT 或 W:H 格式，如 1024x1024、16:9",
+			Original: err,
+		}
+	}
+
+	seed := opts.Seed
+	if seed == 0 {
+		seed = rand.Int63n(1 << 48)
+	}
+	workflow, err := injectWorkflow(p.workflow, map[string]any{
+		comfyPrompt:         prompt,
+		comfyNegativePrompt: opts.NegativePrompt,
+		comfySeed:           seed,
+		comfyWidth:          width,
+		comfyHeight:         height,
+		comfyCount:          opts.count(),
+	})
+	if err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "invalid_workflow",
+			Message:  fmt.Sprintf("ComfyUI 工作流解析失败: %v", err),
+			Hint:     "工作流需要用 \"Save (API Format)\" 导出",
+			Original: err,
+		}
+	}
+
+	// 1. 提交工作流
+	promptID, err := p.queuePrompt(ctx, workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 轮询执行结果
+	outputs, err := p.waitOutputs(ctx, promptID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 取回图片内容
+	images := make([][]byte, 0, len(outputs))
+	for _, img := range outputs {
+		data, err := p.viewImage(ctx, img)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, data)
+	}
+
+	return &GenerateResult{
+		Data:   images[0],
+		Images: images,
+		Model:  p.Name(),
+		Size:   size,
+		Seed:   seed,
+	}, nil
+}
+
+// injectWorkflow 替换工作流 JSON 中的占位符
+func injectWorkflow(workflow []byte, values map[string]any) (map[string]any, error) {
+	var graph map[string]any
+	if err := json.Unmarshal(workflow, &graph); err != nil {
+		return nil, err
+	}
+	return injectValue(graph, values).(map[string]any), nil
+}
+
+// injectValue 递归替换字符串中的占位符
+func injectValue(v any, values map[string]any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			t[k] = injectValue(child, values)
+		}
+		return t
+	case []any:
+		for i, child := range t {
+			t[i] = injectValue(child, values)
+		}
+		return t
+	case string:
+		if value, ok := values[t]; ok {
+			return value
+		}
+		for placeholder, value := range values {
+			if s, ok := value.(string); ok {
+				t = strings.ReplaceAll(t, placeholder, s)
+			}
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// queuePrompt 提交工作流，返回 prompt_id
+func (p *ComfyUIProvider) queuePrompt(ctx context.Context, workflow map[string]any) (string, error) {
+	jsonData, err := json.Marshal(map[string]any{
+		"prompt":    workflow,
+		"client_id": "wechatwriter",
+	})
+	if err != nil {
+		return "", &GenerateError{
+			Provider: p.Name(),
+			Code:     "marshal_error",
+			Message:  "请求构造失败",
+			Original: err,
+		}
+	}
+
+	resp, err := p.do(ctx, http.MethodPost, "/prompt", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusBadRequest {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			return "", &GenerateError{
+				Provider: p.Name(),
+				Code:     "bad_request",
+				Message:  "ComfyUI 拒绝了工作流",
+				Hint:     "检查工作流中的节点和模型在该 ComfyUI 上是否存在",
+				Original: fmt.Errorf("status 400: %s", string(body)),
+			}
+		}
+		return "", localErrorResponse(p.Name(), resp)
+	}
+
+	var result struct {
+		PromptID string `json:"prompt_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.PromptID == "" {
+		return "", &GenerateError{
+			Provider: p.Name(),
+			Code:     "no_task_id",
+			Message:  "未获取到 prompt_id",
+			Original: err,
+		}
+	}
+	return result.PromptID, nil
+}
+
+// waitOutputs 轮询 /history/{prompt_id}，返回输出节点中的图片
+func (p *ComfyUIProvider) waitOutputs(ctx context.Context, promptID string) ([]comfyImage, error) {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+	timeout := time.After(p.maxPollTime)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, &GenerateError{
+				Provider: p.Name(),
+				Code:     "canceled",
+				Message:  "操作已取消",
+				Original: ctx.Err(),
+			}
+		case <-timeout:
+			return nil, &GenerateError{
+				Provider: p.Name(),
+				Code:     "timeout",
+				Message:  fmt.Sprintf("图片生成超时（超过 %v）", p.maxPollTime),
+				Hint:     "ComfyUI 队列可能较长，请在 ComfyUI 界面查看执行进度",
+			}
+		case <-ticker.C:
+			images, done, err := p.history(ctx, promptID)
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				return images, nil
+			}
+		}
+	}
+}
+
+// history 查询执行记录；尚未完成时 done 为 false
+func (p *ComfyUIProvider) history(ctx context.Context, promptID string) ([]comfyImage, bool, error) {
+	resp, err := p.do(ctx, http.MethodGet, "/history/"+url.PathEscape(promptID), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, localErrorResponse(p.Name(), resp)
+	}
+
+	var history map[string]struct {
+		Status struct {
+			StatusStr string `json:"status_str"`
+			Completed bool   `json:"completed"`
+		} `json:"status"`
+		Outputs map[string]struct {
+			Images []comfyImage `json:"images"`
+		} `json:"outputs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		return nil, false, &GenerateError{
+			Provider: p.Name(),
+			Code:     "decode_error",
+			Message:  "执行记录解析失败",
+			Original: err,
+		}
+	}
+
+	entry, ok := history[promptID]
+	if !ok {
+		return nil, false, nil // 仍在队列中
+	}
+	if entry.Status.StatusStr == "error" {
+		return nil, false, &GenerateError{
+			Provider: p.Name(),
+			Code:     "task_failed",
+			Message:  "ComfyUI 工作流执行失败",
+			Hint:     "在 ComfyUI 界面查看报错节点",
+		}
+	}
+
+	// 只取最终输出（type=output），忽略预览图
+	var images []comfyImage
+	for _, out := range entry.Outputs {
+		for _, img := range out.Images {
+			if img.Type == "output" {
+				images = append(images, img)
+			}
+		}
+	}
+	if len(images) == 0 {
+		if entry.Status.Completed {
+			return nil, false, &GenerateError{
+				Provider: p.Name(),
+				Code:     "no_image",
+				Message:  "工作流执行完成但没有输出图片",
+				Hint:     "确认工作流中包含 SaveImage 节点",
+			}
+		}
+		return nil, false, nil
+	}
+	return images, true, nil
+}
+
+// viewImage 通过 /view 下载输出图片
+func (p *ComfyUIProvider) viewImage(ctx context.Context, img comfyImage) ([]byte, error) {
+	query := url.Values{
+		"filename":  {img.Filename},
+		"subfolder": {img.Subfolder},
+		"type":      {img.Type},
+	}
+	resp, err := p.do(ctx, http.MethodGet, "/view?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, localErrorResponse(p.Name(), resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "network_error",
+			Message:  "读取图片失败",
+			Original: err,
+		}
+	}
+	return data, nil
+}
+
+// do 发送请求
+func (p *ComfyUIProvider) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+	if err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "request_error",
+			Message:  "创建请求失败",
+			Original: err,
+		}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	setBasicAuth(req, p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "network_error",
+			Message:  "无法连接 ComfyUI",
+			Hint:     "确认 ComfyUI 已启动并监听局域网地址（--listen），image_base_url 指向其地址（如 http://192.168.1.10:8188）",
+			Original: err,
+		}
+	}
+	return resp, nil
+}
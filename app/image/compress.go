@@ -1,18 +1,38 @@
 package image
 
 import (
+	"bytes"
 	"fmt"
 	"image"
 	"image/jpeg"
 	"image/png"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/disintegration/imaging"
+	"github.com/royalrick/wechatwriter/app/config"
 	"go.uber.org/zap"
 )
 
+// ImageKind 图片用途，target 压缩模式下决定目标大小
+type ImageKind string
+
+const (
+	ImageKindBody  ImageKind = "body"  // 正文配图
+	ImageKindCover ImageKind = "cover" // 封面
+	ImageKindGIF   ImageKind = "gif"   // GIF 动图
+)
+
+// target 模式的搜索范围
+const (
+	targetMinQuality  = 40  // 低于该质量时改为缩小尺寸
+	targetMaxAttempts = 6   // 缩小尺寸的最多次数
+	targetMinScale    = 0.5 // 每次缩小的比例下限
+	targetMaxScale    = 0.9 // 每次缩小的比例上限
+)
+
 // Compressor 图片压缩器
 type Compressor struct {
 	log          *zap.Logger
@@ -21,6 +41,11 @@ type Compressor struct {
 	quality      int // JPEG 质量 1-100
 	enableResize bool
 	enableShrink bool
+
+	targets map[ImageKind]int64 // target 模式下各类图片的目标大小（字节），nil 为 fixed 模式
+	strip   bool                // 去除 EXIF/GPS 等元数据
+
+	watermark *Watermark // 叠加到正文图片上的水印，nil 为不加
 }
 
 // NewCompressor 创建压缩器
@@ -35,125 +60,352 @@ func NewCompressor(log *zap.Logger, maxWidth int, maxSize int64) *Compressor {
 	}
 }
 
-// CompressImage 压缩图片
+// NewCompressorFromConfig 按配置创建压缩器（压缩模式、目标大小、元数据处理）
+func NewCompressorFromConfig(log *zap.Logger, cfg *config.Config) *Compressor {
+	c := NewCompressor(log, cfg.MaxImageWidth, cfg.MaxImageSize)
+	c.strip = cfg.StripImageMetadata
+	if cfg.ImageCompressMode == config.CompressModeTarget {
+		t := cfg.ImageTargetSizes
+		c.SetTargetSizes(int64(t.Cover)*1024, int64(t.Body)*1024, int64(t.GIF)*1024)
+	}
+	return c
+}
+
+// SetTargetSizes 切换到 target 模式并设置各类图片的目标大小（字节，0 使用 maxSize）
+func (c *Compressor) SetTargetSizes(cover, body, gif int64) {
+	c.targets = map[ImageKind]int64{
+		ImageKindCover: cover,
+		ImageKindBody:  body,
+		ImageKindGIF:   gif,
+	}
+}
+
+// SetStripMetadata 设置是否去除 EXIF/GPS 等元数据
+func (c *Compressor) SetStripMetadata(strip bool) {
+	c.strip = strip
+}
+
+// withMaxSize 返回相同设置、大小上限不同的压缩器
+func (c *Compressor) withMaxSize(maxSize int64) *Compressor {
+	clone := *c
+	clone.maxSize = maxSize
+	clone.enableShrink = maxSize > 0
+	if c.targets != nil {
+		clone.targets = map[ImageKind]int64{}
+		for kind, size := range c.targets {
+			if size <= 0 || size > maxSize {
+				size = maxSize
+			}
+			clone.targets[kind] = size
+		}
+	}
+	return &clone
+}
+
+// withWatermark 返回相同设置、叠加指定水印的压缩器（nil 为不加水印）
+func (c *Compressor) withWatermark(w *Watermark) *Compressor {
+	clone := *c
+	clone.watermark = w
+	return &clone
+}
+
+// limitFor 返回该类图片的大小上限
+func (c *Compressor) limitFor(kind ImageKind) int64 {
+	if size := c.targets[kind]; size > 0 {
+		return size
+	}
+	if kind == ImageKindGIF {
+		return gifMaxSize
+	}
+	return c.maxSize
+}
+
+// CompressImage 压缩正文图片
 // 返回: 压缩后的文件路径, 是否进行了压缩, 错误
 func (c *Compressor) CompressImage(filePath string) (string, bool, error) {
-	// 检查文件是否存在
-	fileInfo, err := os.Stat(filePath)
+	return c.CompressImageAs(filePath, ImageKindBody)
+}
+
+// CompressImageAs 按图片用途压缩图片
+// fixed 模式下超过 maxSize 时以固定质量重新编码；target 模式下二分搜索 JPEG 质量，
+// 仍超出时再缩小尺寸，直到不超过该类图片的目标大小。
+// 重新编码后仍超出上限时（包括原图未超限、摆正或加水印后变大的情况），两种模式都改为搜索质量和尺寸。
+// 两种模式都会按 EXIF 方向摆正图片、转换到 sRGB，并按设置去除元数据；
+// 设置了水印时为封面和 GIF 动图以外的图片加水印。
+// 返回: 处理后的文件路径, 是否进行了处理, 错误
+func (c *Compressor) CompressImageAs(filePath string, kind ImageKind) (string, bool, error) {
+	return c.process(filePath, kind, true)
+}
+
+// Sanitize 只摆正方向、转换到 sRGB、去除元数据和加水印，不压缩（image.compress 关闭时使用）
+func (c *Compressor) Sanitize(filePath string) (string, bool, error) {
+	return c.process(filePath, ImageKindBody, false)
+}
+
+// process 处理单张图片；shrink 为 false 时不缩放、不压缩
+func (c *Compressor) process(filePath string, kind ImageKind, shrink bool) (string, bool, error) {
+	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return "", false, fmt.Errorf("stat file: %w", err)
+		return "", false, fmt.Errorf("read file: %w", err)
+	}
+	size := int64(len(data))
+
+	// GIF 动图由 Transcode 抽帧缩减，这里不压成静态图
+	// 逐帧加水印会破坏调色板优化，动图不加水印
+	if isAnimatedGIF(filePath) {
+		if kind != ImageKindCover && c.watermark != nil {
+			c.log.Warn("animated gif is not watermarked", zap.String("path", filePath))
+		}
+		c.log.Debug("animated gif, skipping compression", zap.String("path", filePath))
+		return "", false, nil
 	}
 
-	// 检查文件大小
-	if c.enableShrink && fileInfo.Size() <= c.maxSize {
+	meta := inspectMetadata(data)
+	profile, convert := needsSRGBConversion(meta.ICCProfile)
+	rotate := meta.Orientation > 1
+	limit := c.limitFor(kind)
+	oversize := shrink && c.enableShrink && size > limit
+	mark := kind != ImageKindCover && c.watermark != nil && c.watermark.Applies(orientedWidth(filePath, meta.Orientation))
+
+	if !rotate && !convert && !oversize && !mark {
+		if c.strip && meta.Private {
+			return c.writeStripped(filePath, data)
+		}
 		c.log.Debug("file size within limit, no compression needed",
-			zap.Int64("size", fileInfo.Size()),
-			zap.Int64("max", c.maxSize))
+			zap.Int64("size", size),
+			zap.Int64("max", limit))
 		return "", false, nil
 	}
 
-	// 打开图片文件
-	img, err := imaging.Open(filePath)
+	// 解码并修正方向、色彩空间
+	img, format, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
 		return "", false, fmt.Errorf("open image: %w", err)
 	}
-
-	// 获取原始尺寸
-	originalBounds := img.Bounds()
-	originalWidth := originalBounds.Dx()
-	originalHeight := originalBounds.Dy()
-
+	originalWidth, originalHeight := img.Bounds().Dx(), img.Bounds().Dy()
 	c.log.Debug("image loaded",
 		zap.String("path", filePath),
 		zap.Int("width", originalWidth),
 		zap.Int("height", originalHeight),
-		zap.Int64("size", fileInfo.Size()))
+		zap.Int64("size", size))
 
-	// 判断是否需要调整尺寸
-	var processedImg image.Image
-	needsResize := c.enableResize && originalWidth > c.maxWidth
+	if rotate {
+		img = applyOrientation(img, meta.Orientation)
+		c.log.Info("image rotated by exif orientation", zap.Int("orientation", meta.Orientation))
+	}
+	if convert {
+		img = convertToSRGB(img, profile)
+		c.log.Info("image converted to sRGB", zap.String("path", filePath))
+	}
 
-	if needsResize {
-		// 计算新的高度，保持宽高比
-		newHeight := int(float64(c.maxWidth) * float64(originalHeight) / float64(originalWidth))
-		processedImg = imaging.Resize(img, c.maxWidth, newHeight, imaging.Lanczos)
+	// 判断是否需要调整尺寸
+	if oversize && c.enableResize && img.Bounds().Dx() > c.maxWidth {
+		width, height := img.Bounds().Dx(), img.Bounds().Dy()
+		newHeight := int(float64(c.maxWidth) * float64(height) / float64(width))
+		img = imaging.Resize(img, c.maxWidth, newHeight, imaging.Lanczos)
 
 		c.log.Info("image resized",
-			zap.Int("original_width", originalWidth),
-			zap.Int("original_height", originalHeight),
+			zap.Int("original_width", width),
+			zap.Int("original_height", height),
 			zap.Int("new_width", c.maxWidth),
 			zap.Int("new_height", newHeight))
-	} else {
-		processedImg = img
 	}
 
-	// 创建临时文件保存压缩结果
-	tempDir := os.TempDir()
-	ext := filepath.Ext(filePath)
-	baseName := strings.TrimSuffix(filepath.Base(filePath), ext)
+	// 水印在摆正和缩放之后叠加，位置和大小按最终尺寸计算
+	if mark {
+		img = c.watermark.Apply(img)
+		c.log.Info("watermark applied", zap.String("path", filePath))
+	}
 
-	// 根据扩展名确定输出格式
-	var outputFormat string
-	switch strings.ToLower(ext) {
-	case ".png":
+	// 输出格式：保留 JPEG / PNG，其余转为 JPEG（带透明像素时为 PNG）
+	outputFormat := "jpeg"
+	if format == "png" || (format != "jpeg" && hasTransparency(img)) {
 		outputFormat = "png"
-	case ".jpg", ".jpeg":
-		outputFormat = "jpeg"
-	default:
-		// 默认使用 JPEG 格式以获得更好的压缩
-		outputFormat = "jpeg"
-		ext = ".jpg"
 	}
 
-	tempPath := filepath.Join(tempDir, "compressed_"+baseName+ext)
+	var encoded []byte
+	switch {
+	case oversize && c.targets != nil:
+		encoded, outputFormat = c.encodeToTarget(img, outputFormat, limit)
+	default:
+		encoded, err = c.encode(img, outputFormat, c.quality)
+		if err != nil {
+			return "", false, fmt.Errorf("save compressed image: %w", err)
+		}
+		// 摆正、转换色彩空间或加水印后重新编码可能超过上限（原图未超限时也会发生），
+		// 此时搜索质量并按需缩小尺寸
+		if shrink && c.enableShrink && int64(len(encoded)) > limit {
+			c.log.Debug("re-encoded image exceeds limit, searching quality",
+				zap.Int("size", len(encoded)),
+				zap.Int64("max", limit))
+			encoded, outputFormat = c.encodeToTarget(img, outputFormat, limit)
+		}
+	}
 
-	// 保存压缩后的图片
-	if err := c.saveImage(processedImg, tempPath, outputFormat); err != nil {
-		return "", false, fmt.Errorf("save compressed image: %w", err)
+	// 只为压缩而重新编码、结果反而变大时，使用原图
+	if !rotate && !convert && !mark && int64(len(encoded)) >= size {
+		c.log.Debug("compressed image larger than original, using original")
+		if c.strip && meta.Private {
+			return c.writeStripped(filePath, data)
+		}
+		return "", false, nil
 	}
 
-	// 检查压缩后的大小
-	newFileInfo, err := os.Stat(tempPath)
+	ext := ".jpg"
+	if outputFormat == "png" {
+		ext = ".png"
+	}
+	tempPath, err := writeProcessedImage(filePath, encoded, ext)
 	if err != nil {
-		os.Remove(tempPath)
-		return "", false, fmt.Errorf("stat compressed file: %w", err)
+		return "", false, err
 	}
 
-	compressionRatio := float64(newFileInfo.Size()) / float64(fileInfo.Size()) * 100
-
 	c.log.Info("image compressed",
-		zap.Int64("original_size", fileInfo.Size()),
-		zap.Int64("compressed_size", newFileInfo.Size()),
-		zap.Float64("ratio", compressionRatio),
+		zap.Int64("original_size", size),
+		zap.Int("compressed_size", len(encoded)),
+		zap.Float64("ratio", float64(len(encoded))/float64(size)*100),
 		zap.String("output_path", tempPath))
 
-	// 如果压缩后反而变大，删除临时文件并返回原路径
-	if newFileInfo.Size() >= fileInfo.Size() {
-		os.Remove(tempPath)
-		c.log.Debug("compressed image larger than original, using original")
-		return "", false, nil
-	}
-
 	return tempPath, true, nil
 }
 
-// saveImage 保存图片到文件
-func (c *Compressor) saveImage(img image.Image, filePath, format string) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
+// encodeToTarget 二分搜索 JPEG 质量；最低质量仍超出时缩小尺寸后重试
+// 无透明像素的 PNG 超出目标时改为 JPEG。返回编码结果和实际格式
+func (c *Compressor) encodeToTarget(img image.Image, format string, limit int64) ([]byte, string) {
+	var smallest []byte
+	for attempt := 0; ; attempt++ {
+		var (
+			data []byte
+			ok   bool
+		)
+		if format == "png" {
+			data, _ = c.encode(img, "png", 0)
+			ok = int64(len(data)) <= limit
+			if !ok && !hasTransparency(img) {
+				format = "jpeg"
+			}
+		}
+		if format == "jpeg" {
+			data, ok = c.searchQuality(img, limit)
+		}
+		if smallest == nil || len(data) < len(smallest) {
+			smallest = data
+		}
+		if ok {
+			return data, format
+		}
+		if attempt == targetMaxAttempts {
+			c.log.Warn("image still exceeds target size after resizing",
+				zap.Int("size", len(smallest)),
+				zap.Int64("target", limit))
+			return smallest, format
+		}
+
+		// 按面积估算缩小比例
+		scale := math.Sqrt(float64(limit)/float64(len(data))) * 0.95
+		scale = math.Max(targetMinScale, math.Min(targetMaxScale, scale))
+		width := max(1, int(float64(img.Bounds().Dx())*scale))
+		img = imaging.Resize(img, width, 0, imaging.Lanczos)
+		c.log.Debug("image resized to reach target size",
+			zap.Int("width", width),
+			zap.Int64("target", limit))
 	}
-	defer file.Close()
+}
+
+// searchQuality 二分搜索不超过 limit 的最高 JPEG 质量
+// 找不到时返回最低质量的结果和 false
+func (c *Compressor) searchQuality(img image.Image, limit int64) ([]byte, bool) {
+	lo, hi := targetMinQuality, c.quality
+	var best, lowest []byte
+	for lo <= hi {
+		q := (lo + hi) / 2
+		data, err := c.encode(img, "jpeg", q)
+		if err != nil {
+			return nil, false
+		}
+		if q == targetMinQuality {
+			lowest = data
+		}
+		if int64(len(data)) <= limit {
+			best = data
+			lo = q + 1
+		} else {
+			hi = q - 1
+		}
+	}
+	if best != nil {
+		return best, true
+	}
+	if lowest == nil {
+		lowest, _ = c.encode(img, "jpeg", targetMinQuality)
+	}
+	return lowest, false
+}
 
+// encode 编码图片
+func (c *Compressor) encode(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
 	switch format {
-	case "jpeg", "jpg":
-		return jpeg.Encode(file, img, &jpeg.Options{Quality: c.quality})
 	case "png":
 		encoder := png.Encoder{CompressionLevel: png.DefaultCompression}
-		return encoder.Encode(file, img)
+		err = encoder.Encode(&buf, img)
+	default:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	}
+	return buf.Bytes(), err
+}
+
+// writeStripped 写入去除元数据后的原图（无损）
+func (c *Compressor) writeStripped(filePath string, data []byte) (string, bool, error) {
+	stripped := stripMetadata(data)
+	if len(stripped) == len(data) {
+		return "", false, nil
+	}
+	path, err := writeProcessedImage(filePath, stripped, filepath.Ext(filePath))
+	if err != nil {
+		return "", false, err
+	}
+	c.log.Info("image metadata stripped",
+		zap.String("path", filePath),
+		zap.Int("removed_bytes", len(data)-len(stripped)))
+	return path, true, nil
+}
+
+// writeProcessedImage 写入独立的临时文件，并发处理同名图片时互不覆盖
+func writeProcessedImage(filePath string, data []byte, ext string) (string, error) {
+	baseName := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	tempFile, err := os.CreateTemp("", "compressed_"+baseName+"_*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+	defer tempFile.Close()
+	if _, err := tempFile.Write(data); err != nil {
+		os.Remove(tempFile.Name())
+		return "", fmt.Errorf("save compressed image: %w", err)
+	}
+	return tempFile.Name(), nil
+}
+
+// applyOrientation 按 EXIF 方向摆正图片
+func applyOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate90(img)
 	default:
-		// 默认使用 JPEG
-		return jpeg.Encode(file, img, &jpeg.Options{Quality: c.quality})
+		return img
 	}
 }
 
@@ -209,6 +461,8 @@ func IsValidImageFormat(filePath string) bool {
 		".gif":  true,
 		".bmp":  true,
 		".webp": true,
+		".tif":  true,
+		".tiff": true,
 	}
 
 	ext := strings.ToLower(filepath.Ext(filePath))
@@ -260,3 +514,15 @@ func NeedsCompression(info *ImageInfo, maxWidth int, maxSize int64) bool {
 	}
 	return false
 }
+
+// orientedWidth 返回按 EXIF 方向摆正后的图片宽度，无法读取时返回 0
+func orientedWidth(path string, orientation int) int {
+	width, height, err := GetImageDimensions(path)
+	if err != nil {
+		return 0
+	}
+	if orientation >= 5 && orientation <= 8 { // 旋转 90° 的方向宽高互换
+		return height
+	}
+	return width
+}
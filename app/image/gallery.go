@@ -0,0 +1,306 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// galleryDirName 图片库目录名（位于数据目录下，所有账号共享）
+const galleryDirName = "gallery"
+
+// galleryIDLen 图片 ID 长度（内容 SHA-256 的前缀）
+const galleryIDLen = 12
+
+// GalleryEntry 图片库中一张 AI 生成图片的元数据，保存为图片旁的 <id>.json
+type GalleryEntry struct {
+	ID            string `json:"id"`
+	File          string `json:"file"` // 图片文件名
+	Prompt        string `json:"prompt"`
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
+	Provider      string `json:"provider,omitempty"`
+	Model         string `json:"model,omitempty"`
+	Size          string `json:"size,omitempty"`
+	Seed          int64  `json:"seed,omitempty"`
+	Article       string `json:"article,omitempty"`      // 生成时所在的文章
+	OriginalURL   string `json:"original_url,omitempty"` // 服务返回的图片地址（可能已过期）
+	// MediaIDs 各账号最近一次上传的素材 ID（素材 ID 只在所属账号内有效）
+	MediaIDs  map[string]string `json:"media_ids,omitempty"`
+	Bytes     int64             `json:"bytes"`
+	CreatedAt time.Time         `json:"created_at"`
+
+	// 旧版本只记录一个账号的素材，读取时迁移到 MediaIDs
+	LegacyMediaID string `json:"media_id,omitempty"`
+	LegacyAccount string `json:"account,omitempty"`
+}
+
+// MediaID 返回账号最近一次上传的素材 ID
+func (e *GalleryEntry) MediaID(accountID string) string {
+	return e.MediaIDs[accountID]
+}
+
+// galleryMu 串行化元数据的读-改-写：批量处理的多个 worker、多账号发布可能同时更新同一张图片
+var galleryMu sync.Mutex
+
+// Gallery 本地图片库，按内容哈希保存 AI 生成的图片，供之后通过 gallery:<id> 复用
+type Gallery struct {
+	dir string
+}
+
+// OpenGallery 打开数据目录下的图片库（目录在首次保存时创建）
+func OpenGallery(dataDir string) *Gallery {
+	return &Gallery{dir: filepath.Join(dataDir, galleryDirName)}
+}
+
+// Dir 返回图片库目录
+func (g *Gallery) Dir() string {
+	return g.dir
+}
+
+// Path 返回图片文件路径
+func (g *Gallery) Path(e *GalleryEntry) string {
+	return filepath.Join(g.dir, e.File)
+}
+
+// Save 保存图片及元数据，返回写入的条目
+// 相同内容已存在时保留原 ID 和创建时间，只更新元数据中非空的字段
+func (g *Gallery) Save(data []byte, meta GalleryEntry) (*GalleryEntry, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty image data")
+	}
+	if err := os.MkdirAll(g.dir, 0755); err != nil {
+		return nil, fmt.Errorf("create gallery directory: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:])[:galleryIDLen]
+
+	galleryMu.Lock()
+	defer galleryMu.Unlock()
+
+	entry := &meta
+	if existing, err := g.load(id); err == nil {
+		entry = mergeGalleryEntry(existing, &meta)
+	} else {
+		entry.CreatedAt = time.Now()
+	}
+	entry.ID = id
+	entry.File = id + imageExtension(data)
+	entry.Bytes = int64(len(data))
+
+	if err := writeFileAtomic(g.Path(entry), data); err != nil {
+		return nil, fmt.Errorf("write gallery image: %w", err)
+	}
+	if err := g.write(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Update 写入条目的元数据
+func (g *Gallery) Update(e *GalleryEntry) error {
+	galleryMu.Lock()
+	defer galleryMu.Unlock()
+	return g.write(e)
+}
+
+// RecordUpload 记录图片在账号中最近一次上传的素材 ID，返回更新后的条目
+func (g *Gallery) RecordUpload(id, accountID, mediaID string) (*GalleryEntry, error) {
+	galleryMu.Lock()
+	defer galleryMu.Unlock()
+
+	e, err := g.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.MediaIDs == nil {
+		e.MediaIDs = make(map[string]string)
+	}
+	e.MediaIDs[accountID] = mediaID
+	if err := g.write(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// write 写入条目的元数据（调用方需持有 galleryMu）
+func (g *Gallery) write(e *GalleryEntry) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal gallery entry: %w", err)
+	}
+	if err := writeFileAtomic(g.sidecar(e.ID), data); err != nil {
+		return fmt.Errorf("write gallery entry: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic 先写同目录下的临时文件再重命名，读取方不会看到写了一半的文件
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Get 按 ID 查找图片，支持唯一的 ID 前缀（至少 4 位）
+func (g *Gallery) Get(id string) (*GalleryEntry, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if !isGalleryID(id) {
+		return nil, fmt.Errorf("invalid gallery id: %q", id)
+	}
+	if e, err := g.load(id); err == nil {
+		return e, nil
+	}
+	if len(id) < 4 {
+		return nil, fmt.Errorf("gallery image not found: %s", id)
+	}
+
+	entries, err := g.List()
+	if err != nil {
+		return nil, err
+	}
+	var matches []GalleryEntry
+	for _, e := range entries {
+		if strings.HasPrefix(e.ID, id) {
+			matches = append(matches, e)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("gallery image not found: %s", id)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, fmt.Errorf("gallery id %s is ambiguous (%d matches)", id, len(matches))
+	}
+}
+
+// List 返回所有图片，按创建时间倒序；图片库不存在时返回空列表
+func (g *Gallery) List() ([]GalleryEntry, error) {
+	files, err := os.ReadDir(g.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read gallery: %w", err)
+	}
+
+	var entries []GalleryEntry
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		e, err := g.load(strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil {
+			continue // 跳过损坏的元数据
+		}
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// load 读取元数据
+func (g *Gallery) load(id string) (*GalleryEntry, error) {
+	data, err := os.ReadFile(g.sidecar(id))
+	if err != nil {
+		return nil, err
+	}
+	var e GalleryEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("parse gallery entry %s: %w", id, err)
+	}
+	if e.LegacyMediaID != "" && e.LegacyAccount != "" {
+		if e.MediaIDs == nil {
+			e.MediaIDs = make(map[string]string)
+		}
+		if _, ok := e.MediaIDs[e.LegacyAccount]; !ok {
+			e.MediaIDs[e.LegacyAccount] = e.LegacyMediaID
+		}
+	}
+	e.LegacyMediaID, e.LegacyAccount = "", ""
+	return &e, nil
+}
+
+// isGalleryID ID 只能是十六进制字符（防止拼出图片库之外的路径）
+func isGalleryID(id string) bool {
+	if id == "" || len(id) > galleryIDLen {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *Gallery) sidecar(id string) string {
+	return filepath.Join(g.dir, id+".json")
+}
+
+// mergeGalleryEntry 用新元数据中的非空字段更新已有条目
+func mergeGalleryEntry(existing, meta *GalleryEntry) *GalleryEntry {
+	merged := *existing
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&merged.Prompt, meta.Prompt)
+	set(&merged.RevisedPrompt, meta.RevisedPrompt)
+	set(&merged.Provider, meta.Provider)
+	set(&merged.Model, meta.Model)
+	set(&merged.Size, meta.Size)
+	set(&merged.Article, meta.Article)
+	set(&merged.OriginalURL, meta.OriginalURL)
+	if meta.Seed != 0 {
+		merged.Seed = meta.Seed
+	}
+	return &merged
+}
+
+// imageExtension 按内容识别图片扩展名
+func imageExtension(data []byte) string {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "image/bmp":
+		return ".bmp"
+	default:
+		return ".png"
+	}
+}
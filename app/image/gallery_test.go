@@ -0,0 +1,169 @@
+package image
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGallerySaveAndGet(t *testing.T) {
+	g := OpenGallery(t.TempDir())
+	data, err := os.ReadFile(writeTestPNG(t, "a.png", color.RGBA{R: 255, A: 255}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := g.Save(data, GalleryEntry{Prompt: "a red square", Provider: "OpenAI", Seed: 42})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if len(entry.ID) != galleryIDLen || entry.File != entry.ID+".png" || entry.Bytes != int64(len(data)) {
+		t.Errorf("entry = %+v", entry)
+	}
+	if _, err := os.Stat(g.Path(entry)); err != nil {
+		t.Errorf("image file missing: %v", err)
+	}
+
+	got, err := g.Get(entry.ID[:6])
+	if err != nil {
+		t.Fatalf("Get(prefix) error = %v", err)
+	}
+	if got.Prompt != "a red square" || got.Seed != 42 {
+		t.Errorf("Get() = %+v", got)
+	}
+
+	// 相同内容再次保存：保留 ID 和创建时间，补充新字段
+	again, err := g.Save(data, GalleryEntry{Article: "post.md"})
+	if err != nil {
+		t.Fatalf("Save() again error = %v", err)
+	}
+	if again.ID != entry.ID || !again.CreatedAt.Equal(entry.CreatedAt) {
+		t.Errorf("resave changed identity: %+v", again)
+	}
+	if again.Prompt != "a red square" || again.Article != "post.md" {
+		t.Errorf("resave did not merge metadata: %+v", again)
+	}
+
+	for _, id := range []string{"../etc", "zz", "", "0000"} {
+		if _, err := g.Get(id); err == nil {
+			t.Errorf("Get(%q) expected error", id)
+		}
+	}
+}
+
+func TestGalleryListNewestFirst(t *testing.T) {
+	g := OpenGallery(t.TempDir())
+	if entries, err := g.List(); err != nil || len(entries) != 0 {
+		t.Fatalf("List() on missing gallery = %v, %v", entries, err)
+	}
+
+	var ids []string
+	for i, c := range []color.Color{color.White, color.Black} {
+		data, err := os.ReadFile(writeTestPNG(t, "x.png", c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := g.Save(data, GalleryEntry{Prompt: "img"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.CreatedAt = time.Now().Add(time.Duration(i) * time.Hour)
+		if err := g.Update(e); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, e.ID)
+	}
+
+	entries, err := g.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != ids[1] || entries[1].ID != ids[0] {
+		t.Errorf("List() order = %+v, want newest first", entries)
+	}
+}
+
+func TestProcessorRecordsGeneratedImagesInGallery(t *testing.T) {
+	p, srv := newMockProcessor(t)
+	data, err := os.ReadFile(writeTestPNG(t, "gen.png", color.RGBA{B: 255, A: 255}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.cfg.ImageAPIKey = "test-key"
+	p.provider = &bytesProvider{data: data}
+	p.SetArticle("posts/blue.md")
+
+	res, err := p.GenerateAndUpload("a blue square")
+	if err != nil {
+		t.Fatalf("GenerateAndUpload() error = %v", err)
+	}
+	if res.GalleryID == "" {
+		t.Fatal("GalleryID is empty")
+	}
+
+	entry, err := p.Gallery().Get(res.GalleryID)
+	if err != nil {
+		t.Fatalf("Gallery().Get() error = %v", err)
+	}
+	if entry.Prompt != "a blue square" || entry.Article != "posts/blue.md" || entry.MediaID("mock") != res.MediaID {
+		t.Errorf("gallery entry = %+v", entry)
+	}
+
+	// 复用时命中上传缓存，不再上传
+	up, err := p.UploadFromGallery(res.GalleryID)
+	if err != nil {
+		t.Fatalf("UploadFromGallery() error = %v", err)
+	}
+	if up.MediaID != res.MediaID {
+		t.Errorf("reuse media_id = %s, want %s", up.MediaID, res.MediaID)
+	}
+	if n := len(srv.Materials()); n != 1 {
+		t.Errorf("materials on mock server = %d, want 1", n)
+	}
+}
+
+func TestGalleryRecordUploadPerAccount(t *testing.T) {
+	g := OpenGallery(t.TempDir())
+	data, err := os.ReadFile(writeTestPNG(t, "x.png", color.White))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := g.Save(data, GalleryEntry{Prompt: "img"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 多个 worker 同时为不同账号记录上传结果，互不覆盖
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			account := fmt.Sprintf("acc%d", i)
+			if _, err := g.RecordUpload(e.ID, account, "media-"+account); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	got, err := g.Get(e.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(got.MediaIDs) != 20 || got.MediaID("acc7") != "media-acc7" {
+		t.Errorf("MediaIDs = %v, want one per account", got.MediaIDs)
+	}
+
+	// 旧版本的 media_id / account 字段迁移到 MediaIDs
+	legacy := `{"id":"` + e.ID + `","file":"` + e.File + `","media_id":"old","account":"brand"}`
+	if err := os.WriteFile(g.sidecar(e.ID), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := g.Get(e.ID); err != nil || got.MediaID("brand") != "old" {
+		t.Errorf("legacy entry = %+v, %v", got, err)
+	}
+}
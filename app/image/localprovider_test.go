@@ -0,0 +1,205 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/config"
+)
+
+var fakePNG = []byte("\x89PNG\r\n\x1a\nfake-image")
+
+func TestSDWebUIProviderGenerate(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sdapi/v1/txt2img" {
+			t.Errorf("path = %s, want /sdapi/v1/txt2img", r.URL.Path)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			t.Errorf("basic auth = %q/%q, want admin/secret", user, pass)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		encoded := base64.StdEncoding.EncodeToString(fakePNG)
+		json.NewEncoder(w).Encode(map[string]any{
+			"images": []string{encoded, "data:image/png;base64," + encoded},
+		})
+	}))
+	defer server.Close()
+
+	p, err := NewProvider(&config.Config{
+		ImageProvider: "sdwebui",
+		ImageAPIBase:  server.URL + "/",
+		ImageAPIKey:   "admin:secret",
+		ImageModel:    "sdxl_base.safetensors",
+		ImageSize:     "1024x1024",
+	})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	res, err := p.Generate(context.Background(), "a cup of tea", GenerateOptions{
+		AspectRatio:    "16:9",
+		NegativePrompt: "text",
+		Seed:           42,
+		Count:          2,
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if got["prompt"] != "a cup of tea" || got["negative_prompt"] != "text" {
+		t.Errorf("prompt fields = %v / %v", got["prompt"], got["negative_prompt"])
+	}
+	if got["width"] != float64(1360) || got["height"] != float64(768) {
+		t.Errorf("size = %vx%v, want 1360x768", got["width"], got["height"])
+	}
+	if got["seed"] != float64(42) || got["batch_size"] != float64(2) {
+		t.Errorf("seed/batch_size = %v/%v, want 42/2", got["seed"], got["batch_size"])
+	}
+	override, _ := got["override_settings"].(map[string]any)
+	if override["sd_model_checkpoint"] != "sdxl_base.safetensors" {
+		t.Errorf("override_settings = %v", got["override_settings"])
+	}
+
+	if res.URL != "" {
+		t.Errorf("URL = %q, want empty", res.URL)
+	}
+	if !bytes.Equal(res.Data, fakePNG) || len(res.Images) != 2 || !bytes.Equal(res.Images[1], fakePNG) {
+		t.Errorf("images not decoded: data=%q images=%d", res.Data, len(res.Images))
+	}
+	if res.Size != "1360x768" {
+		t.Errorf("Size = %q, want 1360x768", res.Size)
+	}
+}
+
+func TestComfyUIProviderGenerate(t *testing.T) {
+	workflow := `{
+		"3": {"class_type": "KSampler", "inputs": {"seed": "{{seed}}", "steps": 20}},
+		"5": {"class_type": "EmptyLatentImage", "inputs": {"width": "{{width}}", "height": "{{height}}", "batch_size": "{{count}}"}},
+		"6": {"class_type": "CLIPTextEncode", "inputs": {"text": "masterpiece, {{prompt}}"}},
+		"7": {"class_type": "CLIPTextEncode", "inputs": {"text": "{{negative_prompt}}"}}
+	}`
+	path := filepath.Join(t.TempDir(), "workflow.json")
+	if err := os.WriteFile(path, []byte(workflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var queued struct {
+		Prompt map[string]struct {
+			Inputs map[string]any `json:"inputs"`
+		} `json:"prompt"`
+	}
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/prompt", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&queued)
+		w.Write([]byte(`{"prompt_id":"p1","number":1}`))
+	})
+	mux.HandleFunc("/history/p1", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls == 1 {
+			w.Write([]byte(`{}`)) // 仍在队列中
+			return
+		}
+		w.Write([]byte(`{"p1":{"status":{"status_str":"success","completed":true},"outputs":{"9":{"images":[
+			{"filename":"out_00001_.png","subfolder":"","type":"output"},
+			{"filename":"preview.png","subfolder":"","type":"temp"}]}}}}`))
+	})
+	mux.HandleFunc("/view", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("filename") != "out_00001_.png" || r.URL.Query().Get("type") != "output" {
+			t.Errorf("view query = %s", r.URL.RawQuery)
+		}
+		w.Write(fakePNG)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := NewComfyUIProvider(&config.Config{
+		ImageProvider: "comfyui",
+		ImageAPIBase:  server.URL,
+		ImageWorkflow: path,
+		ImageSize:     "1024x1024",
+	})
+	if err != nil {
+		t.Fatalf("NewComfyUIProvider() error = %v", err)
+	}
+	p.pollInterval = 10 * time.Millisecond
+
+	res, err := p.Generate(context.Background(), "a cup of tea", GenerateOptions{
+		Size:           "768x512",
+		NegativePrompt: "text",
+		Seed:           7,
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if v := queued.Prompt["6"].Inputs["text"]; v != "masterpiece, a cup of tea" {
+		t.Errorf("prompt text = %v", v)
+	}
+	if v := queued.Prompt["7"].Inputs["text"]; v != "text" {
+		t.Errorf("negative text = %v", v)
+	}
+	if v := queued.Prompt["3"].Inputs["seed"]; v != float64(7) {
+		t.Errorf("seed = %v, want 7", v)
+	}
+	latent := queued.Prompt["5"].Inputs
+	if latent["width"] != float64(768) || latent["height"] != float64(512) || latent["batch_size"] != float64(1) {
+		t.Errorf("latent inputs = %v", latent)
+	}
+
+	if len(res.Images) != 1 || !bytes.Equal(res.Data, fakePNG) {
+		t.Errorf("images = %d, data = %q; want only the output image", len(res.Images), res.Data)
+	}
+}
+
+func TestNewComfyUIProviderRequiresWorkflow(t *testing.T) {
+	if _, err := NewComfyUIProvider(&config.Config{ImageProvider: "comfyui"}); err == nil {
+		t.Fatal("expected error without image_workflow")
+	}
+
+	path := filepath.Join(t.TempDir(), "workflow.json")
+	os.WriteFile(path, []byte(`{"6":{"inputs":{"text":"fixed"}}}`), 0o644)
+	if _, err := NewComfyUIProvider(&config.Config{ImageProvider: "comfyui", ImageWorkflow: path}); err == nil {
+		t.Fatal("expected error for workflow without {{prompt}}")
+	}
+}
+
+// bytesProvider 直接返回图片内容的测试 Provider
+type bytesProvider struct{ data []byte }
+
+func (b *bytesProvider) Name() string { return "Bytes" }
+
+func (b *bytesProvider) Generate(ctx context.Context, prompt string, opts GenerateOptions) (*GenerateResult, error) {
+	return &GenerateResult{Data: b.data, Images: [][]byte{b.data}, Size: "32x32"}, nil
+}
+
+func TestProcessorUploadsGeneratedBytes(t *testing.T) {
+	p, srv := newMockProcessor(t)
+	data, err := os.ReadFile(writeTestPNG(t, "gen.png", color.RGBA{R: 255, G: 255, A: 255}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.cfg.ImageAPIKey = "test-key"
+	p.provider = &bytesProvider{data: data}
+
+	res, err := p.GenerateAndUpload("a yellow square")
+	if err != nil {
+		t.Fatalf("GenerateAndUpload() error = %v", err)
+	}
+	if res.MediaID == "" || res.OriginalURL != "" {
+		t.Errorf("result = %+v, want media_id and no original URL", res)
+	}
+	if n := len(srv.Materials()); n != 1 {
+		t.Errorf("materials on mock server = %d, want 1", n)
+	}
+}
@@ -0,0 +1,63 @@
+package image
+
+import (
+	"fmt"
+
+	"github.com/royalrick/wechatwriter/app/media"
+	"go.uber.org/zap"
+)
+
+// UploadMedia 检查并上传视频/语音素材，返回素材 ID
+// 与图片共用账号的上传缓存，键加素材类型前缀区分；相同内容不重复上传
+func (p *Processor) UploadMedia(info *media.Info, title, introduction string) (string, error) {
+	if p.ws == nil {
+		return "", fmt.Errorf("未配置微信公众号账号，无法上传素材")
+	}
+	if err := info.Validate(); err != nil {
+		return "", err
+	}
+
+	var key string
+	if p.cache != nil {
+		hash, _, err := HashFile(info.Path)
+		if err != nil {
+			p.log.Warn("hash media failed, skipping upload cache", zap.Error(err))
+		} else {
+			key = string(info.Kind) + ":" + hash
+			if entry, ok := p.cache.Lookup(key); ok {
+				p.log.Info("upload cache hit, skipping upload", zap.String("source", info.Path))
+				return entry.MediaID, nil
+			}
+		}
+	}
+
+	var mediaID string
+	switch info.Kind {
+	case media.KindVideo:
+		result, err := p.ws.UploadVideo(info.Path, title, introduction)
+		if err != nil {
+			return "", err
+		}
+		mediaID = result.MediaID
+	case media.KindVoice:
+		result, err := p.ws.UploadVoice(info.Path)
+		if err != nil {
+			return "", err
+		}
+		mediaID = result.MediaID
+	default:
+		return "", fmt.Errorf("unsupported media kind: %s", info.Kind)
+	}
+
+	if p.cache != nil && key != "" {
+		if err := p.cache.Store(UploadCacheEntry{
+			Hash:    key,
+			MediaID: mediaID,
+			Source:  info.Path,
+			Size:    info.Size,
+		}); err != nil {
+			p.log.Warn("save upload cache failed", zap.Error(err))
+		}
+	}
+	return mediaID, nil
+}
@@ -0,0 +1,260 @@
+package image
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+)
+
+// imageMetadata 上传前需要处理的图片元数据
+type imageMetadata struct {
+	Orientation int    // EXIF 方向（1 为正常，0 为未知）
+	ICCProfile  []byte // 内嵌的 ICC 色彩配置
+	Private     bool   // 含有 EXIF / GPS / XMP / IPTC / 文本注释等可能泄露隐私的元数据
+}
+
+// inspectMetadata 读取 JPEG / PNG 中的元数据；其他格式返回空结果
+func inspectMetadata(data []byte) imageMetadata {
+	switch {
+	case isJPEG(data):
+		return inspectJPEG(data)
+	case isPNG(data):
+		return inspectPNG(data)
+	default:
+		return imageMetadata{}
+	}
+}
+
+// stripMetadata 无损去除 JPEG / PNG 中的隐私元数据，像素数据保持不变
+func stripMetadata(data []byte) []byte {
+	switch {
+	case isJPEG(data):
+		return stripJPEG(data)
+	case isPNG(data):
+		return stripPNG(data)
+	default:
+		return data
+	}
+}
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+	exifHeader   = []byte("Exif\x00\x00")
+	iccHeader    = []byte("ICC_PROFILE\x00")
+)
+
+func isJPEG(data []byte) bool {
+	return len(data) > 3 && data[0] == 0xFF && data[1] == 0xD8
+}
+
+func isPNG(data []byte) bool {
+	return bytes.HasPrefix(data, pngSignature)
+}
+
+// JPEG 标记
+const (
+	jpegMarkerSOS  = 0xDA
+	jpegMarkerAPP1 = 0xE1 // EXIF / XMP
+	jpegMarkerAPP2 = 0xE2 // ICC
+	jpegMarkerAPPD = 0xED // Photoshop / IPTC
+	jpegMarkerCOM  = 0xFE
+)
+
+// jpegSegment 一个 JPEG 段（不含 SOS 之后的图像数据）
+type jpegSegment struct {
+	marker  byte
+	start   int // 包含 0xFF 标记在内的起始位置
+	end     int
+	payload []byte
+}
+
+// jpegSegments 遍历 SOS 之前的所有段，返回各段和图像数据的起始位置
+func jpegSegments(data []byte) ([]jpegSegment, int) {
+	var segs []jpegSegment
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return segs, -1
+		}
+		marker := data[pos+1]
+		if marker == 0xFF { // 填充字节
+			pos++
+			continue
+		}
+		if marker == jpegMarkerSOS {
+			return segs, pos
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return segs, -1
+		}
+		segs = append(segs, jpegSegment{marker: marker, start: pos, end: end, payload: data[pos+4 : end]})
+		pos = end
+	}
+	return segs, -1
+}
+
+func inspectJPEG(data []byte) imageMetadata {
+	var meta imageMetadata
+	segs, _ := jpegSegments(data)
+
+	// ICC 配置可能分成多段，按序号拼接
+	iccChunks := map[int][]byte{}
+	iccCount := 0
+	for _, s := range segs {
+		switch s.marker {
+		case jpegMarkerAPP1:
+			meta.Private = true
+			if bytes.HasPrefix(s.payload, exifHeader) {
+				meta.Orientation = exifOrientation(s.payload[len(exifHeader):])
+			}
+		case jpegMarkerAPPD, jpegMarkerCOM:
+			meta.Private = true
+		case jpegMarkerAPP2:
+			if bytes.HasPrefix(s.payload, iccHeader) && len(s.payload) > len(iccHeader)+2 {
+				seq := int(s.payload[len(iccHeader)])
+				iccCount = int(s.payload[len(iccHeader)+1])
+				iccChunks[seq] = s.payload[len(iccHeader)+2:]
+			}
+		}
+	}
+	if iccCount > 0 && len(iccChunks) == iccCount {
+		for i := 1; i <= iccCount; i++ {
+			meta.ICCProfile = append(meta.ICCProfile, iccChunks[i]...)
+		}
+	}
+	return meta
+}
+
+// stripJPEG 去掉 APP1（EXIF/XMP）、APP13（IPTC）和注释段，保留 JFIF、ICC、Adobe 等影响解码的段
+func stripJPEG(data []byte) []byte {
+	segs, sos := jpegSegments(data)
+	if sos < 0 {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	for _, s := range segs {
+		switch s.marker {
+		case jpegMarkerAPP1, jpegMarkerAPPD, jpegMarkerCOM:
+			continue
+		}
+		out = append(out, data[s.start:s.end]...)
+	}
+	return append(out, data[sos:]...)
+}
+
+// exifOrientation 从 EXIF（TIFF 结构）的 IFD0 中读取方向标签 0x0112
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			v := int(order.Uint16(tiff[entry+8:]))
+			if v < 1 || v > 8 {
+				return 0
+			}
+			return v
+		}
+	}
+	return 0
+}
+
+// pngPrivateChunks 可能泄露隐私的 PNG 块
+var pngPrivateChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"iTXt": true,
+	"zTXt": true,
+	"tIME": true,
+}
+
+// pngChunks 遍历 PNG 块，对每块调用 fn(类型, 完整块数据, 块内容)
+// 返回是否完整读到 IEND
+func pngChunks(data []byte, fn func(typ string, chunk, body []byte)) bool {
+	pos := len(pngSignature)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return false
+		}
+		typ := string(data[pos+4 : pos+8])
+		fn(typ, data[pos:end], data[pos+8:pos+8+length])
+		if typ == "IEND" {
+			return true
+		}
+		pos = end
+	}
+	return false
+}
+
+func inspectPNG(data []byte) imageMetadata {
+	var meta imageMetadata
+	pngChunks(data, func(typ string, _, body []byte) {
+		switch {
+		case pngPrivateChunks[typ]:
+			meta.Private = true
+			if typ == "eXIf" {
+				meta.Orientation = exifOrientation(body)
+			}
+		case typ == "iCCP":
+			meta.ICCProfile = decodeICCP(body)
+		}
+	})
+	return meta
+}
+
+// stripPNG 去掉 eXIf 和文本类块
+func stripPNG(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	complete := pngChunks(data, func(typ string, chunk, _ []byte) {
+		if !pngPrivateChunks[typ] {
+			out = append(out, chunk...)
+		}
+	})
+	if !complete {
+		return data // 结构异常时不改动
+	}
+	return out
+}
+
+// decodeICCP 解压 iCCP 块中的 ICC 配置（名称\0 + 压缩方式 + zlib 数据）
+func decodeICCP(body []byte) []byte {
+	i := bytes.IndexByte(body, 0)
+	if i < 0 || i+2 > len(body) {
+		return nil
+	}
+	r, err := zlib.NewReader(bytes.NewReader(body[i+2:]))
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+	profile, err := io.ReadAll(r)
+	if err != nil {
+		return nil
+	}
+	return profile
+}
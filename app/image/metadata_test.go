@@ -0,0 +1,233 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// exifSegment 构造带方向标签的 APP1 段
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)      // 1 个条目
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112) // Orientation
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)      // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	seg := []byte{0xFF, jpegMarkerAPP1}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+// writeJPEGWithExif 写入 40x20 的 JPEG，SOI 之后插入 EXIF 段
+func writeJPEGWithExif(t *testing.T, orientation uint16) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for i := range img.Pix {
+		img.Pix[i] = 0xC0
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := append([]byte{0xFF, 0xD8}, exifSegment(orientation)...)
+	data = append(data, buf.Bytes()[2:]...)
+
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCompressorRotatesByExifAndStripsMetadata(t *testing.T) {
+	c := NewCompressor(zap.NewNop(), 1920, 5*1024*1024)
+	c.SetStripMetadata(true)
+
+	out, ok, err := c.CompressImage(writeJPEGWithExif(t, 6))
+	if err != nil || !ok {
+		t.Fatalf("CompressImage() = %q, %v, %v", out, ok, err)
+	}
+	defer os.Remove(out)
+
+	w, h, err := GetImageDimensions(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w != 20 || h != 40 {
+		t.Errorf("dimensions = %dx%d, want 20x40 (rotated)", w, h)
+	}
+	data, _ := os.ReadFile(out)
+	if meta := inspectMetadata(data); meta.Private || meta.Orientation != 0 {
+		t.Errorf("metadata after processing = %+v, want none", meta)
+	}
+}
+
+func TestCompressorRotatedImageStaysWithinLimit(t *testing.T) {
+	// 噪点图以较低质量保存，原图略低于上限，摆正后按默认质量重新编码会超出
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rng.Intn(256))
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 60}); err != nil {
+		t.Fatal(err)
+	}
+	data := append([]byte{0xFF, 0xD8}, exifSegment(6)...)
+	data = append(data, buf.Bytes()[2:]...)
+	path := filepath.Join(t.TempDir(), "rotated.jpg")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	limit := int64(len(data)) + 64
+	c := NewCompressor(zap.NewNop(), 1920, limit)
+	if reencoded, _ := c.encode(applyOrientation(img, 6), "jpeg", c.quality); int64(len(reencoded)) <= limit {
+		t.Fatalf("re-encoded size = %d, test needs it above %d", len(reencoded), limit)
+	}
+
+	out, ok, err := c.CompressImage(path)
+	if err != nil || !ok {
+		t.Fatalf("CompressImage() = %q, %v, %v", out, ok, err)
+	}
+	defer os.Remove(out)
+
+	info, _ := os.Stat(out)
+	if info.Size() > limit {
+		t.Errorf("size = %d, want <= %d", info.Size(), limit)
+	}
+	if w, h, _ := GetImageDimensions(out); w != 100 || h != 200 {
+		t.Errorf("dimensions = %dx%d, want 100x200 (rotated)", w, h)
+	}
+}
+
+func TestCompressorSanitizeStripsWithoutReencoding(t *testing.T) {
+	c := NewCompressor(zap.NewNop(), 1920, 5*1024*1024)
+	c.SetStripMetadata(true)
+	path := writeJPEGWithExif(t, 1)
+	orig, _ := os.ReadFile(path)
+
+	out, ok, err := c.Sanitize(path)
+	if err != nil || !ok {
+		t.Fatalf("Sanitize() = %q, %v, %v", out, ok, err)
+	}
+	defer os.Remove(out)
+
+	data, _ := os.ReadFile(out)
+	if want := len(orig) - len(exifSegment(1)); len(data) != want {
+		t.Errorf("size = %d, want %d (only the EXIF segment removed)", len(data), want)
+	}
+	if inspectMetadata(data).Private {
+		t.Error("EXIF still present")
+	}
+
+	c.SetStripMetadata(false)
+	if out, ok, err := c.Sanitize(path); err != nil || ok {
+		t.Errorf("Sanitize() with strip disabled = %q, %v, %v, want untouched", out, ok, err)
+	}
+}
+
+func TestCompressorTargetSize(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, 600, 400))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rng.Intn(256))
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "noise.jpg")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	const target = 60 * 1024
+	c := NewCompressor(zap.NewNop(), 1920, 100*1024)
+	c.SetTargetSizes(target/2, target, 0)
+
+	for kind, want := range map[ImageKind]int64{ImageKindBody: target, ImageKindCover: target / 2} {
+		out, ok, err := c.CompressImageAs(path, kind)
+		if err != nil || !ok {
+			t.Fatalf("CompressImageAs(%s) = %q, %v, %v", kind, out, ok, err)
+		}
+		info, _ := os.Stat(out)
+		os.Remove(out)
+		if info.Size() > want {
+			t.Errorf("%s size = %d, want <= %d", kind, info.Size(), want)
+		}
+	}
+}
+
+// buildICC 构造只含原色和伽马曲线的最小 ICC 配置
+func buildICC(colorants [3][3]float64, gamma float64) []byte {
+	fixed := func(v float64) []byte {
+		return binary.BigEndian.AppendUint32(nil, uint32(int32(v*65536)))
+	}
+	var tags [][2][]byte
+	for ch, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		body := []byte("XYZ \x00\x00\x00\x00")
+		for row := 0; row < 3; row++ {
+			body = append(body, fixed(colorants[row][ch])...)
+		}
+		tags = append(tags, [2][]byte{[]byte(sig), body})
+	}
+	curve := append([]byte("para\x00\x00\x00\x00\x00\x00\x00\x00"), fixed(gamma)...)
+	for _, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		tags = append(tags, [2][]byte{[]byte(sig), curve})
+	}
+
+	header := make([]byte, 128)
+	copy(header[16:], "RGB ")
+	table := binary.BigEndian.AppendUint32(nil, uint32(len(tags)))
+	offset := 128 + 4 + len(tags)*12
+	var bodies []byte
+	for _, tag := range tags {
+		table = append(table, tag[0]...)
+		table = binary.BigEndian.AppendUint32(table, uint32(offset+len(bodies)))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(tag[1])))
+		bodies = append(bodies, tag[1]...)
+	}
+	return append(append(header, table...), bodies...)
+}
+
+func TestConvertToSRGB(t *testing.T) {
+	if _, convert := needsSRGBConversion(buildICC(srgbToXYZ, 2.2)); convert {
+		t.Error("sRGB profile should not need conversion")
+	}
+
+	displayP3 := [3][3]float64{
+		{0.5151, 0.2920, 0.1571},
+		{0.2412, 0.6922, 0.0666},
+		{-0.0011, 0.0419, 0.7841},
+	}
+	profile, convert := needsSRGBConversion(buildICC(displayP3, 2.2))
+	if !convert {
+		t.Fatal("Display P3 profile should need conversion")
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	img.Set(1, 0, color.NRGBA{G: 255, A: 255})
+	out := convertToSRGB(img, profile)
+
+	if white := out.NRGBAAt(0, 0); white.R < 253 || white.G < 253 || white.B < 253 {
+		t.Errorf("white = %v, want white", white)
+	}
+	// P3 纯绿超出 sRGB 色域，红、蓝分量截断为 0 附近
+	if green := out.NRGBAAt(1, 0); green.G < 250 || green.R > 10 || green.B > 10 {
+		t.Errorf("green = %v, want saturated sRGB green", green)
+	}
+}
@@ -62,25 +62,43 @@ func (p *ModelScopeProvider) Name() string {
 }
 
 // Generate 生成图片（异步模式）
-func (p *ModelScopeProvider) Generate(ctx context.Context, prompt string) (*GenerateResult, error) {
+func (p *ModelScopeProvider) Generate(ctx context.Context, prompt string, opts GenerateOptions) (*GenerateResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "invalid_options",
+			Message:  fmt.Sprintf("生成选项错误: %v", err),
+			Original: err,
+		}
+	}
 	// 1. 发起异步请求，获取 task_id
-	taskID, err := p.createTask(ctx, prompt)
+	taskID, size, err := p.createTask(ctx, prompt, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	// 2. 轮询任务状态直到完成
-	imageURL, err := p.pollTaskStatus(ctx, taskID)
+	imageURLs, err := p.pollTaskStatus(ctx, taskID)
 	if err != nil {
 		return nil, err
 	}
 
-	return &GenerateResult{
-		URL:           imageURL,
-		RevisedPrompt: "", // ModelScope 不返回优化后的提示词
-		Model:         p.model,
-		Size:          p.size,
-	}, nil
+	// ModelScope 不返回优化后的提示词；format 选项不影响响应，输出图片可能是地址或 data URI
+	result := &GenerateResult{
+		Model: p.model,
+		Size:  size,
+	}
+	for _, u := range imageURLs {
+		if err := result.addImage(u, ""); err != nil {
+			return nil, &GenerateError{
+				Provider: p.Name(),
+				Code:     "decode_error",
+				Message:  "图片 base64 解码失败",
+				Original: err,
+			}
+		}
+	}
+	return result, nil
 }
 
 // parseSize 解析尺寸字符串 (如 "1024x1024") 为宽度和高度
@@ -100,11 +118,21 @@ func parseSize(size string) (width, height int, err error) {
 	return width, height, nil
 }
 
-// createTask 创建图片生成任务，返回 task_id
-func (p *ModelScopeProvider) createTask(ctx context.Context, prompt string) (string, error) {
-	width, height, err := parseSize(p.size)
+// createTask 创建图片生成任务，返回 task_id 和请求的尺寸
+func (p *ModelScopeProvider) createTask(ctx context.Context, prompt string, opts GenerateOptions) (string, string, error) {
+	size, err := opts.resolveSize(p.size)
+	if err != nil {
+		return "", "", &GenerateError{
+			Provider: p.Name(),
+			Code:     "invalid_size",
+			Message:  fmt.Sprintf("图片尺寸格式错误: %v", err),
+			Hint:     "请使用 WIDTHxHEIGHT 或 W:H 格式，如 1024x1024、16:9",
+			Original: err,
+		}
+	}
+	width, height, err := parseSize(size)
 	if err != nil {
-		return "", &GenerateError{
+		return "", "", &GenerateError{
 			Provider: p.Name(),
 			Code:     "invalid_size",
 			Message:  fmt.Sprintf("图片尺寸格式错误: %v", err),
@@ -116,14 +144,20 @@ func (p *ModelScopeProvider) createTask(ctx context.Context, prompt string) (str
 	reqBody := map[string]any{
 		"model":  p.model,
 		"prompt": prompt,
-		"n":      1,
+		"n":      opts.count(),
 		"width":  width,
 		"height": height,
 	}
+	if opts.NegativePrompt != "" {
+		reqBody["negative_prompt"] = opts.NegativePrompt
+	}
+	if opts.Seed != 0 {
+		reqBody["seed"] = opts.Seed
+	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", &GenerateError{
+		return "", "", &GenerateError{
 			Provider: p.Name(),
 			Code:     "marshal_error",
 			Message:  "请求构造失败",
@@ -134,7 +168,7 @@ func (p *ModelScopeProvider) createTask(ctx context.Context, prompt string) (str
 	url := p.baseURL + "/v1/images/generations"
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", &GenerateError{
+		return "", "", &GenerateError{
 			Provider: p.Name(),
 			Code:     "request_error",
 			Message:  "创建请求失败",
@@ -149,7 +183,7 @@ func (p *ModelScopeProvider) createTask(ctx context.Context, prompt string) (str
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", &GenerateError{
+		return "", "", &GenerateError{
 			Provider: p.Name(),
 			Code:     "network_error",
 			Message:  "网络请求失败，请检查网络连接",
@@ -161,7 +195,7 @@ func (p *ModelScopeProvider) createTask(ctx context.Context, prompt string) (str
 
 	// 处理错误响应
 	if resp.StatusCode != http.StatusOK {
-		return "", p.handleErrorResponse(resp)
+		return "", "", p.handleErrorResponse(resp)
 	}
 
 	// 解析响应获取 task_id
@@ -170,7 +204,7 @@ func (p *ModelScopeProvider) createTask(ctx context.Context, prompt string) (str
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", &GenerateError{
+		return "", "", &GenerateError{
 			Provider: p.Name(),
 			Code:     "decode_error",
 			Message:  "响应解析失败",
@@ -179,7 +213,7 @@ func (p *ModelScopeProvider) createTask(ctx context.Context, prompt string) (str
 	}
 
 	if result.TaskID == "" {
-		return "", &GenerateError{
+		return "", "", &GenerateError{
 			Provider: p.Name(),
 			Code:     "no_task_id",
 			Message:  "未获取到任务 ID",
@@ -187,11 +221,11 @@ func (p *ModelScopeProvider) createTask(ctx context.Context, prompt string) (str
 		}
 	}
 
-	return result.TaskID, nil
+	return result.TaskID, size, nil
 }
 
-// pollTaskStatus 轮询任务状态直到完成或超时
-func (p *ModelScopeProvider) pollTaskStatus(ctx context.Context, taskID string) (string, error) {
+// pollTaskStatus 轮询任务状态直到完成或超时，返回生成的图片 URL
+func (p *ModelScopeProvider) pollTaskStatus(ctx context.Context, taskID string) ([]string, error) {
 	ticker := time.NewTicker(p.pollInterval)
 	defer ticker.Stop()
 	timeout := time.After(p.maxPollTime)
@@ -199,29 +233,36 @@ func (p *ModelScopeProvider) pollTaskStatus(ctx context.Context, taskID string)
 	for {
 		select {
 		case <-ctx.Done():
-			return "", &GenerateError{
+			return nil, &GenerateError{
 				Provider: p.Name(),
 				Code:     "canceled",
 				Message:  "操作已取消",
 				Original: ctx.Err(),
 			}
 		case <-timeout:
-			return "", &GenerateError{
+			return nil, &GenerateError{
 				Provider: p.Name(),
 				Code:     "timeout",
 				Message:  fmt.Sprintf("图片生成超时（超过 %v）", p.maxPollTime),
 				Hint:     "图片生成时间较长，请稍后在任务列表中查看结果，或尝试简化提示词",
 			}
 		case <-ticker.C:
-			status, url, err := p.getTaskStatus(ctx, taskID)
+			status, urls, err := p.getTaskStatus(ctx, taskID)
 			if err != nil {
-				return "", err
+				return nil, err
 			}
 			if status == "SUCCEED" {
-				return url, nil
+				if len(urls) == 0 {
+					return nil, &GenerateError{
+						Provider: p.Name(),
+						Code:     "no_image",
+						Message:  "任务完成但未返回图片",
+					}
+				}
+				return urls, nil
 			}
 			if status == "FAILED" {
-				return "", &GenerateError{
+				return nil, &GenerateError{
 					Provider: p.Name(),
 					Code:     "task_failed",
 					Message:  "图片生成任务失败",
@@ -233,12 +274,12 @@ func (p *ModelScopeProvider) pollTaskStatus(ctx context.Context, taskID string)
 	}
 }
 
-// getTaskStatus 获取任务状态
-func (p *ModelScopeProvider) getTaskStatus(ctx context.Context, taskID string) (string, string, error) {
+// getTaskStatus 获取任务状态，成功时返回全部图片 URL
+func (p *ModelScopeProvider) getTaskStatus(ctx context.Context, taskID string) (string, []string, error) {
 	url := p.baseURL + "/v1/tasks/" + taskID
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return "", "", &GenerateError{
+		return "", nil, &GenerateError{
 			Provider: p.Name(),
 			Code:     "request_error",
 			Message:  "创建请求失败",
@@ -251,7 +292,7 @@ func (p *ModelScopeProvider) getTaskStatus(ctx context.Context, taskID string) (
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", "", &GenerateError{
+		return "", nil, &GenerateError{
 			Provider: p.Name(),
 			Code:     "network_error",
 			Message:  "查询任务状态失败，请检查网络连接",
@@ -262,7 +303,7 @@ func (p *ModelScopeProvider) getTaskStatus(ctx context.Context, taskID string) (
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", "", p.handleErrorResponse(resp)
+		return "", nil, p.handleErrorResponse(resp)
 	}
 
 	// 解析响应
@@ -273,7 +314,7 @@ func (p *ModelScopeProvider) getTaskStatus(ctx context.Context, taskID string) (
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", "", &GenerateError{
+		return "", nil, &GenerateError{
 			Provider: p.Name(),
 			Code:     "decode_error",
 			Message:  "任务状态响应解析失败",
@@ -283,16 +324,11 @@ func (p *ModelScopeProvider) getTaskStatus(ctx context.Context, taskID string) (
 
 	// 如果有错误信息，返回失败状态
 	if result.ErrorMessage != "" {
-		return "FAILED", "", nil
+		return "FAILED", nil, nil
 	}
 
 	// 返回状态和图片 URL（如果有）
-	imageURL := ""
-	if result.TaskStatus == "SUCCEED" && len(result.OutputImages) > 0 {
-		imageURL = result.OutputImages[0]
-	}
-
-	return result.TaskStatus, imageURL, nil
+	return result.TaskStatus, result.OutputImages, nil
 }
 
 // handleErrorResponse 处理错误响应
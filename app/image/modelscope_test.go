@@ -96,7 +96,7 @@ func TestModelScopeProvider_CreateTask(t *testing.T) {
 	}
 
 	p, _ := NewModelScopeProvider(cfg)
-	gotTaskID, err := p.createTask(context.Background(), "a golden cat")
+	gotTaskID, _, err := p.createTask(context.Background(), "a golden cat", GenerateOptions{})
 	if err != nil {
 		t.Fatalf("createTask() error = %v", err)
 	}
@@ -149,13 +149,13 @@ func TestModelScopeProvider_PollTaskStatus(t *testing.T) {
 	p, _ := NewModelScopeProvider(cfg)
 	p.pollInterval = 10 * time.Millisecond // 加快测试速度
 
-	gotURL, err := p.pollTaskStatus(context.Background(), "test-task-id")
+	gotURLs, err := p.pollTaskStatus(context.Background(), "test-task-id")
 	if err != nil {
 		t.Fatalf("pollTaskStatus() error = %v", err)
 	}
 
-	if gotURL != imageURL {
-		t.Errorf("URL = %v, want %v", gotURL, imageURL)
+	if len(gotURLs) != 1 || gotURLs[0] != imageURL {
+		t.Errorf("URLs = %v, want [%v]", gotURLs, imageURL)
 	}
 
 	if pollCount != 3 {
@@ -196,7 +196,7 @@ func TestModelScopeProvider_Generate(t *testing.T) {
 	p, _ := NewModelScopeProvider(cfg)
 	p.pollInterval = 10 * time.Millisecond
 
-	result, err := p.Generate(context.Background(), "a golden cat")
+	result, err := p.Generate(context.Background(), "a golden cat", GenerateOptions{})
 	if err != nil {
 		t.Fatalf("Generate() error = %v", err)
 	}
@@ -244,7 +244,7 @@ func TestModelScopeProvider_Generate_TaskFailed(t *testing.T) {
 	p, _ := NewModelScopeProvider(cfg)
 	p.pollInterval = 10 * time.Millisecond
 
-	_, err := p.Generate(context.Background(), "test prompt")
+	_, err := p.Generate(context.Background(), "test prompt", GenerateOptions{})
 	if err == nil {
 		t.Fatal("Generate() should return error for failed task")
 	}
@@ -272,7 +272,7 @@ func TestModelScopeProvider_HandleErrorResponse_Unauthorized(t *testing.T) {
 	}
 
 	p, _ := NewModelScopeProvider(cfg)
-	_, err := p.Generate(context.Background(), "test")
+	_, err := p.Generate(context.Background(), "test", GenerateOptions{})
 
 	if err == nil {
 		t.Fatal("Expected error for unauthorized request")
@@ -305,7 +305,7 @@ func TestModelScopeProvider_HandleErrorResponse_RateLimit(t *testing.T) {
 	}
 
 	p, _ := NewModelScopeProvider(cfg)
-	_, err := p.Generate(context.Background(), "test")
+	_, err := p.Generate(context.Background(), "test", GenerateOptions{})
 
 	if err == nil {
 		t.Fatal("Expected error for rate limit")
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/royalrick/wechatwriter/app/config"
@@ -50,13 +51,42 @@ func (p *OpenAIProvider) Name() string {
 }
 
 // Generate 生成图片
-func (p *OpenAIProvider) Generate(ctx context.Context, prompt string) (*GenerateResult, error) {
+func (p *OpenAIProvider) Generate(ctx context.Context, prompt string, opts GenerateOptions) (*GenerateResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "invalid_options",
+			Message:  fmt.Sprintf("生成选项错误: %v", err),
+			Original: err,
+		}
+	}
+	size, err := p.sizeFor(opts)
+	if err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "invalid_size",
+			Message:  fmt.Sprintf("图片尺寸格式错误: %v", err),
+			Hint:     "请使用 WIDTHxHEIGHT 或 W:H 格式，如 1024x1024、16:9",
+			Original: err,
+		}
+	}
+
 	// 构造请求
 	reqBody := map[string]any{
 		"model":  p.model,
-		"prompt": prompt,
-		"n":      1,
-		"size":   p.size,
+		"prompt": opts.promptWithNegative(prompt), // OpenAI 不支持反向提示词和随机种子
+		"n":      opts.count(),
+		"size":   size,
+	}
+	if opts.Quality != "" {
+		reqBody["quality"] = opts.Quality
+	}
+	if opts.Style != "" {
+		reqBody["style"] = opts.Style
+	}
+	// gpt-image 系列始终返回 b64_json，不接受 response_format 参数
+	if opts.ResponseFormat != "" && !strings.HasPrefix(p.model, "gpt-image") {
+		reqBody["response_format"] = opts.ResponseFormat
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -106,6 +136,7 @@ func (p *OpenAIProvider) Generate(ctx context.Context, prompt string) (*Generate
 	var result struct {
 		Data []struct {
 			URL           string `json:"url"`
+			B64JSON       string `json:"b64_json"`
 			RevisedPrompt string `json:"revised_prompt,omitempty"`
 		} `json:"data"`
 	}
@@ -128,12 +159,31 @@ func (p *OpenAIProvider) Generate(ctx context.Context, prompt string) (*Generate
 		}
 	}
 
-	return &GenerateResult{
-		URL:           result.Data[0].URL,
+	generated := &GenerateResult{
 		RevisedPrompt: result.Data[0].RevisedPrompt,
 		Model:         p.model,
-		Size:          p.size,
-	}, nil
+		Size:          size,
+	}
+	for _, d := range result.Data {
+		if err := generated.addImage(d.URL, d.B64JSON); err != nil {
+			return nil, &GenerateError{
+				Provider: p.Name(),
+				Code:     "decode_error",
+				Message:  "图片 base64 解码失败",
+				Original: err,
+			}
+		}
+	}
+	if generated.empty() {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "no_url",
+			Message:  "响应中没有图片 URL 或 b64_json",
+			Hint:     "检查网关是否兼容 OpenAI 图片接口的响应格式",
+		}
+	}
+
+	return generated, nil
 }
 
 // handleErrorResponse 处理错误响应
@@ -169,6 +219,15 @@ func (p *OpenAIProvider) handleErrorResponse(resp *http.Response) error {
 			Original: fmt.Errorf("status 429: %s", string(body)),
 		}
 	case http.StatusBadRequest:
+		if errResp.Error.Code == "content_policy_violation" {
+			return &GenerateError{
+				Provider: p.Name(),
+				Code:     "content_policy",
+				Message:  fmt.Sprintf("提示词未通过内容审核: %s", errResp.Error.Message),
+				Hint:     "修改提示词，或在 api.image_provider 中配置备用图片服务",
+				Original: fmt.Errorf("status 400: %s", string(body)),
+			}
+		}
 		return &GenerateError{
 			Provider: p.Name(),
 			Code:     "bad_request",
@@ -194,3 +253,30 @@ func (p *OpenAIProvider) handleErrorResponse(resp *http.Response) error {
 		}
 	}
 }
+
+// sizeFor 计算请求尺寸；只指定宽高比时选择模型支持的最接近的尺寸
+func (p *OpenAIProvider) sizeFor(opts GenerateOptions) (string, error) {
+	if opts.Size != "" || opts.AspectRatio == "" {
+		return opts.resolveSize(p.size)
+	}
+	ratio, err := parseAspectRatio(opts.AspectRatio)
+	if err != nil {
+		return "", err
+	}
+
+	landscape, portrait := "1536x1024", "1024x1536" // gpt-image 系列
+	switch p.model {
+	case "dall-e-3":
+		landscape, portrait = "1792x1024", "1024x1792"
+	case "dall-e-2":
+		return "1024x1024", nil // 只支持正方形
+	}
+	switch {
+	case ratio > 1.1:
+		return landscape, nil
+	case ratio < 0.9:
+		return portrait, nil
+	default:
+		return "1024x1024", nil
+	}
+}
@@ -0,0 +1,170 @@
+package image
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MaxGenerateCount 单次请求最多生成的图片数
+const MaxGenerateCount = 4
+
+// ParseGenerateSpec 解析 Markdown 中 __generate:…__ 的内容
+//
+// 提示词之后可用 | 分隔附加选项：
+//
+//	一杯乌龙茶，晨光|size=16:9|quality=hd|negative=文字, 水印|seed=42
+//
+// 支持的选项：size（WIDTHxHEIGHT 或宽高比）、ratio、quality、style、negative、
+// seed、n、format。不是已知选项的片段保留在提示词中，兼容包含 | 的旧提示词。
+func ParseGenerateSpec(spec string) (string, GenerateOptions, error) {
+	var opts GenerateOptions
+	parts := strings.Split(spec, "|")
+	prompt := []string{parts[0]}
+
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if !ok || !isGenerateOption(key) {
+			prompt = append(prompt, part)
+			continue
+		}
+		if err := opts.set(key, value); err != nil {
+			return "", opts, err
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(prompt, "|")), opts, nil
+}
+
+// isGenerateOption 是否为 __generate__ 语法支持的选项名
+func isGenerateOption(key string) bool {
+	switch key {
+	case "size", "ratio", "aspect", "quality", "style", "negative", "seed", "n", "count", "format":
+		return true
+	}
+	return false
+}
+
+// set 设置单个选项
+func (o *GenerateOptions) set(key, value string) error {
+	switch key {
+	case "size":
+		if strings.Contains(value, ":") {
+			o.AspectRatio = value
+		} else {
+			o.Size = value
+		}
+	case "ratio", "aspect":
+		o.AspectRatio = value
+	case "quality":
+		o.Quality = value
+	case "style":
+		o.Style = value
+	case "negative":
+		o.NegativePrompt = value
+	case "seed":
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid seed %q", value)
+		}
+		o.Seed = seed
+	case "n", "count":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid count %q", value)
+		}
+		o.Count = n
+	case "format":
+		o.ResponseFormat = value
+	}
+	return o.Validate()
+}
+
+// Validate 检查选项取值
+func (o GenerateOptions) Validate() error {
+	if o.Size != "" {
+		if _, _, err := parseSize(o.Size); err != nil {
+			return err
+		}
+	}
+	if o.AspectRatio != "" {
+		if _, err := parseAspectRatio(o.AspectRatio); err != nil {
+			return err
+		}
+	}
+	if o.Count < 0 || o.Count > MaxGenerateCount {
+		return fmt.Errorf("count must be between 1 and %d", MaxGenerateCount)
+	}
+	switch o.ResponseFormat {
+	case "", "url", "b64_json":
+	default:
+		return fmt.Errorf("invalid response format %q, expected url or b64_json", o.ResponseFormat)
+	}
+	return nil
+}
+
+// count 实际请求的张数
+func (o GenerateOptions) count() int {
+	if o.Count <= 0 {
+		return 1
+	}
+	return o.Count
+}
+
+// promptWithNegative 不支持反向提示词的提供者把它附加到提示词中
+func (o GenerateOptions) promptWithNegative(prompt string) string {
+	if o.NegativePrompt == "" {
+		return prompt
+	}
+	return prompt + "\n\nAvoid: " + o.NegativePrompt
+}
+
+// resolveSize 计算请求尺寸：Size 优先；只指定宽高比时保持默认尺寸的像素数
+// 按比例换算，宽高取 16 的倍数
+func (o GenerateOptions) resolveSize(defaultSize string) (string, error) {
+	if o.Size != "" {
+		return o.Size, nil
+	}
+	if o.AspectRatio == "" {
+		return defaultSize, nil
+	}
+
+	ratio, err := parseAspectRatio(o.AspectRatio)
+	if err != nil {
+		return "", err
+	}
+	w, h, err := parseSize(defaultSize)
+	if err != nil {
+		return "", err
+	}
+	area := float64(w * h)
+	width := roundTo16(math.Sqrt(area * ratio))
+	height := roundTo16(math.Sqrt(area / ratio))
+	return fmt.Sprintf("%dx%d", width, height), nil
+}
+
+// parseAspectRatio 解析 "16:9"、"2.35:1" 形式的宽高比
+func parseAspectRatio(s string) (float64, error) {
+	w, h, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid aspect ratio %q, expected W:H", s)
+	}
+	fw, err1 := strconv.ParseFloat(strings.TrimSpace(w), 64)
+	fh, err2 := strconv.ParseFloat(strings.TrimSpace(h), 64)
+	if err1 != nil || err2 != nil || fw <= 0 || fh <= 0 {
+		return 0, fmt.Errorf("invalid aspect ratio %q, expected W:H", s)
+	}
+	return fw / fh, nil
+}
+
+// roundTo16 四舍五入到 16 的倍数
+func roundTo16(v float64) int {
+	n := int(math.Round(v/16)) * 16
+	if n < 16 {
+		n = 16
+	}
+	return n
+}
@@ -0,0 +1,103 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/royalrick/wechatwriter/app/config"
+)
+
+func TestParseGenerateSpec(t *testing.T) {
+	tests := []struct {
+		spec       string
+		wantPrompt string
+		wantOpts   GenerateOptions
+	}{
+		{"一只猫", "一只猫", GenerateOptions{}},
+		{"一只猫|size=1024x1536|seed=42", "一只猫", GenerateOptions{Size: "1024x1536", Seed: 42}},
+		{"一只猫 | size=16:9 | negative=文字, 水印", "一只猫", GenerateOptions{AspectRatio: "16:9", NegativePrompt: "文字, 水印"}},
+		{"a|b|quality=hd|style=vivid|n=2|format=b64_json", "a|b", GenerateOptions{Quality: "hd", Style: "vivid", Count: 2, ResponseFormat: "b64_json"}},
+		{"x=1|ratio=2.35:1", "x=1", GenerateOptions{AspectRatio: "2.35:1"}},
+	}
+
+	for _, tt := range tests {
+		prompt, opts, err := ParseGenerateSpec(tt.spec)
+		if err != nil {
+			t.Errorf("ParseGenerateSpec(%q) error = %v", tt.spec, err)
+			continue
+		}
+		if prompt != tt.wantPrompt || opts != tt.wantOpts {
+			t.Errorf("ParseGenerateSpec(%q) = %q, %+v, want %q, %+v", tt.spec, prompt, opts, tt.wantPrompt, tt.wantOpts)
+		}
+	}
+
+	for _, spec := range []string{"猫|seed=abc", "猫|n=9", "猫|size=wide", "猫|format=png"} {
+		if _, _, err := ParseGenerateSpec(spec); err == nil {
+			t.Errorf("ParseGenerateSpec(%q) should return error", spec)
+		}
+	}
+}
+
+func TestResolveSize(t *testing.T) {
+	tests := []struct {
+		opts GenerateOptions
+		def  string
+		want string
+	}{
+		{GenerateOptions{}, "1024x1024", "1024x1024"},
+		{GenerateOptions{Size: "800x600", AspectRatio: "1:1"}, "1024x1024", "800x600"},
+		{GenerateOptions{AspectRatio: "16:9"}, "2048x2048", "2736x1536"},
+		{GenerateOptions{AspectRatio: "1:1"}, "2560x1440", "1920x1920"},
+	}
+	for _, tt := range tests {
+		got, err := tt.opts.resolveSize(tt.def)
+		if err != nil || got != tt.want {
+			t.Errorf("resolveSize(%+v, %s) = %s, %v, want %s", tt.opts, tt.def, got, err, tt.want)
+		}
+	}
+
+	p := &OpenAIProvider{model: "dall-e-3", size: "1024x1024"}
+	if got, _ := p.sizeFor(GenerateOptions{AspectRatio: "16:9"}); got != "1792x1024" {
+		t.Errorf("dall-e-3 16:9 size = %s, want 1792x1024", got)
+	}
+	p.model = "gpt-image-1"
+	if got, _ := p.sizeFor(GenerateOptions{AspectRatio: "3:4"}); got != "1024x1536" {
+		t.Errorf("gpt-image-1 3:4 size = %s, want 1024x1536", got)
+	}
+}
+
+func TestOpenAIProviderGenerateOptions(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": []map[string]string{{"url": "https://example.com/1.png"}, {"url": "https://example.com/2.png"}},
+		})
+	}))
+	defer server.Close()
+
+	p, _ := NewOpenAIProvider(&config.Config{ImageAPIKey: "k", ImageAPIBase: server.URL, ImageModel: "dall-e-3"})
+	result, err := p.Generate(context.Background(), "猫", GenerateOptions{
+		AspectRatio:    "16:9",
+		Quality:        "hd",
+		Style:          "natural",
+		NegativePrompt: "文字",
+		Count:          2,
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if body["size"] != "1792x1024" || body["quality"] != "hd" || body["style"] != "natural" || body["n"] != float64(2) {
+		t.Errorf("request body = %v", body)
+	}
+	if body["prompt"] != "猫\n\nAvoid: 文字" {
+		t.Errorf("prompt = %q, want negative prompt appended", body["prompt"])
+	}
+	if len(result.URLs) != 2 || result.URL != "https://example.com/1.png" || result.Size != "1792x1024" {
+		t.Errorf("result = %+v", result)
+	}
+}
@@ -0,0 +1,272 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultConcurrency 未配置 image.concurrency 时同时处理的图片数
+const DefaultConcurrency = 4
+
+// JobKind 批量处理的图片来源类型
+type JobKind string
+
+const (
+	JobLocal    JobKind = "local"    // 本地图片
+	JobOnline   JobKind = "online"   // 在线图片
+	JobGenerate JobKind = "generate" // AI 生成图片
+	JobGallery  JobKind = "gallery"  // 图片库中的图片
+)
+
+// Job 批量处理中的一张图片
+type Job struct {
+	Kind        JobKind // 来源类型
+	Source      string  // 本地路径、图片 URL、生成提示词（可带选项，见 ParseGenerateSpec）或图片库 ID
+	NoWatermark bool    // 不加账号水印（Markdown 说明文字中带 nowm）
+}
+
+// JobResult 单张图片的处理结果
+type JobResult struct {
+	Index       int     `json:"index"`
+	Kind        JobKind `json:"kind"`
+	Source      string  `json:"source"`
+	MediaID     string  `json:"media_id,omitempty"`
+	WechatURL   string  `json:"wechat_url,omitempty"`
+	OriginalURL string  `json:"original_url,omitempty"` // AI 生成图片的原始地址
+	Provider    string  `json:"provider,omitempty"`     // 生成图片的服务
+	GalleryID   string  `json:"gallery_id,omitempty"`   // AI 生成图片在图片库中的 ID
+	Error       string  `json:"error,omitempty"`
+	DurationMS  int64   `json:"duration_ms"`
+}
+
+// OK 是否处理成功
+func (r *JobResult) OK() bool {
+	return r.Error == ""
+}
+
+// BatchOptions 批量处理选项
+type BatchOptions struct {
+	Concurrency int       // 同时处理的图片数，<=0 时使用配置 image.concurrency
+	Progress    io.Writer // 逐张输出处理进度（通常为 os.Stderr），nil 时不输出
+}
+
+// BatchSummary 批量处理汇总，Results 与输入顺序一致
+type BatchSummary struct {
+	Total      int         `json:"total"`
+	Succeeded  int         `json:"succeeded"`
+	Failed     int         `json:"failed"`
+	DurationMS int64       `json:"duration_ms"`
+	Results    []JobResult `json:"results"`
+}
+
+// ProcessBatch 并发处理一组图片（上传本地图片、下载在线图片、AI 生成），
+// 单张失败不影响其他图片；ctx 取消后尚未开始的图片记为失败
+func (p *Processor) ProcessBatch(ctx context.Context, jobs []Job, opts BatchOptions) *BatchSummary {
+	start := time.Now()
+	summary := &BatchSummary{
+		Total:   len(jobs),
+		Results: make([]JobResult, len(jobs)),
+	}
+	if len(jobs) == 0 {
+		return summary
+	}
+
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = p.cfg.ImageConcurrency
+	}
+	if workers <= 0 {
+		workers = DefaultConcurrency
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	p.log.Info("processing images",
+		zap.Int("total", len(jobs)),
+		zap.Int("concurrency", workers))
+
+	progress := &progressReporter{w: opts.Progress, total: len(jobs)}
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				res := p.processJob(ctx, i, jobs[i])
+				summary.Results[i] = res
+				progress.report(&res)
+			}
+		}()
+	}
+
+	for i := range jobs {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	for i := range summary.Results {
+		if summary.Results[i].OK() {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+	}
+	summary.DurationMS = time.Since(start).Milliseconds()
+
+	p.log.Info("images processed",
+		zap.Int("succeeded", summary.Succeeded),
+		zap.Int("failed", summary.Failed),
+		zap.Duration("duration", time.Since(start)))
+	return summary
+}
+
+// processJob 处理单张图片
+func (p *Processor) processJob(ctx context.Context, index int, job Job) JobResult {
+	start := time.Now()
+	res := JobResult{Index: index, Kind: job.Kind, Source: job.Source}
+
+	watermark := !job.NoWatermark
+	var err error
+	if err = ctx.Err(); err == nil {
+		switch job.Kind {
+		case JobLocal:
+			var up *UploadResult
+			if up, err = p.uploadLocal(job.Source, ImageKindBody, watermark); err == nil {
+				res.MediaID, res.WechatURL = up.MediaID, up.WechatURL
+			}
+		case JobOnline:
+			var up *UploadResult
+			if up, err = p.downloadAndUpload(job.Source, watermark); err == nil {
+				res.MediaID, res.WechatURL = up.MediaID, up.WechatURL
+			}
+		case JobGenerate:
+			prompt, opts, perr := ParseGenerateSpec(job.Source)
+			if perr != nil {
+				err = perr
+				break
+			}
+			var gen *GenerateAndUploadResult
+			if gen, err = p.generateAndUpload(ctx, prompt, opts, watermark); err == nil {
+				res.MediaID, res.WechatURL, res.OriginalURL = gen.MediaID, gen.WechatURL, gen.OriginalURL
+				res.Provider, res.GalleryID = gen.Provider, gen.GalleryID
+			}
+		case JobGallery:
+			var up *UploadResult
+			if up, err = p.uploadFromGallery(job.Source, watermark); err == nil {
+				res.MediaID, res.WechatURL, res.GalleryID = up.MediaID, up.WechatURL, job.Source
+			}
+		default:
+			err = fmt.Errorf("unknown image job kind: %s", job.Kind)
+		}
+	}
+
+	res.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		res.Error = err.Error()
+		p.log.Warn("image processing failed",
+			zap.Int("index", index),
+			zap.String("kind", string(job.Kind)),
+			zap.Error(err))
+	}
+	return res
+}
+
+// progressReporter 按完成顺序逐行输出进度
+type progressReporter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	total int
+	done  int
+}
+
+func (r *progressReporter) report(res *JobResult) {
+	if r.w == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.done++
+
+	status := "✓"
+	if !res.OK() {
+		status = "✗"
+	}
+	elapsed := fmt.Sprintf("%.1fs", float64(res.DurationMS)/1000)
+	if res.Provider != "" {
+		elapsed += ", " + res.Provider
+	}
+	if res.Kind == JobGenerate && res.GalleryID != "" {
+		elapsed += ", gallery:" + res.GalleryID
+	}
+	line := fmt.Sprintf("[%d/%d] %s #%d %-8s %s (%s)",
+		r.done, r.total, status, res.Index+1, res.Kind, truncateSource(res.Source), elapsed)
+	if !res.OK() {
+		line += ": " + res.Error
+	}
+	fmt.Fprintln(r.w, line)
+}
+
+// rateLimiter 按固定间隔放行请求
+type rateLimiter struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// Wait 阻塞到下一个可用时间点并预留 interval，ctx 取消时返回错误
+func (l *rateLimiter) Wait(ctx context.Context, interval time.Duration) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*rateLimiter{}
+)
+
+// waitRateLimit 按 image.rate_limits 限制同一图片服务的请求频率（进程内所有处理器共享）
+// 配置键为小写的服务名，如 openai、tuzi、modelscope
+func waitRateLimit(ctx context.Context, rateLimits map[string]int, provider string) error {
+	provider = strings.ToLower(provider)
+	perMinute := rateLimits[provider]
+	if perMinute <= 0 {
+		return nil
+	}
+
+	limitersMu.Lock()
+	l, ok := limiters[provider]
+	if !ok {
+		l = &rateLimiter{}
+		limiters[provider] = l
+	}
+	limitersMu.Unlock()
+
+	return l.Wait(ctx, time.Minute/time.Duration(perMinute))
+}
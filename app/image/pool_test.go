@@ -0,0 +1,84 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessBatchPreservesOrder(t *testing.T) {
+	p, srv := newMockProcessor(t)
+
+	jobs := []Job{
+		{Kind: JobLocal, Source: writeTestPNG(t, "a.png", color.RGBA{R: 255, A: 255})},
+		{Kind: JobLocal, Source: "/nonexistent/missing.png"},
+		{Kind: JobLocal, Source: writeTestPNG(t, "b.png", color.RGBA{G: 255, A: 255})},
+		{Kind: JobLocal, Source: writeTestPNG(t, "c.png", color.RGBA{B: 255, A: 255})},
+		{Kind: JobGenerate, Source: "一只猫"}, // 未配置图片服务
+	}
+
+	var progress bytes.Buffer
+	summary := p.ProcessBatch(context.Background(), jobs, BatchOptions{Concurrency: 3, Progress: &progress})
+
+	if summary.Total != 5 || summary.Succeeded != 3 || summary.Failed != 2 {
+		t.Fatalf("summary = %d/%d/%d, want 5/3/2", summary.Total, summary.Succeeded, summary.Failed)
+	}
+	for i, res := range summary.Results {
+		if res.Index != i || res.Source != jobs[i].Source {
+			t.Errorf("results[%d] = #%d %s, want input order", i, res.Index, res.Source)
+		}
+		if wantOK := i != 1 && i != 4; res.OK() != wantOK {
+			t.Errorf("results[%d].OK() = %v, want %v (error %q)", i, res.OK(), wantOK, res.Error)
+		}
+	}
+	if n := len(srv.Materials()); n != 3 {
+		t.Errorf("materials on mock server = %d, want 3", n)
+	}
+	if lines := strings.Count(progress.String(), "\n"); lines != 5 {
+		t.Errorf("progress lines = %d, want 5:\n%s", lines, progress.String())
+	}
+	if !strings.Contains(progress.String(), "[5/5]") {
+		t.Errorf("progress missing final count:\n%s", progress.String())
+	}
+}
+
+func TestProcessBatchCanceled(t *testing.T) {
+	p, srv := newMockProcessor(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	summary := p.ProcessBatch(ctx, []Job{
+		{Kind: JobLocal, Source: writeTestPNG(t, "a.png", color.RGBA{R: 255, A: 255})},
+	}, BatchOptions{})
+	if summary.Failed != 1 {
+		t.Errorf("failed = %d, want 1", summary.Failed)
+	}
+	if n := len(srv.Materials()); n != 0 {
+		t.Errorf("materials on mock server = %d, want 0", n)
+	}
+}
+
+func TestRateLimiterSpacing(t *testing.T) {
+	l := &rateLimiter{}
+	interval := 20 * time.Millisecond
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background(), interval); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 2*interval {
+		t.Errorf("3 requests took %v, want >= %v", elapsed, 2*interval)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.next = time.Now().Add(time.Hour)
+	if err := l.Wait(ctx, interval); err == nil {
+		t.Error("Wait() with canceled context should return error")
+	}
+}
@@ -15,41 +15,109 @@ type Processor struct {
 	cfg        *config.Config
 	log        *zap.Logger
 	ws         *wechat.Service
+	account    *config.WechatAccount
+	cache      *UploadCache
 	compressor *Compressor
+	watermark  *Watermark // 账号配置的正文图片水印
 	provider   Provider
+	gallery    *Gallery
+	article    string // 当前处理的文章，记录到图片库
 }
 
-// NewProcessor 创建图片处理器
+// NewProcessor 创建图片处理器（使用默认账号上传）
 func NewProcessor(cfg *config.Config, log *zap.Logger) *Processor {
-	// 创建图片生成 Provider
-	provider, err := NewProvider(cfg)
-	if err != nil {
-		// 如果配置了 API Key 但创建失败，记录警告
-		if cfg.ImageAPIKey != "" {
-			log.Warn("failed to create image provider, AI image generation will be unavailable", zap.Error(err))
-		}
-	}
+	p := newProcessor(cfg, log)
 
 	// 选择默认账号用于图片上传
-	var wechatService *wechat.Service
 	if len(cfg.WechatAccounts) > 0 {
 		// 使用第一个账号或默认账号
 		selector := config.NewAccountSelector(cfg.WechatAccounts, cfg.DefaultAccount)
 		account, err := selector.SelectAccount("", "")
 		if err == nil {
-			wechatService = wechat.NewService(account, log)
-		} else {
+			err = p.useAccount(account)
+		}
+		if err != nil {
 			log.Warn("failed to select WeChat account for image upload", zap.Error(err))
 		}
 	}
 
+	return p
+}
+
+// NewProcessorForAccount 创建使用指定账号上传的图片处理器
+// accountID 为空时与 NewProcessor 相同，使用默认账号
+func NewProcessorForAccount(cfg *config.Config, log *zap.Logger, accountID string) (*Processor, error) {
+	selector := config.NewAccountSelector(cfg.WechatAccounts, cfg.DefaultAccount)
+	account, err := selector.SelectAccount("", accountID)
+	if err != nil {
+		return nil, fmt.Errorf("select WeChat account: %w", err)
+	}
+
+	p := newProcessor(cfg, log)
+	if err := p.useAccount(account); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// newProcessor 创建未绑定账号的图片处理器
+func newProcessor(cfg *config.Config, log *zap.Logger) *Processor {
+	// 创建图片生成 Provider
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		// 如果配置了 API Key 或本地图片服务但创建失败，记录警告
+		if cfg.ImageAPIKey != "" || cfg.LocalImageProvider() {
+			log.Warn("failed to create image provider, AI image generation will be unavailable", zap.Error(err))
+		}
+	} else if chain, ok := provider.(*ChainProvider); ok {
+		for _, skipped := range chain.Skipped() {
+			log.Warn("image provider skipped in fallback chain", zap.Error(skipped))
+		}
+	}
+
 	return &Processor{
 		cfg:        cfg,
 		log:        log,
-		ws:         wechatService,
-		compressor: NewCompressor(log, cfg.MaxImageWidth, cfg.MaxImageSize),
+		compressor: NewCompressorFromConfig(log, cfg),
 		provider:   provider,
+		gallery:    OpenGallery(cfg.GetDataDir()),
+	}
+}
+
+// SetArticle 设置当前处理的文章路径，AI 生成的图片在图片库中关联该文章
+func (p *Processor) SetArticle(path string) {
+	p.article = path
+}
+
+// Gallery 返回 AI 生成图片的本地图片库
+func (p *Processor) Gallery() *Gallery {
+	return p.gallery
+}
+
+// useAccount 绑定上传账号及其上传缓存、水印
+// 水印配置无法加载（字体、Logo 缺失等）时返回错误，避免图片在未加水印的情况下上传
+func (p *Processor) useAccount(account *config.WechatAccount) error {
+	watermark, err := NewWatermark(account.Watermark)
+	if err != nil {
+		return fmt.Errorf("account %s: %w", account.ID, err)
+	}
+	p.account = account
+	p.watermark = watermark
+	p.ws = wechat.NewService(account, p.log)
+	p.cache = openAccountCache(p.cfg, account, p.log)
+	return nil
+}
+
+// openAccountCache 打开账号的上传缓存，失败时仅记录警告（不影响上传）
+func openAccountCache(cfg *config.Config, account *config.WechatAccount, log *zap.Logger) *UploadCache {
+	cache, err := OpenUploadCache(cfg.AccountDataDir(account.ID))
+	if err != nil {
+		log.Warn("failed to open upload cache, uploads will not be cached",
+			zap.String("account_id", account.ID),
+			zap.Error(err))
+		return nil
 	}
+	return cache
 }
 
 // UploadResult 上传结果
@@ -62,7 +130,17 @@ type UploadResult struct {
 
 // UploadLocalImage 上传本地图片
 func (p *Processor) UploadLocalImage(filePath string) (*UploadResult, error) {
-	p.log.Info("uploading local image", zap.String("path", filePath))
+	return p.uploadLocal(filePath, ImageKindBody, true)
+}
+
+// UploadCover 上传本地封面图片（target 压缩模式下使用封面的目标大小，不加水印）
+func (p *Processor) UploadCover(filePath string) (*UploadResult, error) {
+	return p.uploadLocal(filePath, ImageKindCover, false)
+}
+
+// uploadLocal 按图片用途处理并上传本地图片；watermark 为 false 时不加水印
+func (p *Processor) uploadLocal(filePath string, kind ImageKind, watermark bool) (*UploadResult, error) {
+	p.log.Info("uploading local image", zap.String("path", filePath), zap.String("kind", string(kind)))
 
 	// 检查文件是否存在
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
@@ -74,33 +152,24 @@ func (p *Processor) UploadLocalImage(filePath string) (*UploadResult, error) {
 		return nil, fmt.Errorf("unsupported image format: %s", filePath)
 	}
 
-	// 如果需要压缩，先处理
-	processedPath := filePath
-	if p.cfg.CompressImages {
-		compressedPath, compressed, err := p.compressor.CompressImage(filePath)
-		if err != nil {
-			p.log.Warn("compress failed, using original", zap.Error(err))
-		} else if compressed {
-			processedPath = compressedPath
-			defer os.Remove(compressedPath)
-			p.log.Info("using compressed image", zap.String("path", processedPath))
-		}
-	}
-
-	// 上传到微信
-	result, err := p.ws.UploadMaterialWithRetry(processedPath, 3)
+	// 转码、压缩
+	processedPath, cleanup, err := p.prepareForUpload(filePath, kind, watermark)
 	if err != nil {
 		return nil, err
 	}
+	defer cleanup()
 
-	return &UploadResult{
-		MediaID:   result.MediaID,
-		WechatURL: result.WechatURL,
-	}, nil
+	// 上传到微信（相同内容命中缓存时跳过上传）
+	return p.uploadWithCache(processedPath, filePath)
 }
 
 // DownloadAndUpload 下载在线图片并上传
 func (p *Processor) DownloadAndUpload(url string) (*UploadResult, error) {
+	return p.downloadAndUpload(url, true)
+}
+
+// downloadAndUpload 下载在线图片并上传；watermark 为 false 时不加水印
+func (p *Processor) downloadAndUpload(url string, watermark bool) (*UploadResult, error) {
 	p.log.Info("downloading and uploading image", zap.String("url", url))
 
 	// 下载图片
@@ -115,157 +184,388 @@ func (p *Processor) DownloadAndUpload(url string) (*UploadResult, error) {
 		return nil, fmt.Errorf("downloaded file is not a valid image")
 	}
 
-	// 压缩（如果需要）
-	processedPath := tmpPath
-	if p.cfg.CompressImages {
-		compressedPath, compressed, err := p.compressor.CompressImage(tmpPath)
-		if err != nil {
-			p.log.Warn("compress failed, using original", zap.Error(err))
-		} else if compressed {
-			processedPath = compressedPath
-			defer os.Remove(compressedPath)
-			p.log.Info("using compressed image", zap.String("path", processedPath))
-		}
-	}
-
-	// 上传到微信
-	result, err := p.ws.UploadMaterialWithRetry(processedPath, 3)
+	// 转码、压缩
+	processedPath, cleanup, err := p.prepareForUpload(tmpPath, ImageKindBody, watermark)
 	if err != nil {
 		return nil, err
 	}
+	defer cleanup()
 
-	return &UploadResult{
-		MediaID:   result.MediaID,
-		WechatURL: result.WechatURL,
-	}, nil
+	// 上传到微信（相同内容命中缓存时跳过上传）
+	return p.uploadWithCache(processedPath, url)
 }
 
 // GenerateAndUploadResult AI 生成图片结果
 type GenerateAndUploadResult struct {
-	Prompt      string `json:"prompt"`
-	OriginalURL string `json:"original_url"`
-	MediaID     string `json:"media_id"`
-	WechatURL   string `json:"wechat_url"`
-	Width       int    `json:"width"`
-	Height      int    `json:"height"`
+	Prompt      string   `json:"prompt"`
+	OriginalURL string   `json:"original_url"`
+	MediaID     string   `json:"media_id"`
+	WechatURL   string   `json:"wechat_url"`
+	Width       int      `json:"width"`
+	Height      int      `json:"height"`
+	Size        string   `json:"size,omitempty"`       // 请求的生成尺寸
+	Candidates  []string `json:"candidates,omitempty"` // Count > 1 时未上传的其余图片地址
+	Provider    string   `json:"provider,omitempty"`   // 实际生成图片的服务
+	GalleryID   string   `json:"gallery_id,omitempty"` // 图片库中的 ID，可用 ![](gallery:<id>) 复用
+	// Count > 1 时其余图片在图片库中的 ID
+	CandidateIDs []string `json:"candidate_gallery_ids,omitempty"`
 }
 
 // GenerateAndUpload AI 生成图片并上传
 func (p *Processor) GenerateAndUpload(prompt string) (*GenerateAndUploadResult, error) {
-	p.log.Info("generating image via AI", zap.String("prompt", prompt))
+	return p.generateAndUpload(context.Background(), prompt, GenerateOptions{}, true)
+}
+
+// GenerateAndUploadWithSize AI 生成指定尺寸的图片并上传
+func (p *Processor) GenerateAndUploadWithSize(prompt string, size string) (*GenerateAndUploadResult, error) {
+	return p.generateAndUpload(context.Background(), prompt, GenerateOptions{Size: size}, true)
+}
+
+// GenerateAndUploadWithOptions 按生成选项 AI 生成图片并上传第一张
+func (p *Processor) GenerateAndUploadWithOptions(prompt string, opts GenerateOptions) (*GenerateAndUploadResult, error) {
+	return p.generateAndUpload(context.Background(), prompt, opts, true)
+}
+
+// generateAndUpload AI 生成图片并上传，生成请求受 image.rate_limits 限制
+// 图片库保存未加水印的原图，watermark 为 false 时上传的图片也不加水印
+func (p *Processor) generateAndUpload(ctx context.Context, prompt string, opts GenerateOptions, watermark bool) (*GenerateAndUploadResult, error) {
+	result, tmpPath, err := p.generate(ctx, prompt, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpPath)
+
+	// 保存到图片库（失败不影响上传）
+	meta := generatedMeta(prompt, opts, result, p.article)
+	entry := p.saveToGallery(tmpPath, meta)
+
+	// 转码、压缩
+	processedPath, cleanup, err := p.prepareForUpload(tmpPath, ImageKindBody, watermark)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// 上传到微信（相同内容命中缓存时跳过上传）
+	uploadResult, err := p.uploadWithCache(processedPath, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &GenerateAndUploadResult{
+		Prompt:       prompt,
+		OriginalURL:  result.URL,
+		MediaID:      uploadResult.MediaID,
+		WechatURL:    uploadResult.WechatURL,
+		Size:         result.Size,
+		Provider:     result.Provider,
+		CandidateIDs: p.saveCandidates(result, meta),
+	}
+	if len(result.URLs) > 1 {
+		out.Candidates = result.URLs[1:]
+	}
+	if entry != nil {
+		out.GalleryID = entry.ID
+		p.recordGalleryUpload(entry, uploadResult.MediaID)
+	}
+	return out, nil
+}
+
+// GenerateToGallery AI 生成图片并保存到图片库，不上传（如作为封面背景再加工）
+func (p *Processor) GenerateToGallery(ctx context.Context, prompt string, opts GenerateOptions) (*GalleryEntry, error) {
+	result, tmpPath, err := p.generate(ctx, prompt, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpPath)
+
+	data, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("read generated image: %w", err)
+	}
+	return p.gallery.Save(data, generatedMeta(prompt, opts, result, p.article))
+}
+
+// generate 调用图片生成服务（降级链负责限流、重试和切换服务），返回生成结果和下载到本地的第一张图片
+func (p *Processor) generate(ctx context.Context, prompt string, opts GenerateOptions) (*GenerateResult, string, error) {
+	p.log.Info("generating image via AI",
+		zap.String("prompt", prompt),
+		zap.String("size", opts.Size),
+		zap.String("aspect_ratio", opts.AspectRatio))
 
 	// 验证配置
 	if err := p.cfg.ValidateForImageGeneration(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// 检查 provider 是否可用
 	if p.provider == nil {
-		return nil, fmt.Errorf("图片生成服务未配置，请检查配置文件中的 api.image_provider 和 api.image_key")
+		return nil, "", fmt.Errorf("图片生成服务未配置，请检查配置文件中的 api.image_provider 和 api.image_key")
 	}
 
-	// 调用图片生成 API
-	ctx := context.Background()
-	result, err := p.provider.Generate(ctx, prompt)
+	// 调用图片生成 API（降级链负责限流、重试和切换服务）
+	result, err := p.provider.Generate(ctx, prompt, opts)
 	if err != nil {
-		return nil, fmt.Errorf("generate image: %w", err)
+		return nil, "", fmt.Errorf("generate image: %w", err)
+	}
+	if len(result.Failures) > 0 {
+		p.log.Warn("image generated after failed attempts",
+			zap.Strings("failures", result.Failures))
 	}
 	p.log.Info("image generated",
 		zap.String("url", result.URL),
-		zap.String("provider", result.Model),
+		zap.Int("bytes", len(result.Data)),
+		zap.String("provider", result.Provider),
+		zap.String("model", result.Model),
 		zap.String("size", result.Size))
 
-	// 下载生成的图片
-	tmpPath, err := wechat.DownloadFile(result.URL)
+	// 下载生成的图片（本地服务直接返回图片内容）
+	var tmpPath string
+	if len(result.Data) > 0 {
+		tmpPath, err = writeTempImage(result.Data)
+	} else {
+		tmpPath, err = wechat.DownloadFile(result.URL)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("download generated image: %w", err)
+		return nil, "", fmt.Errorf("download generated image: %w", err)
 	}
-	defer os.Remove(tmpPath)
+	return result, tmpPath, nil
+}
 
-	// 压缩（如果需要）
-	processedPath := tmpPath
-	if p.cfg.CompressImages {
-		compressedPath, compressed, err := p.compressor.CompressImage(tmpPath)
-		if err != nil {
-			p.log.Warn("compress failed, using original", zap.Error(err))
-		} else if compressed {
-			processedPath = compressedPath
-			defer os.Remove(compressedPath)
-			p.log.Info("using compressed image", zap.String("path", processedPath))
+// generatedMeta 生成图片在图片库中的元数据
+func generatedMeta(prompt string, opts GenerateOptions, result *GenerateResult, article string) GalleryEntry {
+	meta := GalleryEntry{
+		Prompt:        prompt,
+		RevisedPrompt: result.RevisedPrompt,
+		Provider:      result.Provider,
+		Model:         result.Model,
+		Size:          result.Size,
+		Seed:          result.Seed,
+		Article:       article,
+		OriginalURL:   result.URL,
+	}
+	if meta.Seed == 0 {
+		meta.Seed = opts.Seed
+	}
+	return meta
+}
+
+// saveToGallery 将生成的图片保存到图片库，失败时记录警告并返回 nil
+func (p *Processor) saveToGallery(path string, meta GalleryEntry) *GalleryEntry {
+	if p.gallery == nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var entry *GalleryEntry
+		if entry, err = p.gallery.Save(data, meta); err == nil {
+			return entry
 		}
 	}
+	p.log.Warn("failed to save generated image to gallery", zap.Error(err))
+	return nil
+}
 
-	// 上传到微信
-	uploadResult, err := p.ws.UploadMaterialWithRetry(processedPath, 3)
-	if err != nil {
-		return nil, err
+// saveCandidates 将 Count > 1 时的其余图片保存到图片库，返回其 ID
+func (p *Processor) saveCandidates(result *GenerateResult, meta GalleryEntry) []string {
+	if p.gallery == nil {
+		return nil
+	}
+	var ids []string
+	save := func(path string) {
+		if entry := p.saveToGallery(path, meta); entry != nil {
+			ids = append(ids, entry.ID)
+		}
 	}
+	meta.OriginalURL = ""
+	for i := 1; i < len(result.Images); i++ {
+		tmpPath, err := writeTempImage(result.Images[i])
+		if err != nil {
+			continue
+		}
+		save(tmpPath)
+		os.Remove(tmpPath)
+	}
+	for i := 1; i < len(result.URLs); i++ {
+		tmpPath, err := wechat.DownloadFile(result.URLs[i])
+		if err != nil {
+			p.log.Warn("failed to download candidate image", zap.Error(err))
+			continue
+		}
+		meta.OriginalURL = result.URLs[i]
+		save(tmpPath)
+		os.Remove(tmpPath)
+	}
+	return ids
+}
 
-	return &GenerateAndUploadResult{
-		Prompt:      prompt,
-		OriginalURL: result.URL,
-		MediaID:     uploadResult.MediaID,
-		WechatURL:   uploadResult.WechatURL,
-	}, nil
+// recordGalleryUpload 在图片库中记录该账号最近一次上传的素材
+func (p *Processor) recordGalleryUpload(entry *GalleryEntry, mediaID string) {
+	if p.account == nil {
+		return
+	}
+	if _, err := p.gallery.RecordUpload(entry.ID, p.account.ID, mediaID); err != nil {
+		p.log.Warn("failed to update gallery entry", zap.String("id", entry.ID), zap.Error(err))
+	}
 }
 
-// GenerateAndUploadWithSize AI 生成指定尺寸的图片并上传
-func (p *Processor) GenerateAndUploadWithSize(prompt string, size string) (*GenerateAndUploadResult, error) {
-	p.log.Info("generating image via AI with size",
-		zap.String("prompt", prompt),
-		zap.String("size", size))
+// UploadFromGallery 上传图片库中的图片（![](gallery:<id>)）
+func (p *Processor) UploadFromGallery(id string) (*UploadResult, error) {
+	return p.uploadFromGallery(id, true)
+}
 
-	// 验证配置
-	if err := p.cfg.ValidateForImageGeneration(); err != nil {
+// uploadFromGallery 上传图片库中的图片；watermark 为 false 时不加水印
+func (p *Processor) uploadFromGallery(id string, watermark bool) (*UploadResult, error) {
+	entry, err := p.gallery.Get(id)
+	if err != nil {
 		return nil, err
 	}
-
-	// 检查 provider 是否可用
-	if p.provider == nil {
-		return nil, fmt.Errorf("图片生成服务未配置，请检查配置文件中的 api.image_provider 和 api.image_key")
+	result, err := p.uploadLocal(p.gallery.Path(entry), ImageKindBody, watermark)
+	if err != nil {
+		return nil, err
 	}
+	p.recordGalleryUpload(entry, result.MediaID)
+	return result, nil
+}
 
-	// 创建一个临时配置副本，覆盖尺寸
-	originalSize := p.cfg.ImageSize
-	p.cfg.ImageSize = size
-	defer func() { p.cfg.ImageSize = originalSize }()
+// prepareForUpload 将图片转为微信接受的格式，摆正方向、去除元数据、加水印，并按配置压缩，返回待上传文件和清理函数
+// 转码失败时返回错误（原格式无法上传）；压缩失败时使用转码后的文件
+func (p *Processor) prepareForUpload(path string, kind ImageKind, watermark bool) (string, func(), error) {
+	current, cleanup := path, func() {}
 
-	// 重新创建 provider 以使用新尺寸
-	newProvider, err := NewProvider(p.cfg)
+	transcoded, ok, err := p.compressor.Transcode(path)
 	if err != nil {
-		return nil, fmt.Errorf("create provider with size: %w", err)
+		return "", cleanup, fmt.Errorf("transcode image: %w", err)
+	}
+	if ok {
+		current, cleanup = transcoded, func() { os.Remove(transcoded) }
 	}
 
-	// 调用图片生成 API
-	ctx := context.Background()
-	result, err := newProvider.Generate(ctx, prompt)
+	compressor := p.compressorFor(watermark)
+	process := compressor.Sanitize
+	if p.cfg.CompressImages {
+		process = func(path string) (string, bool, error) {
+			return compressor.CompressImageAs(path, kind)
+		}
+	}
+	compressedPath, compressed, err := process(current)
 	if err != nil {
-		return nil, fmt.Errorf("generate image: %w", err)
+		p.log.Warn("compress failed, using original", zap.Error(err))
+		return current, cleanup, nil
 	}
-	p.log.Info("image generated",
-		zap.String("url", result.URL),
-		zap.String("provider", result.Model),
-		zap.String("size", result.Size))
+	if !compressed {
+		return current, cleanup, nil
+	}
+	cleanup()
+	p.log.Info("using compressed image", zap.String("path", compressedPath))
+	return compressedPath, func() { os.Remove(compressedPath) }, nil
+}
+
+// compressorFor 返回上传时使用的压缩器；watermark 为 true 且账号配置了水印时叠加水印
+func (p *Processor) compressorFor(watermark bool) *Compressor {
+	if !watermark || p.watermark == nil {
+		return p.compressor
+	}
+	return p.compressor.withWatermark(p.watermark)
+}
 
-	// 下载生成的图片
-	tmpPath, err := wechat.DownloadFile(result.URL)
+// writeTempImage 将图片内容写入临时文件，按内容识别扩展名
+func writeTempImage(data []byte) (string, error) {
+	f, err := os.CreateTemp("", "wechatwriter_generated_*"+imageExtension(data))
 	if err != nil {
-		return nil, fmt.Errorf("download generated image: %w", err)
+		return "", fmt.Errorf("create temp file: %w", err)
 	}
-	defer os.Remove(tmpPath)
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("write temp file: %w", err)
+	}
+	return f.Name(), nil
+}
 
-	// 上传到微信
-	uploadResult, err := p.ws.UploadMaterialWithRetry(tmpPath, 3)
+// uploadWithCache 上传处理后的图片，先按内容哈希查询上传缓存
+// source 为原始路径、URL 或提示词，仅用于缓存记录
+func (p *Processor) uploadWithCache(processedPath, source string) (*UploadResult, error) {
+	if p.ws == nil {
+		return nil, fmt.Errorf("未配置微信公众号账号，无法上传图片")
+	}
+
+	var hash string
+	var size int64
+	if p.cache != nil {
+		var err error
+		hash, size, err = HashFile(processedPath)
+		if err != nil {
+			p.log.Warn("hash image failed, skipping upload cache", zap.Error(err))
+		} else if entry, ok := p.cache.Lookup(hash); ok {
+			p.log.Info("upload cache hit, skipping upload",
+				zap.String("source", source),
+				zap.String("media_id", maskMediaID(entry.MediaID)))
+			return withDimensions(&UploadResult{
+				MediaID:   entry.MediaID,
+				WechatURL: entry.WechatURL,
+			}, processedPath), nil
+		}
+	}
+
+	result, err := p.ws.UploadMaterialWithRetry(processedPath, 3)
 	if err != nil {
 		return nil, err
 	}
 
-	return &GenerateAndUploadResult{
-		Prompt:      prompt,
-		OriginalURL: result.URL,
-		MediaID:     uploadResult.MediaID,
-		WechatURL:   uploadResult.WechatURL,
-	}, nil
+	if p.cache != nil && hash != "" {
+		if err := p.cache.Store(UploadCacheEntry{
+			Hash:      hash,
+			MediaID:   result.MediaID,
+			WechatURL: result.WechatURL,
+			Source:    source,
+			Size:      size,
+		}); err != nil {
+			p.log.Warn("save upload cache failed", zap.Error(err))
+		}
+	}
+
+	return withDimensions(&UploadResult{
+		MediaID:   result.MediaID,
+		WechatURL: result.WechatURL,
+	}, processedPath), nil
+}
+
+// withDimensions 记录实际上传图片（摆正方向、压缩后）的尺寸
+func withDimensions(r *UploadResult, path string) *UploadResult {
+	if w, h, err := GetImageDimensions(path); err == nil {
+		r.Width, r.Height = w, h
+	}
+	return r
+}
+
+// DeleteMaterial 删除微信永久素材，并使指向该素材的上传缓存失效
+func (p *Processor) DeleteMaterial(mediaID string) error {
+	if p.ws == nil {
+		return fmt.Errorf("未配置微信公众号账号，无法删除素材")
+	}
+
+	if err := p.ws.DeleteMaterial(mediaID); err != nil {
+		return err
+	}
+
+	if p.cache != nil {
+		removed, err := p.cache.RemoveByMediaID(mediaID)
+		if err != nil {
+			p.log.Warn("invalidate upload cache failed", zap.Error(err))
+		} else if removed > 0 {
+			p.log.Info("upload cache invalidated",
+				zap.String("media_id", maskMediaID(mediaID)),
+				zap.Int("entries", removed))
+		}
+	}
+
+	return nil
+}
+
+// Cache 返回当前账号的上传缓存（未配置账号时为 nil）
+func (p *Processor) Cache() *UploadCache {
+	return p.cache
 }
 
 // GetImageInfo 获取图片信息
@@ -282,3 +582,11 @@ func (p *Processor) CompressImage(filePath string) (string, bool, error) {
 func (p *Processor) SetCompressQuality(quality int) {
 	p.compressor.SetQuality(quality)
 }
+
+// maskMediaID 遮蔽 media_id 用于日志
+func maskMediaID(id string) string {
+	if len(id) < 8 {
+		return "***"
+	}
+	return id[:4] + "***" + id[len(id)-4:]
+}
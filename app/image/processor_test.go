@@ -0,0 +1,119 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/royalrick/wechatwriter/app/config"
+	"github.com/royalrick/wechatwriter/app/wechat/wechattest"
+	"go.uber.org/zap"
+)
+
+// newMockProcessor 创建指向模拟服务的图片处理器
+func newMockProcessor(t *testing.T) (*Processor, *wechattest.Server) {
+	t.Helper()
+	srv := wechattest.NewServer()
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	cfg := &config.Config{
+		WechatAccounts: []config.WechatAccount{
+			{ID: "mock", AppID: "mock_appid", Secret: "mock_secret", APIBase: ts.URL},
+		},
+		DefaultAccount: "mock",
+		DataDir:        t.TempDir(),
+		MaxImageWidth:  1920,
+		MaxImageSize:   5 * 1024 * 1024,
+	}
+
+	p, err := NewProcessorForAccount(cfg, zap.NewNop(), "")
+	if err != nil {
+		t.Fatalf("NewProcessorForAccount() error = %v", err)
+	}
+	return p, srv
+}
+
+// writeTestPNG 写入一张纯色测试图片
+func writeTestPNG(t *testing.T, name string, c color.Color) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for x := 0; x < 32; x++ {
+		for y := 0; y < 32; y++ {
+			img.Set(x, y, c)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProcessorUploadLocalImageUsesCache(t *testing.T) {
+	p, srv := newMockProcessor(t)
+	red := color.RGBA{R: 255, A: 255}
+
+	first, err := p.UploadLocalImage(writeTestPNG(t, "a.png", red))
+	if err != nil {
+		t.Fatalf("UploadLocalImage() error = %v", err)
+	}
+
+	// 相同内容的另一个文件命中缓存
+	second, err := p.UploadLocalImage(writeTestPNG(t, "b.png", red))
+	if err != nil {
+		t.Fatalf("UploadLocalImage() error = %v", err)
+	}
+	if second.MediaID != first.MediaID {
+		t.Errorf("cached media_id = %s, want %s", second.MediaID, first.MediaID)
+	}
+
+	if _, err := p.UploadLocalImage(writeTestPNG(t, "c.png", color.RGBA{B: 255, A: 255})); err != nil {
+		t.Fatalf("UploadLocalImage() error = %v", err)
+	}
+
+	if n := len(srv.Materials()); n != 2 {
+		t.Errorf("materials on mock server = %d, want 2", n)
+	}
+	if n := len(p.Cache().Entries()); n != 2 {
+		t.Errorf("cache entries = %d, want 2", n)
+	}
+}
+
+func TestProcessorDeleteMaterialInvalidatesCache(t *testing.T) {
+	p, srv := newMockProcessor(t)
+	path := writeTestPNG(t, "a.png", color.RGBA{G: 255, A: 255})
+
+	result, err := p.UploadLocalImage(path)
+	if err != nil {
+		t.Fatalf("UploadLocalImage() error = %v", err)
+	}
+	if err := p.DeleteMaterial(result.MediaID); err != nil {
+		t.Fatalf("DeleteMaterial() error = %v", err)
+	}
+	if n := len(p.Cache().Entries()); n != 0 {
+		t.Errorf("cache entries after delete = %d, want 0", n)
+	}
+
+	// 删除后重新上传，不应复用已失效的 media_id
+	again, err := p.UploadLocalImage(path)
+	if err != nil {
+		t.Fatalf("UploadLocalImage() error = %v", err)
+	}
+	if again.MediaID == result.MediaID {
+		t.Error("re-upload reused deleted media_id")
+	}
+	if n := len(srv.Materials()); n != 1 {
+		t.Errorf("materials on mock server = %d, want 1", n)
+	}
+}
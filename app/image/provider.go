@@ -2,7 +2,10 @@ package image
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/royalrick/wechatwriter/app/config"
 )
@@ -15,15 +18,83 @@ type Provider interface {
 	// Generate 生成图片，返回图片 URL
 	// ctx: 上下文，用于超时控制
 	// prompt: 图片生成提示词
-	Generate(ctx context.Context, prompt string) (*GenerateResult, error)
+	// opts: 本次请求的生成选项，零值使用配置中的默认值
+	Generate(ctx context.Context, prompt string, opts GenerateOptions) (*GenerateResult, error)
+}
+
+// GenerateOptions 单次生成请求的选项，各提供者映射到自己的 API 参数，不支持的选项忽略
+type GenerateOptions struct {
+	Size           string // 尺寸 WIDTHxHEIGHT，优先于 AspectRatio
+	AspectRatio    string // 宽高比，如 16:9、2.35:1；按提供者支持的尺寸换算
+	Quality        string // 画质，如 standard、hd（OpenAI 兼容接口）
+	Style          string // 风格，如 vivid、natural（OpenAI 兼容接口）
+	NegativePrompt string // 反向提示词；不支持的提供者附加到提示词中
+	Seed           int64  // 随机种子，0 为随机
+	Count          int    // 生成张数，0 为 1 张
+	ResponseFormat string // 响应格式：url（默认）或 b64_json
 }
 
 // GenerateResult 图片生成结果
+// 返回图片地址的服务填 URL；返回 base64（b64_json、data URI）或本地服务（SD WebUI、
+// ComfyUI）直接返回图片内容时填 Data，上传时不再下载
 type GenerateResult struct {
-	URL           string // 生成的图片 URL
-	RevisedPrompt string // 优化后的提示词（某些提供者会返回）
-	Model         string // 实际使用的模型
-	Size          string // 实际尺寸
+	URL           string   // 生成的图片 URL
+	URLs          []string // Count > 1 时的全部图片 URL（第一张与 URL 相同）
+	Data          []byte   // 生成的图片内容（URL 为空时使用）
+	Images        [][]byte // Count > 1 时的全部图片内容（第一张与 Data 相同）
+	RevisedPrompt string   // 优化后的提示词（某些提供者会返回）
+	Model         string   // 实际使用的模型
+	Size          string   // 实际尺寸
+	Seed          int64    // 实际使用的随机种子（服务返回或随机生成时填写）
+	Provider      string   // 实际生成图片的提供者（降级链中可能不是首选）
+	Failures      []string // 成功之前失败的尝试，如 "OpenAI: 请求过于频繁 (rate_limit)"
+}
+
+// addImage 添加一张生成的图片：b64 或 data URI 解码为图片内容，普通地址记入 URLs
+func (r *GenerateResult) addImage(url, b64 string) error {
+	if b64 == "" && strings.HasPrefix(url, "data:") {
+		b64, url = url, ""
+	}
+	if b64 != "" {
+		data, err := decodeImageData(b64)
+		if err != nil {
+			return err
+		}
+		if len(r.Images) == 0 {
+			r.Data = data
+		}
+		r.Images = append(r.Images, data)
+	}
+	if url != "" {
+		if len(r.URLs) == 0 {
+			r.URL = url
+		}
+		r.URLs = append(r.URLs, url)
+	}
+	return nil
+}
+
+// empty 是否没有任何图片
+func (r *GenerateResult) empty() bool {
+	return r.URL == "" && len(r.Data) == 0
+}
+
+// decodeImageData 解码 base64 图片，兼容 data:image/png;base64, 前缀和 URL 安全编码
+func decodeImageData(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "data:") {
+		meta, payload, ok := strings.Cut(s, ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return nil, fmt.Errorf("unsupported data URI: %s", truncateSource(meta))
+		}
+		s = payload
+	}
+	s = strings.TrimSpace(s)
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if data, err := enc.DecodeString(s); err == nil {
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid base64 image data")
 }
 
 // GenerateError 图片生成错误
@@ -47,8 +118,33 @@ func (e *GenerateError) Unwrap() error {
 	return e.Original
 }
 
-// NewProvider 根据配置创建对应的 Provider
+// NewProvider 根据配置创建 Provider
+//
+// api.image_provider 可配置多个提供者组成降级链，返回的 ChainProvider 负责
+// 重试和降级；配置有误的备用提供者会被跳过（见 ChainProvider.Skipped），
+// 全部不可用时返回首选提供者的错误。
 func NewProvider(cfg *config.Config) (Provider, error) {
+	var providers []Provider
+	var skipped []error
+	for _, name := range cfg.ImageProviderChain() {
+		p, err := newProvider(cfg.ForImageProvider(name))
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		providers = append(providers, p)
+	}
+	if len(providers) == 0 {
+		return nil, errors.Unwrap(skipped[0])
+	}
+
+	chain := NewChainProvider(providers, cfg.ImageRetries, cfg.ImageRateLimits)
+	chain.skipped = skipped
+	return chain, nil
+}
+
+// newProvider 创建单个提供者
+func newProvider(cfg *config.Config) (Provider, error) {
 	switch cfg.ImageProvider {
 	case "tuzi":
 		if err := validateTuZiConfig(cfg); err != nil {
@@ -60,6 +156,10 @@ func NewProvider(cfg *config.Config) (Provider, error) {
 			return nil, err
 		}
 		return NewModelScopeProvider(cfg)
+	case "sdwebui", "sd":
+		return NewSDWebUIProvider(cfg)
+	case "comfyui":
+		return NewComfyUIProvider(cfg)
 	case "openai", "":
 		if err := validateOpenAIConfig(cfg); err != nil {
 			return nil, err
@@ -69,7 +169,7 @@ func NewProvider(cfg *config.Config) (Provider, error) {
 		return nil, &config.ConfigError{
 			Field:   "ImageProvider",
 			Message: fmt.Sprintf("未知的图片服务提供者: %s", cfg.ImageProvider),
-			Hint:    "支持的提供者: openai, tuzi, modelscope (或 ms)",
+			Hint:    "支持的提供者: openai, tuzi, modelscope (或 ms), sdwebui (或 sd), comfyui",
 		}
 	}
 }
@@ -124,3 +224,12 @@ func validateModelScopeConfig(cfg *config.Config) error {
 	// ModelScope API Base 有默认值，可以为空
 	return nil
 }
+
+// localAPIBase 本地服务的 API 地址：未配置 image_base_url（仍为默认的 OpenAI 地址）时使用 def
+func localAPIBase(cfg *config.Config, def string) string {
+	base := cfg.ImageAPIBase
+	if base == "" || base == config.DefaultImageAPIBase {
+		base = def
+	}
+	return strings.TrimRight(base, "/")
+}
@@ -0,0 +1,84 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/royalrick/wechatwriter/app/config"
+)
+
+func TestDecodeImageData(t *testing.T) {
+	std := base64.StdEncoding.EncodeToString(fakePNG)
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"raw base64", std},
+		{"data uri", "data:image/png;base64," + std},
+		{"unpadded url-safe", base64.RawURLEncoding.EncodeToString(fakePNG)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeImageData(tt.in)
+			if err != nil {
+				t.Fatalf("decodeImageData() error = %v", err)
+			}
+			if !bytes.Equal(got, fakePNG) {
+				t.Errorf("decodeImageData() = %q", got)
+			}
+		})
+	}
+
+	if _, err := decodeImageData("data:image/svg+xml,<svg/>"); err == nil {
+		t.Error("expected error for non-base64 data URI")
+	}
+}
+
+func TestOpenAIProviderBase64Response(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(fakePNG)
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": []map[string]string{
+				{"b64_json": encoded},
+				{"url": "data:image/png;base64," + encoded},
+			},
+		})
+	}))
+	defer server.Close()
+
+	p, _ := NewOpenAIProvider(&config.Config{ImageAPIKey: "k", ImageAPIBase: server.URL, ImageModel: "gpt-image-1"})
+	result, err := p.Generate(context.Background(), "猫", GenerateOptions{Count: 2, ResponseFormat: "b64_json"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if _, ok := body["response_format"]; ok {
+		t.Errorf("gpt-image request should not send response_format: %v", body)
+	}
+	if result.URL != "" || len(result.URLs) != 0 {
+		t.Errorf("URL = %q, URLs = %v; data URIs must not be kept as URLs", result.URL, result.URLs)
+	}
+	if !bytes.Equal(result.Data, fakePNG) || len(result.Images) != 2 {
+		t.Errorf("Data = %q, Images = %d", result.Data, len(result.Images))
+	}
+}
+
+func TestTuZiProviderNoImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{"revised_prompt":"cat"}]}`))
+	}))
+	defer server.Close()
+
+	p, _ := NewTuZiProvider(&config.Config{ImageAPIKey: "k", ImageAPIBase: server.URL})
+	_, err := p.Generate(context.Background(), "猫", GenerateOptions{})
+	if code := errorCode(err); code != "no_url" {
+		t.Errorf("error code = %q, want no_url (err = %v)", code, err)
+	}
+}
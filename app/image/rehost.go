@@ -0,0 +1,349 @@
+package image
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/royalrick/wechatwriter/app/converter"
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"go.uber.org/zap"
+)
+
+// 图文内图片（uploadimg）限制
+const (
+	articleImageMaxSize = 1 << 20 // 1MB
+	articleCachePrefix  = "uploadimg:"
+)
+
+// wechatImageHosts 已托管在微信的图片域名，无需重新上传
+var wechatImageHosts = []string{"mmbiz.qpic.cn", "mmbiz.qlogo.cn", "mmecoa.qpic.cn"}
+
+// imgTagPattern 匹配 <img> 标签
+var imgTagPattern = regexp.MustCompile(`(?is)<img\b[^>]*>`)
+
+// imgSrcPattern 匹配 <img> 的 src / data-src 属性
+var imgSrcPattern = regexp.MustCompile(`(?is)(\s(?:data-src|src)\s*=\s*)(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// imgAltPattern 匹配 <img> 的 alt 属性
+var imgAltPattern = regexp.MustCompile(`(?is)\salt\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// bgURLPattern 匹配 CSS background / background-image 中的 url()
+var bgURLPattern = regexp.MustCompile(`(?is)(background(?:-image)?\s*:[^;{}<>]*?url\(\s*(?:&quot;|"|')?)(.*?)((?:&quot;|"|')?\s*\))`)
+
+// RehostedImage 单张图片的转存结果
+type RehostedImage struct {
+	Original  string `json:"original"`
+	WechatURL string `json:"wechat_url,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RehostReport HTML 图片转存报告
+type RehostReport struct {
+	Total    int             `json:"total"`    // 发现的图片数（去重后）
+	Rehosted int             `json:"rehosted"` // 转存成功
+	Skipped  int             `json:"skipped"`  // 已在微信托管，无需转存
+	Failed   int             `json:"failed"`   // 转存失败，保留原地址
+	Images   []RehostedImage `json:"images,omitempty"`
+}
+
+// RehostHTML 将 HTML 中的外部图片（<img> 和 CSS background-image）转存到微信并改写地址
+// 本地路径相对 baseDir 解析，且只读取 baseDir 下的文件；单张图片失败时保留原地址并记录在报告中
+func (p *Processor) RehostHTML(content, baseDir string) (string, *RehostReport) {
+	report := &RehostReport{}
+	rewritten := make(map[string]string)
+
+	rehost := func(src string, watermark bool) string {
+		key := strings.TrimSpace(src)
+		cacheKey := key
+		if !watermark {
+			cacheKey += "\x00nowm"
+		}
+		if url, ok := rewritten[cacheKey]; ok {
+			return url
+		}
+		if !needsRehost(key) {
+			if key != "" && !strings.HasPrefix(key, "#") {
+				report.Total++
+				report.Skipped++
+			}
+			rewritten[cacheKey] = src
+			return src
+		}
+
+		report.Total++
+		url, err := p.uploadArticleImage(key, baseDir, watermark)
+		if err != nil {
+			p.log.Warn("rehost image failed, keeping original",
+				zap.String("src", truncateSource(key)),
+				zap.Error(err))
+			report.Failed++
+			report.Images = append(report.Images, RehostedImage{Original: truncateSource(key), Error: err.Error()})
+			rewritten[cacheKey] = src
+			return src
+		}
+
+		report.Rehosted++
+		report.Images = append(report.Images, RehostedImage{Original: truncateSource(key), WechatURL: url})
+		rewritten[cacheKey] = url
+		return url
+	}
+
+	content = imgTagPattern.ReplaceAllStringFunc(content, func(tag string) string {
+		watermark := true
+		if m := imgAltPattern.FindStringSubmatch(tag); m != nil {
+			watermark = !converter.HasNoWatermark(m[1] + m[2] + m[3])
+		}
+		return imgSrcPattern.ReplaceAllStringFunc(tag, func(attr string) string {
+			m := imgSrcPattern.FindStringSubmatch(attr)
+			src := m[2] + m[3] + m[4]
+			// 属性值解码后处理，写回时重新转义，避免地址中的引号、& 等破坏标签
+			return m[1] + `"` + html.EscapeString(rehost(decodeEntities(src), watermark)) + `"`
+		})
+	})
+
+	// 背景图是装饰性图片，不加水印
+	content = bgURLPattern.ReplaceAllStringFunc(content, func(decl string) string {
+		m := bgURLPattern.FindStringSubmatch(decl)
+		return m[1] + rehost(decodeEntities(m[2]), false) + m[3]
+	})
+
+	return content, report
+}
+
+// UploadArticleImage 将图片（baseDir 下的本地路径、http(s) 地址或 data URI）上传为图文内图片，返回微信图片 URL
+// 非 jpg/png 格式先转码，超过 1MB 时压缩；相同内容命中上传缓存时跳过上传
+func (p *Processor) UploadArticleImage(src, baseDir string) (string, error) {
+	return p.uploadArticleImage(src, baseDir, true)
+}
+
+// uploadArticleImage 上传图文内图片；watermark 为 false 时不加水印
+func (p *Processor) uploadArticleImage(src, baseDir string, watermark bool) (string, error) {
+	if p.ws == nil {
+		return "", fmt.Errorf("未配置微信公众号账号，无法上传图片")
+	}
+
+	path, cleanup, err := fetchImageSource(src, baseDir)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	processed, cleanupProcessed, err := p.prepareArticleImage(path, watermark)
+	if err != nil {
+		return "", err
+	}
+	defer cleanupProcessed()
+
+	var key string
+	var size int64
+	if p.cache != nil {
+		hash, n, err := HashFile(processed)
+		if err != nil {
+			p.log.Warn("hash image failed, skipping upload cache", zap.Error(err))
+		} else {
+			// 与永久素材共用缓存文件，键加前缀区分（图文内图片没有 media_id）
+			key, size = articleCachePrefix+hash, n
+			if entry, ok := p.cache.Lookup(key); ok {
+				p.log.Info("upload cache hit, skipping upload", zap.String("source", truncateSource(src)))
+				return entry.WechatURL, nil
+			}
+		}
+	}
+
+	url, err := p.ws.UploadArticleImage(processed)
+	if err != nil {
+		return "", err
+	}
+
+	if p.cache != nil && key != "" {
+		if err := p.cache.Store(UploadCacheEntry{
+			Hash:      key,
+			WechatURL: url,
+			Source:    truncateSource(src),
+			Size:      size,
+		}); err != nil {
+			p.log.Warn("save upload cache failed", zap.Error(err))
+		}
+	}
+	return url, nil
+}
+
+// prepareArticleImage 转为 jpg/png、加水印并压缩到 1MB 以内，返回待上传文件
+func (p *Processor) prepareArticleImage(path string, watermark bool) (string, func(), error) {
+	noop := func() {}
+
+	format, err := GetImageFormat(path)
+	if err != nil {
+		return "", noop, fmt.Errorf("not a supported image: %w", err)
+	}
+
+	current, cleanup := path, noop
+	if format != "jpeg" && format != "png" {
+		converted, err := transcodeForUpload(path, format)
+		if err != nil {
+			return "", noop, err
+		}
+		current, cleanup = converted, func() { os.Remove(converted) }
+	}
+
+	info, err := os.Stat(current)
+	if err != nil {
+		cleanup()
+		return "", noop, err
+	}
+
+	// 摆正方向、去除元数据、加水印；超过 1MB 时压缩
+	compressor := p.compressorFor(watermark).withMaxSize(articleImageMaxSize)
+	compressed, ok, err := compressor.CompressImage(current)
+	if err != nil || !ok {
+		if info.Size() <= articleImageMaxSize {
+			if err != nil {
+				p.log.Warn("sanitize image failed, using original", zap.Error(err))
+			}
+			return current, cleanup, nil
+		}
+		cleanup()
+		if err == nil {
+			err = fmt.Errorf("image is %d bytes, exceeds the 1MB limit for article images", info.Size())
+		}
+		return "", noop, err
+	}
+	cleanup()
+
+	if info, err := os.Stat(compressed); err == nil && info.Size() > articleImageMaxSize {
+		os.Remove(compressed)
+		return "", noop, fmt.Errorf("image is still %d bytes after compression, exceeds the 1MB limit for article images", info.Size())
+	}
+	return compressed, func() { os.Remove(compressed) }, nil
+}
+
+// fetchImageSource 将图片来源转为本地文件，返回路径和清理函数
+func fetchImageSource(src, baseDir string) (string, func(), error) {
+	noop := func() {}
+
+	switch {
+	case strings.HasPrefix(src, "data:"):
+		data, ext, err := decodeDataURI(src)
+		if err != nil {
+			return "", noop, err
+		}
+		tmp, err := os.CreateTemp("", "wechatwriter_datauri_*"+ext)
+		if err != nil {
+			return "", noop, fmt.Errorf("create temp file: %w", err)
+		}
+		defer tmp.Close()
+		if _, err := tmp.Write(data); err != nil {
+			os.Remove(tmp.Name())
+			return "", noop, fmt.Errorf("write temp file: %w", err)
+		}
+		return tmp.Name(), func() { os.Remove(tmp.Name()) }, nil
+
+	case strings.HasPrefix(src, "//"):
+		src = "https:" + src
+		fallthrough
+	case strings.HasPrefix(src, "http://"), strings.HasPrefix(src, "https://"):
+		path, err := wechat.DownloadFile(src)
+		if err != nil {
+			return "", noop, err
+		}
+		return path, func() { os.Remove(path) }, nil
+
+	default:
+		path, err := localImagePath(strings.TrimPrefix(src, "file://"), baseDir)
+		if err != nil {
+			return "", noop, err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return "", noop, fmt.Errorf("file not found: %s", path)
+		}
+		return path, noop, nil
+	}
+}
+
+// localImagePath 解析 HTML 中的本地图片路径，只允许 baseDir（文章所在目录）下的文件，
+// 避免 HTML 中的绝对路径或 ../ 读取并上传任意本地文件
+func localImagePath(path, baseDir string) (string, error) {
+	if baseDir == "" {
+		return "", fmt.Errorf("local image %s: article directory unknown", path)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+
+	dir, err := filepath.Abs(baseDir)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	// 解析符号链接后再比较，目录内指向外部的链接同样拒绝
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	if rel, err := filepath.Rel(dir, abs); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("local image %s is outside the article directory %s", path, baseDir)
+	}
+	return path, nil
+}
+
+// decodeDataURI 解析 data:image/...;base64,... 图片，返回内容和对应的文件扩展名
+func decodeDataURI(uri string) ([]byte, string, error) {
+	meta, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return nil, "", fmt.Errorf("invalid data URI")
+	}
+	mediaType, isBase64 := strings.CutSuffix(meta, ";base64")
+	if !isBase64 {
+		return nil, "", fmt.Errorf("unsupported data URI encoding: %s", meta)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(payload))
+	if err != nil {
+		return nil, "", fmt.Errorf("decode data URI: %w", err)
+	}
+
+	ext := "." + strings.TrimPrefix(strings.ToLower(mediaType), "image/")
+	if ext == ".jpeg" || !strings.HasPrefix(strings.ToLower(mediaType), "image/") {
+		ext = ".jpg"
+	}
+	return data, ext, nil
+}
+
+// needsRehost 图片地址是否需要转存（已在微信托管、空地址、锚点不处理）
+func needsRehost(src string) bool {
+	if src == "" || strings.HasPrefix(src, "#") {
+		return false
+	}
+	lower := strings.ToLower(src)
+	if strings.HasPrefix(lower, "javascript:") || strings.HasPrefix(lower, "about:") {
+		return false
+	}
+	for _, host := range wechatImageHosts {
+		if strings.Contains(lower, "://"+host+"/") || strings.HasPrefix(lower, "//"+host+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeEntities 还原属性值中常见的 HTML 实体
+func decodeEntities(s string) string {
+	return strings.NewReplacer("&amp;", "&", "&quot;", `"`, "&#39;", "'").Replace(s)
+}
+
+// truncateSource 截断过长的来源（data URI）用于日志和报告
+func truncateSource(src string) string {
+	if runes := []rune(src); len(runes) > 80 {
+		return string(runes[:80]) + "..."
+	}
+	return src
+}
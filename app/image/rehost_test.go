@@ -0,0 +1,153 @@
+package image
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/royalrick/wechatwriter/app/wechat/wechattest"
+)
+
+func TestRehostHTML(t *testing.T) {
+	p, srv := newMockProcessor(t)
+
+	// 外部图床
+	remote, err := os.ReadFile(writeTestPNG(t, "remote.png", color.RGBA{R: 255, A: 255}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/remote.png" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(remote)
+	}))
+	t.Cleanup(host.Close)
+
+	// 相对 HTML 文件的本地图片
+	local := writeTestPNG(t, "local.png", color.RGBA{G: 255, A: 255})
+	baseDir := filepath.Dir(local)
+
+	data, err := os.ReadFile(writeTestPNG(t, "inline.png", color.RGBA{B: 255, A: 255}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataURI := "data:image/png;base64," + base64.StdEncoding.EncodeToString(data)
+
+	wechatURL := "https://mmbiz.qpic.cn/mmbiz_png/abc/0?wx_fmt=png"
+	html := `<p><img src="` + host.URL + `/remote.png" alt="a"></p>` +
+		`<p><img data-src='local.png'><img src="` + host.URL + `/remote.png"></p>` +
+		`<section style="background-image: url(&quot;` + dataURI + `&quot;)"></section>` +
+		`<p><img src="` + wechatURL + `"><img src="` + host.URL + `/missing.png"></p>`
+
+	out, report := p.RehostHTML(html, baseDir)
+
+	if report.Total != 5 || report.Rehosted != 3 || report.Skipped != 1 || report.Failed != 1 {
+		t.Errorf("report = %+v, want total 5, rehosted 3, skipped 1, failed 1", report)
+	}
+	if n := srv.Calls("/cgi-bin/media/uploadimg"); n != 3 {
+		t.Errorf("uploadimg calls = %d, want 3 (duplicate src uploaded once)", n)
+	}
+	for _, old := range []string{host.URL + "/remote.png", "local.png", dataURI} {
+		if strings.Contains(out, old) {
+			t.Errorf("output still contains %.40s", old)
+		}
+	}
+	for _, keep := range []string{wechatURL, host.URL + "/missing.png"} {
+		if !strings.Contains(out, keep) {
+			t.Errorf("output lost %s", keep)
+		}
+	}
+	if !strings.Contains(out, `url(&quot;`) {
+		t.Errorf("background-image quoting changed: %s", out)
+	}
+
+	// 再次转存命中上传缓存，不再调用接口
+	if _, report := p.RehostHTML(html, baseDir); report.Rehosted != 3 {
+		t.Errorf("second run rehosted = %d, want 3", report.Rehosted)
+	}
+	if n := srv.Calls("/cgi-bin/media/uploadimg"); n != 3 {
+		t.Errorf("uploadimg calls after second run = %d, want 3", n)
+	}
+}
+
+func TestUploadArticleImageTranscodesGIF(t *testing.T) {
+	p, _ := newMockProcessor(t)
+
+	var buf bytes.Buffer
+	buf.WriteString("GIF89a\x01\x00\x01\x00\x80\x00\x00\xff\x00\x00\x00\x00\x00!\xf9\x04\x01\x00\x00\x00\x00,\x00\x00\x00\x00\x01\x00\x01\x00\x00\x02\x02D\x01\x00;")
+	path := filepath.Join(t.TempDir(), "dot.gif")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	url, err := p.UploadArticleImage(path, filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("UploadArticleImage() error = %v", err)
+	}
+	if !strings.Contains(url, "://"+wechattest.ImageHost+"/") {
+		t.Errorf("url = %s, want uploaded image url", url)
+	}
+
+	// 模拟服务返回的地址与真实接口同域名，再次转存时跳过
+	_, report := p.RehostHTML(`<img src="`+url+`">`, "")
+	if report.Skipped != 1 || report.Rehosted != 0 {
+		t.Errorf("rehost of uploaded url report = %+v, want skipped", report)
+	}
+}
+
+func TestRehostHTMLRestrictsLocalPaths(t *testing.T) {
+	p, srv := newMockProcessor(t)
+
+	outside := writeTestPNG(t, "secret.png", color.RGBA{R: 255, A: 255})
+	baseDir := t.TempDir()
+	link := filepath.Join(baseDir, "link.png")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(baseDir, outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := `<img src="` + outside + `"><img src="file://` + outside + `"><img src="` + rel + `"><img src="link.png">`
+	out, report := p.RehostHTML(html, baseDir)
+	if report.Failed != 4 || report.Rehosted != 0 {
+		t.Errorf("report = %+v, want all 4 local images outside the article directory rejected", report)
+	}
+	if n := srv.Calls("/cgi-bin/media/uploadimg"); n != 0 {
+		t.Errorf("uploadimg calls = %d, want 0", n)
+	}
+	if out != html {
+		t.Errorf("rejected images should keep their original src: %s", out)
+	}
+}
+
+func TestRehostHTMLEscapesRewrittenSrc(t *testing.T) {
+	p, _ := newMockProcessor(t)
+	host := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(host.Close)
+
+	// 转存失败保留原地址：解码后的 & 和引号写回时需重新转义
+	html := `<img src="` + host.URL + `/a.png?x=1&amp;y=&quot;2&quot;" alt="a">` +
+		`<img src='` + host.URL + `/b.png?q="b"'>`
+	out, report := p.RehostHTML(html, "")
+	if report.Failed != 2 {
+		t.Fatalf("report = %+v, want 2 failed", report)
+	}
+	for _, want := range []string{
+		`src="` + host.URL + `/a.png?x=1&amp;y=&#34;2&#34;" alt="a">`,
+		`src="` + host.URL + `/b.png?q=&#34;b&#34;">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output = %s, want it to contain %s", out, want)
+		}
+	}
+}
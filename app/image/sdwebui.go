@@ -0,0 +1,240 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/config"
+)
+
+// SDWebUIProvider 自建 Stable Diffusion WebUI（AUTOMATIC1111 / Forge）图片生成服务
+// 调用 /sdapi/v1/txt2img，图片以 base64 返回
+type SDWebUIProvider struct {
+	baseURL string
+	apiKey  string // 启用 --api-auth 时的 user:password（可选）
+	model   string // sd_model_checkpoint，空则使用 WebUI 当前模型
+	size    string
+	steps   int
+	client  *http.Client
+}
+
+// NewSDWebUIProvider 创建 SD WebUI Provider
+func NewSDWebUIProvider(cfg *config.Config) (*SDWebUIProvider, error) {
+	size := cfg.ImageSize
+	if size == "" {
+		size = "1024x1024"
+	}
+
+	return &SDWebUIProvider{
+		baseURL: localAPIBase(cfg, "http://127.0.0.1:7860"),
+		apiKey:  cfg.ImageAPIKey,
+		model:   localModel(cfg),
+		size:    size,
+		steps:   25,
+		client: &http.Client{
+			Timeout: 5 * time.Minute, // 本地显卡生成较慢
+		},
+	}, nil
+}
+
+// Name 返回提供者名称
+func (p *SDWebUIProvider) Name() string {
+	return "SDWebUI"
+}
+
+// Generate 生成图片
+func (p *SDWebUIProvider) Generate(ctx context.Context, prompt string, opts GenerateOptions) (*GenerateResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "invalid_options",
+			Message:  fmt.Sprintf("生成选项错误: %v", err),
+			Original: err,
+		}
+	}
+	var width, height int
+	size, err := opts.resolveSize(p.size)
+	if err == nil {
+		width, height, err = parseSize(size)
+	}
+	if err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "invalid_size",
+			Message:  fmt.Sprintf("图片尺寸格式错误: %v", err),
+			Hint:     "请使用 WIDTHxHEIGHT 或 W:H 格式，如 1024x1024、16:9",
+			Original: err,
+		}
+	}
+
+	seed := opts.Seed
+	if seed == 0 {
+		seed = -1 // WebUI 中 -1 表示随机
+	}
+	reqBody := map[string]any{
+		"prompt":          prompt,
+		"negative_prompt": opts.NegativePrompt,
+		"width":           width,
+		"height":          height,
+		"seed":            seed,
+		"batch_size":      opts.count(),
+		"steps":           p.steps,
+	}
+	if p.model != "" {
+		reqBody["override_settings"] = map[string]any{"sd_model_checkpoint": p.model}
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "marshal_error",
+			Message:  "请求构造失败",
+			Original: err,
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/sdapi/v1/txt2img", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "request_error",
+			Message:  "创建请求失败",
+			Original: err,
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setBasicAuth(req, p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "network_error",
+			Message:  "无法连接 Stable Diffusion WebUI",
+			Hint:     "确认 WebUI 已使用 --api 参数启动，image_base_url 指向其地址（如 http://192.168.1.10:7860）",
+			Original: err,
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, localErrorResponse(p.Name(), resp)
+	}
+
+	var result struct {
+		Images []string `json:"images"`
+		Info   string   `json:"info"` // JSON 字符串，含实际使用的 seed
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "decode_error",
+			Message:  "响应解析失败",
+			Original: err,
+		}
+	}
+
+	images, err := decodeBase64Images(result.Images)
+	if err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "decode_error",
+			Message:  "图片 base64 解码失败",
+			Original: err,
+		}
+	}
+	if len(images) == 0 {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "no_image",
+			Message:  "未生成图片",
+		}
+	}
+
+	return &GenerateResult{
+		Data:   images[0],
+		Images: images,
+		Model:  p.model, // 空表示 WebUI 当前加载的模型
+		Size:   size,
+		Seed:   infoSeed(result.Info),
+	}, nil
+}
+
+// infoSeed 从 txt2img 响应的 info 中取出实际使用的随机种子
+func infoSeed(info string) int64 {
+	var parsed struct {
+		Seed int64 `json:"seed"`
+	}
+	if json.Unmarshal([]byte(info), &parsed) != nil {
+		return 0
+	}
+	return parsed.Seed
+}
+
+// localModel 本地服务使用的模型：未修改默认的 dall-e-3 时由服务自行决定
+func localModel(cfg *config.Config) string {
+	if cfg.ImageModel == "dall-e-3" {
+		return ""
+	}
+	return cfg.ImageModel
+}
+
+// setBasicAuth 本地服务开启认证时，image_key 填 user:password
+func setBasicAuth(req *http.Request, credentials string) {
+	if credentials == "" {
+		return
+	}
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+}
+
+// decodeBase64Images 解码 base64 图片（兼容带 data URI 前缀的写法）
+func decodeBase64Images(encoded []string) ([][]byte, error) {
+	images := make([][]byte, 0, len(encoded))
+	for _, s := range encoded {
+		data, err := decodeImageData(s)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, data)
+	}
+	return images, nil
+}
+
+// localErrorResponse 处理本地服务的错误响应
+func localErrorResponse(provider string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return &GenerateError{
+			Provider: provider,
+			Code:     "unauthorized",
+			Message:  "本地图片服务拒绝访问",
+			Hint:     "服务开启了认证时，在 api.image_key 中填写 user:password",
+			Original: fmt.Errorf("status %d: %s", resp.StatusCode, string(body)),
+		}
+	case http.StatusNotFound:
+		return &GenerateError{
+			Provider: provider,
+			Code:     "not_found",
+			Message:  "接口不存在",
+			Hint:     "Stable Diffusion WebUI 需要以 --api 参数启动；确认 image_provider 与服务类型一致",
+			Original: fmt.Errorf("status 404: %s", string(body)),
+		}
+	default:
+		return &GenerateError{
+			Provider: provider,
+			Code:     "unknown",
+			Message:  fmt.Sprintf("本地图片服务返回错误 (HTTP %d)", resp.StatusCode),
+			Hint:     "查看服务端日志，常见原因是显存不足或模型未加载",
+			Original: fmt.Errorf("status %d: %s", resp.StatusCode, string(body)),
+		}
+	}
+}
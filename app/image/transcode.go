@@ -0,0 +1,322 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+
+	"github.com/disintegration/imaging"
+	"go.uber.org/zap"
+	_ "golang.org/x/image/bmp"  // 注册 BMP 解码器
+	_ "golang.org/x/image/tiff" // 注册 TIFF 解码器
+	_ "golang.org/x/image/webp" // 注册 WebP 解码器
+)
+
+// 微信对动图的限制
+const (
+	gifMaxSize = 2 << 20 // 2MB，超过后图文中的 GIF 无法显示
+
+	gifMinFrames   = 4  // 抽帧时至少保留的帧数
+	gifMaxAttempts = 10 // 抽帧 / 缩小的最多尝试次数
+	gifScaleStep   = 0.8
+
+	// gifMaxCoalescedPixels 合成后保留的完整帧像素总数上限（约 256MB），
+	// 帧数多、尺寸大的动图在合成时就按间隔抽帧，避免每帧一份完整画面占满内存
+	gifMaxCoalescedPixels = 64 << 20
+)
+
+// Transcode 将微信素材库不接受的格式转为可上传的格式
+// WebP / BMP / TIFF 转为 JPEG，带透明像素时转为 PNG；
+// 超过 2MB 的 GIF 动图通过抽帧和缩小保持动画，不压成静态图。
+// 返回: 转换后的文件路径, 是否进行了转换, 错误
+func (c *Compressor) Transcode(filePath string) (string, bool, error) {
+	format, err := GetImageFormat(filePath)
+	if err != nil {
+		return "", false, fmt.Errorf("unsupported image: %w", err)
+	}
+
+	switch format {
+	case "jpeg", "png":
+		return "", false, nil
+	case "gif":
+		return c.fitGIF(filePath)
+	}
+
+	out, err := transcodeForUpload(filePath, format)
+	if err != nil {
+		return "", false, err
+	}
+	c.log.Info("image transcoded",
+		zap.String("path", filePath),
+		zap.String("from", format),
+		zap.String("output_path", out))
+	return out, true, nil
+}
+
+// fitGIF 缩减超过 2MB（target 模式下为 GIF 目标大小）的 GIF 动图；静态 GIF 和未超限的动图保持原样
+func (c *Compressor) fitGIF(filePath string) (string, bool, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", false, fmt.Errorf("stat file: %w", err)
+	}
+	limit := min(c.limitFor(ImageKindGIF), gifMaxSize)
+	if info.Size() <= limit {
+		return "", false, nil
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", false, fmt.Errorf("open file: %w", err)
+	}
+	g, err := gif.DecodeAll(f)
+	f.Close()
+	if err != nil {
+		return "", false, fmt.Errorf("decode gif: %w", err)
+	}
+	if len(g.Image) < 2 {
+		return "", false, nil // 静态图交给 CompressImage
+	}
+
+	data, err := shrinkGIF(g, c.maxWidth, limit)
+	if err != nil {
+		return "", false, err
+	}
+
+	tmp, err := os.CreateTemp("", "wechatwriter_gif_*.gif")
+	if err != nil {
+		return "", false, fmt.Errorf("create temp file: %w", err)
+	}
+	defer tmp.Close()
+	if _, err := tmp.Write(data); err != nil {
+		os.Remove(tmp.Name())
+		return "", false, fmt.Errorf("write temp file: %w", err)
+	}
+
+	c.log.Info("animated gif reduced",
+		zap.String("path", filePath),
+		zap.Int64("original_size", info.Size()),
+		zap.Int("reduced_size", len(data)),
+		zap.Int("original_frames", len(g.Image)))
+	return tmp.Name(), true, nil
+}
+
+// shrinkGIF 交替抽帧和缩小，直到动图不超过 maxSize
+func shrinkGIF(g *gif.GIF, maxWidth int, maxSize int64) ([]byte, error) {
+	scale := 1.0
+	if width := gifBounds(g).Dx(); maxWidth > 0 && width > maxWidth {
+		scale = float64(maxWidth) / float64(width)
+	}
+	// 合成时已按 maxWidth 缩小，之后的缩放相对于合成结果
+	frames, delays, palettes := coalesceGIF(g, scale, gifMaxCoalescedPixels)
+	scale = 1.0
+
+	var size int
+	for attempt := 0; attempt < gifMaxAttempts; attempt++ {
+		data, err := encodeGIF(frames, delays, palettes, scale, g.LoopCount)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) <= maxSize {
+			return data, nil
+		}
+		size = len(data)
+
+		if attempt%2 == 0 && len(frames) > gifMinFrames {
+			frames, delays, palettes = dropFrames(frames, delays, palettes)
+		} else {
+			scale *= gifScaleStep
+		}
+	}
+	return nil, fmt.Errorf("animated gif is still %d bytes after dropping frames and resizing, exceeds the %dKB limit", size, maxSize>>10)
+}
+
+// gifBounds 动图的画布范围（逻辑屏幕尺寸缺失时取所有帧的并集）
+func gifBounds(g *gif.GIF) image.Rectangle {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		for _, frame := range g.Image {
+			bounds = bounds.Union(frame.Bounds())
+		}
+	}
+	return bounds
+}
+
+// coalesceGIF 按处置方式合成每一帧的完整画面，按 scale 缩小后保留
+// 保留的像素总数超过 maxPixels 时按固定间隔抽帧：所有帧仍参与合成，
+// 只有保留的帧生成完整画面，被跳过帧的时长并入前一个保留帧
+func coalesceGIF(g *gif.GIF, scale float64, maxPixels int) ([]*image.NRGBA, []int, []color.Palette) {
+	bounds := gifBounds(g)
+	width := max(1, int(float64(bounds.Dx())*scale))
+	height := max(1, int(float64(bounds.Dy())*scale))
+	stride := 1
+	if total := len(g.Image) * width * height; total > maxPixels {
+		stride = (total + maxPixels - 1) / maxPixels
+	}
+
+	canvas := image.NewNRGBA(bounds)
+	n := (len(g.Image) + stride - 1) / stride
+	frames := make([]*image.NRGBA, 0, n)
+	delays := make([]int, 0, n)
+	palettes := make([]color.Palette, 0, n)
+	for i, frame := range g.Image {
+		disposal := byte(gif.DisposalNone)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var previous *image.NRGBA
+		if disposal == gif.DisposalPrevious {
+			previous = imaging.Clone(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		delay := 0
+		if i < len(g.Delay) {
+			delay = g.Delay[i]
+		}
+		if i%stride == 0 {
+			full := imaging.Clone(canvas)
+			if width != bounds.Dx() || height != bounds.Dy() {
+				full = imaging.Resize(full, width, height, imaging.Lanczos)
+			}
+			frames = append(frames, full)
+			delays = append(delays, delay)
+			palettes = append(palettes, withTransparent(frame.Palette))
+		} else {
+			delays[len(delays)-1] += delay
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return frames, delays, palettes
+}
+
+// dropFrames 隔帧丢弃，被丢弃帧的时长并入前一帧，总时长不变
+func dropFrames(frames []*image.NRGBA, delays []int, palettes []color.Palette) ([]*image.NRGBA, []int, []color.Palette) {
+	var (
+		keptFrames   []*image.NRGBA
+		keptDelays   []int
+		keptPalettes []color.Palette
+	)
+	for i := range frames {
+		if i%2 == 1 {
+			keptDelays[len(keptDelays)-1] += delays[i]
+			continue
+		}
+		keptFrames = append(keptFrames, frames[i])
+		keptDelays = append(keptDelays, delays[i])
+		keptPalettes = append(keptPalettes, palettes[i])
+	}
+	return keptFrames, keptDelays, keptPalettes
+}
+
+// encodeGIF 按比例缩放完整帧并编码；每帧沿用原调色板，先清空画布再绘制
+func encodeGIF(frames []*image.NRGBA, delays []int, palettes []color.Palette, scale float64, loopCount int) ([]byte, error) {
+	bounds := frames[0].Bounds()
+	width := max(1, int(float64(bounds.Dx())*scale))
+	height := max(1, int(float64(bounds.Dy())*scale))
+
+	out := &gif.GIF{
+		LoopCount: loopCount,
+		Config:    image.Config{Width: width, Height: height},
+	}
+	for i, frame := range frames {
+		var src image.Image = frame
+		if width != bounds.Dx() {
+			src = imaging.Resize(frame, width, height, imaging.Lanczos)
+		}
+		paletted := image.NewPaletted(image.Rect(0, 0, width, height), palettes[i])
+		draw.Draw(paletted, paletted.Bounds(), src, src.Bounds().Min, draw.Src)
+
+		out.Image = append(out.Image, paletted)
+		out.Delay = append(out.Delay, delays[i])
+		out.Disposal = append(out.Disposal, gif.DisposalBackground)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, out); err != nil {
+		return nil, fmt.Errorf("encode gif: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// withTransparent 确保调色板中有透明色，用于合成后画布上的透明区域
+func withTransparent(p color.Palette) color.Palette {
+	for _, c := range p {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			return p
+		}
+	}
+	if len(p) >= 256 {
+		return p
+	}
+	out := make(color.Palette, len(p), len(p)+1)
+	copy(out, p)
+	return append(out, color.Transparent)
+}
+
+// transcodeForUpload 将 gif/bmp/tiff/webp 等格式转为 jpg；带透明像素（或 gif）时转为 png
+func transcodeForUpload(path, format string) (string, error) {
+	img, err := imaging.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("decode %s image: %w", format, err)
+	}
+
+	ext := ".jpg"
+	if format == "gif" || hasTransparency(img) {
+		ext = ".png"
+	}
+	tmp, err := os.CreateTemp("", "wechatwriter_transcode_*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+	defer tmp.Close()
+
+	if ext == ".png" {
+		err = png.Encode(tmp, img)
+	} else {
+		err = jpeg.Encode(tmp, img, &jpeg.Options{Quality: 90})
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("encode image: %w", err)
+	}
+	return tmp.Name(), nil
+}
+
+// hasTransparency 图片是否含有非不透明的像素
+func hasTransparency(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return !o.Opaque()
+	}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isAnimatedGIF 文件是否为多帧 GIF
+func isAnimatedGIF(filePath string) bool {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	g, err := gif.DecodeAll(f)
+	return err == nil && len(g.Image) > 1
+}
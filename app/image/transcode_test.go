@@ -0,0 +1,174 @@
+package image
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+// writeTestImage 写入一张 BMP 或 TIFF 图片，transparent 时左半边透明
+// （BMP 编码不保留透明度，透明图片用 TIFF）
+func writeTestImage(t *testing.T, transparent bool) string {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 16; y++ {
+			c := color.NRGBA{R: 200, G: 100, B: 50, A: 255}
+			if transparent && x < 8 {
+				c.A = 0
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	name, encode := "test.bmp", bmp.Encode
+	if transparent {
+		name, encode = "test.tiff", func(w io.Writer, m image.Image) error { return tiff.Encode(w, m, nil) }
+	}
+	if err := encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCompressorTranscode(t *testing.T) {
+	c := NewCompressor(zap.NewNop(), 1920, 5*1024*1024)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantFormat string
+	}{
+		{"opaque bmp", writeTestImage(t, false), "jpeg"},
+		{"transparent tiff", writeTestImage(t, true), "png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, ok, err := c.Transcode(tt.path)
+			if err != nil || !ok {
+				t.Fatalf("Transcode() = %q, %v, %v", out, ok, err)
+			}
+			defer os.Remove(out)
+			if format, _ := GetImageFormat(out); format != tt.wantFormat {
+				t.Errorf("format = %s, want %s", format, tt.wantFormat)
+			}
+		})
+	}
+
+	if out, ok, err := c.Transcode(writeTestPNG(t, "a.png", color.White)); err != nil || ok {
+		t.Errorf("Transcode(png) = %q, %v, %v, want untouched", out, ok, err)
+	}
+}
+
+// noisyGIF 生成一张难以压缩的多帧 GIF
+func noisyGIF(frames, size int) *gif.GIF {
+	rng := rand.New(rand.NewSource(1))
+	pal := color.Palette{}
+	for i := 0; i < 256; i++ {
+		pal = append(pal, color.RGBA{R: uint8(i), G: uint8(255 - i), B: uint8(i * 7), A: 255})
+	}
+	g := &gif.GIF{Config: image.Config{Width: size, Height: size}}
+	for f := 0; f < frames; f++ {
+		img := image.NewPaletted(image.Rect(0, 0, size, size), pal)
+		for i := range img.Pix {
+			img.Pix[i] = uint8(rng.Intn(256))
+		}
+		g.Image = append(g.Image, img)
+		g.Delay = append(g.Delay, 10)
+	}
+	return g
+}
+
+func TestShrinkGIFKeepsAnimation(t *testing.T) {
+	g := noisyGIF(12, 128)
+	var orig bytes.Buffer
+	if err := gif.EncodeAll(&orig, g); err != nil {
+		t.Fatal(err)
+	}
+
+	limit := int64(orig.Len() / 4)
+	data, err := shrinkGIF(g, 0, limit)
+	if err != nil {
+		t.Fatalf("shrinkGIF() error = %v", err)
+	}
+	if int64(len(data)) > limit {
+		t.Errorf("size = %d, want <= %d", len(data), limit)
+	}
+
+	out, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if len(out.Image) < 2 {
+		t.Fatalf("frames = %d, want animation kept", len(out.Image))
+	}
+	total := 0
+	for _, d := range out.Delay {
+		total += d
+	}
+	if total != 120 {
+		t.Errorf("total delay = %d, want 120 (dropped frames merged)", total)
+	}
+}
+
+func TestCoalesceGIFFrameBudget(t *testing.T) {
+	g := noisyGIF(40, 32)
+	// 预算只够 10 帧完整画面，合成时就按间隔抽帧
+	frames, delays, palettes := coalesceGIF(g, 0.5, 10*16*16)
+	if len(frames) != 10 || len(delays) != 10 || len(palettes) != 10 {
+		t.Fatalf("frames = %d, delays = %d, palettes = %d, want 10", len(frames), len(delays), len(palettes))
+	}
+	if b := frames[0].Bounds(); b.Dx() != 16 || b.Dy() != 16 {
+		t.Errorf("frame size = %v, want scaled to 16x16", b)
+	}
+	total := 0
+	for _, d := range delays {
+		total += d
+	}
+	if total != 400 {
+		t.Errorf("total delay = %d, want 400 (skipped frames merged)", total)
+	}
+}
+
+func TestCompressImageSkipsAnimatedGIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, noisyGIF(3, 64)); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "anim.gif")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCompressor(zap.NewNop(), 32, 1024)
+	if out, ok, err := c.CompressImage(path); err != nil || ok {
+		t.Errorf("CompressImage(animated gif) = %q, %v, %v, want skipped", out, ok, err)
+	}
+}
+
+func TestHasTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	if !hasTransparency(img) {
+		t.Error("empty NRGBA should be transparent")
+	}
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	if hasTransparency(img) {
+		t.Error("opaque NRGBA reported as transparent")
+	}
+}
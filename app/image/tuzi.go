@@ -50,15 +50,46 @@ func (p *TuZiProvider) Name() string {
 }
 
 // Generate 生成图片
-func (p *TuZiProvider) Generate(ctx context.Context, prompt string) (*GenerateResult, error) {
+func (p *TuZiProvider) Generate(ctx context.Context, prompt string, opts GenerateOptions) (*GenerateResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "invalid_options",
+			Message:  fmt.Sprintf("生成选项错误: %v", err),
+			Original: err,
+		}
+	}
+	size, err := opts.resolveSize(p.size)
+	if err != nil {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "invalid_size",
+			Message:  fmt.Sprintf("图片尺寸格式错误: %v", err),
+			Hint:     "请使用 WIDTHxHEIGHT 或 W:H 格式，如 1024x1024、16:9",
+			Original: err,
+		}
+	}
+
 	// 构造请求
 	reqBody := map[string]any{
 		"model":           p.model,
-		"prompt":          prompt,
-		"n":               1,
-		"size":            p.size,
+		"prompt":          opts.promptWithNegative(prompt),
+		"n":               opts.count(),
+		"size":            size,
 		"response_format": "url",
 	}
+	if opts.ResponseFormat != "" {
+		reqBody["response_format"] = opts.ResponseFormat
+	}
+	if opts.Quality != "" {
+		reqBody["quality"] = opts.Quality
+	}
+	if opts.Style != "" {
+		reqBody["style"] = opts.Style
+	}
+	if opts.Seed != 0 {
+		reqBody["seed"] = opts.Seed // Seedream 支持随机种子
+	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -110,6 +141,7 @@ func (p *TuZiProvider) Generate(ctx context.Context, prompt string) (*GenerateRe
 	var result struct {
 		Data []struct {
 			URL           string `json:"url"`
+			B64JSON       string `json:"b64_json"`
 			RevisedPrompt string `json:"revised_prompt,omitempty"`
 		} `json:"data"`
 	}
@@ -132,12 +164,31 @@ func (p *TuZiProvider) Generate(ctx context.Context, prompt string) (*GenerateRe
 		}
 	}
 
-	return &GenerateResult{
-		URL:           result.Data[0].URL,
+	generated := &GenerateResult{
 		RevisedPrompt: result.Data[0].RevisedPrompt,
 		Model:         p.model,
-		Size:          p.size,
-	}, nil
+		Size:          size,
+	}
+	for _, d := range result.Data {
+		if err := generated.addImage(d.URL, d.B64JSON); err != nil {
+			return nil, &GenerateError{
+				Provider: p.Name(),
+				Code:     "decode_error",
+				Message:  "图片 base64 解码失败",
+				Original: err,
+			}
+		}
+	}
+	if generated.empty() {
+		return nil, &GenerateError{
+			Provider: p.Name(),
+			Code:     "no_url",
+			Message:  "响应中没有图片 URL 或 b64_json",
+			Hint:     "检查网关是否兼容 OpenAI 图片接口的响应格式",
+		}
+	}
+
+	return generated, nil
 }
 
 // handleErrorResponse 处理错误响应
@@ -173,6 +224,15 @@ func (p *TuZiProvider) handleErrorResponse(resp *http.Response) error {
 			Original: fmt.Errorf("status 429: %s", string(body)),
 		}
 	case http.StatusBadRequest:
+		if errResp.Error.Code == "content_policy_violation" {
+			return &GenerateError{
+				Provider: p.Name(),
+				Code:     "content_policy",
+				Message:  fmt.Sprintf("提示词未通过内容审核: %s", errResp.Error.Message),
+				Hint:     "修改提示词，或在 api.image_provider 中配置备用图片服务",
+				Original: fmt.Errorf("status 400: %s", string(body)),
+			}
+		}
 		msg := errResp.Error.Message
 		if msg == "" {
 			msg = string(body)
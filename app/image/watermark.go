@@ -0,0 +1,229 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/disintegration/imaging"
+	"github.com/royalrick/wechatwriter/app/config"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// 水印默认值
+const (
+	defaultWatermarkOpacity  = 0.6
+	defaultWatermarkMargin   = 20
+	defaultWatermarkScale    = 0.2
+	defaultWatermarkMinWidth = 300
+
+	watermarkTextSize = 96 // 文字水印的渲染字号（像素），叠加时再按图片宽度缩放
+)
+
+// Watermark 叠加到正文图片上的文字或 Logo 水印
+type Watermark struct {
+	mark     *image.NRGBA // 原始尺寸的水印图案
+	position string
+	opacity  float64
+	margin   int
+	scale    float64
+	minWidth int
+}
+
+// NewWatermark 按账号配置创建水印；未配置时返回 nil
+// 同时配置 logo 和 text 时使用 logo；文字含有字体中没有的字符时返回错误
+func NewWatermark(cfg *config.Watermark) (*Watermark, error) {
+	if cfg == nil || (cfg.Text == "" && cfg.Logo == "") {
+		return nil, nil
+	}
+
+	w := &Watermark{
+		position: cfg.Position,
+		opacity:  cfg.Opacity,
+		margin:   cfg.Margin,
+		scale:    cfg.Scale,
+		minWidth: cfg.MinWidth,
+	}
+	if w.position == "" {
+		w.position = config.WatermarkBottomRight
+	}
+	if w.opacity <= 0 {
+		w.opacity = defaultWatermarkOpacity
+	}
+	if w.margin <= 0 {
+		w.margin = defaultWatermarkMargin
+	}
+	if w.scale <= 0 {
+		w.scale = defaultWatermarkScale
+	}
+	if w.minWidth <= 0 {
+		w.minWidth = defaultWatermarkMinWidth
+	}
+
+	var err error
+	if cfg.Logo != "" {
+		w.mark, err = loadLogo(cfg.Logo)
+	} else {
+		w.mark, err = renderText(cfg.Text, cfg.Font, cfg.Color)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Applies 宽度达到 min_width 的图片才加水印，小图标、表情等保持原样
+func (w *Watermark) Applies(width int) bool {
+	return w != nil && width >= w.minWidth
+}
+
+// Apply 按位置、边距、不透明度和相对宽度将水印叠加到图片上，返回新图片
+func (w *Watermark) Apply(img image.Image) *image.NRGBA {
+	out := imaging.Clone(img)
+	bounds := out.Bounds()
+
+	// 水印宽度为图片宽度的 scale 倍，且不超出边距以内的区域
+	markW := int(math.Round(float64(bounds.Dx()) * w.scale))
+	markW = min(markW, bounds.Dx()-2*w.margin)
+	markH := int(math.Round(float64(markW) * float64(w.mark.Bounds().Dy()) / float64(w.mark.Bounds().Dx())))
+	if maxH := bounds.Dy() - 2*w.margin; markH > maxH {
+		markW = int(float64(markW) * float64(maxH) / float64(markH))
+		markH = maxH
+	}
+	if markW < 1 || markH < 1 {
+		return out
+	}
+	mark := imaging.Resize(w.mark, markW, markH, imaging.Lanczos)
+
+	var x, y int
+	switch w.position {
+	case config.WatermarkTopLeft:
+		x, y = w.margin, w.margin
+	case config.WatermarkTopRight:
+		x, y = bounds.Dx()-w.margin-markW, w.margin
+	case config.WatermarkBottomLeft:
+		x, y = w.margin, bounds.Dy()-w.margin-markH
+	case config.WatermarkCenter:
+		x, y = (bounds.Dx()-markW)/2, (bounds.Dy()-markH)/2
+	default:
+		x, y = bounds.Dx()-w.margin-markW, bounds.Dy()-w.margin-markH
+	}
+
+	rect := image.Rect(x, y, x+markW, y+markH)
+	alpha := image.NewUniform(color.Alpha{A: uint8(math.Round(w.opacity * 255))})
+	draw.DrawMask(out, rect, mark, image.Point{}, alpha, image.Point{}, draw.Over)
+	return out
+}
+
+// loadLogo 读取 Logo 图片（建议使用带透明背景的 PNG）
+func loadLogo(path string) (*image.NRGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open watermark logo: %w", err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode watermark logo %s: %w", path, err)
+	}
+	return imaging.Clone(img), nil
+}
+
+// renderText 将文字渲染为透明背景的图案，带浅色阴影以便在浅色图片上辨认
+// fontPath 为空时使用内置的 Go Regular（仅含西文字符）
+func renderText(text, fontPath, hexColor string) (*image.NRGBA, error) {
+	f, err := LoadFont(fontPath)
+	if err != nil {
+		return nil, fmt.Errorf("watermark: %w", err)
+	}
+	if err := CheckGlyphs(f, text); err != nil {
+		return nil, fmt.Errorf("watermark: %w, set watermark.font to a font that covers the text", err)
+	}
+
+	textColor := color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	if hexColor != "" {
+		if textColor, err = ParseHexColor(hexColor); err != nil {
+			return nil, fmt.Errorf("watermark: %w", err)
+		}
+	}
+
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: watermarkTextSize, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return nil, fmt.Errorf("load watermark font: %w", err)
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	shadow := watermarkTextSize / 32
+	width := font.MeasureString(face, text).Ceil() + shadow
+	height := (metrics.Ascent + metrics.Descent).Ceil() + shadow
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	drawer := &font.Drawer{Dst: img, Face: face}
+	baseline := metrics.Ascent.Ceil()
+
+	drawer.Src = image.NewUniform(color.NRGBA{A: 0x60})
+	drawer.Dot = fixed.P(shadow, baseline+shadow)
+	drawer.DrawString(text)
+
+	drawer.Src = image.NewUniform(textColor)
+	drawer.Dot = fixed.P(0, baseline)
+	drawer.DrawString(text)
+	return img, nil
+}
+
+// LoadFont 读取 TTF/OTF 字体文件，path 为空时使用内置的 Go Regular（仅含西文字符）
+func LoadFont(path string) (*sfnt.Font, error) {
+	data := goregular.TTF
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("read font: %w", err)
+		}
+	}
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse font %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// CheckGlyphs 检查字体是否包含文字中的所有字符，避免渲染出方框
+func CheckGlyphs(f *sfnt.Font, text string) error {
+	var buf sfnt.Buffer
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if idx, err := f.GlyphIndex(&buf, r); err != nil || idx == 0 {
+			return fmt.Errorf("font has no glyph for %q", r)
+		}
+	}
+	return nil
+}
+
+// ParseHexColor 解析 #RGB / #RRGGBB / #RRGGBBAA
+func ParseHexColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
@@ -0,0 +1,161 @@
+package image
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/royalrick/wechatwriter/app/config"
+	"go.uber.org/zap"
+)
+
+// writeSolidPNG 写入指定尺寸的纯色 PNG
+func writeSolidPNG(t *testing.T, dir, name string, w, h int, c color.NRGBA) string {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWatermarkApplyLogo(t *testing.T) {
+	dir := t.TempDir()
+	logo := writeSolidPNG(t, dir, "logo.png", 40, 20, color.NRGBA{R: 255, A: 255})
+	w, err := NewWatermark(&config.Watermark{Logo: logo, Opacity: 0.5, Margin: 10, Scale: 0.25})
+	if err != nil {
+		t.Fatalf("NewWatermark() error = %v", err)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 300))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	out := w.Apply(img)
+
+	// 默认右下角：宽 100、高 50，距边缘 10
+	if got := out.NRGBAAt(340, 265); got.G > 140 || got.G < 115 || got.R != 255 {
+		t.Errorf("pixel inside watermark = %v, want red at 50%% opacity", got)
+	}
+	for _, pt := range []image.Point{{289, 265}, {340, 239}, {395, 295}, {10, 10}} {
+		if got := out.NRGBAAt(pt.X, pt.Y); got != (color.NRGBA{255, 255, 255, 255}) {
+			t.Errorf("pixel %v outside watermark = %v, want untouched", pt, got)
+		}
+	}
+	if img.NRGBAAt(340, 265).G != 255 {
+		t.Error("Apply() modified the source image")
+	}
+}
+
+func TestNewWatermarkText(t *testing.T) {
+	w, err := NewWatermark(&config.Watermark{Text: "@wechatwriter", Color: "#FF0000"})
+	if err != nil {
+		t.Fatalf("NewWatermark() error = %v", err)
+	}
+	if b := w.mark.Bounds(); b.Dx() <= b.Dy() {
+		t.Errorf("text mark bounds = %v, want a wide image", b)
+	}
+	if w.position != config.WatermarkBottomRight || w.opacity != defaultWatermarkOpacity {
+		t.Errorf("defaults = %+v", w)
+	}
+
+	// 内置字体不含中文，未指定字体时报错而不是渲染出方框
+	if _, err := NewWatermark(&config.Watermark{Text: "公众号"}); err == nil {
+		t.Error("NewWatermark() with CJK text and built-in font should fail")
+	}
+	if _, err := NewWatermark(&config.Watermark{Text: "x", Color: "red"}); err == nil {
+		t.Error("NewWatermark() with invalid color should fail")
+	}
+	if w, err := NewWatermark(nil); w != nil || err != nil {
+		t.Errorf("NewWatermark(nil) = %v, %v, want nil", w, err)
+	}
+}
+
+func TestCompressorWatermark(t *testing.T) {
+	dir := t.TempDir()
+	logo := writeSolidPNG(t, dir, "logo.png", 20, 20, color.NRGBA{A: 255})
+	w, err := NewWatermark(&config.Watermark{Logo: logo, Opacity: 1, Position: config.WatermarkTopLeft, Margin: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCompressor(zap.NewNop(), 1920, 5*1024*1024).withWatermark(w)
+	white := color.NRGBA{255, 255, 255, 255}
+	body := writeSolidPNG(t, dir, "body.png", 400, 200, white)
+
+	out, ok, err := c.Sanitize(body)
+	if err != nil || !ok {
+		t.Fatalf("Sanitize() = %q, %v, %v, want watermarked copy", out, ok, err)
+	}
+	defer os.Remove(out)
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := img.At(10, 10).RGBA(); r > 0x1000 {
+		t.Errorf("top-left pixel = %v, want watermark", img.At(10, 10))
+	}
+
+	// 封面和窄图不加水印
+	if out, ok, err := c.CompressImageAs(body, ImageKindCover); err != nil || ok {
+		t.Errorf("CompressImageAs(cover) = %q, %v, %v, want untouched", out, ok, err)
+	}
+	small := writeSolidPNG(t, dir, "icon.png", 120, 120, white)
+	if out, ok, err := c.Sanitize(small); err != nil || ok {
+		t.Errorf("Sanitize(small) = %q, %v, %v, want untouched", out, ok, err)
+	}
+}
+
+func TestCompressorWatermarkStaysWithinLimit(t *testing.T) {
+	dir := t.TempDir()
+	logo := writeSolidPNG(t, dir, "logo.png", 40, 40, color.NRGBA{R: 255, A: 255})
+	w, err := NewWatermark(&config.Watermark{Logo: logo, Opacity: 1, Position: config.WatermarkTopLeft})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 原图略低于上限，加水印后按默认质量重新编码会超出
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rng.Intn(256))
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 60}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "photo.jpg")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	limit := int64(buf.Len()) + 64
+	c := NewCompressor(zap.NewNop(), 1920, limit).withWatermark(w)
+	out, ok, err := c.CompressImage(path)
+	if err != nil || !ok {
+		t.Fatalf("CompressImage() = %q, %v, %v, want watermarked copy", out, ok, err)
+	}
+	defer os.Remove(out)
+	if info, _ := os.Stat(out); info.Size() > limit {
+		t.Errorf("size = %d, want <= %d", info.Size(), limit)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/royalrick/wechatwriter/app/image"
+	"github.com/spf13/cobra"
+)
+
+// imageGalleryCmd 图片库命令组
+func imageGalleryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gallery",
+		Short: "AI 生成图片的本地图片库",
+		Long: `AI 生成图片的本地图片库
+
+每张 AI 生成的图片都保存在数据目录的 gallery/ 下，旁边的 <id>.json 记录
+提示词、生成服务、模型、尺寸、种子、所在文章和素材 ID。
+
+在 Markdown 中用 ![说明](gallery:<id>) 复用已生成的图片，不会重新生成；
+<id> 可以是唯一的前缀（至少 4 位）。
+
+支持的操作：
+  list   - 列出图片库中的图片
+  show   - 查看图片的元数据和文件路径
+  reuse  - 上传图片库中的图片到微信，输出可插入 Markdown 的语法`,
+	}
+
+	cmd.AddCommand(imageGalleryListCmd())
+	cmd.AddCommand(imageGalleryShowCmd())
+	cmd.AddCommand(imageGalleryReuseCmd())
+
+	return cmd
+}
+
+// imageGalleryListCmd 列出图片库
+func imageGalleryListCmd() *cobra.Command {
+	var (
+		query   string
+		article string
+		limit   int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "列出图片库中的图片（最新的在前）",
+		Long: `列出图片库中的图片（最新的在前）
+
+示例：
+  writer image gallery list
+  writer image gallery list --query 乌龙茶 --limit 5
+  writer image gallery list --article posts/tea.md`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			gallery := image.OpenGallery(cfg.GetDataDir())
+			entries, err := gallery.List()
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			total := len(entries)
+			entries = filterGallery(entries, query, article)
+			if limit > 0 && len(entries) > limit {
+				entries = entries[:limit]
+			}
+			if entries == nil {
+				entries = []image.GalleryEntry{}
+			}
+
+			responseSuccess(map[string]any{
+				"dir":    gallery.Dir(),
+				"total":  total,
+				"count":  len(entries),
+				"images": entries,
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&query, "query", "q", "", "按提示词筛选（包含即可，不区分大小写）")
+	cmd.Flags().StringVar(&article, "article", "", "只列出该文章中生成的图片")
+	cmd.Flags().IntVar(&limit, "limit", 20, "最多列出的图片数（0 为不限）")
+
+	return cmd
+}
+
+// imageGalleryShowCmd 查看图片元数据
+func imageGalleryShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <id>",
+		Short: "查看图片的元数据和文件路径",
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			gallery := image.OpenGallery(cfg.GetDataDir())
+			entry, err := gallery.Get(args[0])
+			if err != nil {
+				responseError(err)
+				return
+			}
+			responseSuccess(map[string]any{
+				"image":    entry,
+				"path":     gallery.Path(entry),
+				"markdown": galleryMarkdown(entry),
+			})
+		},
+	}
+}
+
+// imageGalleryReuseCmd 上传图片库中的图片
+func imageGalleryReuseCmd() *cobra.Command {
+	var accountID string
+
+	cmd := &cobra.Command{
+		Use:   "reuse <id>",
+		Short: "上传图片库中的图片到微信素材库",
+		Long: `上传图片库中的图片到微信素材库（相同内容命中上传缓存时不重复上传），
+并输出可插入 Markdown 的 ![](gallery:<id>) 语法。`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			processor, err := image.NewProcessorForAccount(cfg, log, accountID)
+			if err != nil {
+				responseError(err)
+				return
+			}
+			entry, err := processor.Gallery().Get(args[0])
+			if err != nil {
+				responseError(err)
+				return
+			}
+			result, err := processor.UploadFromGallery(entry.ID)
+			if err != nil {
+				responseError(err)
+				return
+			}
+			responseSuccess(map[string]any{
+				"id":         entry.ID,
+				"prompt":     entry.Prompt,
+				"media_id":   result.MediaID,
+				"wechat_url": result.WechatURL,
+				"markdown":   galleryMarkdown(entry),
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+
+	return cmd
+}
+
+// filterGallery 按提示词和文章筛选图片
+func filterGallery(entries []image.GalleryEntry, query, article string) []image.GalleryEntry {
+	if query == "" && article == "" {
+		return entries
+	}
+	query = strings.ToLower(query)
+	var out []image.GalleryEntry
+	for _, e := range entries {
+		if query != "" && !strings.Contains(strings.ToLower(e.Prompt), query) {
+			continue
+		}
+		if article != "" && e.Article != article {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// galleryMarkdown 返回引用图片库图片的 Markdown 语法
+func galleryMarkdown(e *image.GalleryEntry) string {
+	return "![](gallery:" + e.ID + ")"
+}
@@ -61,13 +61,24 @@ Configuration:
 
 	// 添加所有子命令
 	rootCmd.AddCommand(imageCmd())
+	rootCmd.AddCommand(coverCmd())
+	rootCmd.AddCommand(mediaCmd())
 	rootCmd.AddCommand(convertCmd)
 	rootCmd.AddCommand(draftCmd())
+	rootCmd.AddCommand(htmlCmd())
 	rootCmd.AddCommand(writeCmd)
 	rootCmd.AddCommand(humanizeCmd)
 	rootCmd.AddCommand(scoreCmd())
+	rootCmd.AddCommand(statsCmd())
+	rootCmd.AddCommand(commentsCmd())
+	rootCmd.AddCommand(scheduleCmd())
+	rootCmd.AddCommand(archiveCmd())
+	rootCmd.AddCommand(serveCallbackCmd())
 	rootCmd.AddCommand(outlineCmd())
 	rootCmd.AddCommand(configCmd())
+	rootCmd.AddCommand(cacheCmd())
+	rootCmd.AddCommand(mockWechatCmd())
+	rootCmd.AddCommand(doctorCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		responseError(err)
@@ -110,3 +121,13 @@ func maskMediaID(id string) string {
 	}
 	return id[:4] + "***" + id[len(id)-4:]
 }
+
+// selectAccount 按账号 ID 或名称选择微信公众号账号，为空时使用默认账号
+func selectAccount(accountID string) (*config.WechatAccount, error) {
+	selector := config.NewAccountSelector(cfg.WechatAccounts, cfg.DefaultAccount)
+	account, err := selector.SelectAccount("", accountID)
+	if err != nil {
+		return nil, fmt.Errorf("select WeChat account: %w", err)
+	}
+	return account, nil
+}
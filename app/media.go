@@ -0,0 +1,95 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/royalrick/wechatwriter/app/image"
+	"github.com/royalrick/wechatwriter/app/media"
+	"github.com/spf13/cobra"
+)
+
+// mediaCmd 视频/语音素材命令组
+func mediaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "media",
+		Short: "视频/语音素材管理",
+		Long: `视频/语音素材管理
+
+支持的操作：
+  upload - 上传视频（mp4，≤10MB）或语音（mp3/wma/wav/amr，≤2MB，≤60 秒）到永久素材库
+
+在 Markdown 中用 ![video](./clip.mp4) / ![voice](./intro.mp3) 引用时，
+convert --draft 会自动上传，并在文章中对应位置插入素材卡片
+（公众号接口无法直接内嵌视频/语音，需在后台编辑器中从素材库插入替换卡片）。`,
+	}
+
+	cmd.AddCommand(mediaUploadCmd())
+
+	return cmd
+}
+
+// mediaUploadCmd 上传视频/语音素材
+func mediaUploadCmd() *cobra.Command {
+	var (
+		accountID    string
+		title        string
+		introduction string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "upload <file_path>",
+		Short: "上传视频或语音到微信素材库",
+		Long: `上传视频或语音到微信素材库
+
+按扩展名判断类型，上传前检查大小和时长：
+  视频  mp4                    ≤10MB
+  语音  mp3 / wma / wav / amr  ≤2MB，≤60 秒
+
+示例：
+  writer media upload ./clip.mp4 --title "冲泡演示" --intro "第一泡 95℃"
+  writer media upload ./intro.mp3 --account tea`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			filePath := args[0]
+
+			info, err := media.Probe(filePath, "")
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			processor, err := image.NewProcessorForAccount(cfg, log, accountID)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			if title == "" {
+				title = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+			}
+			mediaID, err := processor.UploadMedia(info, title, introduction)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			responseSuccess(map[string]any{
+				"media_id":    mediaID,
+				"kind":        info.Kind,
+				"format":      info.Format,
+				"size":        info.Size,
+				"duration_ms": info.Duration.Milliseconds(),
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "上传使用的微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().StringVar(&title, "title", "", "视频标题（默认使用文件名）")
+	cmd.Flags().StringVar(&introduction, "intro", "", "视频简介")
+
+	return cmd
+}
@@ -0,0 +1,309 @@
+// Package media 检查视频、语音素材的格式、大小和时长
+// 只解析容器头部获取时长，不依赖 ffmpeg
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Kind 素材类型
+type Kind string
+
+const (
+	KindVideo Kind = "video" // 视频
+	KindVoice Kind = "voice" // 语音
+)
+
+// 微信永久素材限制
+const (
+	MaxVideoSize     = 10 << 20         // 视频 10MB
+	MaxVoiceSize     = 2 << 20          // 语音 2MB
+	MaxVoiceDuration = 60 * time.Second // 语音时长 60 秒
+)
+
+// 支持的文件格式
+var formats = map[string]Kind{
+	".mp4": KindVideo,
+	".mp3": KindVoice,
+	".wma": KindVoice,
+	".wav": KindVoice,
+	".amr": KindVoice,
+}
+
+// Info 素材信息
+type Info struct {
+	Path     string        `json:"path"`
+	Kind     Kind          `json:"kind"`
+	Format   string        `json:"format"`
+	Size     int64         `json:"size"`
+	Duration time.Duration `json:"duration"` // 无法解析时为 0
+}
+
+// KindOf 根据扩展名判断素材类型
+func KindOf(path string) (Kind, bool) {
+	kind, ok := formats[strings.ToLower(filepath.Ext(path))]
+	return kind, ok
+}
+
+// Probe 读取素材的格式、大小和时长
+// kind 为空时根据扩展名判断；时长解析失败不视为错误（Duration 为 0）
+func Probe(path string, kind Kind) (*Info, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	formatKind, ok := formats[ext]
+	if !ok {
+		return nil, fmt.Errorf("unsupported media format %q (video: mp4; voice: mp3/wma/wav/amr)", ext)
+	}
+	if kind == "" {
+		kind = formatKind
+	}
+	if kind != formatKind {
+		return nil, fmt.Errorf("%s is not a %s file", filepath.Base(path), kind)
+	}
+
+	info := &Info{
+		Path:   path,
+		Kind:   kind,
+		Format: strings.TrimPrefix(ext, "."),
+		Size:   stat.Size(),
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open media: %w", err)
+	}
+	defer f.Close()
+
+	switch info.Format {
+	case "mp4":
+		info.Duration, _ = mp4Duration(f)
+	case "wav":
+		info.Duration, _ = wavDuration(f)
+	case "mp3":
+		info.Duration, _ = mp3Duration(f, info.Size)
+	case "amr":
+		info.Duration, _ = amrDuration(f)
+	}
+	return info, nil
+}
+
+// Validate 检查是否超出微信素材的大小和时长限制
+func (i *Info) Validate() error {
+	switch i.Kind {
+	case KindVideo:
+		if i.Size > MaxVideoSize {
+			return fmt.Errorf("video is %.1fMB, exceeds the 10MB limit", float64(i.Size)/(1<<20))
+		}
+	case KindVoice:
+		if i.Size > MaxVoiceSize {
+			return fmt.Errorf("voice is %.1fMB, exceeds the 2MB limit", float64(i.Size)/(1<<20))
+		}
+		if i.Duration > MaxVoiceDuration {
+			return fmt.Errorf("voice is %s long, exceeds the 60s limit", i.Duration.Round(time.Second))
+		}
+	}
+	return nil
+}
+
+var errNoDuration = errors.New("duration not found")
+
+// mp4Duration 读取 moov/mvhd 中的时长
+func mp4Duration(r io.ReadSeeker) (time.Duration, error) {
+	moov, err := findBox(r, "moov", -1)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := findBox(r, "mvhd", moov); err != nil {
+		return 0, err
+	}
+
+	var version [4]byte
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return 0, err
+	}
+	var timescale uint32
+	var duration uint64
+	if version[0] == 1 {
+		var v1 struct {
+			Created, Modified uint64
+			Timescale         uint32
+			Duration          uint64
+		}
+		if err := binary.Read(r, binary.BigEndian, &v1); err != nil {
+			return 0, err
+		}
+		timescale, duration = v1.Timescale, v1.Duration
+	} else {
+		var v0 struct {
+			Created, Modified uint32
+			Timescale         uint32
+			Duration          uint32
+		}
+		if err := binary.Read(r, binary.BigEndian, &v0); err != nil {
+			return 0, err
+		}
+		timescale, duration = v0.Timescale, uint64(v0.Duration)
+	}
+	if timescale == 0 {
+		return 0, errNoDuration
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second)), nil
+}
+
+// findBox 在当前位置开始的 limit 字节内查找指定类型的 box（limit < 0 表示到文件末尾）
+// 找到时定位到 box 内容起点并返回内容长度
+func findBox(r io.ReadSeeker, boxType string, limit int64) (int64, error) {
+	var read int64
+	for limit < 0 || read < limit {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return 0, errNoDuration
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		headerLen := int64(8)
+		switch size {
+		case 0: // 延伸到文件末尾
+			size = -1
+		case 1: // 64 位长度
+			var large uint64
+			if err := binary.Read(r, binary.BigEndian, &large); err != nil {
+				return 0, errNoDuration
+			}
+			size, headerLen = int64(large), 16
+		}
+
+		if string(header[4:]) == boxType {
+			if size < 0 {
+				return -1, nil
+			}
+			return size - headerLen, nil
+		}
+		if size < headerLen {
+			return 0, errNoDuration
+		}
+		if _, err := r.Seek(size-headerLen, io.SeekCurrent); err != nil {
+			return 0, errNoDuration
+		}
+		read += size
+	}
+	return 0, errNoDuration
+}
+
+// maxWAVFmtChunk fmt 块的最大长度（WAVE_FORMAT_EXTENSIBLE 为 40 字节），超出视为损坏的文件
+const maxWAVFmtChunk = 256
+
+// wavDuration 根据 fmt 块的字节率和 data 块大小计算时长
+// 块大小来自文件头，不可信：只读取长度受限的 fmt 块，其他块直接跳过
+func wavDuration(r io.ReadSeeker) (time.Duration, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return 0, err
+	}
+	if string(riff[:4]) != "RIFF" || string(riff[8:]) != "WAVE" {
+		return 0, errNoDuration
+	}
+
+	var byteRate uint32
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return 0, errNoDuration
+		}
+		size := binary.LittleEndian.Uint32(chunk[4:])
+		switch string(chunk[:4]) {
+		case "fmt ":
+			if size < 12 || size > maxWAVFmtChunk {
+				return 0, errNoDuration
+			}
+			body := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return 0, errNoDuration
+			}
+			byteRate = binary.LittleEndian.Uint32(body[8:12])
+		case "data":
+			if byteRate == 0 {
+				return 0, errNoDuration
+			}
+			return time.Duration(float64(size) / float64(byteRate) * float64(time.Second)), nil
+		default:
+			if _, err := r.Seek(int64(size)+int64(size%2), io.SeekCurrent); err != nil {
+				return 0, errNoDuration
+			}
+		}
+	}
+}
+
+// mp3 码率表（kbps），按 [MPEG1][Layer III] 和 [MPEG2/2.5][Layer III]
+var (
+	mp3BitratesV1 = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mp3BitratesV2 = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+)
+
+// mp3Duration 按首帧码率估算时长（固定码率），跳过 ID3v2 标签
+func mp3Duration(r io.Reader, size int64) (time.Duration, error) {
+	head := make([]byte, 64<<10)
+	n, _ := io.ReadFull(r, head)
+	head = head[:n]
+
+	offset := 0
+	if len(head) >= 10 && bytes.Equal(head[:3], []byte("ID3")) {
+		tagSize := int(head[6]&0x7f)<<21 | int(head[7]&0x7f)<<14 | int(head[8]&0x7f)<<7 | int(head[9]&0x7f)
+		offset = 10 + tagSize
+	}
+
+	for i := offset; i+4 <= len(head); i++ {
+		if head[i] != 0xff || head[i+1]&0xe0 != 0xe0 {
+			continue
+		}
+		version := (head[i+1] >> 3) & 0x03 // 3 = MPEG1
+		layer := (head[i+1] >> 1) & 0x03   // 1 = Layer III
+		index := head[i+2] >> 4
+		if version == 1 || layer != 1 {
+			continue
+		}
+		kbps := mp3BitratesV2[index]
+		if version == 3 {
+			kbps = mp3BitratesV1[index]
+		}
+		if kbps == 0 {
+			continue
+		}
+		audio := size - int64(i)
+		return time.Duration(float64(audio*8) / float64(kbps*1000) * float64(time.Second)), nil
+	}
+	return 0, errNoDuration
+}
+
+// AMR-NB 各模式的帧长度（不含帧头字节），每帧 20ms
+var amrFrameSizes = [16]int{12, 13, 15, 17, 19, 20, 26, 31, 5, 0, 0, 0, 0, 0, 0, 0}
+
+// amrDuration 统计 AMR-NB 帧数计算时长
+func amrDuration(r io.Reader) (time.Duration, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	const magic = "#!AMR\n"
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return 0, errNoDuration
+	}
+
+	frames := 0
+	for i := len(magic); i < len(data); {
+		i += 1 + amrFrameSizes[(data[i]>>3)&0x0f]
+		frames++
+	}
+	return time.Duration(frames) * 20 * time.Millisecond, nil
+}
@@ -0,0 +1,162 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// box 构造 MP4 box
+func box(boxType string, body []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(8+len(body)))
+	buf.WriteString(boxType)
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// testMP4 构造只含 ftyp 和 moov/mvhd 的 MP4
+func testMP4(timescale, duration uint32) []byte {
+	var mvhd bytes.Buffer
+	mvhd.Write([]byte{0, 0, 0, 0})
+	binary.Write(&mvhd, binary.BigEndian, []uint32{0, 0, timescale, duration})
+	mvhd.Write(make([]byte, 80))
+
+	moov := box("moov", append(box("udta", []byte("meta")), box("mvhd", mvhd.Bytes())...))
+	return append(box("ftyp", []byte("isom\x00\x00\x02\x00")), moov...)
+}
+
+// testWAV 构造 PCM WAV（16 位单声道）
+func testWAV(sampleRate uint32, seconds int) []byte {
+	dataSize := sampleRate * 2 * uint32(seconds)
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, []uint16{1, 1})
+	binary.Write(&buf, binary.LittleEndian, []uint32{sampleRate, sampleRate * 2})
+	binary.Write(&buf, binary.LittleEndian, []uint16{2, 16})
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	buf.Write(make([]byte, dataSize))
+	return buf.Bytes()
+}
+
+// testAMR 构造 AMR-NB（12.2kbps 模式，每帧 32 字节）
+func testAMR(frames int) []byte {
+	data := []byte("#!AMR\n")
+	for i := 0; i < frames; i++ {
+		frame := make([]byte, 32)
+		frame[0] = 7 << 3
+		data = append(data, frame...)
+	}
+	return data
+}
+
+// testMP3 构造带 ID3v2 标签的 128kbps MPEG1 Layer III 数据
+func testMP3(audioBytes int) []byte {
+	data := []byte("ID3\x03\x00\x00\x00\x00\x00\x0a")
+	data = append(data, make([]byte, 10)...)
+	frame := make([]byte, audioBytes)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x00})
+	return append(data, frame...)
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProbeDuration(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		kind Kind
+		want time.Duration
+	}{
+		{"clip.mp4", testMP4(1000, 12500), KindVideo, 12500 * time.Millisecond},
+		{"voice.wav", testWAV(8000, 3), KindVoice, 3 * time.Second},
+		{"voice.amr", testAMR(250), KindVoice, 5 * time.Second},
+		{"voice.mp3", testMP3(16000 * 2), KindVoice, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Probe(writeFile(t, tt.name, tt.data), "")
+			if err != nil {
+				t.Fatalf("Probe() error = %v", err)
+			}
+			if info.Kind != tt.kind {
+				t.Errorf("kind = %s, want %s", info.Kind, tt.kind)
+			}
+			if info.Duration != tt.want {
+				t.Errorf("duration = %s, want %s", info.Duration, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeRejectsWrongKind(t *testing.T) {
+	path := writeFile(t, "clip.mp4", testMP4(1000, 1000))
+	if _, err := Probe(path, KindVoice); err == nil {
+		t.Error("Probe(mp4, voice) error = nil, want kind mismatch")
+	}
+	if _, err := Probe(writeFile(t, "clip.mov", nil), ""); err == nil {
+		t.Error("Probe(mov) error = nil, want unsupported format")
+	}
+}
+
+func TestProbeCorruptWAV(t *testing.T) {
+	// 块大小声明为近 4GB：不能按声明的大小分配内存
+	header := func(chunk string, size uint32) []byte {
+		var buf bytes.Buffer
+		buf.WriteString("RIFF")
+		binary.Write(&buf, binary.LittleEndian, uint32(0xffffffff))
+		buf.WriteString("WAVE" + chunk)
+		binary.Write(&buf, binary.LittleEndian, size)
+		buf.Write(make([]byte, 16))
+		return buf.Bytes()
+	}
+
+	for _, chunk := range []string{"fmt ", "LIST"} {
+		info, err := Probe(writeFile(t, "corrupt.wav", header(chunk, 0xfffffff0)), "")
+		if err != nil {
+			t.Fatalf("Probe(%q) error = %v", chunk, err)
+		}
+		if info.Duration != 0 {
+			t.Errorf("Probe(%q) duration = %s, want 0", chunk, info.Duration)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		info    Info
+		wantErr string
+	}{
+		{Info{Kind: KindVideo, Size: 5 << 20}, ""},
+		{Info{Kind: KindVideo, Size: 11 << 20}, "10MB"},
+		{Info{Kind: KindVoice, Size: 1 << 20, Duration: 59 * time.Second}, ""},
+		{Info{Kind: KindVoice, Size: 1 << 20, Duration: 75 * time.Second}, "60s"},
+		{Info{Kind: KindVoice, Size: 3 << 20}, "2MB"},
+	}
+
+	for _, tt := range tests {
+		err := tt.info.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("Validate(%+v) error = %v", tt.info, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("Validate(%+v) error = %v, want containing %q", tt.info, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/royalrick/wechatwriter/app/wechat/wechattest"
+	"github.com/spf13/cobra"
+)
+
+// mockWechatCmd 启动内存版微信 API 模拟服务
+func mockWechatCmd() *cobra.Command {
+	var (
+		addr   string
+		appID  string
+		secret string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "mock-wechat",
+		Short: "启动本地微信 API 模拟服务（离线测试用）",
+		Long: `启动本地微信 API 模拟服务（离线测试用）
+
+在内存中实现 access_token、永久素材、图文内图片、草稿箱、发布、
+群发预览等接口，数据在进程退出后丢弃。
+
+将账号的 api_base 指向该服务即可离线运行完整的 转换→上传→草稿 流程：
+
+  wechat:
+    accounts:
+      - id: mock
+        appid: mock_appid
+        secret: mock_secret
+        api_base: http://127.0.0.1:8090
+
+不指定 --appid / --secret 时接受任意凭证。`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			srv := wechattest.NewServer()
+			srv.AppID = appID
+			srv.Secret = secret
+
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				responseError(fmt.Errorf("监听 %s 失败: %w", addr, err))
+				return
+			}
+
+			fmt.Fprintf(os.Stderr, "mock wechat api listening on http://%s\n", ln.Addr())
+			if err := http.Serve(ln, srv); err != nil {
+				responseError(err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:8090", "监听地址")
+	cmd.Flags().StringVar(&appID, "appid", "", "只接受指定的 AppID（可选）")
+	cmd.Flags().StringVar(&secret, "secret", "", "只接受指定的 Secret（可选）")
+
+	return cmd
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/config"
+	"github.com/royalrick/wechatwriter/app/converter"
+	"github.com/royalrick/wechatwriter/app/draft"
+	"github.com/royalrick/wechatwriter/app/image"
+	"github.com/royalrick/wechatwriter/app/schedule"
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// scheduleTimeLayouts --at 支持的时间格式（按本地时区解析）
+var scheduleTimeLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+}
+
+// scheduleCmd 定时发布命令组
+func scheduleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "定时发布（本地队列 + 前台守护进程）",
+		Long: `定时发布命令组
+
+微信接口不支持定时发布。这里把发布计划保存在本地队列（<data_dir>/schedule.json），
+由 'writer schedule run' 在前台常驻运行，到点后通过发布接口（freepublish）提交发布，
+失败时自动重试并记录结果。
+
+支持的操作：
+  add     - 添加定时发布任务（草稿 media_id、草稿 JSON 或 Markdown 文件）
+  list    - 列出队列中的任务
+  cancel  - 取消等待中的任务
+  run     - 前台运行，按计划发布到期任务`,
+	}
+
+	cmd.AddCommand(scheduleAddCmd())
+	cmd.AddCommand(scheduleListCmd())
+	cmd.AddCommand(scheduleCancelCmd())
+	cmd.AddCommand(scheduleRunCmd())
+
+	return cmd
+}
+
+// scheduleAddCmd 添加定时发布任务
+func scheduleAddCmd() *cobra.Command {
+	var (
+		accountID string
+		at        string
+		htmlFile  string
+		cover     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "add <media_id|draft.json|article.md>",
+		Short: "添加定时发布任务",
+		Long: `添加定时发布任务
+
+参数可以是：
+  - 草稿 media_id：直接加入队列
+  - 草稿 JSON 文件（与 'writer draft create' 格式相同）：立即创建草稿后加入队列
+  - Markdown 文件：与 'writer convert --draft --html' 相同，读取 front matter 作为草稿字段，
+    正文取自 --html（默认同目录同名的 .html，即按 convert 的 AI 提示词生成的 HTML），
+    上传图片和视频/语音、追加账号尾部后立即创建草稿，再加入队列
+
+草稿在添加时即创建，可先用 'writer draft preview' 在手机上确认效果。
+
+示例：
+  writer schedule add Xy1_media_id --at "2026-10-20 08:00" --account my-account
+  writer schedule add article.md --at "2026-10-20 08:00" --cover cover.jpg`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			when, err := parseScheduleTime(at, time.Now())
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			account, err := selectAccount(accountID)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			job := schedule.Job{AccountID: account.ID, At: when}
+			if err := resolveScheduleSource(cmd, &job, account, args[0], htmlFile, cover); err != nil {
+				responseError(err)
+				return
+			}
+
+			added, err := schedule.OpenQueue(cfg.GetDataDir()).Add(job)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			log.Info("scheduled publish added",
+				zap.String("job", added.ID),
+				zap.String("account", added.AccountID),
+				zap.String("media_id", maskMediaID(added.MediaID)),
+				zap.Time("at", added.At))
+			responseSuccess(added)
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().StringVar(&at, "at", "", `计划发布时间，本地时区，例如 "2026-10-20 08:00"（必填）`)
+	cmd.Flags().StringVar(&htmlFile, "html", "", "Markdown 文件对应的正文 HTML（默认同目录同名 .html）")
+	cmd.Flags().StringVar(&cover, "cover", "", "封面图片路径（Markdown 文件，优先于 front matter 中的 cover）")
+	cmd.MarkFlagRequired("at")
+
+	return cmd
+}
+
+// scheduleListCmd 列出定时发布任务
+func scheduleListCmd() *cobra.Command {
+	var (
+		accountID string
+		status    string
+		all       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "列出定时发布任务",
+		Long: `列出定时发布任务
+
+默认只列出等待中和发布中的任务，--all 列出全部（含已发布、失败、已取消）。`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			jobs, err := schedule.OpenQueue(cfg.GetDataDir()).List()
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			list := make([]*schedule.Job, 0, len(jobs))
+			for _, j := range jobs {
+				if accountID != "" && j.AccountID != accountID {
+					continue
+				}
+				if status != "" && j.Status != status {
+					continue
+				}
+				if status == "" && !all && !j.Active() {
+					continue
+				}
+				list = append(list, j)
+			}
+
+			responseSuccess(map[string]any{
+				"jobs":  list,
+				"total": len(list),
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "只列出指定账号的任务")
+	cmd.Flags().StringVar(&status, "status", "", "按状态过滤: pending, publishing, published, failed, canceled")
+	cmd.Flags().BoolVar(&all, "all", false, "列出全部任务（含已结束的任务）")
+
+	return cmd
+}
+
+// scheduleCancelCmd 取消定时发布任务
+func scheduleCancelCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cancel <job_id>",
+		Short: "取消等待中的定时发布任务",
+		Long: `取消等待中的定时发布任务
+
+job_id 可使用 'writer schedule list' 中显示的 ID 或其唯一前缀（至少 4 位）。
+已提交发布的任务无法取消；草稿本身不会被删除。`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			job, err := schedule.OpenQueue(cfg.GetDataDir()).Cancel(args[0])
+			if err != nil {
+				responseError(err)
+				return
+			}
+			responseSuccess(job)
+		},
+	}
+
+	return cmd
+}
+
+// scheduleRunCmd 前台运行定时发布
+func scheduleRunCmd() *cobra.Command {
+	var (
+		interval    time.Duration
+		once        bool
+		maxAttempts int
+		retryDelay  time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "前台运行，按计划发布到期任务",
+		Long: `前台运行定时发布守护进程
+
+每隔 --interval 检查一次队列：到期任务提交发布，已提交的任务查询发布结果。
+失败时按指数退避重试（--retry-delay 起，最长 30 分钟），超过 --max-attempts 次
+或遇到无法重试的错误（如草稿不存在、审核不通过）时标记为 failed。
+
+按 Ctrl+C 退出；已提交的任务会在下次运行时继续查询结果。
+可配合 systemd、launchd 或 nohup 常驻运行。`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if interval <= 0 {
+				return fmt.Errorf("--interval 必须大于 0，当前为 %s", interval)
+			}
+			if maxAttempts <= 0 {
+				return fmt.Errorf("--max-attempts 必须大于 0，当前为 %d", maxAttempts)
+			}
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			queue := schedule.OpenQueue(cfg.GetDataDir())
+			runner := schedule.NewRunner(queue, schedulePublisher, log)
+			runner.MaxAttempts = maxAttempts
+			runner.RetryDelay = retryDelay
+
+			if once {
+				changed, err := runner.Tick()
+				if err != nil {
+					responseError(err)
+					return
+				}
+				responseSuccess(map[string]any{
+					"processed": changed,
+					"total":     len(changed),
+				})
+				return
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			log.Info("schedule runner started",
+				zap.String("queue", queue.Path()),
+				zap.Duration("interval", interval))
+			if err := runner.Run(ctx, interval); err != nil {
+				responseError(err)
+				return
+			}
+			log.Info("schedule runner stopped")
+		},
+	}
+
+	cmd.Flags().DurationVar(&interval, "interval", 30*time.Second, "检查队列的间隔")
+	cmd.Flags().BoolVar(&once, "once", false, "只处理一次到期任务后退出（适合由 cron 调用）")
+	cmd.Flags().IntVar(&maxAttempts, "max-attempts", schedule.DefaultMaxAttempts, "每个任务最多提交发布的次数")
+	cmd.Flags().DurationVar(&retryDelay, "retry-delay", schedule.DefaultRetryDelay, "首次重试间隔")
+
+	return cmd
+}
+
+// schedulePublisher 按账号 ID 创建发布接口
+func schedulePublisher(accountID string) (schedule.Publisher, error) {
+	for i := range cfg.WechatAccounts {
+		if cfg.WechatAccounts[i].ID == accountID {
+			return wechat.NewService(&cfg.WechatAccounts[i], log), nil
+		}
+	}
+	return nil, fmt.Errorf("账号 %s 不存在（可能已从配置文件中移除）", accountID)
+}
+
+// parseScheduleTime 解析计划发布时间，不允许早于当前时间
+func parseScheduleTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range scheduleTimeLayouts {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err != nil {
+			continue
+		}
+		if t.Before(now) {
+			return time.Time{}, fmt.Errorf("计划发布时间 %s 早于当前时间", s)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		if t.Before(now) {
+			return time.Time{}, fmt.Errorf("计划发布时间 %s 早于当前时间", s)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf(`无法解析时间 %q，格式示例: "2026-10-20 08:00"`, s)
+}
+
+// resolveScheduleSource 解析任务来源，文件来源会立即创建草稿
+func resolveScheduleSource(cmd *cobra.Command, job *schedule.Job, account *config.WechatAccount, source, htmlFile, cover string) error {
+	info, err := os.Stat(source)
+	if err != nil || info.IsDir() {
+		// 非文件，视为草稿 media_id
+		job.MediaID = source
+		return nil
+	}
+
+	abs, _ := filepath.Abs(source)
+	job.Source = abs
+
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".json":
+	case ".md", ".markdown":
+		return scheduleDraftFromMarkdown(cmd, job, account, source, htmlFile, cover)
+	default:
+		return fmt.Errorf("不支持的文件类型 %s（支持草稿 JSON 和 Markdown）", source)
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+	var req draft.DraftRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("parse json: %w", err)
+	}
+	if len(req.Articles) == 0 {
+		return fmt.Errorf("no articles in %s", source)
+	}
+	draft.SetBaseDir(req.Articles, filepath.Dir(source))
+
+	result, err := draft.NewService(cfg, log).CreateDraftWithAccount(req.Articles, job.AccountID)
+	if err != nil {
+		return fmt.Errorf("create draft: %w", err)
+	}
+	job.MediaID = result.MediaID
+	job.Title = req.Articles[0].Title
+	return nil
+}
+
+// scheduleDraftFromMarkdown 按 'writer convert --draft --html' 的流程由 Markdown 创建草稿：
+// 上传正文图片和视频/语音、替换占位符、追加账号尾部后创建（或更新由该文件创建的）草稿
+func scheduleDraftFromMarkdown(cmd *cobra.Command, job *schedule.Job, account *config.WechatAccount, markdownFile, htmlFile, cover string) error {
+	markdown, err := os.ReadFile(markdownFile)
+	if err != nil {
+		return fmt.Errorf("read markdown file: %w", err)
+	}
+	meta, body, err := converter.ParseFrontMatter(string(markdown))
+	if err != nil {
+		return err
+	}
+
+	if htmlFile == "" {
+		htmlFile = strings.TrimSuffix(markdownFile, filepath.Ext(markdownFile)) + ".html"
+	}
+	html, err := os.ReadFile(htmlFile)
+	if err != nil {
+		return &DraftError{
+			Message: fmt.Sprintf("找不到正文 HTML: %s", htmlFile),
+			Hint:    fmt.Sprintf("先运行 'writer convert %s' 获取 AI 转换提示词，将生成的 HTML 保存为 %s，或使用 --html 指定", markdownFile, htmlFile),
+		}
+	}
+
+	base := articleFromFrontMatter(meta, body, markdownFile, "", cover)
+	if base.Cover == "" && base.ThumbMediaID == "" {
+		return &DraftError{
+			Message: "创建草稿需要封面图片",
+			Hint:    "请使用 --cover 参数指定封面图片路径，或在 front matter 中设置 cover / thumb_media_id",
+		}
+	}
+
+	images := converter.NewConverter(cfg, log).ExtractImages(body)
+	_, mediaRefs := converter.ExtractMedia(body)
+	res := &AccountDraftResult{AccountID: account.ID, Theme: accountTheme(cmd, account)}
+	if err := createAccountDraft(draft.NewService(cfg, log), account, base, markdown, markdownFile, string(html),
+		images, mediaRefs, make(map[string]image.Job), res); err != nil {
+		return fmt.Errorf("create draft: %w", err)
+	}
+	for _, failure := range append(res.ImageErrors, res.MediaErrors...) {
+		log.Warn("scheduled draft upload failed", zap.String("item", failure))
+	}
+
+	job.MediaID = res.MediaID
+	job.Title = base.Title
+	return nil
+}
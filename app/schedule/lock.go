@@ -0,0 +1,51 @@
+package schedule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	lockTimeout = 30 * time.Second // 等待文件锁的最长时间
+	lockStale   = 5 * time.Minute  // 超过该时间的锁文件视为进程异常退出遗留
+	lockPoll    = 50 * time.Millisecond
+)
+
+// withLock 持有队列文件锁执行 fn
+// 使用独占创建锁文件的方式，兼容不支持 flock 的平台
+func (q *Queue) withLock(fn func() error) error {
+	lockPath := q.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			break
+		}
+		if !os.IsExist(err) {
+			// 数据目录不存在时先创建
+			if os.IsNotExist(err) {
+				if mkErr := os.MkdirAll(filepath.Dir(lockPath), 0755); mkErr == nil {
+					continue
+				}
+			}
+			return fmt.Errorf("lock schedule queue: %w", err)
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("lock schedule queue: timeout waiting for %s（如确认没有其他进程在运行，可删除该文件）", lockPath)
+		}
+		time.Sleep(lockPoll)
+	}
+	defer os.Remove(lockPath)
+
+	return fn()
+}
@@ -0,0 +1,258 @@
+// Package schedule 定时发布队列
+//
+// 微信接口不支持定时发布，这里在本地持久化发布计划，由前台守护进程
+// (writer schedule run) 到点后通过 freepublish 接口提交发布并记录结果。
+package schedule
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// queueFile 定时发布队列文件名（位于数据目录下，所有账号共用）
+const queueFile = "schedule.json"
+
+// 任务状态
+const (
+	StatusPending    = "pending"    // 等待发布（含失败后等待重试）
+	StatusPublishing = "publishing" // 已提交，等待微信发布结果
+	StatusPublished  = "published"  // 发布成功
+	StatusFailed     = "failed"     // 发布失败（不再重试）
+	StatusCanceled   = "canceled"   // 已取消
+)
+
+// Job 定时发布任务
+type Job struct {
+	ID          string    `json:"id"`
+	AccountID   string    `json:"account_id"`
+	MediaID     string    `json:"media_id"`         // 草稿 media_id
+	Title       string    `json:"title,omitempty"`  // 文章标题（仅用于展示）
+	Source      string    `json:"source,omitempty"` // 来源文件（由文件创建草稿时记录）
+	At          time.Time `json:"at"`               // 计划发布时间
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`               // 已提交发布的次数
+	NextAttempt time.Time `json:"next_attempt,omitempty"` // 失败后下次重试时间
+	PublishID   int64     `json:"publish_id,omitempty"`   // 微信发布任务 ID
+	ArticleID   string    `json:"article_id,omitempty"`   // 发布成功后的 article_id
+	ArticleURLs []string  `json:"article_urls,omitempty"` // 发布成功后的文章链接
+	LastError   string    `json:"last_error,omitempty"`   // 最近一次失败原因
+	Unconfirmed bool      `json:"unconfirmed,omitempty"`  // 上次提交结果未知（网络错误或进程中断），重新提交前需确认草稿仍在草稿箱
+	ClaimedAt   time.Time `json:"claimed_at,omitempty"`   // 执行器开始处理的时间，处理期间不能取消
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	PublishedAt time.Time `json:"published_at,omitempty"`
+}
+
+// Active 任务是否仍在队列中（未结束）
+func (j *Job) Active() bool {
+	return j.Status == StatusPending || j.Status == StatusPublishing
+}
+
+// Due 任务在 now 时是否需要处理
+func (j *Job) Due(now time.Time) bool {
+	switch j.Status {
+	case StatusPending:
+		if !j.NextAttempt.IsZero() {
+			return !now.Before(j.NextAttempt)
+		}
+		return !now.Before(j.At)
+	case StatusPublishing:
+		return true
+	default:
+		return false
+	}
+}
+
+// claimTimeout 执行器认领任务后超过该时间仍未写回结果，视为进程异常退出，任务可被重新处理
+const claimTimeout = 10 * time.Minute
+
+// Claimed 任务是否正由执行器处理（提交发布或查询结果期间不持有队列锁）
+func (j *Job) Claimed(now time.Time) bool {
+	return !j.ClaimedAt.IsZero() && now.Sub(j.ClaimedAt) < claimTimeout
+}
+
+// ErrJobNotFound 任务不存在
+var ErrJobNotFound = errors.New("schedule job not found")
+
+// Queue 持久化的定时发布队列
+// 每次读写都重新加载文件并持有文件锁，允许 add/cancel 与守护进程同时运行
+type Queue struct {
+	path string
+}
+
+// OpenQueue 打开数据目录下的定时发布队列
+func OpenQueue(dataDir string) *Queue {
+	return &Queue{path: filepath.Join(dataDir, queueFile)}
+}
+
+// Path 返回队列文件路径
+func (q *Queue) Path() string {
+	return q.path
+}
+
+// List 返回全部任务，按计划时间排序
+func (q *Queue) List() ([]*Job, error) {
+	var jobs []*Job
+	err := q.withLock(func() error {
+		var err error
+		jobs, err = q.load()
+		return err
+	})
+	return jobs, err
+}
+
+// Add 添加任务，返回带 ID 的任务
+func (q *Queue) Add(job Job) (*Job, error) {
+	if job.AccountID == "" || job.MediaID == "" {
+		return nil, fmt.Errorf("account_id and media_id are required")
+	}
+	if job.At.IsZero() {
+		return nil, fmt.Errorf("publish time is required")
+	}
+
+	now := time.Now()
+	job.ID = newJobID()
+	job.Status = StatusPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	err := q.Update(func(jobs []*Job) ([]*Job, error) {
+		for _, j := range jobs {
+			if j.Active() && j.AccountID == job.AccountID && j.MediaID == job.MediaID {
+				return nil, fmt.Errorf("草稿 %s 已在队列中（任务 %s，计划 %s）",
+					job.MediaID, j.ID, j.At.Format("2006-01-02 15:04"))
+			}
+		}
+		return append(jobs, &job), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Cancel 取消等待中的任务，id 支持唯一前缀
+func (q *Queue) Cancel(id string) (*Job, error) {
+	var canceled *Job
+	err := q.Update(func(jobs []*Job) ([]*Job, error) {
+		job, err := findJob(jobs, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status != StatusPending {
+			return nil, fmt.Errorf("任务 %s 当前状态为 %s，只能取消等待中的任务", job.ID, job.Status)
+		}
+		if job.Claimed(time.Now()) {
+			return nil, fmt.Errorf("任务 %s 正在提交发布，请稍后查看结果", job.ID)
+		}
+		job.Status = StatusCanceled
+		job.UpdatedAt = time.Now()
+		canceled = job
+		return jobs, nil
+	})
+	return canceled, err
+}
+
+// Update 在文件锁内读取、修改并保存队列
+func (q *Queue) Update(fn func(jobs []*Job) ([]*Job, error)) error {
+	return q.withLock(func() error {
+		jobs, err := q.load()
+		if err != nil {
+			return err
+		}
+		jobs, err = fn(jobs)
+		if err != nil {
+			return err
+		}
+		return q.save(jobs)
+	})
+}
+
+// findJob 按 ID 或唯一前缀查找任务
+func findJob(jobs []*Job, id string) (*Job, error) {
+	var found *Job
+	for _, j := range jobs {
+		if j.ID == id {
+			return j, nil
+		}
+		if len(id) >= 4 && len(j.ID) > len(id) && j.ID[:len(id)] == id {
+			if found != nil {
+				return nil, fmt.Errorf("任务 ID 前缀 %s 不唯一", id)
+			}
+			found = j
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return found, nil
+}
+
+// load 读取队列文件，文件不存在时返回空队列（调用方需持有锁）
+func (q *Queue) load() ([]*Job, error) {
+	data, err := os.ReadFile(q.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read schedule queue: %w", err)
+	}
+
+	var jobs []*Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("parse schedule queue %s: %w", q.path, err)
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// save 写入队列文件（调用方需持有锁）
+func (q *Queue) save(jobs []*Job) error {
+	sortJobs(jobs)
+	if jobs == nil {
+		jobs = []*Job{}
+	}
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal schedule queue: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return fmt.Errorf("create data directory: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免中断时损坏队列
+	tmpPath := q.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write schedule queue: %w", err)
+	}
+	if err := os.Rename(tmpPath, q.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("write schedule queue: %w", err)
+	}
+	return nil
+}
+
+// sortJobs 按计划时间排序
+func sortJobs(jobs []*Job) {
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].At.Before(jobs[j].At)
+	})
+}
+
+// newJobID 生成任务 ID
+func newJobID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%012x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,315 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"go.uber.org/zap"
+)
+
+// 默认重试参数
+const (
+	DefaultMaxAttempts = 5
+	DefaultRetryDelay  = time.Minute
+	maxRetryDelay      = 30 * time.Minute
+)
+
+// Publisher 发布接口（由 wechat.Service 实现）
+type Publisher interface {
+	SubmitPublish(mediaID string) (int64, error)
+	GetPublishStatus(publishID int64) (*wechat.PublishStatus, error)
+	DraftExists(mediaID string) (bool, error)
+}
+
+// PublisherFunc 按账号 ID 获取发布接口
+type PublisherFunc func(accountID string) (Publisher, error)
+
+// Runner 定时发布执行器
+type Runner struct {
+	queue        *Queue
+	publisherFor PublisherFunc
+	publishers   map[string]Publisher
+	log          *zap.Logger
+
+	MaxAttempts int           // 每个任务最多提交发布的次数
+	RetryDelay  time.Duration // 首次重试间隔，之后指数增长
+	Now         func() time.Time
+}
+
+// NewRunner 创建执行器
+func NewRunner(queue *Queue, publisherFor PublisherFunc, log *zap.Logger) *Runner {
+	return &Runner{
+		queue:        queue,
+		publisherFor: publisherFor,
+		publishers:   make(map[string]Publisher),
+		log:          log,
+		MaxAttempts:  DefaultMaxAttempts,
+		RetryDelay:   DefaultRetryDelay,
+		Now:          time.Now,
+	}
+}
+
+// Run 前台循环执行，每隔 interval 处理一次到期任务，直到 ctx 结束
+func (r *Runner) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Tick(); err != nil {
+			r.log.Error("schedule tick failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick 处理一次到期任务，返回状态发生变化的任务
+//
+// 微信接口调用（含重试退避）可能耗时较长，不能一直持有队列锁：
+// 先在锁内认领到期任务，释放锁后逐个处理，最后重新加锁按任务 ID 写回结果。
+// 处理期间 add / cancel 可以正常执行，被认领的任务不能取消。
+func (r *Runner) Tick() ([]*Job, error) {
+	claimed, err := r.claim()
+	if err != nil || len(claimed) == 0 {
+		return nil, err
+	}
+
+	type result struct {
+		before string // 认领时的状态，用于判断期间是否被其他进程更新
+		job    *Job
+	}
+	results := make([]result, 0, len(claimed))
+	for _, job := range claimed {
+		before := job.Status
+		r.process(job, r.Now())
+		results = append(results, result{before: before, job: job})
+	}
+
+	var changed []*Job
+	err = r.queue.Update(func(jobs []*Job) ([]*Job, error) {
+		now := r.Now()
+		for _, res := range results {
+			stored, err := findJob(jobs, res.job.ID)
+			if err != nil {
+				continue
+			}
+			if stored.Status != res.before {
+				// 处理期间已由回调事件等更新，以队列中的结果为准
+				stored.ClaimedAt = time.Time{}
+				continue
+			}
+			updated := *res.job
+			updated.ClaimedAt = time.Time{}
+			if updated.Status != res.before || updated.Status == StatusPending {
+				updated.UpdatedAt = now
+				copied := updated
+				changed = append(changed, &copied)
+			}
+			*stored = updated
+		}
+		return jobs, nil
+	})
+	return changed, err
+}
+
+// claim 在队列锁内标记到期任务为处理中，返回任务副本
+func (r *Runner) claim() ([]*Job, error) {
+	var claimed []*Job
+	err := r.queue.Update(func(jobs []*Job) ([]*Job, error) {
+		now := r.Now()
+		for _, job := range jobs {
+			if !job.Due(now) || job.Claimed(now) {
+				continue
+			}
+			if !job.ClaimedAt.IsZero() && job.Status == StatusPending {
+				// 上次认领后未写回结果（进程中断），可能已提交成功但没有记录 publish_id
+				job.Unconfirmed = true
+			}
+			job.ClaimedAt = now
+			copied := *job
+			claimed = append(claimed, &copied)
+		}
+		return jobs, nil
+	})
+	return claimed, err
+}
+
+// process 处理单个到期任务
+func (r *Runner) process(job *Job, now time.Time) {
+	pub, err := r.publisher(job.AccountID)
+	if err != nil {
+		// 账号配置问题无法自动恢复
+		r.fail(job, err.Error())
+		return
+	}
+
+	if job.Status == StatusPublishing {
+		r.poll(job, pub, now)
+		return
+	}
+
+	if job.Unconfirmed && !r.confirmUnpublished(job, pub, now) {
+		return
+	}
+
+	job.Attempts++
+	publishID, err := pub.SubmitPublish(job.MediaID)
+	if err != nil {
+		if wechat.Classify(err) == wechat.ClassPermanent {
+			r.fail(job, err.Error())
+			return
+		}
+		// 网络错误或 HTTP 5xx 时请求可能已被微信处理，重新提交前先确认
+		job.Unconfirmed = wechat.IsTransportError(err)
+		r.retryLater(job, err.Error(), now)
+		return
+	}
+
+	job.Status = StatusPublishing
+	job.PublishID = publishID
+	job.NextAttempt = time.Time{}
+	job.LastError = ""
+	job.Unconfirmed = false
+	r.log.Info("scheduled publish submitted",
+		zap.String("job", job.ID),
+		zap.String("account", job.AccountID),
+		zap.Int64("publish_id", publishID))
+
+	// 发布通常很快完成，提交后立即查询一次
+	r.poll(job, pub, now)
+}
+
+// confirmUnpublished 上次提交结果未知时，确认草稿仍在草稿箱后才允许重新提交
+// 发布成功后草稿会移出草稿箱；草稿已不存在时不再提交，避免重复发布
+func (r *Runner) confirmUnpublished(job *Job, pub Publisher, now time.Time) bool {
+	exists, err := pub.DraftExists(job.MediaID)
+	if err != nil {
+		if wechat.Classify(err) == wechat.ClassPermanent {
+			r.fail(job, err.Error())
+			return false
+		}
+		r.retryLater(job, fmt.Sprintf("确认上次提交结果失败: %v", err), now)
+		return false
+	}
+	if !exists {
+		r.fail(job, "上次提交结果未知，草稿已不在草稿箱（可能已发布成功或被删除），为避免重复发布不再提交，请在公众号后台确认")
+		return false
+	}
+	job.Unconfirmed = false
+	return true
+}
+
+// poll 查询发布结果
+func (r *Runner) poll(job *Job, pub Publisher, now time.Time) {
+	status, err := pub.GetPublishStatus(job.PublishID)
+	if err != nil {
+		// 查询失败不影响已提交的发布，下次继续查询
+		job.LastError = err.Error()
+		r.log.Warn("get publish status failed",
+			zap.String("job", job.ID),
+			zap.Int64("publish_id", job.PublishID),
+			zap.Error(err))
+		return
+	}
+	r.apply(job, status, now)
+}
+
+// apply 根据发布结果更新任务（发布中时不处理）
+func (r *Runner) apply(job *Job, status *wechat.PublishStatus, now time.Time) {
+	if !status.Done() {
+		return
+	}
+
+	if status.Succeeded() {
+		job.Status = StatusPublished
+		job.ArticleID = status.ArticleID
+		job.ArticleURLs = status.ArticleURLs
+		job.PublishedAt = now
+		job.LastError = ""
+		r.log.Info("scheduled publish succeeded",
+			zap.String("job", job.ID),
+			zap.String("article_id", status.ArticleID))
+		return
+	}
+
+	msg := fmt.Sprintf("publish_id %d: %s", job.PublishID, status.Description())
+	job.PublishID = 0
+	if status.Status == wechat.PublishStatusFail {
+		// 常规失败可重新提交
+		job.Status = StatusPending
+		r.retryLater(job, msg, now)
+		return
+	}
+	r.fail(job, msg)
+}
+
+// HandleStatus 处理推送的发布结果（例如 PUBLISHJOBFINISH 事件），更新对应的发布中任务
+// 没有匹配的任务时返回 nil（例如任务已由轮询更新，或不是通过定时发布提交的）
+func (r *Runner) HandleStatus(accountID string, status *wechat.PublishStatus) (*Job, error) {
+	var matched *Job
+	err := r.queue.Update(func(jobs []*Job) ([]*Job, error) {
+		now := r.Now()
+		for _, job := range jobs {
+			if job.AccountID != accountID || job.Status != StatusPublishing || job.PublishID != status.PublishID {
+				continue
+			}
+			r.apply(job, status, now)
+			job.UpdatedAt = now
+			copied := *job
+			matched = &copied
+			break
+		}
+		return jobs, nil
+	})
+	return matched, err
+}
+
+// retryLater 记录失败并安排重试，超过最大次数时标记为失败
+func (r *Runner) retryLater(job *Job, msg string, now time.Time) {
+	if job.Attempts >= r.MaxAttempts {
+		r.fail(job, fmt.Sprintf("重试 %d 次后仍失败: %s", job.Attempts, msg))
+		return
+	}
+
+	delay := r.RetryDelay << (job.Attempts - 1)
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	job.LastError = msg
+	job.NextAttempt = now.Add(delay)
+	r.log.Warn("scheduled publish failed, will retry",
+		zap.String("job", job.ID),
+		zap.Int("attempt", job.Attempts),
+		zap.Time("next_attempt", job.NextAttempt),
+		zap.String("error", msg))
+}
+
+// fail 标记任务失败
+func (r *Runner) fail(job *Job, msg string) {
+	job.Status = StatusFailed
+	job.LastError = msg
+	job.NextAttempt = time.Time{}
+	r.log.Error("scheduled publish failed",
+		zap.String("job", job.ID),
+		zap.String("account", job.AccountID),
+		zap.String("error", msg))
+}
+
+// publisher 获取账号的发布接口（同一账号复用，以复用 access_token）
+func (r *Runner) publisher(accountID string) (Publisher, error) {
+	if pub, ok := r.publishers[accountID]; ok {
+		return pub, nil
+	}
+	pub, err := r.publisherFor(accountID)
+	if err != nil {
+		return nil, err
+	}
+	r.publishers[accountID] = pub
+	return pub, nil
+}
@@ -0,0 +1,379 @@
+package schedule
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/config"
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"github.com/royalrick/wechatwriter/app/wechat/wechattest"
+	"go.uber.org/zap"
+)
+
+// newTestRunner 创建指向模拟服务的执行器，返回执行器、模拟服务和一个草稿 media_id 生成函数
+func newTestRunner(t *testing.T) (*Runner, *wechattest.Server, func() string) {
+	t.Helper()
+	srv := wechattest.NewServer()
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	ws := wechat.NewService(&config.WechatAccount{
+		ID:      "mock",
+		AppID:   "mock_appid",
+		Secret:  "mock_secret",
+		APIBase: ts.URL,
+	}, zap.NewNop())
+	ws.SetRetryPolicy(wechat.RetryPolicy{MaxAttempts: 1})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	coverPath := filepath.Join(t.TempDir(), "cover.png")
+	if err := os.WriteFile(coverPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cover, err := ws.UploadMaterial(coverPath)
+	if err != nil {
+		t.Fatalf("UploadMaterial() error = %v", err)
+	}
+
+	newDraft := func() string {
+		res, err := ws.CreateDraft([]*wechat.Article{{Title: "定时", Content: "<p>x</p>", ThumbMediaID: cover.MediaID}})
+		if err != nil {
+			t.Fatalf("CreateDraft() error = %v", err)
+		}
+		return res.MediaID
+	}
+
+	runner := NewRunner(OpenQueue(t.TempDir()), func(accountID string) (Publisher, error) {
+		return ws, nil
+	}, zap.NewNop())
+	return runner, srv, newDraft
+}
+
+// jobByID 从队列中读取任务
+func jobByID(t *testing.T, q *Queue, id string) *Job {
+	t.Helper()
+	jobs, err := q.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	job, err := findJob(jobs, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestRunnerPublishesDueJobs(t *testing.T) {
+	runner, _, newDraft := newTestRunner(t)
+	now := time.Now()
+	runner.Now = func() time.Time { return now }
+
+	due, err := runner.queue.Add(Job{AccountID: "mock", MediaID: newDraft(), At: now.Add(-time.Minute)})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	later, err := runner.queue.Add(Job{AccountID: "mock", MediaID: newDraft(), At: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	changed, err := runner.Tick()
+	if err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+	if len(changed) != 1 || changed[0].ID != due.ID {
+		t.Fatalf("Tick() changed = %+v, want only the due job", changed)
+	}
+
+	got := jobByID(t, runner.queue, due.ID)
+	if got.Status != StatusPublished || got.ArticleID == "" || len(got.ArticleURLs) != 1 {
+		t.Errorf("due job = %+v, want published with article", got)
+	}
+	if got := jobByID(t, runner.queue, later.ID); got.Status != StatusPending {
+		t.Errorf("future job status = %s, want pending", got.Status)
+	}
+}
+
+func TestRunnerPollsPublishingJob(t *testing.T) {
+	runner, srv, newDraft := newTestRunner(t)
+	srv.SetPublishing(true)
+
+	job, err := runner.queue.Add(Job{AccountID: "mock", MediaID: newDraft(), At: time.Now().Add(-time.Second)})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if _, err := runner.Tick(); err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+	got := jobByID(t, runner.queue, job.ID)
+	if got.Status != StatusPublishing || got.PublishID == 0 {
+		t.Fatalf("job = %+v, want publishing", got)
+	}
+
+	if err := srv.CompletePublish(got.PublishID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Tick(); err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+	if got := jobByID(t, runner.queue, job.ID); got.Status != StatusPublished {
+		t.Errorf("status = %s, want published", got.Status)
+	}
+	if n := srv.Calls("/cgi-bin/freepublish/submit"); n != 1 {
+		t.Errorf("submit called %d times, want 1", n)
+	}
+}
+
+func TestRunnerRetriesTransientFailure(t *testing.T) {
+	runner, srv, newDraft := newTestRunner(t)
+	now := time.Now()
+	runner.Now = func() time.Time { return now }
+
+	job, err := runner.queue.Add(Job{AccountID: "mock", MediaID: newDraft(), At: now.Add(-time.Second)})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	srv.FailNext("/cgi-bin/freepublish/submit", wechattest.ErrCodeSystemBusy, "system error")
+	if _, err := runner.Tick(); err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+	got := jobByID(t, runner.queue, job.ID)
+	if got.Status != StatusPending || got.LastError == "" || !got.NextAttempt.Equal(now.Add(DefaultRetryDelay)) {
+		t.Fatalf("job = %+v, want pending with retry scheduled", got)
+	}
+
+	// 未到重试时间不处理
+	if changed, _ := runner.Tick(); len(changed) != 0 {
+		t.Fatalf("Tick() before retry time changed %d jobs", len(changed))
+	}
+
+	now = now.Add(DefaultRetryDelay)
+	if _, err := runner.Tick(); err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+	got = jobByID(t, runner.queue, job.ID)
+	if got.Status != StatusPublished || got.Attempts != 2 {
+		t.Errorf("job = %+v, want published after 2 attempts", got)
+	}
+}
+
+func TestRunnerFailsPermanentError(t *testing.T) {
+	runner, _, _ := newTestRunner(t)
+
+	job, err := runner.queue.Add(Job{AccountID: "mock", MediaID: "not-exist", At: time.Now().Add(-time.Second)})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := runner.Tick(); err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+
+	got := jobByID(t, runner.queue, job.ID)
+	if got.Status != StatusFailed || got.Attempts != 1 || !strings.Contains(got.LastError, "40007") {
+		t.Errorf("job = %+v, want failed after 1 attempt with errcode 40007", got)
+	}
+}
+
+func TestQueueAddAndCancel(t *testing.T) {
+	q := OpenQueue(t.TempDir())
+	at := time.Now().Add(time.Hour)
+
+	job, err := q.Add(Job{AccountID: "a", MediaID: "m1", At: at})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := q.Add(Job{AccountID: "a", MediaID: "m1", At: at}); err == nil {
+		t.Error("Add() duplicate media_id should fail")
+	}
+	if _, err := q.Add(Job{AccountID: "b", MediaID: "m1", At: at}); err != nil {
+		t.Errorf("Add() same media_id on another account error = %v", err)
+	}
+
+	canceled, err := q.Cancel(job.ID[:6])
+	if err != nil {
+		t.Fatalf("Cancel() by prefix error = %v", err)
+	}
+	if canceled.ID != job.ID || canceled.Status != StatusCanceled {
+		t.Errorf("Cancel() = %+v", canceled)
+	}
+	if _, err := q.Cancel(job.ID); err == nil {
+		t.Error("Cancel() twice should fail")
+	}
+
+	// 取消后可重新添加
+	if _, err := q.Add(Job{AccountID: "a", MediaID: "m1", At: at}); err != nil {
+		t.Errorf("Add() after cancel error = %v", err)
+	}
+}
+
+func TestRunnerHandleStatus(t *testing.T) {
+	runner, srv, newDraft := newTestRunner(t)
+	srv.SetPublishing(true)
+
+	job, err := runner.queue.Add(Job{AccountID: "mock", MediaID: newDraft(), At: time.Now().Add(-time.Second)})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := runner.Tick(); err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+	publishID := jobByID(t, runner.queue, job.ID).PublishID
+
+	// 其他账号的同一 publish_id 不匹配
+	if got, err := runner.HandleStatus("other", &wechat.PublishStatus{PublishID: publishID}); err != nil || got != nil {
+		t.Fatalf("HandleStatus() other account = %+v, %v, want no match", got, err)
+	}
+
+	got, err := runner.HandleStatus("mock", &wechat.PublishStatus{
+		PublishID: publishID,
+		Status:    wechat.PublishStatusAuditRefused,
+		FailIndex: []uint{1},
+	})
+	if err != nil {
+		t.Fatalf("HandleStatus() error = %v", err)
+	}
+	if got == nil || got.ID != job.ID || got.Status != StatusFailed {
+		t.Fatalf("HandleStatus() = %+v, want job failed", got)
+	}
+	if stored := jobByID(t, runner.queue, job.ID); stored.Status != StatusFailed || !strings.Contains(stored.LastError, "审核") {
+		t.Errorf("stored job = %+v, want failed with audit error", stored)
+	}
+}
+
+// slowPublisher 提交发布时阻塞，直到 release 关闭
+type slowPublisher struct {
+	Publisher
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (p *slowPublisher) SubmitPublish(mediaID string) (int64, error) {
+	close(p.entered)
+	<-p.release
+	return p.Publisher.SubmitPublish(mediaID)
+}
+
+func TestRunnerTickDoesNotHoldLockDuringPublish(t *testing.T) {
+	runner, _, newDraft := newTestRunner(t)
+	inner, _ := runner.publisherFor("mock")
+	slow := &slowPublisher{Publisher: inner, entered: make(chan struct{}), release: make(chan struct{})}
+	runner.publishers["mock"] = slow
+
+	due, err := runner.queue.Add(Job{AccountID: "mock", MediaID: newDraft(), At: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := runner.Tick()
+		done <- err
+	}()
+	<-slow.entered
+
+	// 提交发布期间可以添加任务，但不能取消正在提交的任务
+	start := time.Now()
+	added, err := runner.queue.Add(Job{AccountID: "mock", MediaID: newDraft(), At: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Add() during publish error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Add() waited %v for the queue lock", elapsed)
+	}
+	if _, err := runner.queue.Cancel(due.ID); err == nil {
+		t.Error("Cancel() of a job being published should fail")
+	}
+
+	close(slow.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+
+	got := jobByID(t, runner.queue, due.ID)
+	if got.Status != StatusPublished || !got.ClaimedAt.IsZero() {
+		t.Errorf("due job = %+v, want published and unclaimed", got)
+	}
+	if got := jobByID(t, runner.queue, added.ID); got.Status != StatusPending {
+		t.Errorf("job added during tick status = %s, want pending", got.Status)
+	}
+}
+
+// lostResponsePublisher 提交发布成功，但模拟响应在网络中丢失
+type lostResponsePublisher struct {
+	Publisher
+}
+
+func (p *lostResponsePublisher) SubmitPublish(mediaID string) (int64, error) {
+	if _, err := p.Publisher.SubmitPublish(mediaID); err != nil {
+		return 0, err
+	}
+	return 0, &url.Error{Op: "Post", URL: "https://api.weixin.qq.com/cgi-bin/freepublish/submit", Err: errors.New("connection reset by peer")}
+}
+
+func TestRunnerConfirmsBeforeResubmitting(t *testing.T) {
+	runner, srv, newDraft := newTestRunner(t)
+	now := time.Now()
+	runner.Now = func() time.Time { return now }
+	inner, _ := runner.publisherFor("mock")
+
+	// 提交已被微信处理但响应丢失：草稿已移出草稿箱，不再重新提交
+	runner.publishers["mock"] = &lostResponsePublisher{Publisher: inner}
+	lost, err := runner.queue.Add(Job{AccountID: "mock", MediaID: newDraft(), At: now.Add(-time.Second)})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := runner.Tick(); err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+	if got := jobByID(t, runner.queue, lost.ID); got.Status != StatusPending || !got.Unconfirmed {
+		t.Fatalf("job = %+v, want pending and unconfirmed", got)
+	}
+
+	runner.publishers["mock"] = inner
+	now = now.Add(DefaultRetryDelay)
+	if _, err := runner.Tick(); err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+	if got := jobByID(t, runner.queue, lost.ID); got.Status != StatusFailed || !strings.Contains(got.LastError, "草稿箱") {
+		t.Errorf("job = %+v, want failed without resubmitting", got)
+	}
+	if calls := srv.Calls("/cgi-bin/freepublish/submit"); calls != 1 {
+		t.Errorf("freepublish/submit called %d times, want 1", calls)
+	}
+
+	// 网关返回 502 且未处理：草稿仍在草稿箱，确认后重新提交
+	failed, err := runner.queue.Add(Job{AccountID: "mock", MediaID: newDraft(), At: now.Add(-time.Second)})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	srv.FailNextHTTP("/cgi-bin/freepublish/submit", http.StatusBadGateway)
+	if _, err := runner.Tick(); err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+	now = now.Add(DefaultRetryDelay)
+	if _, err := runner.Tick(); err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+	got := jobByID(t, runner.queue, failed.ID)
+	if got.Status != StatusPublished || got.Unconfirmed || got.Attempts != 2 {
+		t.Errorf("job = %+v, want published after confirming the draft", got)
+	}
+	if calls := srv.Calls("/cgi-bin/draft/get"); calls != 2 {
+		t.Errorf("draft/get called %d times, want 2", calls)
+	}
+}
@@ -38,6 +38,10 @@ func scoreCmd() *cobra.Command {
 		inputFile string
 		domain    string
 		output    string
+		articleID string
+		accountID string
+		from      string
+		to        string
 	)
 
 	cmd := &cobra.Command{
@@ -48,8 +52,18 @@ func scoreCmd() *cobra.Command {
 支持的指标：
 - 阅读量、点赞量、分享量、评论量
 - 互动率、分享率等衍生指标
-- 领域相关性评估`,
+- 领域相关性评估
+
+已发布文章可先用 'writer stats fetch' 拉取数据，再按文章 ID 或群发日期评分：
+  writer score --article 2247483653_1
+  writer score --from 2026-10-01 --to 2026-10-07`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if articleID != "" || from != "" || to != "" {
+				if err := initConfig(); err != nil {
+					return err
+				}
+				return runScoreStored(accountID, articleID, from, to, domain, output)
+			}
 			return runScore(inputFile, domain, output)
 		},
 	}
@@ -57,6 +71,10 @@ func scoreCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "输入文件路径（JSON格式），留空则从stdin读取")
 	cmd.Flags().StringVarP(&domain, "domain", "d", "tea", "领域配置 (tea, tech, lifestyle)")
 	cmd.Flags().StringVarP(&output, "output", "o", "json", "输出格式 (json, text)")
+	cmd.Flags().StringVar(&articleID, "article", "", "按文章 ID（stats 中的 msgid）评分本地统计数据")
+	cmd.Flags().StringVar(&from, "from", "", "按群发日期评分：开始日期 (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&to, "to", "", "按群发日期评分：结束日期 (YYYY-MM-DD)")
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "统计数据所属账号ID（可选，不指定则使用默认账号）")
 
 	return cmd
 }
@@ -87,6 +105,20 @@ func runScore(inputFile, domain, outputFormat string) error {
 		request.Domain = domain
 	}
 
+	result := scoreRequest(&request)
+
+	if outputFormat == "json" {
+		output, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(output))
+	} else {
+		printTextScore(result)
+	}
+
+	return nil
+}
+
+// scoreRequest 计算衍生指标与评分，返回评分结果
+func scoreRequest(request *ScoreRequest) map[string]interface{} {
 	// 计算评分
 	if request.Metrics.ReadCount > 0 {
 		request.Metrics.EngagementRate = float64(request.Metrics.LikeCount+request.Metrics.ShareCount+request.Metrics.CommentCount) / float64(request.Metrics.ReadCount)
@@ -95,11 +127,10 @@ func runScore(inputFile, domain, outputFormat string) error {
 		request.Metrics.CommentRate = float64(request.Metrics.CommentCount) / float64(request.Metrics.ReadCount)
 	}
 
-	request.Metrics.ViralScore = calculateViralScore(request)
+	request.Metrics.ViralScore = calculateViralScore(*request)
 	request.Metrics.ViralLevel = getViralLevel(request.Metrics.ViralScore)
 
-	// 输出结果
-	result := map[string]interface{}{
+	return map[string]interface{}{
 		"score":           request.Metrics.ViralScore,
 		"level":           request.Metrics.ViralLevel,
 		"engagement_rate": request.Metrics.EngagementRate,
@@ -107,14 +138,66 @@ func runScore(inputFile, domain, outputFormat string) error {
 		"metrics":         request.Metrics,
 		"recommendations": generateRecommendations(request.Metrics),
 	}
+}
+
+// runScoreStored 对 'writer stats fetch' 保存的已发布文章评分
+func runScoreStored(accountID, articleID, from, to, domain, outputFormat string) error {
+	account, err := selectAccount(accountID)
+	if err != nil {
+		return err
+	}
+
+	records, err := loadArticleStats(account.ID)
+	if err != nil {
+		return err
+	}
+
+	var targets []*ArticleStats
+	if articleID != "" {
+		rec, ok := records[articleID]
+		if !ok {
+			return fmt.Errorf("未找到文章 %s 的统计数据，请先运行 'writer stats fetch --account %s --from <群发日期>'", articleID, account.ID)
+		}
+		targets = append(targets, rec)
+	} else {
+		targets = filterArticleStats(sortedArticleStats(records), from, to)
+		if len(targets) == 0 {
+			return fmt.Errorf("指定日期范围内没有统计数据，请先运行 'writer stats fetch'")
+		}
+	}
+
+	var results []map[string]interface{}
+	for _, rec := range targets {
+		request := ScoreRequest{
+			Metrics:  rec.Metrics,
+			Topic:    rec.Title,
+			Platform: "wechat",
+			Domain:   domain,
+		}
+		result := scoreRequest(&request)
+		result["msgid"] = rec.MsgID
+		result["title"] = rec.Title
+		result["ref_date"] = rec.RefDate
+		results = append(results, result)
+	}
 
 	if outputFormat == "json" {
-		output, _ := json.MarshalIndent(result, "", "  ")
+		var payload any = results
+		if len(results) == 1 {
+			payload = results[0]
+		}
+		output, _ := json.MarshalIndent(payload, "", "  ")
 		fmt.Println(string(output))
-	} else {
-		printTextScore(result)
+		return nil
 	}
 
+	for i, result := range results {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s (%s, %s)\n", result["title"], result["msgid"], result["ref_date"])
+		printTextScore(result)
+	}
 	return nil
 }
 
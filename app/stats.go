@@ -0,0 +1,383 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/wechat"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// statsFile 文章统计数据文件名（位于账号数据目录下）
+const statsFile = "article_stats.json"
+
+// ArticleStats 单篇文章的统计记录
+type ArticleStats struct {
+	MsgID     string       `json:"msgid"`               // 数据统计接口的图文 ID（msgid_index）
+	Title     string       `json:"title"`               // 文章标题
+	RefDate   string       `json:"ref_date"`            // 群发日期
+	StatDate  string       `json:"stat_date,omitempty"` // 累计数据的统计截止日期
+	Target    int64        `json:"target_user"`         // 送达人数
+	Metrics   ViralMetrics `json:"metrics"`             // 归一化后的评分指标
+	Daily     []DailyStats `json:"daily,omitempty"`     // 每日数据
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// DailyStats 单日统计数据
+type DailyStats struct {
+	Date         string `json:"date"`
+	ReadCount    int64  `json:"read_count"`
+	ShareCount   int64  `json:"share_count"`
+	CollectCount int64  `json:"collect_count"`
+}
+
+// hasTotal 是否已有累计数据（累计数据优先于每日数据汇总）
+func (a *ArticleStats) hasTotal() bool {
+	return a.StatDate != ""
+}
+
+// mergeDaily 合并每日数据，并在没有累计数据时用每日数据汇总指标
+func (a *ArticleStats) mergeDaily(d DailyStats) {
+	replaced := false
+	for i := range a.Daily {
+		if a.Daily[i].Date == d.Date {
+			a.Daily[i] = d
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		a.Daily = append(a.Daily, d)
+		sort.Slice(a.Daily, func(i, j int) bool { return a.Daily[i].Date < a.Daily[j].Date })
+	}
+
+	if a.hasTotal() {
+		return
+	}
+	var read, share, collect int64
+	for _, day := range a.Daily {
+		read += day.ReadCount
+		share += day.ShareCount
+		collect += day.CollectCount
+	}
+	a.Metrics.ReadCount = read
+	a.Metrics.ShareCount = share
+	a.Metrics.CollectCount = collect
+}
+
+// loadArticleStats 读取账号的文章统计数据
+func loadArticleStats(accountID string) (map[string]*ArticleStats, error) {
+	path := filepath.Join(cfg.AccountDataDir(accountID), statsFile)
+	records := make(map[string]*ArticleStats)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return records, nil
+		}
+		return nil, fmt.Errorf("读取统计数据失败: %w", err)
+	}
+
+	var list []*ArticleStats
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("解析统计数据失败: %w", err)
+	}
+	for _, r := range list {
+		records[r.MsgID] = r
+	}
+
+	return records, nil
+}
+
+// saveArticleStats 保存账号的文章统计数据
+func saveArticleStats(accountID string, records map[string]*ArticleStats) error {
+	dir := cfg.AccountDataDir(accountID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %w", err)
+	}
+
+	data, err := json.MarshalIndent(sortedArticleStats(records), "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化统计数据失败: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(dir, statsFile), data, 0644)
+}
+
+// sortedArticleStats 按群发日期倒序排列统计记录
+func sortedArticleStats(records map[string]*ArticleStats) []*ArticleStats {
+	list := make([]*ArticleStats, 0, len(records))
+	for _, r := range records {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].RefDate != list[j].RefDate {
+			return list[i].RefDate > list[j].RefDate
+		}
+		return list[i].MsgID < list[j].MsgID
+	})
+	return list
+}
+
+// statsCmd 文章数据统计命令组
+func statsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "文章数据统计（拉取阅读、分享、收藏数据）",
+		Long: `文章数据统计命令组
+
+通过微信数据统计接口（datacube）拉取图文群发数据，保存到本地后可直接用于
+'writer score --article <msgid>' 或 'writer score --from ... --to ...' 评分。
+
+支持的操作：
+  fetch  - 拉取指定日期范围的图文数据
+  list   - 列出本地已保存的统计数据`,
+	}
+
+	cmd.AddCommand(statsFetchCmd())
+	cmd.AddCommand(statsListCmd())
+
+	return cmd
+}
+
+// statsFetchCmd 拉取图文统计数据
+func statsFetchCmd() *cobra.Command {
+	var (
+		accountID string
+		from      string
+		to        string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "fetch",
+		Short: "拉取图文群发数据并保存到本地",
+		Long: `拉取图文群发数据并保存到本地
+
+每天分别调用 getarticletotal（当天群发文章的累计数据）和 getarticlesummary
+（当天的阅读数据）。微信只提供到昨天为止的数据，--to 默认为昨天，--from 默认与 --to 相同。`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			start, end, err := parseDateRange(from, to)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			result, err := runStatsFetch(accountID, start, end)
+			if err != nil {
+				responseError(err)
+				return
+			}
+			responseSuccess(result)
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().StringVar(&from, "from", "", "开始日期 (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&to, "to", "", "结束日期 (YYYY-MM-DD，默认昨天)")
+
+	return cmd
+}
+
+// statsListCmd 列出本地统计数据
+func statsListCmd() *cobra.Command {
+	var (
+		accountID string
+		from      string
+		to        string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "列出本地已保存的统计数据",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			account, err := selectAccount(accountID)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			records, err := loadArticleStats(account.ID)
+			if err != nil {
+				responseError(err)
+				return
+			}
+
+			list := filterArticleStats(sortedArticleStats(records), from, to)
+			responseSuccess(map[string]any{
+				"account_id": account.ID,
+				"articles":   list,
+				"total":      len(list),
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&accountID, "account", "a", "", "指定微信公众号账号ID（可选，不指定则使用默认账号）")
+	cmd.Flags().StringVar(&from, "from", "", "按群发日期过滤：开始日期 (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&to, "to", "", "按群发日期过滤：结束日期 (YYYY-MM-DD)")
+
+	return cmd
+}
+
+// runStatsFetch 逐日拉取统计数据并合并到本地
+func runStatsFetch(accountID string, start, end time.Time) (map[string]any, error) {
+	account, err := selectAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := loadArticleStats(account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := wechat.NewService(account, log)
+	updated := make(map[string]bool)
+	days := 0
+
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format(wechat.DatacubeDateLayout)
+		days++
+
+		total, err := ws.GetArticleTotal(date)
+		if err != nil {
+			return nil, fmt.Errorf("拉取 %s 累计数据失败: %w", date, err)
+		}
+		for _, item := range total.List {
+			if len(item.Details) == 0 {
+				continue
+			}
+			rec := getOrCreateStats(records, item.MsgID, item.RefDate)
+			rec.Title = item.Title
+			rec.RefDate = item.RefDate
+
+			// details 按统计日期递增，最后一条为最新累计值
+			latest := item.Details[len(item.Details)-1]
+			rec.StatDate = latest.StatDate
+			rec.Target = int64(latest.TargetUser)
+			rec.Metrics.ReadCount = int64(latest.IntPageReadCount)
+			rec.Metrics.ShareCount = int64(latest.ShareCount)
+			rec.Metrics.CollectCount = int64(latest.AddToFavCount)
+			rec.UpdatedAt = time.Now()
+			updated[item.MsgID] = true
+		}
+
+		summary, err := ws.GetArticleSummary(date)
+		if err != nil {
+			return nil, fmt.Errorf("拉取 %s 每日数据失败: %w", date, err)
+		}
+		for _, item := range summary.List {
+			rec := getOrCreateStats(records, item.MsgID, item.RefDate)
+			if rec.Title == "" {
+				rec.Title = item.Title
+			}
+			rec.mergeDaily(DailyStats{
+				Date:         item.RefDate,
+				ReadCount:    int64(item.IntPageReadCount),
+				ShareCount:   int64(item.ShareCount),
+				CollectCount: int64(item.AddToFavCount),
+			})
+			rec.UpdatedAt = time.Now()
+			updated[item.MsgID] = true
+		}
+	}
+
+	if err := saveArticleStats(account.ID, records); err != nil {
+		return nil, err
+	}
+
+	log.Info("article stats fetched",
+		zap.String("account_id", account.ID),
+		zap.Int("days", days),
+		zap.Int("articles", len(updated)))
+
+	return map[string]any{
+		"account_id": account.ID,
+		"from":       start.Format(wechat.DatacubeDateLayout),
+		"to":         end.Format(wechat.DatacubeDateLayout),
+		"days":       days,
+		"updated":    len(updated),
+		"total":      len(records),
+	}, nil
+}
+
+// getOrCreateStats 获取或创建统计记录，refDate 为数据行的群发日期，
+// 记录缺少群发日期时补上，否则按日期过滤时会被漏掉
+func getOrCreateStats(records map[string]*ArticleStats, msgID, refDate string) *ArticleStats {
+	rec, ok := records[msgID]
+	if !ok {
+		rec = &ArticleStats{MsgID: msgID}
+		records[msgID] = rec
+	}
+	if rec.RefDate == "" {
+		rec.RefDate = refDate
+	}
+	return rec
+}
+
+// filterArticleStats 按群发日期过滤统计记录（日期为空表示不限）
+func filterArticleStats(list []*ArticleStats, from, to string) []*ArticleStats {
+	if from == "" && to == "" {
+		return list
+	}
+	var result []*ArticleStats
+	for _, r := range list {
+		if from != "" && r.RefDate < from {
+			continue
+		}
+		if to != "" && r.RefDate > to {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// parseDateRange 解析日期范围，to 默认为昨天，from 默认与 to 相同
+func parseDateRange(from, to string) (time.Time, time.Time, error) {
+	yesterday := time.Now().AddDate(0, 0, -1)
+	end, err := parseDate(to, yesterday)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	start, err := parseDate(from, end)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if start.After(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("开始日期 %s 晚于结束日期 %s",
+			start.Format(wechat.DatacubeDateLayout), end.Format(wechat.DatacubeDateLayout))
+	}
+	if end.After(yesterday) {
+		return time.Time{}, time.Time{}, fmt.Errorf("微信数据统计只提供到昨天为止的数据，结束日期不能晚于 %s",
+			yesterday.Format(wechat.DatacubeDateLayout))
+	}
+
+	return start, end, nil
+}
+
+// parseDate 解析 YYYY-MM-DD 日期，为空时返回默认值
+func parseDate(s string, defaultVal time.Time) (time.Time, error) {
+	if s == "" {
+		y, m, d := defaultVal.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local), nil
+	}
+	t, err := time.ParseInLocation(wechat.DatacubeDateLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式错误 %q，应为 YYYY-MM-DD", s)
+	}
+	return t, nil
+}
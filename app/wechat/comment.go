@@ -0,0 +1,184 @@
+package wechat
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/silenceper/wechat/v2/util"
+	"go.uber.org/zap"
+)
+
+// 留言管理接口
+const (
+	commentOpenURL        = DefaultAPIBase + "/cgi-bin/comment/open"
+	commentCloseURL       = DefaultAPIBase + "/cgi-bin/comment/close"
+	commentListURL        = DefaultAPIBase + "/cgi-bin/comment/list"
+	commentMarkElectURL   = DefaultAPIBase + "/cgi-bin/comment/markelect"
+	commentUnmarkElectURL = DefaultAPIBase + "/cgi-bin/comment/unmarkelect"
+	commentDeleteURL      = DefaultAPIBase + "/cgi-bin/comment/delete"
+	commentReplyURL       = DefaultAPIBase + "/cgi-bin/comment/reply/add"
+	commentDeleteReplyURL = DefaultAPIBase + "/cgi-bin/comment/reply/delete"
+)
+
+// 留言列表类型（comment/list 的 type）
+const (
+	CommentTypeAll      = 0 // 全部留言
+	CommentTypeNormal   = 1 // 普通留言（未精选）
+	CommentTypeFeatured = 2 // 精选留言
+)
+
+// MaxCommentPageSize comment/list 单页最多返回的留言数
+const MaxCommentPageSize = 50
+
+// CommentArticle 留言所属的群发图文
+type CommentArticle struct {
+	MsgDataID int64 `json:"msg_data_id"` // 群发返回的 msg_data_id
+	Index     int   `json:"index"`       // 多图文中的第几篇，从 0 开始
+}
+
+// CommentReply 作者回复
+type CommentReply struct {
+	Content    string `json:"content"`
+	CreateTime int64  `json:"create_time"`
+}
+
+// Comment 用户留言
+type Comment struct {
+	UserCommentID int64         `json:"user_comment_id"`
+	OpenID        string        `json:"openid"`
+	CreateTime    int64         `json:"create_time"`
+	Content       string        `json:"content"`
+	CommentType   int           `json:"comment_type"` // 1 为精选
+	Reply         *CommentReply `json:"reply,omitempty"`
+}
+
+// Featured 是否为精选留言
+func (c *Comment) Featured() bool {
+	return c.CommentType == 1
+}
+
+// Replied 是否已回复
+func (c *Comment) Replied() bool {
+	return c.Reply != nil && c.Reply.Content != ""
+}
+
+// CommentPage comment/list 的一页结果
+type CommentPage struct {
+	Total    int        `json:"total"`
+	Comments []*Comment `json:"comment"`
+}
+
+// OpenComment 打开文章留言
+func (s *Service) OpenComment(article CommentArticle) error {
+	return s.postComment("OpenComment", commentOpenURL, article, nil)
+}
+
+// CloseComment 关闭文章留言
+func (s *Service) CloseComment(article CommentArticle) error {
+	return s.postComment("CloseComment", commentCloseURL, article, nil)
+}
+
+// ListComments 分页获取文章留言，count 不超过 MaxCommentPageSize
+func (s *Service) ListComments(article CommentArticle, begin, count, commentType int) (*CommentPage, error) {
+	if count <= 0 || count > MaxCommentPageSize {
+		count = MaxCommentPageSize
+	}
+	req := map[string]any{
+		"msg_data_id": article.MsgDataID,
+		"index":       article.Index,
+		"begin":       begin,
+		"count":       count,
+		"type":        commentType,
+	}
+	var page CommentPage
+	if err := s.postComment("ListComments", commentListURL, req, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// AllComments 翻页获取文章的全部留言
+func (s *Service) AllComments(article CommentArticle, commentType int) ([]*Comment, error) {
+	var all []*Comment
+	for begin := 0; ; begin += MaxCommentPageSize {
+		page, err := s.ListComments(article, begin, MaxCommentPageSize, commentType)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page.Comments...)
+		if len(page.Comments) < MaxCommentPageSize || len(all) >= page.Total {
+			return all, nil
+		}
+	}
+}
+
+// ElectComment 将留言标记为精选；elect 为 false 时取消精选
+func (s *Service) ElectComment(article CommentArticle, commentID int64, elect bool) error {
+	if elect {
+		return s.postComment("MarkElectComment", commentMarkElectURL, commentReq(article, commentID), nil)
+	}
+	return s.postComment("UnmarkElectComment", commentUnmarkElectURL, commentReq(article, commentID), nil)
+}
+
+// DeleteComment 删除留言
+func (s *Service) DeleteComment(article CommentArticle, commentID int64) error {
+	return s.postComment("DeleteComment", commentDeleteURL, commentReq(article, commentID), nil)
+}
+
+// ReplyComment 回复留言
+func (s *Service) ReplyComment(article CommentArticle, commentID int64, content string) error {
+	req := commentReq(article, commentID)
+	req["content"] = content
+	return s.postComment("ReplyComment", commentReplyURL, req, nil)
+}
+
+// DeleteCommentReply 删除留言的作者回复
+func (s *Service) DeleteCommentReply(article CommentArticle, commentID int64) error {
+	return s.postComment("DeleteCommentReply", commentDeleteReplyURL, commentReq(article, commentID), nil)
+}
+
+// commentReq 针对单条留言的请求参数
+func commentReq(article CommentArticle, commentID int64) map[string]any {
+	return map[string]any{
+		"msg_data_id":     article.MsgDataID,
+		"index":           article.Index,
+		"user_comment_id": commentID,
+	}
+}
+
+// postComment 调用留言管理接口，out 非 nil 时解析响应
+// 回复留言不是幂等操作，网络错误时不重试
+func (s *Service) postComment(api, url string, req any, out any) error {
+	call := s.call
+	if api == "ReplyComment" {
+		call = s.callWrite
+	}
+	var response []byte
+	err := call(api, func() error {
+		accessToken, err := s.getOfficialAccount().GetAccessToken()
+		if err != nil {
+			return err
+		}
+		response, err = util.PostJSON(fmt.Sprintf("%s?access_token=%s", url, accessToken), req)
+		if err != nil {
+			return err
+		}
+		var res struct {
+			util.CommonError
+		}
+		return util.DecodeWithError(response, &res, api)
+	})
+	if err != nil {
+		s.log.Error("comment api failed",
+			zap.String("api", api),
+			zap.Error(err))
+		return fmt.Errorf("%s: %w", api, err)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(response, out); err != nil {
+			return fmt.Errorf("%s: decode response: %w", api, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package wechat
+
+import (
+	"fmt"
+
+	"github.com/silenceper/wechat/v2/officialaccount/datacube"
+	"go.uber.org/zap"
+)
+
+// DatacubeDateLayout 数据统计接口的日期格式
+const DatacubeDateLayout = "2006-01-02"
+
+// GetArticleSummary 获取图文群发每日数据（单日）
+// date 格式为 2006-01-02，接口最大跨度为 1 天
+func (s *Service) GetArticleSummary(date string) (*datacube.ResArticleSummary, error) {
+	var res datacube.ResArticleSummary
+	err := s.call("GetArticleSummary", func() error {
+		var err error
+		res, err = s.getOfficialAccount().GetDataCube().GetArticleSummary(date, date)
+		return err
+	})
+	if err != nil {
+		s.log.Error("get article summary failed",
+			zap.String("date", date),
+			zap.Error(err))
+		return nil, fmt.Errorf("get article summary: %w", err)
+	}
+	return &res, nil
+}
+
+// GetArticleTotal 获取图文群发总数据（单日群发的文章，含发布后 7 天的累计数据）
+// date 格式为 2006-01-02，接口最大跨度为 1 天
+func (s *Service) GetArticleTotal(date string) (*datacube.ResArticleTotal, error) {
+	var res datacube.ResArticleTotal
+	err := s.call("GetArticleTotal", func() error {
+		var err error
+		res, err = s.getOfficialAccount().GetDataCube().GetArticleTotal(date, date)
+		return err
+	})
+	if err != nil {
+		s.log.Error("get article total failed",
+			zap.String("date", date),
+			zap.Error(err))
+		return nil, fmt.Errorf("get article total: %w", err)
+	}
+	return &res, nil
+}
@@ -0,0 +1,93 @@
+package wechat
+
+import (
+	"fmt"
+
+	"github.com/silenceper/wechat/v2/util"
+)
+
+// quotaGetURL 查询接口调用额度（SDK 未封装）
+const quotaGetURL = DefaultAPIBase + "/cgi-bin/openapi/quota/get"
+
+// MaterialCount 永久素材数量
+type MaterialCount struct {
+	Image int64 `json:"image"`
+	Video int64 `json:"video"`
+	Voice int64 `json:"voice"`
+	News  int64 `json:"news"`
+}
+
+// GetMaterialCount 获取永久素材总数（可用于检查素材接口权限）
+func (s *Service) GetMaterialCount() (*MaterialCount, error) {
+	var count MaterialCount
+	err := s.call("GetMaterialCount", func() error {
+		res, err := s.getOfficialAccount().GetMaterial().GetMaterialCount()
+		if err != nil {
+			return err
+		}
+		count = MaterialCount{Image: res.ImageCount, Video: res.VideoCount, Voice: res.VoiceCount, News: res.NewsCount}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get material count: %w", err)
+	}
+	return &count, nil
+}
+
+// CountDrafts 获取草稿总数（可用于检查草稿接口权限）
+func (s *Service) CountDrafts() (int, error) {
+	var total uint
+	err := s.call("CountDraft", func() error {
+		var err error
+		total, err = s.getOfficialAccount().GetDraft().CountDraft()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("count drafts: %w", err)
+	}
+	return int(total), nil
+}
+
+// Quota 接口每日调用额度
+type Quota struct {
+	CGIPath    string `json:"cgi_path"`
+	DailyLimit int64  `json:"daily_limit"`
+	Used       int64  `json:"used"`
+	Remain     int64  `json:"remain"`
+}
+
+// GetQuota 查询指定接口的每日调用额度
+// cgiPath 形如 "/cgi-bin/draft/add"
+func (s *Service) GetQuota(cgiPath string) (*Quota, error) {
+	var res struct {
+		util.CommonError
+		Quota struct {
+			DailyLimit int64 `json:"daily_limit"`
+			Used       int64 `json:"used"`
+			Remain     int64 `json:"remain"`
+		} `json:"quota"`
+	}
+	err := s.call("GetQuota", func() error {
+		res.CommonError = util.CommonError{} // 重试时清掉上次的错误码
+		accessToken, err := s.getOfficialAccount().GetAccessToken()
+		if err != nil {
+			return err
+		}
+		uri := fmt.Sprintf("%s?access_token=%s", quotaGetURL, accessToken)
+		response, err := util.PostJSON(uri, map[string]string{"cgi_path": cgiPath})
+		if err != nil {
+			return err
+		}
+		return util.DecodeWithError(response, &res, "GetQuota")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get quota: %w", err)
+	}
+
+	return &Quota{
+		CGIPath:    cgiPath,
+		DailyLimit: res.Quota.DailyLimit,
+		Used:       res.Quota.Used,
+		Remain:     res.Quota.Remain,
+	}, nil
+}
@@ -0,0 +1,41 @@
+package wechat
+
+import "testing"
+
+func TestServiceDiagnose(t *testing.T) {
+	ws, srv := newMockService(t)
+
+	if _, err := ws.UploadMaterial(writeTestPNG(t)); err != nil {
+		t.Fatalf("UploadMaterial() error = %v", err)
+	}
+
+	count, err := ws.GetMaterialCount()
+	if err != nil {
+		t.Fatalf("GetMaterialCount() error = %v", err)
+	}
+	if count.Image != 1 {
+		t.Errorf("image count = %d, want 1", count.Image)
+	}
+
+	total, err := ws.CountDrafts()
+	if err != nil {
+		t.Fatalf("CountDrafts() error = %v", err)
+	}
+	if total != 0 {
+		t.Errorf("draft count = %d, want 0", total)
+	}
+
+	quota, err := ws.GetQuota("/cgi-bin/material/add_material")
+	if err != nil {
+		t.Fatalf("GetQuota() error = %v", err)
+	}
+	if quota.Used != 1 || quota.Remain != quota.DailyLimit-1 {
+		t.Errorf("quota = %+v, want used 1", quota)
+	}
+
+	// 无素材权限
+	srv.FailNext("/cgi-bin/material/get_materialcount", ErrCodeAPIUnauthorized, "api unauthorized")
+	if _, err := ws.GetMaterialCount(); !IsErrCode(err, ErrCodeAPIUnauthorized) {
+		t.Errorf("GetMaterialCount() error = %v, want errcode 48001", err)
+	}
+}
@@ -0,0 +1,77 @@
+package wechat
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/silenceper/wechat/v2/util"
+)
+
+// DefaultAPIBase 微信公众平台 API 默认地址
+const DefaultAPIBase = "https://api.weixin.qq.com"
+
+// SDK 的接口地址是写死的常量，只能通过进程级的 URI 修改器改写。
+// activeBase 为当前生效的 API 地址：与之相同的调用共享读锁并发执行；
+// 需要切换到其他地址时持有写锁切换，并在写锁内完成这次调用。
+// 通常进程内所有账号使用同一地址，只在首次调用时切换一次。
+var (
+	endpointOnce sync.Once
+	endpointMu   sync.RWMutex
+	activeBase   string
+)
+
+// installURIModifier 安装 SDK URI 修改器（只安装一次）
+func installURIModifier() {
+	endpointOnce.Do(func() {
+		util.SetURIModifier(func(uri string) string {
+			if activeBase == "" || activeBase == DefaultAPIBase {
+				return uri
+			}
+			if rest, ok := strings.CutPrefix(uri, DefaultAPIBase); ok {
+				return activeBase + rest
+			}
+			return uri
+		})
+	})
+}
+
+// NormalizeAPIBase 规范化 API 地址，为空时返回默认地址
+func NormalizeAPIBase(base string) string {
+	base = strings.TrimRight(strings.TrimSpace(base), "/")
+	if base == "" {
+		return DefaultAPIBase
+	}
+	return base
+}
+
+// APIBase 返回当前账号使用的 API 地址
+func (s *Service) APIBase() string {
+	return NormalizeAPIBase(s.account.APIBase)
+}
+
+// withEndpoint 在账号配置的 API 地址下执行 SDK 调用
+// 注意：fn 内不能再嵌套调用 withEndpoint
+func (s *Service) withEndpoint(fn func() error) error {
+	base := s.APIBase()
+
+	endpointMu.RLock()
+	if currentBase() == base {
+		defer endpointMu.RUnlock()
+		return fn()
+	}
+	endpointMu.RUnlock()
+
+	// 地址不同：等待进行中的调用结束后切换，RWMutex 不能降级，本次调用在写锁内执行
+	endpointMu.Lock()
+	defer endpointMu.Unlock()
+	activeBase = base
+	return fn()
+}
+
+// currentBase 当前生效的 API 地址，调用方需持有 endpointMu
+func currentBase() string {
+	if activeBase == "" {
+		return DefaultAPIBase
+	}
+	return activeBase
+}
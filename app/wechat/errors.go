@@ -0,0 +1,221 @@
+package wechat
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/silenceper/wechat/v2/util"
+)
+
+// 常用微信错误码
+const (
+	ErrCodeSystemBusy        = -1
+	ErrCodeInvalidCredential = 40001
+	ErrCodeInvalidMediaType  = 40004
+	ErrCodeInvalidMediaID    = 40007
+	ErrCodeInvalidFileSize   = 40009
+	ErrCodeInvalidAppID      = 40013
+	ErrCodeInvalidToken      = 40014
+	ErrCodeInvalidSecret     = 40125
+	ErrCodeIPNotWhitelisted  = 40164
+	ErrCodeTokenExpired      = 42001
+	ErrCodeQuotaExceeded     = 45009
+	ErrCodeAPIUnauthorized   = 48001
+)
+
+// ErrorClass 错误分类，决定重试策略
+type ErrorClass int
+
+const (
+	// ClassPermanent 永久错误（参数错误、权限不足等），不重试
+	ClassPermanent ErrorClass = iota
+	// ClassRetryable 临时错误（系统繁忙、网络错误），指数退避后重试
+	ClassRetryable
+	// ClassTokenExpired access_token 失效，刷新后重试
+	ClassTokenExpired
+	// ClassQuota 接口调用次数超限，立即失败
+	ClassQuota
+	// ClassIPWhitelist 出口 IP 不在白名单，立即失败
+	ClassIPWhitelist
+)
+
+// String 返回分类名称
+func (c ErrorClass) String() string {
+	switch c {
+	case ClassRetryable:
+		return "retryable"
+	case ClassTokenExpired:
+		return "token_expired"
+	case ClassQuota:
+		return "quota"
+	case ClassIPWhitelist:
+		return "ip_whitelist"
+	default:
+		return "permanent"
+	}
+}
+
+// APIError 微信接口错误（带错误码和处理建议）
+type APIError struct {
+	API   string     // 接口名称
+	Code  int64      // 微信 errcode
+	Msg   string     // 微信 errmsg
+	Class ErrorClass // 错误分类
+	Hint  string     // 处理建议
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s: errcode=%d, errmsg=%s", e.API, e.Code, e.Msg)
+	if e.Hint != "" {
+		msg += fmt.Sprintf("\n💡 提示: %s", e.Hint)
+	}
+	return msg
+}
+
+// errcodePattern 匹配 SDK 以字符串形式返回的错误码（如 AddMaterial、获取 access_token）
+var errcodePattern = regexp.MustCompile(`errcode=(-?\d+)\s*,\s*(?:errmsg|errormsg)=(.*)`)
+
+// whitelistIPPattern 从 40164 的 errmsg 中提取出口 IP
+var whitelistIPPattern = regexp.MustCompile(`invalid ip ([0-9A-Fa-f.:]+)`)
+
+// ErrCode 提取错误中的微信 errcode
+func ErrCode(err error) (code int64, msg string, ok bool) {
+	if err == nil {
+		return 0, "", false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code, apiErr.Msg, true
+	}
+
+	var commonErr *util.CommonError
+	if errors.As(err, &commonErr) {
+		return commonErr.ErrCode, commonErr.ErrMsg, true
+	}
+
+	if m := errcodePattern.FindStringSubmatch(err.Error()); m != nil {
+		code, convErr := strconv.ParseInt(m[1], 10, 64)
+		if convErr == nil {
+			return code, strings.TrimSpace(m[2]), true
+		}
+	}
+
+	return 0, "", false
+}
+
+// IsErrCode 判断错误是否为指定的微信错误码
+func IsErrCode(err error, code int64) bool {
+	c, _, ok := ErrCode(err)
+	return ok && c == code
+}
+
+// Classify 对错误进行分类
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ClassPermanent
+	}
+
+	if code, _, ok := ErrCode(err); ok {
+		return classifyCode(code)
+	}
+
+	// 网络错误和 HTTP 状态码错误视为临时错误
+	if IsTransportError(err) {
+		return ClassRetryable
+	}
+
+	return ClassPermanent
+}
+
+// IsTransportError 判断是否为网络错误或 HTTP 5xx：请求可能已被微信处理，
+// 只有幂等接口可以安全重试
+func IsTransportError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return strings.Contains(err.Error(), "statusCode=5")
+}
+
+// classifyCode 按错误码分类
+func classifyCode(code int64) ErrorClass {
+	switch code {
+	case ErrCodeSystemBusy:
+		return ClassRetryable
+	case ErrCodeInvalidCredential, ErrCodeInvalidToken, ErrCodeTokenExpired:
+		return ClassTokenExpired
+	case ErrCodeQuotaExceeded:
+		return ClassQuota
+	case ErrCodeIPNotWhitelisted:
+		return ClassIPWhitelist
+	default:
+		return ClassPermanent
+	}
+}
+
+// newAPIError 将 SDK 错误转换为带处理建议的 APIError
+// 不含 errcode 的错误（网络错误等）原样返回
+func newAPIError(api string, err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return err
+	}
+
+	code, msg, ok := ErrCode(err)
+	if !ok {
+		return err
+	}
+
+	return &APIError{
+		API:   api,
+		Code:  code,
+		Msg:   msg,
+		Class: classifyCode(code),
+		Hint:  errorHint(code, msg),
+	}
+}
+
+// errorHint 返回错误码对应的处理建议
+func errorHint(code int64, msg string) string {
+	switch code {
+	case ErrCodeSystemBusy:
+		return "微信服务繁忙，已自动重试仍失败，请稍后再试"
+	case ErrCodeInvalidCredential, ErrCodeInvalidToken, ErrCodeTokenExpired:
+		return "access_token 无效：请检查 secret 是否正确（AppSecret 重置后需同步更新配置），并确认没有其他服务在使用同一 AppID 刷新 access_token"
+	case ErrCodeInvalidAppID:
+		return "AppID 无效，请检查配置中的 appid"
+	case ErrCodeInvalidSecret:
+		return "AppSecret 无效，请检查配置中的 secret"
+	case ErrCodeIPNotWhitelisted:
+		ip := "当前出口 IP"
+		if m := whitelistIPPattern.FindStringSubmatch(msg); m != nil {
+			ip = m[1]
+		}
+		return fmt.Sprintf("%s 不在公众号 IP 白名单中：登录 mp.weixin.qq.com → 设置与开发 → 基本配置 → IP白名单，添加该 IP 后重试", ip)
+	case ErrCodeQuotaExceeded:
+		return "接口今日调用次数已达上限，重试无效：请明天再试，或在公众平台 → 设置与开发 → 接口权限 中查看配额（每月可重置 10 次）"
+	case ErrCodeAPIUnauthorized:
+		return "公众号没有该接口权限（如未认证的订阅号无法使用草稿、发布接口），请在公众平台 → 接口权限 中确认"
+	case ErrCodeInvalidMediaID:
+		return "media_id 无效或素材已被删除，如果来自上传缓存，可运行 'writer cache prune --all' 清理后重新上传"
+	case ErrCodeInvalidFileSize:
+		return "文件大小超出限制（永久图片素材 10MB，图文内图片 1MB，缩略图 64KB）"
+	case ErrCodeInvalidMediaType:
+		return "不支持的素材类型，图片仅支持 bmp/png/jpeg/jpg/gif"
+	default:
+		return ""
+	}
+}
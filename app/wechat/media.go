@@ -0,0 +1,59 @@
+package wechat
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/silenceper/wechat/v2/officialaccount/material"
+	"go.uber.org/zap"
+)
+
+// UploadVideo 上传永久视频素材，title / introduction 为素材库中显示的标题和简介
+func (s *Service) UploadVideo(filePath, title, introduction string) (*UploadMaterialResult, error) {
+	startTime := time.Now()
+
+	var mediaID string
+	err := s.callWrite("AddVideo", func() error {
+		var err error
+		mediaID, _, err = s.getOfficialAccount().GetMaterial().AddVideo(filePath, title, introduction)
+		return err
+	})
+	if err != nil {
+		s.log.Error("upload video failed",
+			zap.String("path", filePath),
+			zap.Error(err))
+		return nil, fmt.Errorf("upload video: %w", err)
+	}
+
+	s.log.Info("video uploaded",
+		zap.String("path", filePath),
+		zap.String("media_id", maskMediaID(mediaID)),
+		zap.Duration("duration", time.Since(startTime)))
+
+	return &UploadMaterialResult{MediaID: mediaID}, nil
+}
+
+// UploadVoice 上传永久语音素材
+func (s *Service) UploadVoice(filePath string) (*UploadMaterialResult, error) {
+	startTime := time.Now()
+
+	var mediaID string
+	err := s.callWrite("AddVoice", func() error {
+		var err error
+		mediaID, _, err = s.getOfficialAccount().GetMaterial().AddMaterial(material.MediaTypeVoice, filePath)
+		return err
+	})
+	if err != nil {
+		s.log.Error("upload voice failed",
+			zap.String("path", filePath),
+			zap.Error(err))
+		return nil, fmt.Errorf("upload voice: %w", err)
+	}
+
+	s.log.Info("voice uploaded",
+		zap.String("path", filePath),
+		zap.String("media_id", maskMediaID(mediaID)),
+		zap.Duration("duration", time.Since(startTime)))
+
+	return &UploadMaterialResult{MediaID: mediaID}, nil
+}
@@ -0,0 +1,96 @@
+package wechat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/silenceper/wechat/v2/util"
+	"go.uber.org/zap"
+)
+
+// previewURL 群发预览接口（支持 touser / towxname）
+const previewURL = "https://api.weixin.qq.com/cgi-bin/message/mass/preview"
+
+// openIDPattern 公众号 OpenID 格式（28 位，以 o 开头）
+var openIDPattern = regexp.MustCompile(`^o[A-Za-z0-9_-]{27}$`)
+
+// PreviewRecipient 预览接收人，OpenID 与 WxName 二选一
+type PreviewRecipient struct {
+	OpenID string `json:"openid,omitempty"`
+	WxName string `json:"wxname,omitempty"`
+}
+
+// String 返回接收人的显示名称
+func (r PreviewRecipient) String() string {
+	if r.WxName != "" {
+		return "wxname:" + r.WxName
+	}
+	return "openid:" + r.OpenID
+}
+
+// ParsePreviewRecipient 解析预览接收人
+// 支持显式前缀 "openid:xxx" / "wxname:xxx"；无前缀时符合 OpenID 格式的视为 OpenID，否则视为微信号
+func ParsePreviewRecipient(s string) (PreviewRecipient, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return PreviewRecipient{}, fmt.Errorf("preview recipient is empty")
+	}
+
+	if v, ok := strings.CutPrefix(s, "openid:"); ok {
+		return PreviewRecipient{OpenID: v}, nil
+	}
+	if v, ok := strings.CutPrefix(s, "wxname:"); ok {
+		return PreviewRecipient{WxName: v}, nil
+	}
+
+	if openIDPattern.MatchString(s) {
+		return PreviewRecipient{OpenID: s}, nil
+	}
+	return PreviewRecipient{WxName: s}, nil
+}
+
+// SendPreview 发送图文预览到指定用户的手机
+// mediaID 为草稿的 media_id，返回消息 ID
+func (s *Service) SendPreview(mediaID string, to PreviewRecipient) (int64, error) {
+	req := map[string]any{
+		"mpnews":  map[string]any{"media_id": mediaID},
+		"msgtype": "mpnews",
+	}
+	if to.WxName != "" {
+		req["towxname"] = to.WxName
+	} else {
+		req["touser"] = to.OpenID
+	}
+
+	var res struct {
+		util.CommonError
+		MsgID int64 `json:"msg_id"`
+	}
+	err := s.callWrite("MassPreview", func() error {
+		res.CommonError = util.CommonError{} // 重试时清掉上次的错误码
+		accessToken, err := s.getOfficialAccount().GetAccessToken()
+		if err != nil {
+			return err
+		}
+		uri := fmt.Sprintf("%s?access_token=%s", previewURL, accessToken)
+		response, err := util.PostJSON(uri, req)
+		if err != nil {
+			return err
+		}
+		return util.DecodeWithError(response, &res, "MassPreview")
+	})
+	if err != nil {
+		s.log.Error("send preview failed",
+			zap.String("media_id", maskMediaID(mediaID)),
+			zap.String("to", to.String()),
+			zap.Error(err))
+		return 0, fmt.Errorf("send preview: %w", err)
+	}
+
+	s.log.Info("preview sent",
+		zap.String("media_id", maskMediaID(mediaID)),
+		zap.String("to", to.String()))
+
+	return res.MsgID, nil
+}
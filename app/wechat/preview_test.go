@@ -0,0 +1,31 @@
+package wechat
+
+import "testing"
+
+func TestParsePreviewRecipient(t *testing.T) {
+	tests := []struct {
+		input string
+		want  PreviewRecipient
+	}{
+		{"oAbCdEfGhIjKlMnOpQrStUvWxYz1", PreviewRecipient{OpenID: "oAbCdEfGhIjKlMnOpQrStUvWxYz1"}},
+		{"editor_wx", PreviewRecipient{WxName: "editor_wx"}},
+		{"openid:o123", PreviewRecipient{OpenID: "o123"}},
+		{"wxname:oAbCdEfGhIjKlMnOpQrStUvWxYz1", PreviewRecipient{WxName: "oAbCdEfGhIjKlMnOpQrStUvWxYz1"}},
+		{"  editor_wx  ", PreviewRecipient{WxName: "editor_wx"}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePreviewRecipient(tt.input)
+		if err != nil {
+			t.Errorf("ParsePreviewRecipient(%q) error = %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePreviewRecipient(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+
+	if _, err := ParsePreviewRecipient(""); err == nil {
+		t.Errorf("ParsePreviewRecipient(\"\") should return error")
+	}
+}
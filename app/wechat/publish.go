@@ -0,0 +1,171 @@
+package wechat
+
+import (
+	"fmt"
+
+	"github.com/silenceper/wechat/v2/officialaccount/freepublish"
+	"go.uber.org/zap"
+)
+
+// 发布任务状态（freepublish/get 的 publish_status）
+const (
+	PublishStatusSuccess      = int(freepublish.PublishStatusSuccess)      // 发布成功
+	PublishStatusPublishing   = int(freepublish.PublishStatusPublishing)   // 发布中
+	PublishStatusOriginalFail = int(freepublish.PublishStatusOriginalFail) // 原创声明失败
+	PublishStatusFail         = int(freepublish.PublishStatusFail)         // 常规失败
+	PublishStatusAuditRefused = int(freepublish.PublishStatusAuditRefused) // 平台审核不通过
+	PublishStatusUserDeleted  = int(freepublish.PublishStatusUserDeleted)  // 成功后用户删除所有文章
+	PublishStatusSystemBanned = int(freepublish.PublishStatusSystemBanned) // 成功后系统封禁所有文章
+)
+
+// PublishStatus 发布任务状态
+type PublishStatus struct {
+	PublishID   int64    `json:"publish_id"`
+	Status      int      `json:"publish_status"`
+	ArticleID   string   `json:"article_id,omitempty"`
+	ArticleURLs []string `json:"article_urls,omitempty"`
+	FailIndex   []uint   `json:"fail_idx,omitempty"`
+}
+
+// Done 发布任务是否已结束（不再是发布中）
+func (p *PublishStatus) Done() bool {
+	return p.Status != PublishStatusPublishing
+}
+
+// Succeeded 发布是否成功
+func (p *PublishStatus) Succeeded() bool {
+	return p.Status == PublishStatusSuccess
+}
+
+// Description 发布状态的中文描述
+func (p *PublishStatus) Description() string {
+	switch p.Status {
+	case PublishStatusSuccess:
+		return "发布成功"
+	case PublishStatusPublishing:
+		return "发布中"
+	case PublishStatusOriginalFail:
+		return fmt.Sprintf("原创声明失败（第 %v 篇）", p.FailIndex)
+	case PublishStatusFail:
+		return "常规失败"
+	case PublishStatusAuditRefused:
+		return fmt.Sprintf("平台审核不通过（第 %v 篇）", p.FailIndex)
+	case PublishStatusUserDeleted:
+		return "发布成功后用户已删除所有文章"
+	case PublishStatusSystemBanned:
+		return "发布成功后系统封禁了所有文章"
+	default:
+		return fmt.Sprintf("未知状态 %d", p.Status)
+	}
+}
+
+// SubmitPublish 提交草稿发布任务，返回 publish_id
+// 发布是异步的，需通过 GetPublishStatus 轮询结果
+func (s *Service) SubmitPublish(mediaID string) (int64, error) {
+	var publishID int64
+	err := s.callWrite("SubmitPublish", func() error {
+		var err error
+		publishID, err = s.getOfficialAccount().GetFreePublish().Publish(mediaID)
+		return err
+	})
+	if err != nil {
+		s.log.Error("submit publish failed",
+			zap.String("media_id", maskMediaID(mediaID)),
+			zap.Error(err))
+		return 0, fmt.Errorf("submit publish: %w", err)
+	}
+
+	s.log.Info("publish submitted",
+		zap.String("media_id", maskMediaID(mediaID)),
+		zap.Int64("publish_id", publishID))
+	return publishID, nil
+}
+
+// GetPublishStatus 查询发布任务状态
+func (s *Service) GetPublishStatus(publishID int64) (*PublishStatus, error) {
+	var res freepublish.PublishStatusList
+	err := s.call("GetPublishStatus", func() error {
+		var err error
+		res, err = s.getOfficialAccount().GetFreePublish().SelectStatus(publishID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get publish status: %w", err)
+	}
+
+	status := &PublishStatus{
+		PublishID: publishID,
+		Status:    int(res.PublishStatus),
+		ArticleID: res.ArticleID,
+		FailIndex: res.FailIndex,
+	}
+	for _, item := range res.ArticleDetail.Items {
+		status.ArticleURLs = append(status.ArticleURLs, item.ArticleURL)
+	}
+	return status, nil
+}
+
+// PublishedArticle 已发布的单篇文章
+type PublishedArticle struct {
+	Title            string `json:"title"`
+	Author           string `json:"author"`
+	Digest           string `json:"digest"`
+	Content          string `json:"content"`
+	ContentSourceURL string `json:"content_source_url"`
+	ThumbMediaID     string `json:"thumb_media_id"`
+	URL              string `json:"url"`
+	IsDeleted        bool   `json:"is_deleted"`
+}
+
+// PublishedItem 一次发布（可包含多篇文章）
+type PublishedItem struct {
+	ArticleID  string             `json:"article_id"`
+	UpdateTime int64              `json:"update_time"`
+	Articles   []PublishedArticle `json:"articles"`
+}
+
+// PublishedPage 已发布列表的一页
+type PublishedPage struct {
+	TotalCount int64           `json:"total_count"`
+	Items      []PublishedItem `json:"items"`
+}
+
+// MaxPublishedPageSize freepublish/batchget 每页最多返回的条数
+const MaxPublishedPageSize = 20
+
+// ListPublished 分页获取已发布的文章列表（按更新时间倒序）
+// noContent 为 true 时不返回正文
+func (s *Service) ListPublished(offset, count int64, noContent bool) (*PublishedPage, error) {
+	if count <= 0 || count > MaxPublishedPageSize {
+		count = MaxPublishedPageSize
+	}
+
+	var res freepublish.ArticleList
+	err := s.call("ListPublished", func() error {
+		var err error
+		res, err = s.getOfficialAccount().GetFreePublish().Paginate(offset, count, noContent)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list published: %w", err)
+	}
+
+	page := &PublishedPage{TotalCount: res.TotalCount}
+	for _, item := range res.Item {
+		p := PublishedItem{ArticleID: item.ArticleID, UpdateTime: item.UpdateTime}
+		for _, a := range item.Content.NewsItem {
+			p.Articles = append(p.Articles, PublishedArticle{
+				Title:            a.Title,
+				Author:           a.Author,
+				Digest:           a.Digest,
+				Content:          a.Content,
+				ContentSourceURL: a.ContentSourceURL,
+				ThumbMediaID:     a.ThumbMediaID,
+				URL:              a.URL,
+				IsDeleted:        a.IsDeleted,
+			})
+		}
+		page.Items = append(page.Items, p)
+	}
+	return page, nil
+}
@@ -0,0 +1,105 @@
+package wechat
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RetryPolicy 微信接口重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数（含首次调用）
+	BaseDelay   time.Duration // 首次退避时间，之后按指数增长
+	MaxDelay    time.Duration // 单次退避上限
+}
+
+// DefaultRetryPolicy 默认重试策略：最多 4 次，退避 1s、2s、4s
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   time.Second,
+	MaxDelay:    8 * time.Second,
+}
+
+// backoff 返回第 attempt 次失败后的等待时间（attempt 从 1 开始）
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return delay
+}
+
+// SetRetryPolicy 设置重试策略
+func (s *Service) SetRetryPolicy(policy RetryPolicy) {
+	s.retry = policy
+}
+
+// call 按默认策略调用幂等的查询类接口
+// fn 内需通过 s.getOfficialAccount() 获取实例，以便刷新 access_token 后重试时生效
+func (s *Service) call(api string, fn func() error) error {
+	return s.callWithPolicy(api, s.retry, true, fn)
+}
+
+// callWrite 按默认策略调用非幂等接口（新建草稿、上传素材、提交发布等）
+// 网络错误或 HTTP 5xx 时请求可能已被微信处理，不重试，避免重复创建或重复发布
+func (s *Service) callWrite(api string, fn func() error) error {
+	return s.callWithPolicy(api, s.retry, false, fn)
+}
+
+// callWithPolicy 按错误码分类重试：
+//   - access_token 失效（40001/42001）：刷新 token 后立即重试一次
+//   - 系统繁忙（-1）：指数退避后重试
+//   - 网络错误、HTTP 5xx：idempotent 时指数退避后重试，否则立即失败
+//   - 配额超限（45009）、IP 白名单（40164）及其他错误：立即失败并给出处理建议
+func (s *Service) callWithPolicy(api string, policy RetryPolicy, idempotent bool, fn func() error) error {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	tokenRefreshed := false
+	var err error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		err = s.withEndpoint(fn)
+		if err == nil {
+			return nil
+		}
+
+		class := Classify(err)
+		if attempt == policy.MaxAttempts {
+			break
+		}
+
+		switch class {
+		case ClassTokenExpired:
+			if tokenRefreshed {
+				return newAPIError(api, err)
+			}
+			tokenRefreshed = true
+			s.log.Warn("access_token expired, refreshing",
+				zap.String("api", api),
+				zap.Error(err))
+			s.refreshToken()
+		case ClassRetryable:
+			if !idempotent && IsTransportError(err) {
+				s.log.Warn("request may have reached wechat, not retrying",
+					zap.String("api", api),
+					zap.Error(err))
+				return newAPIError(api, err)
+			}
+			delay := policy.backoff(attempt)
+			s.log.Warn("wechat api busy, retrying",
+				zap.String("api", api),
+				zap.Int("attempt", attempt),
+				zap.Duration("delay", delay),
+				zap.Error(err))
+			time.Sleep(delay)
+		default:
+			return newAPIError(api, err)
+		}
+	}
+
+	return newAPIError(api, err)
+}
@@ -0,0 +1,160 @@
+package wechat
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/silenceper/wechat/v2/util"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"common error busy", util.NewCommonError("AddDraft", -1, "system error"), ClassRetryable},
+		{"common error token", util.NewCommonError("AddDraft", 42001, "access_token expired"), ClassTokenExpired},
+		{"wrapped invalid credential", fmt.Errorf("create draft: %w", util.NewCommonError("AddDraft", 40001, "invalid credential")), ClassTokenExpired},
+		{"string errcode", errors.New("AddMaterial error : errcode=45009 , errmsg=reach max api daily quota limit"), ClassQuota},
+		{"token errormsg", errors.New("get access_token error : errcode=40164 , errormsg=invalid ip 1.2.3.4 ipv6 ::ffff:1.2.3.4, not in whitelist"), ClassIPWhitelist},
+		{"invalid media type", util.NewCommonError("AddMaterial", 40004, "invalid media type"), ClassPermanent},
+		{"http status 502", errors.New("http post error : uri=x , statusCode=502"), ClassRetryable},
+		{"file not found", errors.New("open /tmp/x.png: no such file or directory"), ClassPermanent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestCallRefreshesExpiredToken(t *testing.T) {
+	ws, srv := newMockService(t)
+
+	if _, err := ws.GetAccessToken(); err != nil {
+		t.Fatalf("GetAccessToken() error = %v", err)
+	}
+	srv.ExpireTokens()
+
+	if _, err := ws.UploadMaterial(writeTestPNG(t)); err != nil {
+		t.Fatalf("UploadMaterial() after token expiry error = %v", err)
+	}
+	if got := srv.Calls("/cgi-bin/token"); got != 2 {
+		t.Errorf("token requested %d times, want 2", got)
+	}
+}
+
+func TestCallRetriesSystemBusy(t *testing.T) {
+	ws, srv := newMockService(t)
+	ws.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	srv.FailNext("/cgi-bin/material/add_material", -1, "system error")
+	srv.FailNext("/cgi-bin/material/add_material", -1, "system error")
+
+	if _, err := ws.UploadMaterial(writeTestPNG(t)); err != nil {
+		t.Fatalf("UploadMaterial() error = %v", err)
+	}
+	if got := srv.Calls("/cgi-bin/material/add_material"); got != 3 {
+		t.Errorf("add_material called %d times, want 3", got)
+	}
+}
+
+func TestCallGivesUpAfterMaxAttempts(t *testing.T) {
+	ws, srv := newMockService(t)
+	for i := 0; i < 3; i++ {
+		srv.FailNext("/cgi-bin/material/add_material", -1, "system error")
+	}
+
+	ws.SetRetryPolicy(RetryPolicy{BaseDelay: time.Millisecond})
+	_, err := ws.UploadMaterialWithRetry(writeTestPNG(t), 2)
+	if !IsErrCode(err, ErrCodeSystemBusy) {
+		t.Fatalf("UploadMaterialWithRetry() error = %v, want errcode -1", err)
+	}
+	if got := srv.Calls("/cgi-bin/material/add_material"); got != 2 {
+		t.Errorf("add_material called %d times, want 2", got)
+	}
+}
+
+func TestCallFailsFast(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     int
+		msg      string
+		wantHint string
+	}{
+		{"quota", ErrCodeQuotaExceeded, "reach max api daily quota limit", "调用次数已达上限"},
+		{"ip whitelist", ErrCodeIPNotWhitelisted, "invalid ip 1.2.3.4 ipv6 ::ffff:1.2.3.4, not in whitelist", "1.2.3.4 不在公众号 IP 白名单中"},
+		{"invalid media type", ErrCodeInvalidMediaType, "invalid media type", "不支持的素材类型"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws, srv := newMockService(t)
+			ws.SetRetryPolicy(RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond})
+			srv.FailNext("/cgi-bin/material/add_material", tt.code, tt.msg)
+
+			_, err := ws.UploadMaterial(writeTestPNG(t))
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("UploadMaterial() error = %v, want *APIError", err)
+			}
+			if apiErr.Code != int64(tt.code) {
+				t.Errorf("Code = %d, want %d", apiErr.Code, tt.code)
+			}
+			if !strings.Contains(apiErr.Hint, tt.wantHint) {
+				t.Errorf("Hint = %q, want to contain %q", apiErr.Hint, tt.wantHint)
+			}
+			if got := srv.Calls("/cgi-bin/material/add_material"); got != 1 {
+				t.Errorf("add_material called %d times, want 1", got)
+			}
+		})
+	}
+}
+
+func TestCallRetriesTransportErrorsOnlyForIdempotentCalls(t *testing.T) {
+	ws, srv := newMockService(t)
+	ws.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	// 查询接口：HTTP 502 后重试
+	srv.FailNextHTTP("/cgi-bin/draft/count", http.StatusBadGateway)
+	if _, err := ws.CountDrafts(); err != nil {
+		t.Fatalf("CountDrafts() error = %v", err)
+	}
+	if got := srv.Calls("/cgi-bin/draft/count"); got != 2 {
+		t.Errorf("draft/count called %d times, want 2", got)
+	}
+
+	// 新建草稿：请求可能已被处理，不重试
+	upload, err := ws.UploadMaterial(writeTestPNG(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.FailNextHTTP("/cgi-bin/draft/add", http.StatusBadGateway)
+	if _, err := ws.CreateDraft([]*Article{{Title: "t", Content: "x", ThumbMediaID: upload.MediaID}}); err == nil {
+		t.Fatal("CreateDraft() error = nil, want the 502")
+	}
+	if got := srv.Calls("/cgi-bin/draft/add"); got != 1 {
+		t.Errorf("draft/add called %d times, want 1", got)
+	}
+
+	// 系统繁忙（errcode -1）表示请求未被处理，新建草稿也会重试
+	srv.FailNext("/cgi-bin/draft/add", -1, "system error")
+	if _, err := ws.CreateDraft([]*Article{{Title: "t", Content: "x", ThumbMediaID: upload.MediaID}}); err != nil {
+		t.Fatalf("CreateDraft() after system busy error = %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	neturl "net/url"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/royalrick/wechatwriter/app/config"
@@ -17,8 +18,8 @@ import (
 	wechatcache "github.com/silenceper/wechat/v2/cache"
 	"github.com/silenceper/wechat/v2/officialaccount"
 	wechatconfig "github.com/silenceper/wechat/v2/officialaccount/config"
-	"github.com/silenceper/wechat/v2/officialaccount/draft"
 	"github.com/silenceper/wechat/v2/officialaccount/material"
+	"github.com/silenceper/wechat/v2/util"
 	"go.uber.org/zap"
 )
 
@@ -27,26 +28,43 @@ type Service struct {
 	account *config.WechatAccount
 	log     *zap.Logger
 	wc      *wechat.Wechat
+	retry   RetryPolicy
+	oaMu    sync.Mutex
+	oa      *officialaccount.OfficialAccount
 }
 
 // NewService 创建微信服务
 func NewService(account *config.WechatAccount, log *zap.Logger) *Service {
+	installURIModifier()
 	return &Service{
 		account: account,
 		log:     log,
 		wc:      wechat.NewWechat(),
+		retry:   DefaultRetryPolicy,
 	}
 }
 
-// getOfficialAccount 获取公众号实例
+// getOfficialAccount 获取公众号实例（同一 Service 内复用 access_token 缓存）
 func (s *Service) getOfficialAccount() *officialaccount.OfficialAccount {
-	memory := wechatcache.NewMemory()
-	wechatCfg := &wechatconfig.Config{
-		AppID:     s.account.AppID,
-		AppSecret: s.account.Secret,
-		Cache:     memory,
+	s.oaMu.Lock()
+	defer s.oaMu.Unlock()
+	if s.oa == nil {
+		memory := wechatcache.NewMemory()
+		wechatCfg := &wechatconfig.Config{
+			AppID:     s.account.AppID,
+			AppSecret: s.account.Secret,
+			Cache:     memory,
+		}
+		s.oa = s.wc.GetOfficialAccount(wechatCfg)
 	}
-	return s.wc.GetOfficialAccount(wechatCfg)
+	return s.oa
+}
+
+// refreshToken 丢弃缓存的 access_token，下次调用时重新获取
+func (s *Service) refreshToken() {
+	s.oaMu.Lock()
+	defer s.oaMu.Unlock()
+	s.oa = nil
 }
 
 // UploadMaterialResult 上传素材结果
@@ -59,12 +77,20 @@ type UploadMaterialResult struct {
 
 // UploadMaterial 上传素材到微信
 func (s *Service) UploadMaterial(filePath string) (*UploadMaterialResult, error) {
+	return s.uploadMaterial(filePath, s.retry)
+}
+
+// uploadMaterial 按指定重试策略上传素材
+func (s *Service) uploadMaterial(filePath string, policy RetryPolicy) (*UploadMaterialResult, error) {
 	startTime := time.Now()
-	oa := s.getOfficialAccount()
-	mat := oa.GetMaterial()
 
 	// 调用微信 API 上传（SDK 接受文件路径字符串）
-	mediaID, url, err := mat.AddMaterial(material.MediaTypeImage, filePath)
+	var mediaID, url string
+	err := s.callWithPolicy("AddMaterial", policy, false, func() error {
+		var err error
+		mediaID, url, err = s.getOfficialAccount().GetMaterial().AddMaterial(material.MediaTypeImage, filePath)
+		return err
+	})
 	if err != nil {
 		s.log.Error("upload material failed",
 			zap.String("path", filePath),
@@ -84,6 +110,62 @@ func (s *Service) UploadMaterial(filePath string) (*UploadMaterialResult, error)
 	}, nil
 }
 
+// UploadArticleImage 上传图文消息内的图片（media/uploadimg），返回图片 URL
+// 该接口不占用素材库额度，仅支持 jpg/png，大小不超过 1MB
+func (s *Service) UploadArticleImage(filePath string) (string, error) {
+	var url string
+	err := s.callWrite("UploadImage", func() error {
+		var err error
+		url, err = s.getOfficialAccount().GetMaterial().ImageUpload(filePath)
+		return err
+	})
+	if err != nil {
+		s.log.Error("upload article image failed",
+			zap.String("path", filePath),
+			zap.Error(err))
+		return "", fmt.Errorf("upload article image: %w", err)
+	}
+
+	s.log.Info("article image uploaded",
+		zap.String("path", filePath),
+		zap.String("url", url))
+	return url, nil
+}
+
+// DeleteMaterial 删除永久素材
+func (s *Service) DeleteMaterial(mediaID string) error {
+	err := s.call("DeleteMaterial", func() error {
+		return s.getOfficialAccount().GetMaterial().DeleteMaterial(mediaID)
+	})
+	if err != nil {
+		s.log.Error("delete material failed",
+			zap.String("media_id", maskMediaID(mediaID)),
+			zap.Error(err))
+		return fmt.Errorf("delete material: %w", err)
+	}
+
+	s.log.Info("material deleted", zap.String("media_id", maskMediaID(mediaID)))
+	return nil
+}
+
+// draftAddURL 新建草稿接口（SDK 的 draft.Article 缺少封面裁剪字段，直接调用）
+const draftAddURL = DefaultAPIBase + "/cgi-bin/draft/add"
+
+// Article 草稿图文（字段与微信草稿接口一致）
+type Article struct {
+	Title              string `json:"title"`
+	Author             string `json:"author,omitempty"`
+	Digest             string `json:"digest,omitempty"`
+	Content            string `json:"content"`
+	ContentSourceURL   string `json:"content_source_url,omitempty"`
+	ThumbMediaID       string `json:"thumb_media_id"`
+	ShowCoverPic       uint   `json:"show_cover_pic"`
+	NeedOpenComment    uint   `json:"need_open_comment"`
+	OnlyFansCanComment uint   `json:"only_fans_can_comment"`
+	PicCrop2351        string `json:"pic_crop_235_1,omitempty"` // 2.35:1 封面裁剪坐标 X1_Y1_X2_Y2
+	PicCrop11          string `json:"pic_crop_1_1,omitempty"`   // 1:1 封面裁剪坐标 X1_Y1_X2_Y2
+}
+
 // CreateDraftResult 创建草稿结果
 type CreateDraftResult struct {
 	MediaID  string `json:"media_id"`
@@ -91,17 +173,32 @@ type CreateDraftResult struct {
 }
 
 // CreateDraft 创建草稿
-func (s *Service) CreateDraft(articles []*draft.Article) (*CreateDraftResult, error) {
+func (s *Service) CreateDraft(articles []*Article) (*CreateDraftResult, error) {
 	startTime := time.Now()
-	oa := s.getOfficialAccount()
-	dm := oa.GetDraft()
 
-	// 直接调用 SDK 方法，SDK 接受 []*draft.Article
-	mediaID, err := dm.AddDraft(articles)
+	req := map[string]any{"articles": articles}
+	var res struct {
+		util.CommonError
+		MediaID string `json:"media_id"`
+	}
+	err := s.callWrite("AddDraft", func() error {
+		res.CommonError = util.CommonError{} // 重试时清掉上次的错误码
+		accessToken, err := s.getOfficialAccount().GetAccessToken()
+		if err != nil {
+			return err
+		}
+		uri := fmt.Sprintf("%s?access_token=%s", draftAddURL, accessToken)
+		response, err := util.PostJSON(uri, req)
+		if err != nil {
+			return err
+		}
+		return util.DecodeWithError(response, &res, "AddDraft")
+	})
 	if err != nil {
 		s.log.Error("create draft failed", zap.Error(err))
 		return nil, fmt.Errorf("create draft: %w", err)
 	}
+	mediaID := res.MediaID
 
 	duration := time.Since(startTime)
 	s.log.Info("draft created",
@@ -117,6 +214,60 @@ func (s *Service) CreateDraft(articles []*draft.Article) (*CreateDraftResult, er
 	}, nil
 }
 
+// draftUpdateURL 修改草稿接口
+const draftUpdateURL = DefaultAPIBase + "/cgi-bin/draft/update"
+
+// UpdateDraft 修改草稿中第 index 篇文章（从 0 开始）
+// 草稿已被删除或已发布时返回 errcode 40007
+func (s *Service) UpdateDraft(mediaID string, index int, article *Article) error {
+	req := map[string]any{
+		"media_id": mediaID,
+		"index":    index,
+		"articles": article,
+	}
+	err := s.call("UpdateDraft", func() error {
+		accessToken, err := s.getOfficialAccount().GetAccessToken()
+		if err != nil {
+			return err
+		}
+		uri := fmt.Sprintf("%s?access_token=%s", draftUpdateURL, accessToken)
+		response, err := util.PostJSON(uri, req)
+		if err != nil {
+			return err
+		}
+		var res struct {
+			util.CommonError
+		}
+		return util.DecodeWithError(response, &res, "UpdateDraft")
+	})
+	if err != nil {
+		s.log.Error("update draft failed",
+			zap.String("media_id", maskMediaID(mediaID)),
+			zap.Error(err))
+		return fmt.Errorf("update draft: %w", err)
+	}
+
+	s.log.Info("draft updated",
+		zap.String("media_id", maskMediaID(mediaID)),
+		zap.Int("index", index))
+	return nil
+}
+
+// DraftExists 草稿是否仍在草稿箱中（发布成功或被删除后不再存在）
+func (s *Service) DraftExists(mediaID string) (bool, error) {
+	err := s.call("GetDraft", func() error {
+		_, err := s.getOfficialAccount().GetDraft().GetDraft(mediaID)
+		return err
+	})
+	if IsErrCode(err, ErrCodeInvalidMediaID) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get draft: %w", err)
+	}
+	return true, nil
+}
+
 // UploadMaterialFromBytes 从字节数据上传素材
 func (s *Service) UploadMaterialFromBytes(data []byte, filename string) (*UploadMaterialResult, error) {
 	// 创建临时文件
@@ -138,8 +289,12 @@ type AccessTokenResult struct {
 
 // GetAccessToken 获取 access_token（调试用）
 func (s *Service) GetAccessToken() (*AccessTokenResult, error) {
-	oa := s.getOfficialAccount()
-	accessToken, err := oa.GetAccessToken()
+	var accessToken string
+	err := s.call("GetAccessToken", func() error {
+		var err error
+		accessToken, err = s.getOfficialAccount().GetAccessToken()
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("get access token: %w", err)
 	}
@@ -158,20 +313,12 @@ func maskMediaID(id string) string {
 	return id[:4] + "***" + id[len(id)-4:]
 }
 
-// UploadMaterialWithRetry 带重试的上传
+// UploadMaterialWithRetry 带重试的上传，最多尝试 maxRetries 次
+// 仅对临时错误退避重试，永久错误（素材类型错误、IP 白名单等）立即返回
 func (s *Service) UploadMaterialWithRetry(filePath string, maxRetries int) (*UploadMaterialResult, error) {
-	var lastErr error
-	for i := 0; i < maxRetries; i++ {
-		result, err := s.UploadMaterial(filePath)
-		if err == nil {
-			return result, nil
-		}
-		lastErr = err
-		if i < maxRetries-1 {
-			time.Sleep(time.Second)
-		}
-	}
-	return nil, lastErr
+	policy := s.retry
+	policy.MaxAttempts = maxRetries
+	return s.uploadMaterial(filePath, policy)
 }
 
 // DownloadFile 下载文件到临时目录
@@ -201,12 +348,13 @@ func DownloadFile(url string) (string, error) {
 			ext = pathExt
 		}
 	}
-	tmpPath := filepath.Join(tmpDir, "wechatwriter_download_"+ext)
-	tmpFile, err := os.Create(tmpPath)
+	// 每次下载使用独立的临时文件，支持并发下载
+	tmpFile, err := os.CreateTemp(tmpDir, "wechatwriter_download_*"+ext)
 	if err != nil {
 		return "", fmt.Errorf("create temp file: %w", err)
 	}
 	defer tmpFile.Close()
+	tmpPath := tmpFile.Name()
 
 	// 写入文件
 	if _, err := io.Copy(tmpFile, resp.Body); err != nil {
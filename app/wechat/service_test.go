@@ -0,0 +1,308 @@
+package wechat
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/royalrick/wechatwriter/app/config"
+	"github.com/royalrick/wechatwriter/app/wechat/wechattest"
+	"go.uber.org/zap"
+)
+
+// newMockService 创建指向模拟服务的微信服务
+func newMockService(t *testing.T) (*Service, *wechattest.Server) {
+	t.Helper()
+	srv := wechattest.NewServer()
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	account := &config.WechatAccount{
+		ID:      "mock",
+		AppID:   "mock_appid",
+		Secret:  "mock_secret",
+		APIBase: ts.URL,
+	}
+	return NewService(account, zap.NewNop()), srv
+}
+
+// writeTestPNG 写入一张测试图片
+func writeTestPNG(t *testing.T) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for x := 0; x < 16; x++ {
+		img.Set(x, x, color.RGBA{R: 255, A: 255})
+	}
+
+	path := filepath.Join(t.TempDir(), "test.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNormalizeAPIBase(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", DefaultAPIBase},
+		{"  ", DefaultAPIBase},
+		{"http://127.0.0.1:8090/", "http://127.0.0.1:8090"},
+		{"https://proxy.example.com/wx", "https://proxy.example.com/wx"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeAPIBase(tt.in); got != tt.want {
+			t.Errorf("NormalizeAPIBase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWithEndpointRunsSameBaseConcurrently(t *testing.T) {
+	ws, srv := newMockService(t)
+	other, otherSrv := newMockService(t)
+
+	// 首次调用切换到该地址
+	if _, err := ws.CountDrafts(); err != nil {
+		t.Fatalf("CountDrafts() error = %v", err)
+	}
+
+	// 同一地址的两次调用必须同时进行，才能都通过屏障
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- ws.withEndpoint(func() error {
+				arrived.Done()
+				done := make(chan struct{})
+				go func() { arrived.Wait(); close(done) }()
+				select {
+				case <-done:
+					return nil
+				case <-time.After(2 * time.Second):
+					return errors.New("calls on the same api base were serialized")
+				}
+			})
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 切换地址后请求发往对应的服务
+	if _, err := other.CountDrafts(); err != nil {
+		t.Fatalf("CountDrafts() on other base error = %v", err)
+	}
+	if _, err := ws.CountDrafts(); err != nil {
+		t.Fatalf("CountDrafts() error = %v", err)
+	}
+	if srv.Calls("/cgi-bin/draft/count") != 2 || otherSrv.Calls("/cgi-bin/draft/count") != 1 {
+		t.Errorf("draft/count calls = %d / %d, want 2 / 1",
+			srv.Calls("/cgi-bin/draft/count"), otherSrv.Calls("/cgi-bin/draft/count"))
+	}
+}
+
+func TestServiceUploadAndCreateDraft(t *testing.T) {
+	ws, srv := newMockService(t)
+
+	upload, err := ws.UploadMaterial(writeTestPNG(t))
+	if err != nil {
+		t.Fatalf("UploadMaterial() error = %v", err)
+	}
+	if upload.MediaID == "" || upload.WechatURL == "" {
+		t.Fatalf("UploadMaterial() = %+v, want media_id and url", upload)
+	}
+
+	result, err := ws.CreateDraft([]*Article{{
+		Title:        "测试文章",
+		Content:      "<p>hello</p>",
+		ThumbMediaID: upload.MediaID,
+	}})
+	if err != nil {
+		t.Fatalf("CreateDraft() error = %v", err)
+	}
+
+	d, ok := srv.Draft(result.MediaID)
+	if !ok {
+		t.Fatalf("draft %s not found on mock server", result.MediaID)
+	}
+	if len(d.Articles) != 1 || d.Articles[0].Title != "测试文章" {
+		t.Errorf("draft articles = %+v", d.Articles)
+	}
+
+	// access_token 在同一 Service 内复用
+	if got := srv.Calls("/cgi-bin/token"); got != 1 {
+		t.Errorf("token requested %d times, want 1", got)
+	}
+}
+
+func TestServiceCreateDraftInvalidThumb(t *testing.T) {
+	ws, _ := newMockService(t)
+
+	_, err := ws.CreateDraft([]*Article{{
+		Title:        "测试文章",
+		Content:      "<p>hello</p>",
+		ThumbMediaID: "not-exist",
+	}})
+	if err == nil {
+		t.Fatal("CreateDraft() with unknown thumb_media_id should fail")
+	}
+}
+
+func TestServiceDeleteMaterial(t *testing.T) {
+	ws, srv := newMockService(t)
+
+	upload, err := ws.UploadMaterial(writeTestPNG(t))
+	if err != nil {
+		t.Fatalf("UploadMaterial() error = %v", err)
+	}
+	if err := ws.DeleteMaterial(upload.MediaID); err != nil {
+		t.Fatalf("DeleteMaterial() error = %v", err)
+	}
+	if n := len(srv.Materials()); n != 0 {
+		t.Errorf("materials left = %d, want 0", n)
+	}
+	if err := ws.DeleteMaterial(upload.MediaID); err == nil {
+		t.Error("DeleteMaterial() twice should fail")
+	}
+}
+
+func TestServiceSendPreview(t *testing.T) {
+	ws, srv := newMockService(t)
+
+	upload, err := ws.UploadMaterial(writeTestPNG(t))
+	if err != nil {
+		t.Fatalf("UploadMaterial() error = %v", err)
+	}
+	result, err := ws.CreateDraft([]*Article{{Title: "预览", Content: "x", ThumbMediaID: upload.MediaID}})
+	if err != nil {
+		t.Fatalf("CreateDraft() error = %v", err)
+	}
+
+	if _, err := ws.SendPreview(result.MediaID, PreviewRecipient{WxName: "tester"}); err != nil {
+		t.Fatalf("SendPreview() error = %v", err)
+	}
+	previews := srv.Previews()
+	if len(previews) != 1 || previews[0].ToWxName != "tester" {
+		t.Errorf("previews = %+v", previews)
+	}
+}
+
+func TestServiceInvalidCredential(t *testing.T) {
+	ws, srv := newMockService(t)
+	srv.Secret = "another_secret"
+
+	if _, err := ws.GetAccessToken(); err == nil {
+		t.Fatal("GetAccessToken() with wrong secret should fail")
+	}
+}
+
+func TestUploadVideoAndVoice(t *testing.T) {
+	svc, srv := newMockService(t)
+	dir := t.TempDir()
+	video := filepath.Join(dir, "clip.mp4")
+	voice := filepath.Join(dir, "intro.mp3")
+	for _, path := range []string{video, voice} {
+		if err := os.WriteFile(path, []byte("media"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v, err := svc.UploadVideo(video, "冲泡演示", "第一泡")
+	if err != nil {
+		t.Fatalf("UploadVideo() error = %v", err)
+	}
+	if _, err := svc.UploadVoice(voice); err != nil {
+		t.Fatalf("UploadVoice() error = %v", err)
+	}
+
+	types := map[string]int{}
+	for _, m := range srv.Materials() {
+		types[m.Type]++
+		if m.MediaID == v.MediaID && (m.Title != "冲泡演示" || m.Description != "第一泡") {
+			t.Errorf("video description = %q / %q", m.Title, m.Description)
+		}
+	}
+	if types["video"] != 1 || types["voice"] != 1 {
+		t.Errorf("materials by type = %v, want one video and one voice", types)
+	}
+}
+
+func TestServiceComments(t *testing.T) {
+	svc, srv := newMockService(t)
+	article := CommentArticle{MsgDataID: 2247483653, Index: 1}
+
+	var ids []int64
+	for i := 0; i < MaxCommentPageSize+3; i++ {
+		ids = append(ids, srv.AddComment(article.MsgDataID, article.Index, "openid", "好文"))
+	}
+
+	all, err := svc.AllComments(article, CommentTypeAll)
+	if err != nil {
+		t.Fatalf("AllComments() error = %v", err)
+	}
+	if len(all) != len(ids) {
+		t.Fatalf("AllComments() = %d comments, want %d", len(all), len(ids))
+	}
+
+	if err := svc.ElectComment(article, ids[0], true); err != nil {
+		t.Fatalf("ElectComment() error = %v", err)
+	}
+	if err := svc.ReplyComment(article, ids[1], "谢谢"); err != nil {
+		t.Fatalf("ReplyComment() error = %v", err)
+	}
+	if err := svc.DeleteComment(article, ids[2]); err != nil {
+		t.Fatalf("DeleteComment() error = %v", err)
+	}
+
+	featured, err := svc.AllComments(article, CommentTypeFeatured)
+	if err != nil {
+		t.Fatalf("AllComments(featured) error = %v", err)
+	}
+	if len(featured) != 1 || featured[0].UserCommentID != ids[0] || !featured[0].Featured() {
+		t.Errorf("featured comments = %+v, want only %d", featured, ids[0])
+	}
+
+	page, err := svc.ListComments(article, 0, 2, CommentTypeNormal)
+	if err != nil {
+		t.Fatalf("ListComments() error = %v", err)
+	}
+	if page.Total != len(ids)-2 || len(page.Comments) != 2 {
+		t.Errorf("ListComments(normal) total=%d len=%d, want %d/2", page.Total, len(page.Comments), len(ids)-2)
+	}
+	if page.Comments[0].UserCommentID != ids[1] || !page.Comments[0].Replied() {
+		t.Errorf("first normal comment = %+v, want replied %d", page.Comments[0], ids[1])
+	}
+
+	if err := svc.DeleteCommentReply(article, ids[1]); err != nil {
+		t.Fatalf("DeleteCommentReply() error = %v", err)
+	}
+	if thread := srv.Comments(article.MsgDataID, article.Index); thread.Comments[1].Reply != "" {
+		t.Errorf("reply after delete = %q, want empty", thread.Comments[1].Reply)
+	}
+
+	if err := svc.CloseComment(article); err != nil {
+		t.Fatalf("CloseComment() error = %v", err)
+	}
+	if srv.Comments(article.MsgDataID, article.Index).Open {
+		t.Errorf("comments still open after CloseComment")
+	}
+	if err := svc.DeleteComment(article, 999999); err == nil {
+		t.Errorf("DeleteComment(unknown) should return error")
+	}
+}
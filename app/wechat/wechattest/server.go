@@ -0,0 +1,1236 @@
+// Package wechattest 提供内存版的微信公众平台 API 模拟服务
+//
+// 实现了 access_token、永久素材、图文内图片（uploadimg）、草稿箱、发布、
+// 群发预览、留言管理和图文数据统计接口，用于在没有真实公众号凭证的情况下
+// 离线运行 转换→上传→草稿 的完整流程。
+//
+// 测试中的用法：
+//
+//	srv := wechattest.NewServer()
+//	ts := httptest.NewServer(srv)
+//	defer ts.Close()
+//	account.APIBase = ts.URL
+package wechattest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 微信错误码（与真实接口保持一致）
+const (
+	ErrCodeSystemBusy      = -1
+	ErrCodeInvalidCredit   = 40001
+	ErrCodeInvalidAppID    = 40013
+	ErrCodeInvalidMediaID  = 40007
+	ErrCodeInvalidFileSize = 40009
+	ErrCodeInvalidSecret   = 40125
+	ErrCodeTokenExpired    = 42001
+	ErrCodeInvalidArgument = 47001
+)
+
+// ImageHost 模拟服务返回的图片地址使用的域名（与真实接口一致），
+// 客户端按该域名判断图片已托管在微信，无需特殊处理模拟服务地址
+const ImageHost = "mmbiz.qpic.cn"
+
+// 素材大小限制（与微信接口文档一致）
+const (
+	maxImageSize    = 10 << 20 // 永久图片素材 10MB
+	maxUploadImage  = 1 << 20  // 图文内图片 1MB
+	maxThumbSize    = 64 << 10 // 缩略图 64KB
+	maxVoiceSize    = 2 << 20  // 语音 2MB
+	maxVideoSize    = 10 << 20 // 视频 10MB
+	maxMultipartMem = 32 << 20
+	tokenExpiresIn  = 7200
+	quotaDailyLimit = 1000
+)
+
+// Article 草稿/已发布图文（字段与微信接口一致）
+type Article struct {
+	Title              string `json:"title"`
+	Author             string `json:"author"`
+	Digest             string `json:"digest"`
+	Content            string `json:"content"`
+	ContentSourceURL   string `json:"content_source_url"`
+	ThumbMediaID       string `json:"thumb_media_id"`
+	ShowCoverPic       uint   `json:"show_cover_pic"`
+	NeedOpenComment    uint   `json:"need_open_comment"`
+	OnlyFansCanComment uint   `json:"only_fans_can_comment"`
+	PicCrop2351        string `json:"pic_crop_235_1,omitempty"`
+	PicCrop11          string `json:"pic_crop_1_1,omitempty"`
+	URL                string `json:"url,omitempty"`
+	IsDeleted          bool   `json:"is_deleted,omitempty"`
+}
+
+// Material 永久素材
+type Material struct {
+	MediaID     string
+	Type        string
+	Name        string
+	Data        []byte
+	URL         string
+	Title       string
+	Description string
+	UpdateTime  int64
+}
+
+// Draft 草稿
+type Draft struct {
+	MediaID    string
+	Articles   []Article
+	UpdateTime int64
+}
+
+// PublishJob 发布任务
+type PublishJob struct {
+	PublishID int64
+	MediaID   string
+	ArticleID string
+	Status    int
+}
+
+// Published 已发布图文
+type Published struct {
+	ArticleID  string
+	Articles   []Article
+	UpdateTime int64
+}
+
+// Preview 群发预览记录
+type Preview struct {
+	MediaID  string
+	ToUser   string
+	ToWxName string
+	MsgID    int64
+}
+
+// Comment 用户留言
+type Comment struct {
+	UserCommentID int64
+	OpenID        string
+	Content       string
+	CreateTime    int64
+	Featured      bool
+	Reply         string
+	ReplyTime     int64
+}
+
+// CommentThread 一篇群发图文的留言
+type CommentThread struct {
+	Open     bool
+	Comments []Comment
+}
+
+// Server 内存版微信 API 服务，实现 http.Handler
+type Server struct {
+	// AppID/Secret 为空时接受任意凭证
+	AppID  string
+	Secret string
+
+	mu         sync.Mutex
+	seq        int64
+	tokens     map[string]bool
+	materials  map[string]*Material
+	images     map[string][]byte
+	drafts     map[string]*Draft
+	jobs       map[int64]*PublishJob
+	published  map[string]*Published
+	previews   []Preview
+	comments   map[string]*CommentThread
+	failures   map[string][]failure
+	calls      map[string]int
+	publishing bool
+	mux        *http.ServeMux
+}
+
+type failure struct {
+	code   int
+	msg    string
+	status int // 非 0 时返回该 HTTP 状态码
+}
+
+// NewServer 创建模拟服务
+func NewServer() *Server {
+	s := &Server{
+		tokens:    make(map[string]bool),
+		materials: make(map[string]*Material),
+		images:    make(map[string][]byte),
+		drafts:    make(map[string]*Draft),
+		jobs:      make(map[int64]*PublishJob),
+		published: make(map[string]*Published),
+		comments:  make(map[string]*CommentThread),
+		failures:  make(map[string][]failure),
+		calls:     make(map[string]int),
+		mux:       http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/cgi-bin/token", s.handleToken)
+	s.mux.HandleFunc("/cgi-bin/stable_token", s.handleStableToken)
+
+	s.handle("/cgi-bin/material/add_material", s.handleAddMaterial)
+	s.handle("/cgi-bin/material/del_material", s.handleDelMaterial)
+	s.handle("/cgi-bin/material/get_materialcount", s.handleMaterialCount)
+	s.handle("/cgi-bin/material/batchget_material", s.handleBatchGetMaterial)
+	s.handle("/cgi-bin/media/uploadimg", s.handleUploadImg)
+
+	s.handle("/cgi-bin/draft/add", s.handleDraftAdd)
+	s.handle("/cgi-bin/draft/get", s.handleDraftGet)
+	s.handle("/cgi-bin/draft/update", s.handleDraftUpdate)
+	s.handle("/cgi-bin/draft/delete", s.handleDraftDelete)
+	s.handle("/cgi-bin/draft/count", s.handleDraftCount)
+	s.handle("/cgi-bin/draft/batchget", s.handleDraftBatchGet)
+
+	s.handle("/cgi-bin/freepublish/submit", s.handlePublishSubmit)
+	s.handle("/cgi-bin/freepublish/get", s.handlePublishGet)
+	s.handle("/cgi-bin/freepublish/getarticle", s.handlePublishGetArticle)
+	s.handle("/cgi-bin/freepublish/batchget", s.handlePublishBatchGet)
+	s.handle("/cgi-bin/freepublish/delete", s.handlePublishDelete)
+
+	s.handle("/cgi-bin/message/mass/preview", s.handlePreview)
+	s.handle("/cgi-bin/openapi/quota/get", s.handleQuota)
+	s.handle("/cgi-bin/comment/open", s.handleCommentOpen)
+	s.handle("/cgi-bin/comment/close", s.handleCommentClose)
+	s.handle("/cgi-bin/comment/list", s.handleCommentList)
+	s.handle("/cgi-bin/comment/markelect", s.handleCommentElect)
+	s.handle("/cgi-bin/comment/unmarkelect", s.handleCommentElect)
+	s.handle("/cgi-bin/comment/delete", s.handleCommentDelete)
+	s.handle("/cgi-bin/comment/reply/add", s.handleCommentReply)
+	s.handle("/cgi-bin/comment/reply/delete", s.handleCommentReply)
+	s.handle("/datacube/getarticlesummary", s.handleDatacube)
+	s.handle("/datacube/getarticletotal", s.handleDatacube)
+
+	// 素材文件下载：图片地址的域名为 ImageHost，把该域名指向模拟服务（hosts 或代理）即可访问
+	s.mux.HandleFunc("/mmbiz/", s.handleMedia)
+	s.mux.HandleFunc("/mmbiz_png/", s.handleMedia)
+	s.mux.HandleFunc("/mmbiz_jpg/", s.handleMedia)
+
+	return s
+}
+
+// ServeHTTP 实现 http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ==================== 测试辅助 ====================
+
+// FailNext 让指定接口的下一次调用返回错误码（可多次调用排队）
+// path 形如 "/cgi-bin/draft/add"
+func (s *Server) FailNext(path string, errcode int, errmsg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = append(s.failures[path], failure{code: errcode, msg: errmsg})
+}
+
+// FailNextHTTP 让指定接口的下一次调用返回 HTTP 错误状态码（如 502），模拟网关或网络故障
+func (s *Server) FailNextHTTP(path string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = append(s.failures[path], failure{status: status})
+}
+
+// ExpireTokens 使所有已签发的 access_token 失效（模拟 token 过期）
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]bool)
+}
+
+// SetPublishing 设置发布任务是否停留在"发布中"状态（默认立即发布成功）
+func (s *Server) SetPublishing(pending bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.publishing = pending
+}
+
+// CompletePublish 将发布中的任务置为成功
+func (s *Server) CompletePublish(publishID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[publishID]
+	if !ok {
+		return fmt.Errorf("publish job %d not found", publishID)
+	}
+	s.finishPublishLocked(job)
+	return nil
+}
+
+// Calls 返回指定接口的调用次数（包括失败的调用）
+func (s *Server) Calls(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[path]
+}
+
+// Materials 返回所有永久素材（按 media_id 排序）
+func (s *Server) Materials() []Material {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Material, 0, len(s.materials))
+	for _, m := range s.materials {
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].MediaID < list[j].MediaID })
+	return list
+}
+
+// Draft 返回指定草稿
+func (s *Server) Draft(mediaID string) (Draft, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.drafts[mediaID]
+	if !ok {
+		return Draft{}, false
+	}
+	return Draft{MediaID: d.MediaID, Articles: append([]Article(nil), d.Articles...), UpdateTime: d.UpdateTime}, true
+}
+
+// RemoveDraft 删除草稿（模拟在公众号后台删除或草稿已发布）
+func (s *Server) RemoveDraft(mediaID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.drafts[mediaID]
+	delete(s.drafts, mediaID)
+	return ok
+}
+
+// Drafts 返回所有草稿（按 media_id 排序）
+func (s *Server) Drafts() []Draft {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedDraftsLocked()
+}
+
+// Previews 返回所有群发预览记录
+func (s *Server) Previews() []Preview {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Preview(nil), s.previews...)
+}
+
+// AddComment 为群发图文添加一条用户留言（index 从 0 开始），返回 user_comment_id
+func (s *Server) AddComment(msgDataID int64, index int, openID, content string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.commentThreadLocked(msgDataID, index)
+	thread.Open = true
+	c := Comment{UserCommentID: s.nextIDLocked(), OpenID: openID, Content: content, CreateTime: time.Now().Unix()}
+	thread.Comments = append(thread.Comments, c)
+	return c.UserCommentID
+}
+
+// Comments 返回群发图文的留言状态
+func (s *Server) Comments(msgDataID int64, index int) CommentThread {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.commentThreadLocked(msgDataID, index)
+	return CommentThread{Open: thread.Open, Comments: append([]Comment(nil), thread.Comments...)}
+}
+
+// ==================== 通用处理 ====================
+
+// handle 注册需要 access_token 的接口
+func (s *Server) handle(path string, fn func(w http.ResponseWriter, r *http.Request)) {
+	s.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.calls[path]++
+		if queue := s.failures[path]; len(queue) > 0 {
+			f := queue[0]
+			s.failures[path] = queue[1:]
+			s.mu.Unlock()
+			if f.status != 0 {
+				http.Error(w, http.StatusText(f.status), f.status)
+				return
+			}
+			writeError(w, f.code, f.msg)
+			return
+		}
+		valid := s.tokens[r.URL.Query().Get("access_token")]
+		s.mu.Unlock()
+
+		if !valid {
+			writeError(w, ErrCodeInvalidCredit, "invalid credential, access_token is invalid or not latest")
+			return
+		}
+		fn(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, map[string]any{"errcode": code, "errmsg": msg})
+}
+
+func writeOK(w http.ResponseWriter, fields map[string]any) {
+	resp := map[string]any{"errcode": 0, "errmsg": "ok"}
+	for k, v := range fields {
+		resp[k] = v
+	}
+	writeJSON(w, resp)
+}
+
+func decodeBody(r *http.Request, v any) error {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// nextIDLocked 生成递增 ID
+func (s *Server) nextIDLocked() int64 {
+	s.seq++
+	return s.seq
+}
+
+// baseURL 根据请求推断服务地址，用于生成素材 URL
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// ==================== access_token ====================
+
+func (s *Server) checkCredential(w http.ResponseWriter, appID, secret string) bool {
+	if s.AppID != "" && appID != s.AppID {
+		writeError(w, ErrCodeInvalidAppID, "invalid appid")
+		return false
+	}
+	if s.Secret != "" && secret != s.Secret {
+		writeError(w, ErrCodeInvalidSecret, "invalid appsecret")
+		return false
+	}
+	return true
+}
+
+func (s *Server) issueToken(w http.ResponseWriter) {
+	s.mu.Lock()
+	token := fmt.Sprintf("MOCK_TOKEN_%d_%d", time.Now().UnixNano(), s.nextIDLocked())
+	s.tokens[token] = true
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]any{"access_token": token, "expires_in": tokenExpiresIn})
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.calls["/cgi-bin/token"]++
+	s.mu.Unlock()
+
+	q := r.URL.Query()
+	if q.Get("grant_type") != "client_credential" {
+		writeError(w, ErrCodeInvalidArgument, "invalid grant_type")
+		return
+	}
+	if !s.checkCredential(w, q.Get("appid"), q.Get("secret")) {
+		return
+	}
+	s.issueToken(w)
+}
+
+func (s *Server) handleStableToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.calls["/cgi-bin/stable_token"]++
+	s.mu.Unlock()
+
+	var req struct {
+		AppID  string `json:"appid"`
+		Secret string `json:"secret"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+	if !s.checkCredential(w, req.AppID, req.Secret) {
+		return
+	}
+	s.issueToken(w)
+}
+
+// ==================== 素材 ====================
+
+// readUpload 读取 multipart 中的 media 文件
+func readUpload(r *http.Request) (name string, data []byte, err error) {
+	if err := r.ParseMultipartForm(maxMultipartMem); err != nil {
+		return "", nil, err
+	}
+	file, header, err := r.FormFile("media")
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+	data, err = io.ReadAll(file)
+	return header.Filename, data, err
+}
+
+// formFieldOrFile 读取普通表单字段；SDK 以文件形式发送的字段（如视频 description）也一并支持
+func formFieldOrFile(r *http.Request, name string) string {
+	if v := r.FormValue(name); v != "" {
+		return v
+	}
+	file, _, err := r.FormFile(name)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	data, _ := io.ReadAll(file)
+	return string(data)
+}
+
+func (s *Server) handleAddMaterial(w http.ResponseWriter, r *http.Request) {
+	mediaType := r.URL.Query().Get("type")
+	limit := map[string]int{
+		"image": maxImageSize,
+		"thumb": maxThumbSize,
+		"voice": maxVoiceSize,
+		"video": maxVideoSize,
+	}[mediaType]
+	if limit == 0 {
+		writeError(w, 40004, "invalid media type")
+		return
+	}
+
+	name, data, err := readUpload(r)
+	if err != nil {
+		writeError(w, 41005, "media data missing")
+		return
+	}
+	if len(data) > limit {
+		writeError(w, ErrCodeInvalidFileSize, "invalid media size")
+		return
+	}
+
+	m := &Material{Type: mediaType, Name: name, Data: data, UpdateTime: time.Now().Unix()}
+	if desc := formFieldOrFile(r, "description"); desc != "" {
+		var d struct {
+			Title        string `json:"title"`
+			Introduction string `json:"introduction"`
+		}
+		if err := json.Unmarshal([]byte(desc), &d); err == nil {
+			m.Title, m.Description = d.Title, d.Introduction
+		}
+	}
+
+	s.mu.Lock()
+	m.MediaID = fmt.Sprintf("MOCK_MEDIA_%s_%06d", mediaType, s.nextIDLocked())
+	if mediaType == "image" || mediaType == "thumb" {
+		m.URL = imageURL(m.MediaID, m.Data)
+	}
+	s.materials[m.MediaID] = m
+	s.mu.Unlock()
+
+	resp := map[string]any{"media_id": m.MediaID}
+	if m.URL != "" {
+		resp["url"] = m.URL
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleDelMaterial(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MediaID string `json:"media_id"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+
+	s.mu.Lock()
+	_, ok := s.materials[req.MediaID]
+	delete(s.materials, req.MediaID)
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, ErrCodeInvalidMediaID, "invalid media_id")
+		return
+	}
+	writeOK(w, nil)
+}
+
+func (s *Server) handleMaterialCount(w http.ResponseWriter, r *http.Request) {
+	counts := map[string]int{}
+	s.mu.Lock()
+	for _, m := range s.materials {
+		counts[m.Type]++
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]any{
+		"voice_count": counts["voice"],
+		"video_count": counts["video"],
+		"image_count": counts["image"] + counts["thumb"],
+		"news_count":  0,
+	})
+}
+
+func (s *Server) handleBatchGetMaterial(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Type   string `json:"type"`
+		Offset int    `json:"offset"`
+		Count  int    `json:"count"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+
+	var items []map[string]any
+	for _, m := range s.Materials() {
+		if m.Type != req.Type && !(req.Type == "image" && m.Type == "thumb") {
+			continue
+		}
+		items = append(items, map[string]any{
+			"media_id":    m.MediaID,
+			"name":        m.Name,
+			"url":         m.URL,
+			"update_time": m.UpdateTime,
+		})
+	}
+
+	total := len(items)
+	items = paginate(items, req.Offset, req.Count)
+	writeJSON(w, map[string]any{"total_count": total, "item_count": len(items), "item": items})
+}
+
+func (s *Server) handleUploadImg(w http.ResponseWriter, r *http.Request) {
+	_, data, err := readUpload(r)
+	if err != nil {
+		writeError(w, 41005, "media data missing")
+		return
+	}
+	if len(data) > maxUploadImage {
+		writeError(w, ErrCodeInvalidFileSize, "invalid image size")
+		return
+	}
+
+	s.mu.Lock()
+	id := fmt.Sprintf("MOCK_IMG_%06d", s.nextIDLocked())
+	s.images[id] = data
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]any{"url": imageURL(id, data)})
+}
+
+// imageURL 与真实接口格式一致的图片地址（mmbiz.qpic.cn 域名），
+// 客户端据此识别已托管在微信的图片；内容可通过模拟服务的同名路径获取
+func imageURL(id string, data []byte) string {
+	format := "jpg"
+	if http.DetectContentType(data) == "image/png" {
+		format = "png"
+	}
+	return fmt.Sprintf("http://%s/mmbiz_%s/%s/0?wx_fmt=%s", ImageHost, format, id, format)
+}
+
+// handleMedia 返回素材文件内容，路径为 /mmbiz/<id> 或图片地址中的 /mmbiz_<fmt>/<id>/0
+func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 {
+		http.NotFound(w, r)
+		return
+	}
+	id := parts[1]
+
+	s.mu.Lock()
+	var data []byte
+	if m, ok := s.materials[id]; ok {
+		data = m.Data
+	} else {
+		data = s.images[id]
+	}
+	s.mu.Unlock()
+
+	if data == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	_, _ = w.Write(data)
+}
+
+// ==================== 草稿箱 ====================
+
+// validateArticlesLocked 校验封面素材是否存在
+func (s *Server) validateArticlesLocked(articles []Article) (int, string) {
+	if len(articles) == 0 {
+		return ErrCodeInvalidArgument, "articles is empty"
+	}
+	for _, a := range articles {
+		if a.Title == "" {
+			return 45003, "title size out of limit"
+		}
+		if _, ok := s.materials[a.ThumbMediaID]; !ok {
+			return ErrCodeInvalidMediaID, "invalid media_id"
+		}
+	}
+	return 0, ""
+}
+
+func (s *Server) handleDraftAdd(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Articles []Article `json:"articles"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+
+	s.mu.Lock()
+	if code, msg := s.validateArticlesLocked(req.Articles); code != 0 {
+		s.mu.Unlock()
+		writeError(w, code, msg)
+		return
+	}
+	d := &Draft{
+		MediaID:    fmt.Sprintf("MOCK_DRAFT_%06d", s.nextIDLocked()),
+		Articles:   req.Articles,
+		UpdateTime: time.Now().Unix(),
+	}
+	s.drafts[d.MediaID] = d
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]any{"media_id": d.MediaID})
+}
+
+func (s *Server) handleDraftGet(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MediaID string `json:"media_id"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+
+	d, ok := s.Draft(req.MediaID)
+	if !ok {
+		writeError(w, ErrCodeInvalidMediaID, "invalid media_id")
+		return
+	}
+	writeOK(w, map[string]any{"news_item": d.Articles})
+}
+
+func (s *Server) handleDraftUpdate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MediaID string   `json:"media_id"`
+		Index   int      `json:"index"`
+		Article *Article `json:"articles"`
+	}
+	if err := decodeBody(r, &req); err != nil || req.Article == nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.drafts[req.MediaID]
+	if !ok {
+		writeError(w, ErrCodeInvalidMediaID, "invalid media_id")
+		return
+	}
+	if req.Index < 0 || req.Index >= len(d.Articles) {
+		writeError(w, ErrCodeInvalidArgument, "invalid index")
+		return
+	}
+	if code, msg := s.validateArticlesLocked([]Article{*req.Article}); code != 0 {
+		writeError(w, code, msg)
+		return
+	}
+	d.Articles[req.Index] = *req.Article
+	d.UpdateTime = time.Now().Unix()
+	writeOK(w, nil)
+}
+
+func (s *Server) handleDraftDelete(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MediaID string `json:"media_id"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+
+	s.mu.Lock()
+	_, ok := s.drafts[req.MediaID]
+	delete(s.drafts, req.MediaID)
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, ErrCodeInvalidMediaID, "invalid media_id")
+		return
+	}
+	writeOK(w, nil)
+}
+
+func (s *Server) handleDraftCount(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	total := len(s.drafts)
+	s.mu.Unlock()
+	writeOK(w, map[string]any{"total_count": total})
+}
+
+func (s *Server) handleDraftBatchGet(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Offset    int  `json:"offset"`
+		Count     int  `json:"count"`
+		NoContent bool `json:"no_content"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+
+	drafts := s.Drafts()
+	items := make([]map[string]any, 0, len(drafts))
+	for _, d := range drafts {
+		items = append(items, map[string]any{
+			"media_id":    d.MediaID,
+			"content":     map[string]any{"news_item": stripContent(d.Articles, req.NoContent)},
+			"update_time": d.UpdateTime,
+		})
+	}
+
+	total := len(items)
+	items = paginate(items, req.Offset, req.Count)
+	writeOK(w, map[string]any{"total_count": total, "item_count": len(items), "item": items})
+}
+
+func (s *Server) sortedDraftsLocked() []Draft {
+	list := make([]Draft, 0, len(s.drafts))
+	for _, d := range s.drafts {
+		list = append(list, Draft{MediaID: d.MediaID, Articles: append([]Article(nil), d.Articles...), UpdateTime: d.UpdateTime})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].MediaID < list[j].MediaID })
+	return list
+}
+
+// ==================== 发布 ====================
+
+func (s *Server) handlePublishSubmit(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MediaID string `json:"media_id"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.drafts[req.MediaID]; !ok {
+		writeError(w, ErrCodeInvalidMediaID, "invalid media_id")
+		return
+	}
+	job := &PublishJob{PublishID: 100000 + s.nextIDLocked(), MediaID: req.MediaID, Status: 1}
+	s.jobs[job.PublishID] = job
+	if !s.publishing {
+		s.finishPublishLocked(job)
+	}
+	writeOK(w, map[string]any{"publish_id": job.PublishID})
+}
+
+// finishPublishLocked 发布成功：草稿移出草稿箱，生成 article_id
+func (s *Server) finishPublishLocked(job *PublishJob) {
+	if job.Status == 0 {
+		return
+	}
+	d, ok := s.drafts[job.MediaID]
+	if !ok {
+		job.Status = 3
+		return
+	}
+	job.Status = 0
+	job.ArticleID = fmt.Sprintf("MOCK_ARTICLE_%06d", s.nextIDLocked())
+	articles := append([]Article(nil), d.Articles...)
+	for i := range articles {
+		articles[i].URL = fmt.Sprintf("https://mp.weixin.qq.com/s/%s_%d", job.ArticleID, i+1)
+	}
+	s.published[job.ArticleID] = &Published{ArticleID: job.ArticleID, Articles: articles, UpdateTime: time.Now().Unix()}
+	delete(s.drafts, job.MediaID)
+}
+
+func (s *Server) handlePublishGet(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PublishID json.Number `json:"publish_id"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+	id, _ := req.PublishID.Int64()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		writeError(w, ErrCodeInvalidArgument, "invalid publish_id")
+		return
+	}
+
+	resp := map[string]any{
+		"publish_id":     job.PublishID,
+		"publish_status": job.Status,
+		"fail_idx":       []int{},
+	}
+	if job.Status == 0 {
+		p := s.published[job.ArticleID]
+		items := make([]map[string]any, 0, len(p.Articles))
+		for i, a := range p.Articles {
+			items = append(items, map[string]any{"idx": i + 1, "article_url": a.URL})
+		}
+		resp["article_id"] = job.ArticleID
+		resp["article_detail"] = map[string]any{"count": len(items), "item": items}
+	}
+	writeOK(w, resp)
+}
+
+func (s *Server) handlePublishGetArticle(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ArticleID string `json:"article_id"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+
+	s.mu.Lock()
+	p, ok := s.published[req.ArticleID]
+	var articles []Article
+	if ok {
+		articles = append(articles, p.Articles...)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, ErrCodeInvalidArgument, "invalid article_id")
+		return
+	}
+	writeOK(w, map[string]any{"news_item": articles})
+}
+
+func (s *Server) handlePublishBatchGet(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Offset    int  `json:"offset"`
+		Count     int  `json:"count"`
+		NoContent bool `json:"no_content"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+
+	s.mu.Lock()
+	list := make([]*Published, 0, len(s.published))
+	for _, p := range s.published {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ArticleID > list[j].ArticleID })
+	items := make([]map[string]any, 0, len(list))
+	for _, p := range list {
+		items = append(items, map[string]any{
+			"article_id":  p.ArticleID,
+			"content":     map[string]any{"news_item": stripContent(p.Articles, req.NoContent)},
+			"update_time": p.UpdateTime,
+		})
+	}
+	s.mu.Unlock()
+
+	total := len(items)
+	items = paginate(items, req.Offset, req.Count)
+	writeOK(w, map[string]any{"total_count": total, "item_count": len(items), "item": items})
+}
+
+func (s *Server) handlePublishDelete(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ArticleID string `json:"article_id"`
+		Index     int    `json:"index"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.published[req.ArticleID]
+	if !ok {
+		writeError(w, ErrCodeInvalidArgument, "invalid article_id")
+		return
+	}
+	if req.Index == 0 {
+		delete(s.published, req.ArticleID)
+	} else if req.Index > 0 && req.Index <= len(p.Articles) {
+		p.Articles[req.Index-1].IsDeleted = true
+	} else {
+		writeError(w, ErrCodeInvalidArgument, "invalid index")
+		return
+	}
+	writeOK(w, nil)
+}
+
+// ==================== 预览与数据统计 ====================
+
+func (s *Server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ToUser   string `json:"touser"`
+		ToWxName string `json:"towxname"`
+		MsgType  string `json:"msgtype"`
+		MPNews   struct {
+			MediaID string `json:"media_id"`
+		} `json:"mpnews"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+	if req.ToUser == "" && req.ToWxName == "" {
+		writeError(w, 40003, "invalid openid")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.drafts[req.MPNews.MediaID]; !ok {
+		writeError(w, ErrCodeInvalidMediaID, "invalid media_id")
+		return
+	}
+	p := Preview{MediaID: req.MPNews.MediaID, ToUser: req.ToUser, ToWxName: req.ToWxName, MsgID: s.nextIDLocked()}
+	s.previews = append(s.previews, p)
+	writeOK(w, map[string]any{"msg_id": p.MsgID})
+}
+
+// handleQuota 查询接口额度，used 为模拟服务上该接口的调用次数
+func (s *Server) handleQuota(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CGIPath string `json:"cgi_path"`
+	}
+	if err := decodeBody(r, &req); err != nil || req.CGIPath == "" {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return
+	}
+
+	s.mu.Lock()
+	used := s.calls[req.CGIPath]
+	s.mu.Unlock()
+
+	writeOK(w, map[string]any{"quota": map[string]any{
+		"daily_limit": quotaDailyLimit,
+		"used":        used,
+		"remain":      quotaDailyLimit - used,
+	}})
+}
+
+// handleDatacube 数据统计接口，模拟服务不产生统计数据，返回空列表
+func (s *Server) handleDatacube(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{"list": []any{}})
+}
+
+// ==================== 工具函数 ====================
+
+func paginate(items []map[string]any, offset, count int) []map[string]any {
+	if offset < 0 || offset >= len(items) {
+		return []map[string]any{}
+	}
+	end := len(items)
+	if count > 0 && offset+count < end {
+		end = offset + count
+	}
+	return items[offset:end]
+}
+
+func stripContent(articles []Article, noContent bool) []Article {
+	list := append([]Article(nil), articles...)
+	if noContent {
+		for i := range list {
+			list[i].Content = ""
+		}
+	}
+	return list
+}
+
+// ==================== 留言管理 ====================
+
+// 留言接口错误码
+const (
+	errCodeCommentNotExist  = 88008
+	errCodeCommentReplyLen  = 88007
+	errCodeCommentCountErr  = 88010
+	errCodeCommentMsgDataID = 88001
+)
+
+type commentRequest struct {
+	MsgDataID     int64  `json:"msg_data_id"`
+	Index         int    `json:"index"`
+	Begin         int    `json:"begin"`
+	Count         int    `json:"count"`
+	Type          int    `json:"type"`
+	UserCommentID int64  `json:"user_comment_id"`
+	Content       string `json:"content"`
+}
+
+// commentThreadLocked 返回（必要时创建）群发图文的留言
+func (s *Server) commentThreadLocked(msgDataID int64, index int) *CommentThread {
+	key := fmt.Sprintf("%d_%d", msgDataID, index)
+	thread, ok := s.comments[key]
+	if !ok {
+		thread = &CommentThread{}
+		s.comments[key] = thread
+	}
+	return thread
+}
+
+// decodeCommentRequest 解析留言请求并返回对应的留言，失败时已写入错误响应
+func (s *Server) decodeCommentRequest(w http.ResponseWriter, r *http.Request) (*commentRequest, bool) {
+	var req commentRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, ErrCodeInvalidArgument, "data format error")
+		return nil, false
+	}
+	if req.MsgDataID <= 0 {
+		writeError(w, errCodeCommentMsgDataID, "msg_data is not exist")
+		return nil, false
+	}
+	return &req, true
+}
+
+// findCommentLocked 查找留言
+func (s *Server) findCommentLocked(req *commentRequest) *Comment {
+	thread := s.commentThreadLocked(req.MsgDataID, req.Index)
+	for i := range thread.Comments {
+		if thread.Comments[i].UserCommentID == req.UserCommentID {
+			return &thread.Comments[i]
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleCommentOpen(w http.ResponseWriter, r *http.Request) {
+	s.setCommentOpen(w, r, true)
+}
+
+func (s *Server) handleCommentClose(w http.ResponseWriter, r *http.Request) {
+	s.setCommentOpen(w, r, false)
+}
+
+func (s *Server) setCommentOpen(w http.ResponseWriter, r *http.Request, open bool) {
+	req, ok := s.decodeCommentRequest(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	s.commentThreadLocked(req.MsgDataID, req.Index).Open = open
+	s.mu.Unlock()
+	writeOK(w, nil)
+}
+
+func (s *Server) handleCommentList(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodeCommentRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Count <= 0 || req.Count > 50 {
+		writeError(w, errCodeCommentCountErr, "count range error. cout <= 0 or count > 50")
+		return
+	}
+
+	s.mu.Lock()
+	var matched []Comment
+	for _, c := range s.commentThreadLocked(req.MsgDataID, req.Index).Comments {
+		if (req.Type == 1 && c.Featured) || (req.Type == 2 && !c.Featured) {
+			continue
+		}
+		matched = append(matched, c)
+	}
+	s.mu.Unlock()
+
+	items := make([]map[string]any, 0)
+	for i := req.Begin; i < len(matched) && i < req.Begin+req.Count; i++ {
+		c := matched[i]
+		item := map[string]any{
+			"user_comment_id": c.UserCommentID,
+			"openid":          c.OpenID,
+			"create_time":     c.CreateTime,
+			"content":         c.Content,
+			"comment_type":    0,
+		}
+		if c.Featured {
+			item["comment_type"] = 1
+		}
+		if c.Reply != "" {
+			item["reply"] = map[string]any{"content": c.Reply, "create_time": c.ReplyTime}
+		}
+		items = append(items, item)
+	}
+	writeOK(w, map[string]any{"total": len(matched), "comment": items})
+}
+
+func (s *Server) handleCommentElect(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodeCommentRequest(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.findCommentLocked(req)
+	if c == nil {
+		writeError(w, errCodeCommentNotExist, "comment is not exist")
+		return
+	}
+	c.Featured = strings.HasSuffix(r.URL.Path, "/markelect")
+	writeOK(w, nil)
+}
+
+func (s *Server) handleCommentDelete(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodeCommentRequest(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.commentThreadLocked(req.MsgDataID, req.Index)
+	for i, c := range thread.Comments {
+		if c.UserCommentID == req.UserCommentID {
+			thread.Comments = append(thread.Comments[:i], thread.Comments[i+1:]...)
+			writeOK(w, nil)
+			return
+		}
+	}
+	writeError(w, errCodeCommentNotExist, "comment is not exist")
+}
+
+func (s *Server) handleCommentReply(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodeCommentRequest(w, r)
+	if !ok {
+		return
+	}
+	add := strings.HasSuffix(r.URL.Path, "/reply/add")
+	if add && req.Content == "" {
+		writeError(w, errCodeCommentReplyLen, "reply content beyond max len or content len is zero")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.findCommentLocked(req)
+	if c == nil {
+		writeError(w, errCodeCommentNotExist, "comment is not exist")
+		return
+	}
+	if add {
+		c.Reply, c.ReplyTime = req.Content, time.Now().Unix()
+	} else {
+		c.Reply, c.ReplyTime = "", 0
+	}
+	writeOK(w, nil)
+}
@@ -7,6 +7,7 @@ require (
 	github.com/silenceper/wechat/v2 v2.1.11
 	github.com/spf13/cobra v1.10.2
 	go.uber.org/zap v1.27.1
+	golang.org/x/image v0.35.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -25,6 +26,6 @@ require (
 	github.com/tidwall/pretty v1.2.1 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
 	golang.org/x/crypto v0.47.0 // indirect
-	golang.org/x/image v0.35.0 // indirect
 	golang.org/x/sys v0.40.0 // indirect
+	golang.org/x/text v0.33.0 // indirect
 )